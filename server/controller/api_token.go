@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ---- v1: API tokens ----
+
+// contextTokenKey is the gin context key of the API token a request was made with
+const contextTokenKey = "api_token"
+
+// triggerRunRoutes are the write routes allowed for tokens with the trigger-runs scope
+var triggerRunRoutes = map[string]bool{
+	"/api/v1/backup-profiles/:id/run":     true,
+	"/api/v1/backup-profiles/:id/execute": true,
+	"/api/v1/backup-profiles/:id/dry-run": true,
+}
+
+// authenticateToken authenticates a request by its "Authorization: Bearer" header and
+// enforces the scope and profile restriction of the token
+func authenticateToken(c *gin.Context, header string) {
+	scheme, plain, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(plain) == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "expected a Bearer token"})
+		return
+	}
+	token, err := service.ServiceAuthenticateAPIToken(strings.TrimSpace(plain))
+	if err != nil {
+		if err == service.ErrTokenExpired {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API token"})
+		}
+		return
+	}
+
+	if !tokenScopeAllows(token.Scope, c.Request.Method, c.FullPath()) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token scope " + token.Scope + " does not allow this request"})
+		return
+	}
+	if token.RestrictToProfiles {
+		allowed, err := tokenProfileAllows(c, token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token is not allowed to access this resource"})
+			return
+		}
+	}
+
+	c.Set(contextUserKey, token.User)
+	c.Set(contextTokenKey, token)
+	c.Next()
+}
+
+// tokenScopeAllows reports whether a token scope permits a request to a route
+func tokenScopeAllows(scope, method, route string) bool {
+	switch scope {
+	case entity.TokenScopeAdmin:
+		return true
+	case entity.TokenScopeTriggerRuns:
+		if method == http.MethodPost && triggerRunRoutes[route] {
+			return true
+		}
+	}
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// tokenProfileAllows checks a profile-restricted token against the profile a request
+// refers to. Routes that do not belong to a single profile are refused.
+func tokenProfileAllows(c *gin.Context, token *entity.APIToken) (bool, error) {
+	route := c.FullPath()
+	switch {
+	case route == "/api/v1/auth/me":
+		return true, nil
+
+	case route == "/api/v1/backup-runs":
+		profileID, err := strconv.ParseUint(c.Query("profile_id"), 10, 32)
+		return err == nil && service.TokenAllowsProfile(token, uint(profileID)), nil
+
+	case strings.HasPrefix(route, "/api/v1/backup-profiles/:id"):
+		profileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		return err == nil && service.TokenAllowsProfile(token, uint(profileID)), nil
+
+	case strings.HasPrefix(route, "/api/v1/backup-runs/:id"):
+		runID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return false, nil
+		}
+		return tokenRunAllows(token, uint(runID))
+
+	case strings.HasPrefix(route, "/api/v1/backup-files/:fileId"):
+		fileID, err := strconv.ParseUint(c.Param("fileId"), 10, 32)
+		if err != nil {
+			return false, nil
+		}
+		file, err := service.ServiceGetBackupFile(uint(fileID))
+		if err != nil {
+			// Unknown files are reported as not found by the handler
+			return err == gorm.ErrRecordNotFound, ignoreNotFound(err)
+		}
+		return tokenRunAllows(token, file.BackupRunID)
+	}
+	return false, nil
+}
+
+func tokenRunAllows(token *entity.APIToken, runID uint) (bool, error) {
+	run, err := service.ServiceGetBackupRun(runID)
+	if err != nil {
+		return err == gorm.ErrRecordNotFound, ignoreNotFound(err)
+	}
+	return service.TokenAllowsProfile(token, run.BackupProfileID), nil
+}
+
+func ignoreNotFound(err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	return err
+}
+
+// tokenOwnerFilter limits token management to the user's own tokens unless they are a global admin
+func tokenOwnerFilter(c *gin.Context) (*uint, error) {
+	perms, err := permissions(c)
+	if err != nil {
+		return nil, err
+	}
+	if perms.Global() >= service.RoleLevel(entity.RoleAdmin) {
+		return nil, nil
+	}
+	return &currentUser(c).ID, nil
+}
+
+func handleAPITokensList(c *gin.Context) {
+	owner, err := tokenOwnerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tokens, err := service.ServiceListAPITokens(owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func handleAPITokensCreate(c *gin.Context) {
+	var input entity.APITokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	token, err := service.ServiceCreateAPIToken(currentUser(c).ID, &input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+func handleAPITokenDelete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	owner, err := tokenOwnerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ServiceDeleteAPIToken(uint(id), owner); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
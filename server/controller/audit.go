@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---- v1: Audit log ----
+
+// auditTargetID returns the ID of the target a request refers to. Routes that create
+// their target have none; the ID is then read from the response.
+type auditTargetID func(c *gin.Context) (uint, bool)
+
+// auditParam reads the target ID from a path parameter
+func auditParam(name string) auditTargetID {
+	return func(c *gin.Context) (uint, bool) {
+		id, err := strconv.ParseUint(c.Param(name), 10, 32)
+		return uint(id), err == nil
+	}
+}
+
+// auditCurrentUser targets the signed-in user, e.g. when changing the own password
+func auditCurrentUser(c *gin.Context) (uint, bool) {
+	return currentUser(c).ID, true
+}
+
+// auditSubscriptionEndpoint targets the push subscription whose endpoint is given in the
+// request body. The body is restored for the handler.
+func auditSubscriptionEndpoint(c *gin.Context) (uint, bool) {
+	if service.NotificationSvc == nil {
+		return 0, false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return 0, false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var input struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if json.Unmarshal(body, &input) != nil || input.Endpoint == "" {
+		return 0, false
+	}
+	sub, err := service.NotificationSvc.GetSubscription(input.Endpoint)
+	if err != nil {
+		return 0, false
+	}
+	return sub.ID, true
+}
+
+// responseCapture keeps the response body of create requests to read the new ID
+type responseCapture struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseCapture) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// audited records successful requests in the audit log, with the changes of the target
+func audited(action, targetType string, targetID auditTargetID) gin.HandlerFunc {
+	tracksChanges := action == entity.AuditActionCreate || action == entity.AuditActionUpdate || action == entity.AuditActionDelete
+	return func(c *gin.Context) {
+		var id uint
+		hasID := false
+		if targetID != nil {
+			id, hasID = targetID(c)
+		}
+
+		var before map[string]interface{}
+		if hasID {
+			// A missing target is reported by the handler
+			before, _ = service.ServiceAuditSnapshot(targetType, id)
+		}
+		var capture *responseCapture
+		if targetID == nil {
+			capture = &responseCapture{ResponseWriter: c.Writer}
+			c.Writer = capture
+		}
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		if capture != nil {
+			var created struct {
+				ID uint `json:"id"`
+			}
+			if json.Unmarshal(capture.body.Bytes(), &created) == nil && created.ID != 0 {
+				id, hasID = created.ID, true
+			}
+		}
+
+		entry := &entity.AuditLog{
+			Action:     action,
+			TargetType: targetType,
+			SourceIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+		}
+		if user := currentUser(c); user != nil {
+			entry.UserID = &user.ID
+			entry.Actor = user.Username
+		}
+		if token, ok := c.Get(contextTokenKey); ok {
+			entry.TokenName = token.(*entity.APIToken).Name
+		}
+		if hasID {
+			entry.TargetID = &id
+		}
+
+		var after map[string]interface{}
+		if hasID && action != entity.AuditActionDelete {
+			after, _ = service.ServiceAuditSnapshot(targetType, id)
+		}
+		entry.TargetName = service.AuditTargetName(after)
+		if entry.TargetName == "" {
+			entry.TargetName = service.AuditTargetName(before)
+		}
+		if tracksChanges {
+			entry.Changes = service.AuditChanges(before, after)
+		}
+		if err := service.ServiceRecordAudit(entry); err != nil {
+			requestLog(c).Error("Failed to write audit log entry", "action", action, "target_type", targetType, "error", err)
+		}
+	}
+}
+
+// auditLogFilter reads the audit log filter from the query string
+func auditLogFilter(c *gin.Context) (*entity.AuditLogFilter, bool) {
+	filter := &entity.AuditLogFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+	if value := c.Query("target_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_id"})
+			return nil, false
+		}
+		targetID := uint(id)
+		filter.TargetID = &targetID
+	}
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", expected RFC 3339"})
+				return nil, false
+			}
+			*target = &t
+		}
+	}
+	filter.Page, _ = strconv.Atoi(c.Query("page"))
+	filter.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	return filter, true
+}
+
+func handleAuditLogList(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+	entries, total, err := service.ServiceListAuditLogs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries":   entries,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+func handleAuditLogExport(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	c.Status(http.StatusOK)
+	if err := service.ServiceExportAuditLogs(filter, c.Writer); err != nil {
+		// The status is sent already, so the export just ends early
+		requestLog(c).Error("Failed to export audit log", "error", err)
+	}
+}
@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ---- v1: Authentication ----
+
+// sessionCookieName is the cookie holding the browser session token
+const sessionCookieName = "backapp_session"
+
+// contextUserKey is the gin context key of the authenticated user
+const contextUserKey = "user"
+
+type credentialsInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// authRequired rejects requests without a valid session or API token
+func authRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); header != "" {
+			authenticateToken(c, header)
+			return
+		}
+
+		token, err := c.Cookie(sessionCookieName)
+		if err != nil || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		user, err := service.ServiceGetSessionUser(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session expired"})
+			return
+		}
+		c.Set(contextUserKey, user)
+		c.Next()
+	}
+}
+
+// currentUser returns the user authenticated by authRequired
+func currentUser(c *gin.Context) *entity.User {
+	if user, ok := c.Get(contextUserKey); ok {
+		return user.(*entity.User)
+	}
+	return nil
+}
+
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	// Secure when served over HTTPS directly or behind a TLS-terminating proxy
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, token, maxAge, "/", "", secure, true)
+}
+
+func handleAuthStatus(c *gin.Context) {
+	setupRequired, err := service.ServiceSetupRequired()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := gin.H{"setup_required": setupRequired, "oidc_enabled": service.OIDCSvc != nil}
+	if service.OIDCSvc != nil {
+		status["oidc_provider_name"] = service.OIDCSvc.ProviderName()
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleAuthSetup creates the first user and signs them in
+func handleAuthSetup(c *gin.Context) {
+	var input credentialsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	if _, err := service.ServiceSetupAdmin(input.Username, input.Password); err != nil {
+		if err == service.ErrSetupCompleted {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	handleLoginWith(c, input)
+}
+
+func handleAuthLogin(c *gin.Context) {
+	var input credentialsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	handleLoginWith(c, input)
+}
+
+func handleLoginWith(c *gin.Context, input credentialsInput) {
+	token, user, err := service.ServiceLogin(input.Username, input.Password)
+	if err != nil {
+		if err == service.ErrInvalidCredentials {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	setSessionCookie(c, token, int(service.SessionDuration.Seconds()))
+	c.JSON(http.StatusOK, user)
+}
+
+func handleAuthLogout(c *gin.Context) {
+	if token, err := c.Cookie(sessionCookieName); err == nil && token != "" {
+		if err := service.ServiceLogout(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	setSessionCookie(c, "", -1)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// handleAuthMe returns the signed-in user including their roles
+func handleAuthMe(c *gin.Context) {
+	user, err := service.ServiceGetUser(currentUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// handleAuthChangePassword changes the password of the signed-in user
+func handleAuthChangePassword(c *gin.Context) {
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	user := currentUser(c)
+	if err := service.ServiceVerifyPassword(user.ID, input.CurrentPassword); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return
+	}
+	if _, err := service.ServiceUpdateUserPassword(user.ID, input.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// All sessions were ended by the password change; start a fresh one for this browser
+	handleLoginWith(c, credentialsInput{Username: user.Username, Password: input.NewPassword})
+}
+
+// ---- v1: Users ----
+
+func handleUsersList(c *gin.Context) {
+	users, err := service.ServiceListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// handleUsersCreate creates a user, optionally with a global role
+func handleUsersCreate(c *gin.Context) {
+	var input struct {
+		credentialsInput
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	user, err := service.ServiceCreateUser(input.Username, input.Password, input.Role)
+	if err != nil {
+		if err == service.ErrUsernameTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// handleUserUpdate resets the password of a user
+func handleUserUpdate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	user, err := service.ServiceUpdateUserPassword(uint(id), input.Password)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func handleUserDelete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := service.ServiceDeleteUser(uint(id)); err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case service.ErrLastUser, service.ErrLastAdmin:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
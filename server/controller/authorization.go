@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ---- v1: Role-based access control ----
+
+// contextPermissionsKey caches the permissions of the current user for a request
+const contextPermissionsKey = "permissions"
+
+// roleResolver returns the current user's role level for the resource a request refers
+// to. found is false when the resource does not exist; the handler then reports it.
+type roleResolver func(c *gin.Context, perms *service.Permissions) (level int, found bool, err error)
+
+// permissions returns the permissions of the current user
+func permissions(c *gin.Context) (*service.Permissions, error) {
+	if perms, ok := c.Get(contextPermissionsKey); ok {
+		return perms.(*service.Permissions), nil
+	}
+	perms, err := service.ServiceLoadPermissions(currentUser(c).ID)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(contextPermissionsKey, perms)
+	return perms, nil
+}
+
+// requireRole rejects requests unless the user has at least role on the resource
+func requireRole(role string, resolve roleResolver) gin.HandlerFunc {
+	need := service.RoleLevel(role)
+	return func(c *gin.Context) {
+		perms, err := permissions(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		level, found, err := resolve(c, perms)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if found && level < need {
+			abortForbidden(c, role)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireAnyRole rejects users without any role, e.g. for shared settings
+func requireAnyRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perms, err := permissions(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !perms.Any() {
+			abortForbidden(c, entity.RoleViewer)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortForbidden(c *gin.Context, role string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this requires the " + role + " role"})
+}
+
+// hasRole checks a role inside a handler and reports 403 when it is missing
+func hasRole(c *gin.Context, role string, level func(perms *service.Permissions) int) bool {
+	perms, err := permissions(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if level(perms) < service.RoleLevel(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this requires the " + role + " role"})
+		return false
+	}
+	return true
+}
+
+// visibleProfiles reports for every backup profile ID whether the user may view it
+func visibleProfiles(c *gin.Context) (map[uint]bool, error) {
+	perms, err := permissions(c)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := perms.ProfileLevels()
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[uint]bool, len(levels))
+	for id, level := range levels {
+		visible[id] = level >= service.RoleLevel(entity.RoleViewer)
+	}
+	return visible, nil
+}
+
+// ---- Resolvers ----
+
+func globalRole(_ *gin.Context, perms *service.Permissions) (int, bool, error) {
+	return perms.Global(), true, nil
+}
+
+// serverRole resolves the server in the :id parameter. Users with a role on one of its
+// profiles may view the server.
+func serverRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	level := perms.Server(uint(id))
+	if level == 0 && perms.SeesServer(uint(id)) {
+		level = service.RoleLevel(entity.RoleViewer)
+	}
+	return level, true, nil
+}
+
+// profileRole resolves the backup profile in the :id parameter
+func profileRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	return profileLevel(perms, uint(id))
+}
+
+// profileServerRole resolves the server of the backup profile in the :id parameter
+func profileServerRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	profile, err := service.ServiceGetBackupProfile(uint(id))
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return perms.Server(profile.ServerID), true, nil
+}
+
+// runRole resolves the backup profile of the backup run in the :id parameter
+func runRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	return runLevel(perms, uint(id))
+}
+
+// fileRole resolves the backup profile of the backup file in the :fileId parameter
+func fileRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("fileId"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	file, err := service.ServiceGetBackupFile(uint(id))
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return runLevel(perms, file.BackupRunID)
+}
+
+// commandRole resolves the backup profile of the command in the :id parameter
+func commandRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	cmd, err := service.ServiceGetCommand(uint(id))
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return profileLevel(perms, cmd.BackupProfileID)
+}
+
+// fileRuleRole resolves the backup profile of the file rule in the :id parameter
+func fileRuleRole(c *gin.Context, perms *service.Permissions) (int, bool, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false, nil
+	}
+	rule, err := service.ServiceGetFileRule(uint(id))
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return profileLevel(perms, rule.BackupProfileID)
+}
+
+func profileLevel(perms *service.Permissions, profileID uint) (int, bool, error) {
+	profile, err := service.ServiceGetBackupProfile(profileID)
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return perms.Profile(profile.ID, profile.ServerID), true, nil
+}
+
+func runLevel(perms *service.Permissions, runID uint) (int, bool, error) {
+	run, err := service.ServiceGetBackupRun(runID)
+	if err != nil {
+		return 0, false, ignoreNotFound(err)
+	}
+	return profileLevel(perms, run.BackupProfileID)
+}
+
+// ---- v1: Role assignments ----
+
+func handleUserRolesList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	roles, err := service.ServiceListRoleAssignments(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func handleUserRolesCreate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var input entity.RoleAssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	role, err := service.ServiceCreateRoleAssignment(uint(id), &input)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+func handleUserRoleDelete(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+	if err := service.ServiceDeleteRoleAssignment(uint(userID), uint(roleID)); err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "role assignment not found"})
+		case service.ErrLastAdmin:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role removed"})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -19,7 +20,18 @@ func handleBackupProfilesList(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, profiles)
+	perms, err := permissions(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := profiles[:0]
+	for _, profile := range profiles {
+		if perms.Profile(profile.ID, profile.ServerID) >= service.RoleLevel(entity.RoleViewer) {
+			visible = append(visible, profile)
+		}
+	}
+	c.JSON(http.StatusOK, visible)
 }
 
 func handleBackupProfilesCreate(c *gin.Context) {
@@ -32,9 +44,17 @@ func handleBackupProfilesCreate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
+	// Profiles are created by admins of the server they back up
+	if !hasRole(c, entity.RoleAdmin, func(perms *service.Permissions) int { return perms.Server(input.ServerID) }) {
+		return
+	}
 	profile, err := service.ServiceCreateBackupProfile(&input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrInvalidBackupProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.JSON(http.StatusCreated, profile)
@@ -69,10 +89,18 @@ func handleBackupProfileUpdate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
 		return
 	}
+	// Moving a profile to another server requires admin rights on that server
+	if existing, err := service.ServiceGetBackupProfile(uint(id)); err == nil && input.ServerID != existing.ServerID {
+		if !hasRole(c, entity.RoleAdmin, func(perms *service.Permissions) int { return perms.Server(input.ServerID) }) {
+			return
+		}
+	}
 	profile, err := service.ServiceUpdateBackupProfile(uint(id), &input)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "backup profile not found"})
+		} else if errors.Is(err, service.ErrInvalidBackupProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -234,7 +262,7 @@ func handleBackupProfileDryRun(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	profile, err := service.ServiceGetBackupProfileFull(uint(id))
+	result, err := service.ServiceDryRunBackupProfile(uint(id))
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "backup profile not found"})
@@ -243,10 +271,5 @@ func handleBackupProfileDryRun(c *gin.Context) {
 		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"profile":    profile,
-		"commands":   profile.Commands,
-		"file_rules": profile.FileRules,
-		"message":    "Dry run only, nothing executed",
-	})
+	c.JSON(http.StatusOK, result)
 }
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"archive/zip"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,7 +32,18 @@ func handleBackupRunsList(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, runs)
+	visibleProfileIDs, err := visibleProfiles(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := runs[:0]
+	for _, run := range runs {
+		if visibleProfileIDs[run.BackupProfileID] {
+			visible = append(visible, run)
+		}
+	}
+	c.JSON(http.StatusOK, visible)
 }
 
 func handleBackupRunGet(c *gin.Context) {
@@ -80,35 +92,67 @@ func handleBackupRunDownloadZip(c *gin.Context) {
 	}
 
 	type zipSource struct {
-		file   *os.File
+		file   *os.File // nil for directories and symlinks
+		body   string   // symlink target
 		header *zip.FileHeader
 	}
 
 	sources := make([]zipSource, 0, len(files))
-	for _, file := range files {
+	for i := range files {
+		file := &files[i]
 		if file.Deleted || file.LocalPath == "" {
 			continue
 		}
 
-		stat, err := os.Stat(file.LocalPath)
-		if err != nil {
+		// Symlinks are recorded with their target only
+		if file.FileType == "symlink" {
+			header := &zip.FileHeader{Name: zipEntryName(uint(runID), file.RemotePath, file.LocalPath)}
+			header.SetMode(service.BackupFileMode(file))
+			if file.ModTime != nil {
+				header.Modified = *file.ModTime
+			}
+			header.Extra = append(header.Extra, zipUnixOwnerExtra(file.UID, file.GID)...)
+			sources = append(sources, zipSource{header: header, body: file.LinkTarget})
 			continue
 		}
 
-		openedFile, err := os.Open(file.LocalPath)
+		// Lstat so that a symlink in the backup is never followed to local files
+		stat, err := os.Lstat(file.LocalPath)
 		if err != nil {
 			continue
 		}
 
 		header, err := zip.FileInfoHeader(stat)
 		if err != nil {
-			_ = openedFile.Close()
 			continue
 		}
-		header.Method = zip.Deflate
 		header.Name = zipEntryName(uint(runID), file.RemotePath, file.LocalPath)
+		if file.Mode != 0 {
+			header.SetMode(service.BackupFileMode(file))
+		}
+		if file.ModTime != nil {
+			header.Modified = *file.ModTime
+		}
+		header.Extra = append(header.Extra, zipUnixOwnerExtra(file.UID, file.GID)...)
 
-		sources = append(sources, zipSource{file: openedFile, header: header})
+		source := zipSource{header: header}
+		switch {
+		case stat.Mode()&os.ModeSymlink != 0:
+			continue
+		case stat.IsDir():
+			if file.FileType != "dir" {
+				continue
+			}
+			header.Name += "/"
+		default:
+			openedFile, err := os.Open(file.LocalPath)
+			if err != nil {
+				continue
+			}
+			header.Method = zip.Deflate
+			source.file = openedFile
+		}
+		sources = append(sources, source)
 	}
 
 	if len(sources) == 0 {
@@ -117,7 +161,9 @@ func handleBackupRunDownloadZip(c *gin.Context) {
 	}
 	defer func() {
 		for _, source := range sources {
-			_ = source.file.Close()
+			if source.file != nil {
+				_ = source.file.Close()
+			}
 		}
 	}()
 
@@ -133,6 +179,10 @@ func handleBackupRunDownloadZip(c *gin.Context) {
 		if err != nil {
 			continue
 		}
+		if source.file == nil {
+			_, _ = io.WriteString(writer, source.body)
+			continue
+		}
 		if _, err := source.file.Seek(0, 0); err != nil {
 			continue
 		}
@@ -143,6 +193,19 @@ func handleBackupRunDownloadZip(c *gin.Context) {
 	_ = zipWriter.Close()
 }
 
+// zipUnixOwnerExtra returns an Info-ZIP "ux" extra field so that unzip -X restores ownership
+func zipUnixOwnerExtra(uid, gid int) []byte {
+	extra := make([]byte, 15)
+	binary.LittleEndian.PutUint16(extra[0:], 0x7875) // header ID
+	binary.LittleEndian.PutUint16(extra[2:], 11)     // data size
+	extra[4] = 1                                     // version
+	extra[5] = 4                                     // uid size
+	binary.LittleEndian.PutUint32(extra[6:], uint32(uid))
+	extra[10] = 4 // gid size
+	binary.LittleEndian.PutUint32(extra[11:], uint32(gid))
+	return extra
+}
+
 func zipEntryName(runID uint, remotePath, localPath string) string {
 	name := strings.TrimSpace(remotePath)
 	if name == "" {
@@ -232,11 +295,21 @@ func handleBackupFileDownload(c *gin.Context) {
 		return
 	}
 
-	// Check if file exists on disk
-	if _, err := os.Stat(file.LocalPath); os.IsNotExist(err) {
+	if file.FileType == "symlink" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symlink is not a regular file and can only be downloaded as part of the run archive"})
+		return
+	}
+
+	// Check if file exists on disk, without following symlinks
+	stat, err := os.Lstat(file.LocalPath)
+	if os.IsNotExist(err) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "file not found on disk"})
 		return
 	}
+	if err == nil && !stat.Mode().IsRegular() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not a regular file and can only be downloaded as part of the run archive", file.FileType)})
+		return
+	}
 
 	// Serve the file for download
 	c.FileAttachment(file.LocalPath, filepath.Base(file.RemotePath))
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ---- v1: Backup Run live events (Server-Sent Events) ----
+
+// sseKeepAliveInterval keeps idle event streams open through proxies
+const sseKeepAliveInterval = 15 * time.Second
+
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// handleBackupRunEvents streams the logs of a run followed by live log, progress
+// and state events until the run has finished or the client disconnects
+func handleBackupRunEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	// Subscribe before loading history so no event falls in between
+	hub := service.GetRunEventHub()
+	events, unsubscribe := hub.Subscribe(uint(id))
+	defer unsubscribe()
+
+	run, err := service.ServiceGetBackupRun(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "backup run not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	logs, err := service.ServiceGetBackupRunLogs(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setSSEHeaders(c)
+
+	var lastLogID uint
+	for i := range logs {
+		c.SSEvent("log", logs[i])
+		if logs[i].ID > lastLogID {
+			lastLogID = logs[i].ID
+		}
+	}
+	if run.Status != "running" {
+		c.SSEvent("state", run)
+		c.Writer.Flush()
+		return
+	}
+	if progress := hub.LatestProgress(uint(id)); progress != nil {
+		c.SSEvent("progress", progress)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			// Close the stream if its state event was lost, e.g. after timing out
+			if current, err := service.ServiceGetBackupRun(uint(id)); err == nil && current.Status != "running" {
+				c.SSEvent("state", current)
+				return false
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return true
+		case event := <-events:
+			switch event.Type {
+			case "log":
+				// Skip entries already sent as part of the history
+				if event.Log.ID <= lastLogID {
+					return true
+				}
+				lastLogID = event.Log.ID
+				c.SSEvent("log", event.Log)
+			case "progress":
+				c.SSEvent("progress", event.Progress)
+			case "state":
+				c.SSEvent("state", event.Run)
+				return event.Run.Status == "running"
+			}
+			return true
+		}
+	})
+}
+
+// handleBackupRunsEvents streams state changes of all backup runs for dashboards.
+// Only runs of profiles the user may view are sent.
+func handleBackupRunsEvents(c *gin.Context) {
+	visibleProfileIDs, err := visibleProfiles(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, unsubscribe := service.GetRunEventHub().SubscribeAll()
+	defer unsubscribe()
+
+	setSSEHeaders(c)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return true
+		case event := <-events:
+			profileID := event.Run.BackupProfileID
+			if _, known := visibleProfileIDs[profileID]; !known {
+				// A profile created after subscribing; refresh the visible profiles
+				if refreshed, err := visibleProfiles(c); err == nil {
+					visibleProfileIDs = refreshed
+				}
+			}
+			if visibleProfileIDs[profileID] {
+				c.SSEvent("state", event.Run)
+			}
+			return true
+		}
+	})
+}
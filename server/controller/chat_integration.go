@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// handleListChatIntegrations returns all chat integrations with their preferences
+func handleListChatIntegrations(c *gin.Context) {
+	integrations, err := service.NotificationSvc.ListChatIntegrations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, integrations)
+}
+
+// handleCreateChatIntegration adds a chat integration
+func handleCreateChatIntegration(c *gin.Context) {
+	var input entity.ChatIntegrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	integration, err := service.NotificationSvc.CreateChatIntegration(&input)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidChatIntegration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, integration)
+}
+
+// handleUpdateChatIntegration changes a chat integration
+func handleUpdateChatIntegration(c *gin.Context) {
+	integration, ok := chatIntegrationParam(c)
+	if !ok {
+		return
+	}
+
+	var input entity.ChatIntegrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := service.NotificationSvc.UpdateChatIntegration(integration.ID, &input)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidChatIntegration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// handleDeleteChatIntegration removes a chat integration and its preferences
+func handleDeleteChatIntegration(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := service.NotificationSvc.DeleteChatIntegration(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat integration not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// handleCreateChatIntegrationPreference adds a preference to a chat integration
+func handleCreateChatIntegrationPreference(c *gin.Context) {
+	integration, ok := chatIntegrationParam(c)
+	if !ok {
+		return
+	}
+
+	var input entity.NotificationPreferenceInput
+	if !bindPreferenceInput(c, &input) {
+		return
+	}
+
+	pref, err := service.NotificationSvc.CreateChatIntegrationPreference(integration.ID, &input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pref)
+}
+
+// handleSendTestChat sends a test message through a chat integration
+func handleSendTestChat(c *gin.Context) {
+	integration, ok := chatIntegrationParam(c)
+	if !ok {
+		return
+	}
+
+	if err := service.NotificationSvc.SendTestChat(integration); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "message sent"})
+}
+
+// chatIntegrationParam loads the chat integration of the request
+func chatIntegrationParam(c *gin.Context) (*entity.ChatIntegration, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	integration, err := service.NotificationSvc.GetChatIntegration(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chat integration not found"})
+		return nil, false
+	}
+	return integration, true
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader carries the request ID, taken from a proxy or generated, back to the client
+const requestIDHeader = "X-Request-ID"
+
+// contextRequestIDKey is the gin context key of the request ID
+const contextRequestIDKey = "request_id"
+
+// validRequestID limits request IDs from clients to what is safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestLogger assigns each request an ID, logs the request when it completes and turns
+// panics in handlers into 500 responses
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(contextRequestIDKey, id)
+		c.Header(requestIDHeader, id)
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				requestLog(c).Error("Request panicked", "error", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			}
+
+			status := c.Writer.Status()
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			// The query is left out, as it may hold secrets such as OIDC codes
+			requestLog(c).Log(c.Request.Context(), level, "Request",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"status", status,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"client_ip", c.ClientIP())
+		}()
+		c.Next()
+	}
+}
+
+// requestLog returns the logger of a request, which adds the request ID to messages
+func requestLog(c *gin.Context) *slog.Logger {
+	return slog.With("request_id", c.GetString(contextRequestIDKey))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleMetrics returns the metrics in the Prometheus text format
+func handleMetrics(c *gin.Context) {
+	var body bytes.Buffer
+	if err := service.GetMetrics().Write(&body); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", body.Bytes())
+}
@@ -1,13 +1,16 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"backapp-server/entity"
 	"backapp-server/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // handleGetVAPIDPublicKey returns the VAPID public key for push subscriptions
@@ -146,8 +149,11 @@ func handleCreateNotificationPreference(c *gin.Context) {
 	}
 
 	var input entity.NotificationPreferenceInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindPreferenceInput(c, &input) {
+		return
+	}
+
+	if !hasRole(c, entity.RoleViewer, func(perms *service.Permissions) int { return notificationTargetLevel(perms, &input) }) {
 		return
 	}
 
@@ -174,8 +180,14 @@ func handleUpdateNotificationPreference(c *gin.Context) {
 	}
 
 	var input entity.NotificationPreferenceInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindPreferenceInput(c, &input) {
+		return
+	}
+
+	if !hasRole(c, entity.RoleViewer, func(perms *service.Permissions) int { return notificationTargetLevel(perms, &input) }) {
+		return
+	}
+	if !requireSharedPreferenceAdmin(c, uint(id)) {
 		return
 	}
 
@@ -201,6 +213,10 @@ func handleDeleteNotificationPreference(c *gin.Context) {
 		return
 	}
 
+	if !requireSharedPreferenceAdmin(c, uint(id)) {
+		return
+	}
+
 	if err := service.NotificationSvc.DeletePreference(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -270,3 +286,197 @@ func handleSendTestNotification(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "notification sent"})
 }
+
+// bindPreferenceInput reads and validates a notification preference from the request body
+func bindPreferenceInput(c *gin.Context, input *entity.NotificationPreferenceInput) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	// Consecutive failures start at the second failed run, so lower thresholds never or always match
+	switch {
+	case input.ConsecutiveFailureThreshold == 0:
+		input.ConsecutiveFailureThreshold = 3
+	case input.ConsecutiveFailureThreshold < 2:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "consecutive_failure_threshold must be at least 2"})
+		return false
+	}
+	if input.LowStorageThreshold < 0 || input.LowStorageThreshold > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "low_storage_threshold must be between 0 and 100"})
+		return false
+	}
+	switch input.DigestFrequency {
+	case "", entity.DigestDaily, entity.DigestWeekly:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "digest_frequency must be daily, weekly or empty"})
+		return false
+	}
+	if !validQuietHours(input.QuietHoursStart, input.QuietHoursEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quiet_hours_start and quiet_hours_end must both be empty or different HH:MM times"})
+		return false
+	}
+	return true
+}
+
+// validQuietHours reports whether quiet hours are disabled or two different times
+func validQuietHours(start, end string) bool {
+	if start == "" && end == "" {
+		return true
+	}
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return false
+	}
+	until, err := time.Parse("15:04", end)
+	return err == nil && !from.Equal(until)
+}
+
+// notificationTargetLevel returns the user's role level on what a preference notifies
+// about; preferences for all backups require a global role
+func notificationTargetLevel(perms *service.Permissions, input *entity.NotificationPreferenceInput) int {
+	switch {
+	case input.BackupProfileID != nil:
+		level, _, _ := profileLevel(perms, *input.BackupProfileID)
+		return level
+	case input.ServerID != nil:
+		if perms.SeesServer(*input.ServerID) {
+			return max(perms.Server(*input.ServerID), service.RoleLevel(entity.RoleViewer))
+		}
+		return 0
+	}
+	return perms.Global()
+}
+
+// handleGetEmailStatus reports whether email notifications are configured
+func handleGetEmailStatus(c *gin.Context) {
+	if service.EmailSvc == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "from": service.EmailSvc.From()})
+}
+
+// handleListEmailRecipients returns all email recipients with their preferences
+func handleListEmailRecipients(c *gin.Context) {
+	recipients, err := service.NotificationSvc.ListEmailRecipients()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recipients)
+}
+
+// handleCreateEmailRecipient adds an email recipient
+func handleCreateEmailRecipient(c *gin.Context) {
+	var input struct {
+		Address string `json:"address" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipient, err := service.NotificationSvc.CreateEmailRecipient(input.Address)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrEmailRecipientTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, recipient)
+}
+
+// handleDeleteEmailRecipient removes an email recipient and its preferences
+func handleDeleteEmailRecipient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := service.NotificationSvc.DeleteEmailRecipient(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "email recipient not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// emailRecipientParam loads the email recipient of the request
+func emailRecipientParam(c *gin.Context) (*entity.EmailRecipient, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	recipient, err := service.NotificationSvc.GetEmailRecipient(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "email recipient not found"})
+		return nil, false
+	}
+	return recipient, true
+}
+
+// handleCreateEmailRecipientPreference adds a preference to an email recipient
+func handleCreateEmailRecipientPreference(c *gin.Context) {
+	recipient, ok := emailRecipientParam(c)
+	if !ok {
+		return
+	}
+
+	var input entity.NotificationPreferenceInput
+	if !bindPreferenceInput(c, &input) {
+		return
+	}
+
+	pref, err := service.NotificationSvc.CreateEmailRecipientPreference(recipient.ID, &input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pref)
+}
+
+// handleSendTestEmail sends a test email to a recipient
+func handleSendTestEmail(c *gin.Context) {
+	recipient, ok := emailRecipientParam(c)
+	if !ok {
+		return
+	}
+
+	if err := service.NotificationSvc.SendTestEmail(recipient); err != nil {
+		if errors.Is(err, service.ErrEmailNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email sent"})
+}
+
+// requireSharedPreferenceAdmin lets only global admins change the preferences of email
+// recipients and chat integrations, which are shared unlike the preferences of a browser
+func requireSharedPreferenceAdmin(c *gin.Context, prefID uint) bool {
+	pref, err := service.NotificationSvc.GetPreference(prefID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification preference not found"})
+		return false
+	}
+	if pref.EmailRecipientID == nil && pref.ChatIntegrationID == nil {
+		return true
+	}
+	return hasRole(c, entity.RoleAdmin, func(perms *service.Permissions) int { return perms.Global() })
+}
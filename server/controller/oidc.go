@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---- v1: OpenID Connect single sign-on ----
+
+// oidcStateCookieName binds a login started at the provider to this browser
+const oidcStateCookieName = "backapp_oidc_state"
+
+// oidcCallbackURL derives the callback URL from the request, honouring TLS-terminating proxies
+func oidcCallbackURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/auth/oidc/callback"
+}
+
+// handleOIDCLogin redirects the browser to the identity provider
+func handleOIDCLogin(c *gin.Context) {
+	if service.OIDCSvc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+	authURL, state, err := service.OIDCSvc.AuthorizationURL(service.OIDCSvc.RedirectURL(oidcCallbackURL(c)))
+	if err != nil {
+		requestLog(c).Warn("OIDC login failed", "error", err)
+		redirectWithLoginError(c, "The identity provider is not reachable")
+		return
+	}
+
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, state, 600, "/api/v1/auth/oidc", "", secure, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// handleOIDCCallback completes the login when the provider redirects back
+func handleOIDCCallback(c *gin.Context) {
+	if service.OIDCSvc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		redirectWithLoginError(c, "Sign-in was rejected by the identity provider: "+providerError)
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, err := c.Cookie(oidcStateCookieName)
+	c.SetCookie(oidcStateCookieName, "", -1, "/api/v1/auth/oidc", "", false, true)
+	if err != nil || state == "" || cookieState != state {
+		redirectWithLoginError(c, "Sign-in could not be verified, please try again")
+		return
+	}
+
+	token, user, err := service.OIDCSvc.CompleteLogin(state, c.Query("code"))
+	if err != nil {
+		requestLog(c).Warn("OIDC login failed", "error", err)
+		if err == service.ErrOIDCUsernameTaken {
+			redirectWithLoginError(c, err.Error())
+		} else {
+			redirectWithLoginError(c, "Sign-in failed, please try again")
+		}
+		return
+	}
+	requestLog(c).Info("User signed in with single sign-on", "user", user.Username)
+	setSessionCookie(c, token, int(service.SessionDuration.Seconds()))
+	c.Redirect(http.StatusFound, "/")
+}
+
+// redirectWithLoginError returns to the login page, which shows the message
+func redirectWithLoginError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, "/?login_error="+url.QueryEscape(message))
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"backapp-server/config"
+	"backapp-server/entity"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,84 +15,156 @@ func SetupRouter(r *gin.Engine) {
 	// Health endpoint (root level) for Docker healthcheck
 	r.GET("/health", handleHealth)
 
-	api := r.Group("/api/v1")
+	// Public endpoints: health checks and signing in
+	public := r.Group("/api/v1")
 	{
 		// Health endpoint under API as well
-		api.GET("/health", handleHealth)
+		public.GET("/health", handleHealth)
+		public.GET("/auth/status", handleAuthStatus)
+		public.POST("/auth/setup", handleAuthSetup)
+		public.POST("/auth/login", handleAuthLogin)
+		public.POST("/auth/logout", handleAuthLogout)
+		public.GET("/auth/oidc/login", handleOIDCLogin)
+		public.GET("/auth/oidc/callback", handleOIDCCallback)
+	}
+
+	// Everything else requires a signed-in user or an API token, and a role on the
+	// resource: viewers read, operators also run and download, admins also change
+	viewer, operator, admin := entity.RoleViewer, entity.RoleOperator, entity.RoleAdmin
+	// Changes, manual runs and downloads are recorded in the audit log
+	create, update, remove := entity.AuditActionCreate, entity.AuditActionUpdate, entity.AuditActionDelete
+	run, dryRun, download := entity.AuditActionRun, entity.AuditActionDryRun, entity.AuditActionDownload
+	// Prometheus scrapes metrics with the API token of a user with a global role
+	r.GET("/metrics", authRequired(), requireRole(viewer, globalRole), handleMetrics)
+
+	api := r.Group("/api/v1", authRequired())
+	{
+		api.GET("/auth/me", handleAuthMe)
+		api.PUT("/auth/password", audited(update, entity.AuditTargetUser, auditCurrentUser), handleAuthChangePassword)
+
+		api.GET("/users", requireRole(admin, globalRole), handleUsersList)
+		api.POST("/users", requireRole(admin, globalRole), audited(create, entity.AuditTargetUser, nil), handleUsersCreate)
+		api.PUT("/users/:id", requireRole(admin, globalRole), audited(update, entity.AuditTargetUser, auditParam("id")), handleUserUpdate)
+		api.DELETE("/users/:id", requireRole(admin, globalRole), audited(remove, entity.AuditTargetUser, auditParam("id")), handleUserDelete)
+		api.GET("/users/:id/roles", requireRole(admin, globalRole), handleUserRolesList)
+		api.POST("/users/:id/roles", requireRole(admin, globalRole), audited(create, entity.AuditTargetRoleAssignment, nil), handleUserRolesCreate)
+		api.DELETE("/users/:id/roles/:roleId", requireRole(admin, globalRole), audited(remove, entity.AuditTargetRoleAssignment, auditParam("roleId")), handleUserRoleDelete)
+
+		// Tokens act with the rights of their owner; non-admins manage their own tokens
+		api.GET("/api-tokens", handleAPITokensList)
+		api.POST("/api-tokens", audited(create, entity.AuditTargetAPIToken, nil), handleAPITokensCreate)
+		api.DELETE("/api-tokens/:id", audited(remove, entity.AuditTargetAPIToken, auditParam("id")), handleAPITokenDelete)
+
+		api.GET("/audit-log", requireRole(admin, globalRole), handleAuditLogList)
+		api.GET("/audit-log/export", requireRole(admin, globalRole), handleAuditLogExport)
+
 		api.GET("/servers", handleServersList)
-		api.POST("/servers", handleServersCreate)
-		api.GET("/servers/:id", handleServerGet)
-		api.PUT("/servers/:id", handleServerUpdate)
-		api.DELETE("/servers/:id", handleServerDelete)
-		api.GET("/servers/:id/deletion-impact", handleServerDeletionImpact)
-		api.POST("/servers/:id/test-connection", handleServerTestConnection)
-		api.GET("/servers/:id/files", handleServerListFiles)
-
-		api.GET("/storage-locations", handleStorageLocationsList)
-		api.POST("/storage-locations", handleStorageLocationsCreate)
-		api.PUT("/storage-locations/:id", handleStorageLocationUpdate)
-		api.DELETE("/storage-locations/:id", handleStorageLocationDelete)
-		api.GET("/storage-locations/:id/move-impact", handleStorageLocationMoveImpact)
-		api.GET("/storage-locations/:id/deletion-impact", handleStorageLocationDeletionImpact)
-		api.GET("/local-files", handleLocalFilesList)
-
-		api.GET("/naming-rules", handleNamingRulesList)
-		api.POST("/naming-rules", handleNamingRulesCreate)
-		api.POST("/naming-rules/translate", handleNamingRuleTranslate)
-		api.PUT("/naming-rules/:id", handleNamingRuleUpdate)
-		api.DELETE("/naming-rules/:id", handleNamingRuleDelete)
+		api.POST("/servers", requireRole(admin, globalRole), audited(create, entity.AuditTargetServer, nil), handleServersCreate)
+		api.GET("/servers/:id", requireRole(viewer, serverRole), handleServerGet)
+		api.PUT("/servers/:id", requireRole(admin, serverRole), audited(update, entity.AuditTargetServer, auditParam("id")), handleServerUpdate)
+		api.DELETE("/servers/:id", requireRole(admin, serverRole), audited(remove, entity.AuditTargetServer, auditParam("id")), handleServerDelete)
+		api.GET("/servers/:id/deletion-impact", requireRole(admin, serverRole), handleServerDeletionImpact)
+		api.POST("/servers/:id/test-connection", requireRole(operator, serverRole), handleServerTestConnection)
+		api.GET("/servers/:id/files", requireRole(operator, serverRole), handleServerListFiles)
+
+		api.GET("/storage-locations", requireAnyRole(), handleStorageLocationsList)
+		api.POST("/storage-locations", requireRole(admin, globalRole), audited(create, entity.AuditTargetStorageLocation, nil), handleStorageLocationsCreate)
+		api.PUT("/storage-locations/:id", requireRole(admin, globalRole), audited(update, entity.AuditTargetStorageLocation, auditParam("id")), handleStorageLocationUpdate)
+		api.DELETE("/storage-locations/:id", requireRole(admin, globalRole), audited(remove, entity.AuditTargetStorageLocation, auditParam("id")), handleStorageLocationDelete)
+		api.GET("/storage-locations/:id/move-impact", requireRole(admin, globalRole), handleStorageLocationMoveImpact)
+		api.GET("/storage-locations/:id/deletion-impact", requireRole(admin, globalRole), handleStorageLocationDeletionImpact)
+		api.GET("/local-files", requireRole(admin, globalRole), handleLocalFilesList)
+
+		api.GET("/naming-rules", requireAnyRole(), handleNamingRulesList)
+		api.POST("/naming-rules", requireRole(admin, globalRole), audited(create, entity.AuditTargetNamingRule, nil), handleNamingRulesCreate)
+		api.POST("/naming-rules/translate", requireAnyRole(), handleNamingRuleTranslate)
+		api.PUT("/naming-rules/:id", requireRole(admin, globalRole), audited(update, entity.AuditTargetNamingRule, auditParam("id")), handleNamingRuleUpdate)
+		api.DELETE("/naming-rules/:id", requireRole(admin, globalRole), audited(remove, entity.AuditTargetNamingRule, auditParam("id")), handleNamingRuleDelete)
 
 		api.GET("/backup-profiles", handleBackupProfilesList)
-		api.POST("/backup-profiles", handleBackupProfilesCreate)
-		api.GET("/backup-profiles/:id", handleBackupProfileGet)
-		api.PUT("/backup-profiles/:id", handleBackupProfileUpdate)
-		api.DELETE("/backup-profiles/:id", handleBackupProfileDelete)
-		api.POST("/backup-profiles/:id/duplicate", handleBackupProfileDuplicate)
-		api.GET("/backup-profiles/:id/commands", handleBackupProfileCommandsList)
-		api.POST("/backup-profiles/:id/commands", handleBackupProfileCommandsCreate)
-		api.GET("/backup-profiles/:id/file-rules", handleBackupProfileFileRulesList)
-		api.POST("/backup-profiles/:id/file-rules", handleBackupProfileFileRulesCreate)
-		api.POST("/backup-profiles/:id/run", handleBackupProfileRun)
-		api.POST("/backup-profiles/:id/execute", handleBackupProfileExecute)
-		api.POST("/backup-profiles/:id/dry-run", handleBackupProfileDryRun)
-
-		api.PUT("/commands/:id", handleCommandUpdate)
-		api.DELETE("/commands/:id", handleCommandDelete)
-
-		api.PUT("/file-rules/:id", handleFileRuleUpdate)
-		api.DELETE("/file-rules/:id", handleFileRuleDelete)
+		api.POST("/backup-profiles", audited(create, entity.AuditTargetBackupProfile, nil), handleBackupProfilesCreate)
+		api.GET("/backup-profiles/:id", requireRole(viewer, profileRole), handleBackupProfileGet)
+		api.PUT("/backup-profiles/:id", requireRole(admin, profileRole), audited(update, entity.AuditTargetBackupProfile, auditParam("id")), handleBackupProfileUpdate)
+		api.DELETE("/backup-profiles/:id", requireRole(admin, profileRole), audited(remove, entity.AuditTargetBackupProfile, auditParam("id")), handleBackupProfileDelete)
+		api.POST("/backup-profiles/:id/duplicate", requireRole(admin, profileServerRole), audited(create, entity.AuditTargetBackupProfile, nil), handleBackupProfileDuplicate)
+		api.GET("/backup-profiles/:id/commands", requireRole(viewer, profileRole), handleBackupProfileCommandsList)
+		api.POST("/backup-profiles/:id/commands", requireRole(admin, profileRole), audited(create, entity.AuditTargetCommand, nil), handleBackupProfileCommandsCreate)
+		api.GET("/backup-profiles/:id/file-rules", requireRole(viewer, profileRole), handleBackupProfileFileRulesList)
+		api.POST("/backup-profiles/:id/file-rules", requireRole(admin, profileRole), audited(create, entity.AuditTargetFileRule, nil), handleBackupProfileFileRulesCreate)
+		api.POST("/backup-profiles/:id/run", requireRole(operator, profileRole), audited(run, entity.AuditTargetBackupProfile, auditParam("id")), handleBackupProfileRun)
+		api.POST("/backup-profiles/:id/execute", requireRole(operator, profileRole), audited(run, entity.AuditTargetBackupProfile, auditParam("id")), handleBackupProfileExecute)
+		api.POST("/backup-profiles/:id/dry-run", requireRole(operator, profileRole), audited(dryRun, entity.AuditTargetBackupProfile, auditParam("id")), handleBackupProfileDryRun)
+
+		api.PUT("/commands/:id", requireRole(admin, commandRole), audited(update, entity.AuditTargetCommand, auditParam("id")), handleCommandUpdate)
+		api.DELETE("/commands/:id", requireRole(admin, commandRole), audited(remove, entity.AuditTargetCommand, auditParam("id")), handleCommandDelete)
+
+		api.PUT("/file-rules/:id", requireRole(admin, fileRuleRole), audited(update, entity.AuditTargetFileRule, auditParam("id")), handleFileRuleUpdate)
+		api.DELETE("/file-rules/:id", requireRole(admin, fileRuleRole), audited(remove, entity.AuditTargetFileRule, auditParam("id")), handleFileRuleDelete)
 
 		api.GET("/backup-runs", handleBackupRunsList)
-		api.GET("/backup-runs/:id", handleBackupRunGet)
-		api.GET("/backup-runs/:id/files", handleBackupRunFiles)
-		api.GET("/backup-runs/:id/download-zip", handleBackupRunDownloadZip)
-		api.GET("/backup-runs/:id/logs", handleBackupRunLogs)
-		api.GET("/backup-runs/:id/deletion-impact", handleBackupRunDeletionImpact)
-		api.DELETE("/backup-runs/:id", handleBackupRunDelete)
-		api.GET("/backup-files/:fileId", handleBackupFileGet)
-		api.GET("/backup-files/:fileId/download", handleBackupFileDownload)
-		api.DELETE("/backup-files/:fileId", handleBackupFileDelete)
+		api.GET("/backup-runs/events", handleBackupRunsEvents)
+		api.GET("/backup-runs/:id", requireRole(viewer, runRole), handleBackupRunGet)
+		api.GET("/backup-runs/:id/files", requireRole(viewer, runRole), handleBackupRunFiles)
+		api.GET("/backup-runs/:id/download-zip", requireRole(operator, runRole), audited(download, entity.AuditTargetBackupRun, auditParam("id")), handleBackupRunDownloadZip)
+		api.GET("/backup-runs/:id/logs", requireRole(viewer, runRole), handleBackupRunLogs)
+		api.GET("/backup-runs/:id/events", requireRole(viewer, runRole), handleBackupRunEvents)
+		api.GET("/backup-runs/:id/deletion-impact", requireRole(admin, runRole), handleBackupRunDeletionImpact)
+		api.DELETE("/backup-runs/:id", requireRole(admin, runRole), audited(remove, entity.AuditTargetBackupRun, auditParam("id")), handleBackupRunDelete)
+		api.GET("/backup-files/:fileId", requireRole(viewer, fileRole), handleBackupFileGet)
+		api.GET("/backup-files/:fileId/download", requireRole(operator, fileRole), audited(download, entity.AuditTargetBackupFile, auditParam("fileId")), handleBackupFileDownload)
+		api.DELETE("/backup-files/:fileId", requireRole(admin, fileRole), audited(remove, entity.AuditTargetBackupFile, auditParam("fileId")), handleBackupFileDelete)
 
 		// Push notifications
-		api.GET("/notifications/vapid-key", handleGetVAPIDPublicKey)
-		api.POST("/notifications/subscribe", handleSubscribePush)
-		api.POST("/notifications/unsubscribe", handleUnsubscribePush)
-		api.GET("/notifications/subscription", handleGetSubscription)
-		api.GET("/notifications/preferences", handleGetNotificationPreferences)
-		api.POST("/notifications/preferences", handleCreateNotificationPreference)
-		api.PUT("/notifications/preferences/:id", handleUpdateNotificationPreference)
-		api.DELETE("/notifications/preferences/:id", handleDeleteNotificationPreference)
-		api.POST("/notifications/test", handleSendTestNotification)
+		notifications := api.Group("/notifications", requireAnyRole())
+		notifications.GET("/vapid-key", handleGetVAPIDPublicKey)
+		notifications.POST("/subscribe", audited(create, entity.AuditTargetPushSubscription, nil), handleSubscribePush)
+		notifications.POST("/unsubscribe", audited(remove, entity.AuditTargetPushSubscription, auditSubscriptionEndpoint), handleUnsubscribePush)
+		notifications.GET("/subscription", handleGetSubscription)
+		notifications.GET("/preferences", handleGetNotificationPreferences)
+		notifications.POST("/preferences", audited(create, entity.AuditTargetNotificationPreference, nil), handleCreateNotificationPreference)
+		notifications.PUT("/preferences/:id", audited(update, entity.AuditTargetNotificationPreference, auditParam("id")), handleUpdateNotificationPreference)
+		notifications.DELETE("/preferences/:id", audited(remove, entity.AuditTargetNotificationPreference, auditParam("id")), handleDeleteNotificationPreference)
+		notifications.POST("/test", handleSendTestNotification)
+
+		// Email notifications
+		notifications.GET("/email", handleGetEmailStatus)
+		notifications.GET("/email-recipients", requireRole(admin, globalRole), handleListEmailRecipients)
+		notifications.POST("/email-recipients", requireRole(admin, globalRole), audited(create, entity.AuditTargetEmailRecipient, nil), handleCreateEmailRecipient)
+		notifications.DELETE("/email-recipients/:id", requireRole(admin, globalRole), audited(remove, entity.AuditTargetEmailRecipient, auditParam("id")), handleDeleteEmailRecipient)
+		notifications.POST("/email-recipients/:id/preferences", requireRole(admin, globalRole), audited(create, entity.AuditTargetNotificationPreference, nil), handleCreateEmailRecipientPreference)
+		notifications.POST("/email-recipients/:id/test", requireRole(admin, globalRole), handleSendTestEmail)
+
+		// Webhooks
+		webhooks := notifications.Group("/webhooks", requireRole(admin, globalRole))
+		webhooks.GET("", handleListWebhooks)
+		webhooks.POST("", audited(create, entity.AuditTargetWebhook, nil), handleCreateWebhook)
+		webhooks.PUT("/:id", audited(update, entity.AuditTargetWebhook, auditParam("id")), handleUpdateWebhook)
+		webhooks.DELETE("/:id", audited(remove, entity.AuditTargetWebhook, auditParam("id")), handleDeleteWebhook)
+		webhooks.GET("/:id/deliveries", handleListWebhookDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/redeliver", handleRedeliverWebhook)
+		webhooks.POST("/:id/test", handleSendTestWebhook)
+
+		// Chat integrations
+		chat := notifications.Group("/chat-integrations", requireRole(admin, globalRole))
+		chat.GET("", handleListChatIntegrations)
+		chat.POST("", audited(create, entity.AuditTargetChatIntegration, nil), handleCreateChatIntegration)
+		chat.PUT("/:id", audited(update, entity.AuditTargetChatIntegration, auditParam("id")), handleUpdateChatIntegration)
+		chat.DELETE("/:id", audited(remove, entity.AuditTargetChatIntegration, auditParam("id")), handleDeleteChatIntegration)
+		chat.POST("/:id/preferences", audited(create, entity.AuditTargetNotificationPreference, nil), handleCreateChatIntegrationPreference)
+		chat.POST("/:id/test", handleSendTestChat)
 
 		// Storage usage
-		api.GET("/storage-usage", handleGetStorageUsage)
-		api.GET("/storage-locations/:id/usage", handleGetStorageLocationUsage)
+		api.GET("/storage-usage", requireAnyRole(), handleGetStorageUsage)
+		api.GET("/storage-locations/:id/usage", requireAnyRole(), handleGetStorageLocationUsage)
 
 		// Test-only endpoints
 		if config.TestMode {
-			api.POST("/test/reset-database", handleResetDatabase)
-			api.POST("/test/trigger-retention-cleanup", handleTriggerRetentionCleanup)
-			api.PUT("/test/backup-runs/:id/date", handleUpdateBackupRunDate)
+			api.POST("/test/reset-database", requireRole(admin, globalRole), handleResetDatabase)
+			api.POST("/test/trigger-retention-cleanup", requireRole(admin, globalRole), handleTriggerRetentionCleanup)
+			api.PUT("/test/backup-runs/:id/date", requireRole(admin, globalRole), handleUpdateBackupRunDate)
+			api.POST("/test/trigger-watchdog", requireRole(admin, globalRole), handleTriggerWatchdog)
+			api.POST("/test/trigger-digest", requireRole(admin, globalRole), handleTriggerDigest)
+			api.POST("/test/deliver-held-notifications", requireRole(admin, globalRole), handleDeliverHeldNotifications)
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -21,86 +22,111 @@ func handleServersList(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, servers)
+	perms, err := permissions(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := servers[:0]
+	for _, server := range servers {
+		if perms.SeesServer(server.ID) {
+			visible = append(visible, server)
+		}
+	}
+	c.JSON(http.StatusOK, visible)
 }
 
 func handleServersCreate(c *gin.Context) {
 	ct := c.GetHeader("Content-Type")
 	if strings.HasPrefix(ct, "multipart/form-data") {
-		// Handle browser form uploads with either SSH key file or password
+		// Handle browser form uploads with an SSH key file or any other auth type
 		if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse multipart form"})
 			return
 		}
-		name := c.PostForm("name")
-		host := c.PostForm("host")
-		username := c.PostForm("username")
-		portStr := c.PostForm("port")
-		authType := c.PostForm("auth_type")
-		password := c.PostForm("password")
-		if name == "" || host == "" || username == "" {
+		input := entity.ServerInput{
+			Name:          c.PostForm("name"),
+			Host:          c.PostForm("host"),
+			Port:          22,
+			Username:      c.PostForm("username"),
+			AuthType:      c.PostForm("auth_type"),
+			Password:      c.PostForm("password"),
+			KeyPassphrase: c.PostForm("key_passphrase"),
+			KeyFilePath:   c.PostForm("key_file_path"),
+			AgentSocket:   c.PostForm("agent_socket"),
+			SecretCommand: c.PostForm("secret_command"),
+			Certificate:   c.PostForm("certificate"),
+		}
+		// Browsers send the selected fallbacks comma-separated
+		for _, fallback := range strings.Split(c.PostForm("fallback_auth_types"), ",") {
+			if fallback = strings.TrimSpace(fallback); fallback != "" {
+				input.FallbackAuthTypes = append(input.FallbackAuthTypes, fallback)
+			}
+		}
+		if input.Name == "" || input.Host == "" || input.Username == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 			return
 		}
-		port := 22
-		if portStr != "" {
+		if portStr := c.PostForm("port"); portStr != "" {
 			if p, err := strconv.Atoi(portStr); err == nil {
-				port = p
+				input.Port = p
 			}
 		}
-		if authType == "password" {
-			if password == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "password is required for password auth"})
+		if jumpStr := c.PostForm("jump_host_id"); jumpStr != "" {
+			jumpHostID, err := strconv.ParseUint(jumpStr, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jump_host_id"})
 				return
 			}
-			// Optionally: test password SSH connection here if desired
-			server := &entity.Server{
-				Name:     name,
-				Host:     host,
-				Port:     port,
-				Username: username,
-				AuthType: "password",
-				Password: password,
-			}
-			if err := service.DB.Create(server).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			c.JSON(http.StatusCreated, server)
-			return
-		}
-		// Default to keyfile auth
-		file, _, err := c.Request.FormFile("keyfile")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing or invalid keyfile"})
-			return
+			jump := uint(jumpHostID)
+			input.JumpHostID = &jump
 		}
-		defer file.Close()
-		keyContent, err := io.ReadAll(file)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read key file"})
-			return
+		if input.AuthType == "" {
+			input.AuthType = entity.AuthTypeKey
 		}
-		if len(keyContent) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "key file is empty"})
+		if (input.AuthType == entity.AuthTypePassword || input.AuthType == entity.AuthTypeKeyboardInteractive) && input.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password is required for " + input.AuthType + " auth"})
 			return
 		}
-		// Test the SSH connection before storing
-		if err := service.TestSSHConnection(host, username, string(keyContent), port); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "SSH connection test failed: " + err.Error()})
-			return
+		if file, _, err := c.Request.FormFile("keyfile"); err == nil && input.AuthType != entity.AuthTypeKey {
+			// A key used as fallback is optional
+			keyContent, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read key file"})
+				return
+			}
+			input.PrivateKey = string(keyContent)
 		}
-		server, err := service.ServiceCreateServerWithKey(name, host, port, username, keyContent)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		if input.AuthType == entity.AuthTypeKey {
+			file, _, err := c.Request.FormFile("keyfile")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "missing or invalid keyfile"})
+				return
+			}
+			defer file.Close()
+			keyContent, err := io.ReadAll(file)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read key file"})
+				return
+			}
+			if len(keyContent) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "key file is empty"})
+				return
+			}
+			input.PrivateKey = string(keyContent)
+			// Test the SSH connection before storing
+			if err := service.ServiceTestServerInput(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "SSH connection test failed: " + err.Error()})
+				return
+			}
 		}
-		c.JSON(http.StatusCreated, server)
+		createServer(c, &input)
 		return
 	}
 
 	// JSON body for API clients
-	var input entity.Server
+	var input entity.ServerInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
 		return
@@ -109,9 +135,17 @@ func handleServersCreate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
-	server, err := service.ServiceCreateServerFromJSON(&input)
+	createServer(c, &input)
+}
+
+func createServer(c *gin.Context, input *entity.ServerInput) {
+	server, err := service.ServiceCreateServerFromJSON(input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrInvalidServer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.JSON(http.StatusCreated, server)
@@ -141,14 +175,37 @@ func handleServerUpdate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	var input entity.Server
+	var input entity.ServerInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
 		return
 	}
+	server, err := service.GetServerByID(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "server not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	// Key files, the agent and secret commands of the BackApp host reach beyond this server
+	if service.HostCredentialsChanged(server, &input) &&
+		!hasRole(c, entity.RoleAdmin, func(perms *service.Permissions) int { return perms.Global() }) {
+		return
+	}
+	// Connecting through a jump host uses its credentials
+	if input.JumpHostID != nil && (server.JumpHostID == nil || *server.JumpHostID != *input.JumpHostID) &&
+		!hasRole(c, entity.RoleAdmin, func(perms *service.Permissions) int { return perms.Server(*input.JumpHostID) }) {
+		return
+	}
 	updated, err := service.ServiceUpdateServer(uint(id), &input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrInvalidServer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, updated)
@@ -161,7 +218,11 @@ func handleServerDelete(c *gin.Context) {
 		return
 	}
 	if err := service.ServiceDeleteServer(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrServerIsJumpHost) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.Status(http.StatusOK)
@@ -23,6 +23,81 @@ func handleTriggerRetentionCleanup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "retention cleanup completed"})
 }
 
+// handleTriggerWatchdog checks for missed backups immediately, optionally as if it was the
+// given time
+func handleTriggerWatchdog(c *gin.Context) {
+	var input struct {
+		At string `json:"at"` // RFC3339 format
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	at := time.Now()
+	if input.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, input.At); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at format, use RFC3339"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, service.GetWatchdog().Check(at))
+}
+
+// handleTriggerDigest sends the daily or weekly digests immediately, optionally as if it
+// was the given time
+func handleTriggerDigest(c *gin.Context) {
+	var input struct {
+		Period string `json:"period"`
+		At     string `json:"at"` // RFC3339 format
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Period != entity.DigestDaily && input.Period != entity.DigestWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be daily or weekly"})
+		return
+	}
+
+	at := time.Now()
+	if input.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, input.At); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at format, use RFC3339"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, service.NotificationSvc.SendDigests(input.Period, at))
+}
+
+// handleDeliverHeldNotifications delivers the notifications held by quiet hours that ended,
+// optionally as if it was the given time
+func handleDeliverHeldNotifications(c *gin.Context) {
+	var input struct {
+		At string `json:"at"` // RFC3339 format
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	at := time.Now()
+	if input.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, input.At); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at format, use RFC3339"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, service.NotificationSvc.DeliverHeld(at))
+}
+
 // handleUpdateBackupRunDate updates the end_time of a backup run for testing retention
 func handleUpdateBackupRunDate(c *gin.Context) {
 	runIdStr := c.Param("id")
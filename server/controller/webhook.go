@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backapp-server/entity"
+	"backapp-server/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// handleListWebhooks returns all webhooks
+func handleListWebhooks(c *gin.Context) {
+	webhooks, err := service.NotificationSvc.ListWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// handleCreateWebhook adds a webhook and returns its secret once
+func handleCreateWebhook(c *gin.Context) {
+	var input entity.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := service.NotificationSvc.CreateWebhook(&input)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhook) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// handleUpdateWebhook changes a webhook
+func handleUpdateWebhook(c *gin.Context) {
+	webhook, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	var input entity.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := service.NotificationSvc.UpdateWebhook(webhook.ID, &input)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhook) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// handleDeleteWebhook removes a webhook and its delivery log
+func handleDeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := service.NotificationSvc.DeleteWebhook(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// handleListWebhookDeliveries returns the delivery log of a webhook
+func handleListWebhookDeliveries(c *gin.Context) {
+	webhook, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := service.NotificationSvc.ListWebhookDeliveries(webhook.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// handleRedeliverWebhook sends a logged delivery again and returns the new delivery
+func handleRedeliverWebhook(c *gin.Context) {
+	webhook, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+	delivery, err := service.NotificationSvc.GetWebhookDelivery(webhook.ID, uint(deliveryID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+		return
+	}
+
+	redelivery, err := service.NotificationSvc.RedeliverWebhook(delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, redelivery)
+}
+
+// handleSendTestWebhook sends a ping event and returns its delivery
+func handleSendTestWebhook(c *gin.Context) {
+	webhook, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := service.NotificationSvc.SendTestWebhook(webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// webhookParam loads the webhook of the request
+func webhookParam(c *gin.Context) (*entity.Webhook, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	webhook, err := service.NotificationSvc.GetWebhook(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return nil, false
+	}
+	return webhook, true
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// API token scopes, from least to most privileged
+const (
+	TokenScopeReadOnly    = "read-only"
+	TokenScopeTriggerRuns = "trigger-runs"
+	TokenScopeAdmin       = "admin"
+)
+
+// APIToken authenticates scripts via the Authorization header. It acts on behalf of
+// the user who created it, limited to its scope. Only a hash of the token is stored.
+type APIToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"not null" json:"name"`
+	UserID      uint       `gorm:"not null;index;constraint:OnDelete:CASCADE" json:"user_id"`
+	Scope       string     `gorm:"not null" json:"scope"`
+	TokenPrefix string     `gorm:"not null" json:"token_prefix"` // first characters, to recognize a token
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // nil means the token never expires
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// RestrictToProfiles limits the token to BackupProfiles. It is kept separately so that
+	// deleting the last allowed profile does not turn the token into an unrestricted one.
+	RestrictToProfiles bool            `gorm:"default:false" json:"restrict_to_profiles"`
+	BackupProfiles     []BackupProfile `gorm:"many2many:api_token_profiles;constraint:OnDelete:CASCADE" json:"backup_profiles,omitempty"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	// Token is the plain token. It is only returned once, when the token is created.
+	Token string `gorm:"-" json:"token,omitempty"`
+}
+
+// APITokenInput is used for creating tokens
+type APITokenInput struct {
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	ProfileIDs []uint     `json:"profile_ids"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+// Audit log actions
+const (
+	AuditActionCreate   = "create"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"
+	AuditActionRun      = "run"
+	AuditActionDryRun   = "dry_run"
+	AuditActionDownload = "download"
+)
+
+// Audit log target types
+const (
+	AuditTargetServer                 = "server"
+	AuditTargetStorageLocation        = "storage_location"
+	AuditTargetNamingRule             = "naming_rule"
+	AuditTargetBackupProfile          = "backup_profile"
+	AuditTargetCommand                = "command"
+	AuditTargetFileRule               = "file_rule"
+	AuditTargetBackupRun              = "backup_run"
+	AuditTargetBackupFile             = "backup_file"
+	AuditTargetUser                   = "user"
+	AuditTargetRoleAssignment         = "role_assignment"
+	AuditTargetAPIToken               = "api_token"
+	AuditTargetNotificationPreference = "notification_preference"
+	AuditTargetPushSubscription       = "push_subscription"
+	AuditTargetEmailRecipient         = "email_recipient"
+	AuditTargetWebhook                = "webhook"
+	AuditTargetChatIntegration        = "chat_integration"
+)
+
+// AuditLog records who changed what, or who ran or downloaded a backup. Entries are
+// append-only; the database rejects updates and deletes.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"`
+	Actor     string    `gorm:"index" json:"actor"`
+	// TokenName is set when the request was made with an API token
+	TokenName  string `json:"token_name,omitempty"`
+	Action     string `gorm:"index;not null" json:"action"`
+	TargetType string `gorm:"index;not null" json:"target_type"`
+	TargetID   *uint  `gorm:"index" json:"target_id,omitempty"`
+	// TargetName is the name of the target at the time, kept after it was deleted
+	TargetName string `json:"target_name,omitempty"`
+	// Changes maps changed fields to their old and new values; secrets are redacted
+	Changes  map[string]AuditChange `gorm:"serializer:json" json:"changes,omitempty"`
+	SourceIP string                 `json:"source_ip"`
+	Method   string                 `json:"method"`
+	Path     string                 `json:"path"`
+}
+
+// AuditChange is the old and new value of a field
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogFilter selects audit log entries. Page starts at 1.
+type AuditLogFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   *uint
+	From       *time.Time
+	To         *time.Time
+	Page       int
+	PageSize   int
+}
@@ -0,0 +1,35 @@
+package entity
+
+// DryRunFile is a file that a backup run would transfer
+type DryRunFile struct {
+	FileRuleID uint   `json:"file_rule_id"`
+	RemotePath string `json:"remote_path"`
+	LocalPath  string `json:"local_path"`
+	SizeBytes  int64  `json:"size_bytes"`
+	FileType   string `json:"file_type"` // file, symlink or dir
+}
+
+// DryRunRule summarises what a single file rule would transfer
+type DryRunRule struct {
+	FileRuleID     uint   `json:"file_rule_id"`
+	RemotePath     string `json:"remote_path"`
+	FileCount      int    `json:"file_count"`
+	TotalSizeBytes int64  `json:"total_size_bytes"`
+	Error          string `json:"error,omitempty"`
+}
+
+// BackupDryRun is the result of resolving a backup profile without executing it
+type BackupDryRun struct {
+	BackupProfileID   uint         `json:"backup_profile_id"`
+	BackupPath        string       `json:"backup_path"`
+	Rules             []DryRunRule `json:"rules"`
+	Files             []DryRunFile `json:"files"`
+	Commands          []Command    `json:"commands"`
+	TotalFiles        int          `json:"total_files"`
+	TotalSizeBytes    int64        `json:"total_size_bytes"`
+	StorageLocationID uint         `json:"storage_location_id"`
+	StorageFreeBytes  int64        `json:"storage_free_bytes"`
+	StorageTotalBytes int64        `json:"storage_total_bytes"`
+	Fits              bool         `json:"fits"`
+	Warnings          []string     `json:"warnings"`
+}
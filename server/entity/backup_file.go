@@ -12,6 +12,12 @@ type BackupFile struct {
 	SizeBytes   int64      `json:"size_bytes"`
 	FileSize    int64      `json:"file_size,omitempty"`
 	Checksum    string     `json:"checksum,omitempty"`
+	FileType    string     `gorm:"default:file" json:"file_type"` // file, symlink or dir
+	Mode        uint32     `json:"mode"`                          // unix permission bits of the remote entry
+	UID         int        `json:"uid"`
+	GID         int        `json:"gid"`
+	ModTime     *time.Time `json:"mod_time,omitempty"`
+	LinkTarget  string     `json:"link_target,omitempty"`
 	Deleted     bool       `gorm:"default:false" json:"deleted"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
@@ -14,6 +14,14 @@ type BackupProfile struct {
 	Enabled           bool      `json:"enabled"`
 	CreatedAt         time.Time `json:"created_at"`
 
+	// MissedBackupGraceMinutes is how long after a scheduled time a successful run must have
+	// completed before a missed backup is reported; nil uses the default, 0 turns it off
+	MissedBackupGraceMinutes *int `json:"missed_backup_grace_minutes"`
+
+	// HeartbeatURL is pinged when runs start, complete and fail, the way Healthchecks.io
+	// expects: <url>/start, <url> and <url>/fail
+	HeartbeatURL string `json:"heartbeat_url,omitempty"`
+
 	Server          *Server          `gorm:"foreignKey:ServerID" json:"server,omitempty"`
 	StorageLocation *StorageLocation `gorm:"foreignKey:StorageLocationID" json:"storage_location,omitempty"`
 	NamingRule      *NamingRule      `gorm:"foreignKey:NamingRuleID" json:"naming_rule,omitempty"`
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// BackupRunProgress is a snapshot of how far a running backup has got
+type BackupRunProgress struct {
+	BackupRunID    uint      `json:"backup_run_id"`
+	Stage          string    `json:"stage"` // connect, pre, transfer, post, retention
+	CurrentRule    string    `json:"current_rule,omitempty"`
+	RuleIndex      int       `json:"rule_index"`
+	RuleCount      int       `json:"rule_count"`
+	CurrentFile    string    `json:"current_file,omitempty"`
+	FilesDone      int       `json:"files_done"`
+	FilesTotal     int       `json:"files_total"`
+	BytesDone      int64     `json:"bytes_done"`
+	BytesTotal     int64     `json:"bytes_total"`
+	BytesPerSecond float64   `json:"bytes_per_second"`
+	ETASeconds     int64     `json:"eta_seconds"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BackupRunEvent is pushed to live subscribers of backup runs
+type BackupRunEvent struct {
+	Type        string             `json:"type"` // log, progress, state
+	BackupRunID uint               `json:"backup_run_id"`
+	Log         *BackupRunLog      `json:"log,omitempty"`
+	Progress    *BackupRunProgress `json:"progress,omitempty"`
+	Run         *BackupRun         `json:"run,omitempty"`
+}
@@ -2,12 +2,20 @@ package entity
 
 import "time"
 
+// Levels of run log entries
+const (
+	LogLevelDebug   = "DEBUG"
+	LogLevelInfo    = "INFO"
+	LogLevelWarning = "WARNING"
+	LogLevelError   = "ERROR"
+)
+
 type BackupRunLog struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	BackupRunID uint      `json:"backup_run_id" gorm:"not null;index;constraint:OnDelete:CASCADE"`
 	BackupRun   BackupRun `json:"-" gorm:"foreignKey:BackupRunID"`
 	Timestamp   time.Time `json:"timestamp" gorm:"not null"`
-	Level       string    `json:"level" gorm:"not null"` // INFO, WARNING, ERROR, DEBUG
+	Level       string    `json:"level" gorm:"not null"` // one of the LogLevel constants
 	Message     string    `json:"message" gorm:"type:text;not null"`
 	CreatedAt   time.Time `json:"created_at"`
 }
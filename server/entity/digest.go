@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// Digest frequencies of notification preferences
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Digest summarizes the backups of a scope over a day or a week
+type Digest struct {
+	Period          string    `json:"period"` // daily or weekly
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	BackupProfileID *uint     `json:"backup_profile_id,omitempty"`
+	ServerID        *uint     `json:"server_id,omitempty"`
+	// Scope names the profile or server the digest is limited to, if any
+	Scope              string          `json:"scope,omitempty"`
+	Runs               int             `json:"runs"`
+	Succeeded          int             `json:"succeeded"`
+	Failed             int             `json:"failed"`
+	TransferredBytes   int64           `json:"transferred_bytes"`
+	StorageGrowthBytes int64           `json:"storage_growth_bytes"`
+	Profiles           []DigestProfile `json:"profiles"`
+}
+
+// DigestProfile is the part of a digest about one backup profile
+type DigestProfile struct {
+	BackupProfileID  uint   `json:"backup_profile_id"`
+	BackupProfile    string `json:"backup_profile"`
+	ServerID         uint   `json:"server_id"`
+	Server           string `json:"server"`
+	Runs             int    `json:"runs"`
+	Succeeded        int    `json:"succeeded"`
+	Failed           int    `json:"failed"`
+	TransferredBytes int64  `json:"transferred_bytes"`
+	StoredBytes      int64  `json:"stored_bytes"`
+	// StorageGrowthBytes is the size of files added minus the size of files deleted
+	StorageGrowthBytes int64      `json:"storage_growth_bytes"`
+	LastSuccessAt      *time.Time `json:"last_success_at,omitempty"`
+	// NoRecentSuccess marks enabled profiles without a successful run in the period, unless
+	// their schedule did not expect one
+	NoRecentSuccess bool `json:"no_recent_success"`
+	// RetentionDeletions are the runs retention will delete before the next digest
+	RetentionDeletions     int   `json:"retention_deletions"`
+	RetentionDeletionBytes int64 `json:"retention_deletion_bytes"`
+}
@@ -4,10 +4,13 @@ import "time"
 
 // FileRule defines what files or directories to copy
 type FileRule struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	BackupProfileID uint      `gorm:"not null;constraint:OnDelete:CASCADE" json:"backup_profile_id"`
-	RemotePath      string    `gorm:"not null" json:"remote_path"`
-	Recursive       bool      `gorm:"default:true" json:"recursive"`
-	ExcludePattern  string    `json:"exclude_pattern,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	BackupProfileID  uint       `gorm:"not null;constraint:OnDelete:CASCADE" json:"backup_profile_id"`
+	RemotePath       string     `gorm:"not null" json:"remote_path"`
+	Recursive        bool       `gorm:"default:true" json:"recursive"`
+	IncludePattern   string     `json:"include_pattern,omitempty"`     // gitignore-style patterns, one per line
+	ExcludePattern   string     `json:"exclude_pattern,omitempty"`     // gitignore-style patterns, one per line
+	MaxFileSizeBytes int64      `json:"max_file_size_bytes,omitempty"` // 0 means no limit
+	ModifiedSince    *time.Time `json:"modified_since,omitempty"`      // only files modified after this time, nil means no limit
+	CreatedAt        time.Time  `json:"created_at"`
 }
@@ -12,19 +12,80 @@ type PushSubscription struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// NotificationPreference stores notification settings
+// EmailRecipient receives notifications by email, e.g. a shared ops mailbox
+type EmailRecipient struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Address   string    `gorm:"not null;uniqueIndex" json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Preferences []NotificationPreference `gorm:"foreignKey:EmailRecipientID;constraint:OnDelete:CASCADE" json:"preferences,omitempty"`
+}
+
+// Chat providers
+const (
+	ChatProviderSlack   = "slack"
+	ChatProviderDiscord = "discord"
+	ChatProviderMatrix  = "matrix"
+	ChatProviderNtfy    = "ntfy"
+	ChatProviderGotify  = "gotify"
+)
+
+// ChatIntegration posts notifications to a chat or push service. URL is the incoming
+// webhook URL for Slack and Discord and the server URL otherwise; Channel is the Matrix
+// room ID or the ntfy topic. URL and Token are encrypted with the master key and never
+// returned, as webhook URLs are credentials themselves.
+type ChatIntegration struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	URL       string    `gorm:"not null" json:"-"`
+	Channel   string    `json:"channel"`
+	Token     string    `json:"-"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Preferences []NotificationPreference `gorm:"foreignKey:ChatIntegrationID;constraint:OnDelete:CASCADE" json:"preferences,omitempty"`
+
+	// MaskedURL is the scheme and host of URL, for telling integrations apart
+	MaskedURL string `gorm:"-" json:"masked_url"`
+	HasToken  bool   `gorm:"-" json:"has_token"`
+}
+
+// ChatIntegrationInput is used for creating and updating chat integrations
+type ChatIntegrationInput struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	URL      string `json:"url"` // kept on update when empty
+	Channel  string `json:"channel"`
+	Token    string `json:"token"`   // kept on update when empty
+	Enabled  *bool  `json:"enabled"` // defaults to true on create and is kept on update
+}
+
+// NotificationPreference stores notification settings of a push subscription, an email
+// recipient or a chat integration
 type NotificationPreference struct {
 	ID                          uint  `gorm:"primaryKey" json:"id"`
-	SubscriptionID              uint  `gorm:"not null;constraint:OnDelete:CASCADE" json:"subscription_id"`
+	SubscriptionID              *uint `gorm:"constraint:OnDelete:CASCADE" json:"subscription_id,omitempty"`
+	EmailRecipientID            *uint `gorm:"index" json:"email_recipient_id,omitempty"`
+	ChatIntegrationID           *uint `gorm:"index" json:"chat_integration_id,omitempty"`
 	BackupProfileID             *uint `gorm:"constraint:OnDelete:CASCADE" json:"backup_profile_id,omitempty"`
 	ServerID                    *uint `gorm:"constraint:OnDelete:CASCADE" json:"server_id,omitempty"`
 	NotifyOnStart               bool  `gorm:"default:false" json:"notify_on_start"`
 	NotifyOnSuccess             bool  `gorm:"default:false" json:"notify_on_success"`
 	NotifyOnFailure             bool  `gorm:"default:true" json:"notify_on_failure"`
 	NotifyOnConsecutiveFailures bool  `gorm:"default:true" json:"notify_on_consecutive_failures"`
+	NotifyOnMissedBackup        bool  `gorm:"default:true" json:"notify_on_missed_backup"`
 	ConsecutiveFailureThreshold int   `gorm:"default:3" json:"consecutive_failure_threshold"`
 	NotifyOnLowStorage          bool  `gorm:"default:true" json:"notify_on_low_storage"`
 	LowStorageThreshold         int   `gorm:"default:10" json:"low_storage_threshold"` // percentage
+	// DigestFrequency is DigestDaily or DigestWeekly to receive a summary of the backups in
+	// scope; empty receives none
+	DigestFrequency string `json:"digest_frequency"`
+	// QuietHoursStart and QuietHoursEnd (HH:MM, server time) hold back notifications other
+	// than failures, which are sent together when the quiet hours end; empty disables them
+	QuietHoursStart string `json:"quiet_hours_start"`
+	QuietHoursEnd   string `json:"quiet_hours_end"`
 
 	Subscription  *PushSubscription `gorm:"foreignKey:SubscriptionID" json:"subscription,omitempty"`
 	BackupProfile *BackupProfile    `gorm:"foreignKey:BackupProfileID" json:"backup_profile,omitempty"`
@@ -39,9 +100,14 @@ type NotificationPreferenceInput struct {
 	NotifyOnSuccess             bool  `json:"notify_on_success"`
 	NotifyOnFailure             bool  `json:"notify_on_failure"`
 	NotifyOnConsecutiveFailures bool  `json:"notify_on_consecutive_failures"`
+	NotifyOnMissedBackup        bool  `json:"notify_on_missed_backup"`
 	ConsecutiveFailureThreshold int   `json:"consecutive_failure_threshold"`
 	NotifyOnLowStorage          bool  `json:"notify_on_low_storage"`
 	LowStorageThreshold         int   `json:"low_storage_threshold"`
+
+	DigestFrequency string `json:"digest_frequency"`
+	QuietHoursStart string `json:"quiet_hours_start"`
+	QuietHoursEnd   string `json:"quiet_hours_end"`
 }
 
 // VAPIDKeys stores the VAPID keys for push notifications
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// Roles, from least to most privileged
+const (
+	RoleViewer   = "viewer"   // view profiles, runs and logs
+	RoleOperator = "operator" // additionally run backups and download backed up files
+	RoleAdmin    = "admin"    // additionally edit and delete
+)
+
+// RoleSourceOIDC marks roles that are synced from OpenID Connect claims on every login
+const RoleSourceOIDC = "oidc"
+
+// RoleAssignment grants a user a role globally, for one server and its backup profiles,
+// or for a single backup profile. At most one of ServerID and BackupProfileID is set.
+type RoleAssignment struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"not null;index;constraint:OnDelete:CASCADE" json:"user_id"`
+	Role            string    `gorm:"not null" json:"role"`
+	ServerID        *uint     `gorm:"index;constraint:OnDelete:CASCADE" json:"server_id,omitempty"`
+	BackupProfileID *uint     `gorm:"index;constraint:OnDelete:CASCADE" json:"backup_profile_id,omitempty"`
+	Source          string    `json:"source,omitempty"` // "oidc" for roles synced from single sign-on claims
+	CreatedAt       time.Time `json:"created_at"`
+
+	Server        *Server        `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+	BackupProfile *BackupProfile `gorm:"foreignKey:BackupProfileID" json:"backup_profile,omitempty"`
+}
+
+// RoleAssignmentInput is used for creating role assignments
+type RoleAssignmentInput struct {
+	Role            string `json:"role"`
+	ServerID        *uint  `json:"server_id,omitempty"`
+	BackupProfileID *uint  `json:"backup_profile_id,omitempty"`
+}
@@ -2,15 +2,68 @@ package entity
 
 import "time"
 
-// Server stores SSH connection details
+// Authentication types of a server
+const (
+	// AuthTypePassword uses the stored password
+	AuthTypePassword = "password"
+	// AuthTypeKey uses the stored private key
+	AuthTypeKey = "key"
+	// AuthTypeKeyFile reads the private key from KeyFilePath on every connect
+	AuthTypeKeyFile = "key_file"
+	// AuthTypeAgent signs with the keys of the SSH agent at AgentSocket or SSH_AUTH_SOCK
+	AuthTypeAgent = "agent"
+	// AuthTypePasswordCommand uses the output of SecretCommand as password
+	AuthTypePasswordCommand = "password_command"
+	// AuthTypeKeyCommand uses the output of SecretCommand as private key
+	AuthTypeKeyCommand = "key_command"
+	// AuthTypeKeyboardInteractive answers keyboard-interactive prompts with the stored password
+	AuthTypeKeyboardInteractive = "keyboard_interactive"
+)
+
+// Server stores SSH connection details. Password, PrivateKeyPath and KeyPassphrase are
+// encrypted with the master key and are never returned by the API.
 type Server struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Name           string    `gorm:"not null" json:"name"`
-	Host           string    `gorm:"not null" json:"host"`
-	Port           int       `gorm:"default:22" json:"port"`
-	Username       string    `gorm:"not null" json:"username"`
-	AuthType       string    `gorm:"type:text;check:auth_type IN ('password', 'key')" json:"auth_type"`
-	Password       string    `json:"password,omitempty"`
-	PrivateKeyPath string    `json:"-"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	Name           string `gorm:"not null" json:"name"`
+	Host           string `gorm:"not null" json:"host"`
+	Port           int    `gorm:"default:22" json:"port"`
+	Username       string `gorm:"not null" json:"username"`
+	AuthType       string `gorm:"type:text;check:chk_servers_auth_methods,auth_type IN ('password', 'key', 'key_file', 'agent', 'password_command', 'key_command', 'keyboard_interactive')" json:"auth_type"`
+	Password       string `json:"-"`
+	PrivateKeyPath string `json:"-"`
+	// KeyPassphrase decrypts an encrypted private key of the key, key_file and key_command types
+	KeyPassphrase string `json:"-"`
+	KeyFilePath   string `json:"key_file_path,omitempty"`
+	AgentSocket   string `json:"agent_socket,omitempty"`
+	// SecretCommand is run with sh on the BackApp host, e.g. "pass show servers/web"
+	SecretCommand string `json:"secret_command,omitempty"`
+	// FallbackAuthTypes are tried in order when AuthType is rejected
+	FallbackAuthTypes []string `gorm:"serializer:json" json:"fallback_auth_types,omitempty"`
+	// Certificate is an OpenSSH user certificate presented with the private key
+	Certificate string `json:"certificate,omitempty"`
+	// JumpHostID is the server to connect through; jump hosts may have jump hosts themselves
+	JumpHostID       *uint     `gorm:"index" json:"jump_host_id,omitempty"`
+	HasPassword      bool      `gorm:"-" json:"has_password"`
+	HasPrivateKey    bool      `gorm:"-" json:"has_private_key"`
+	HasKeyPassphrase bool      `gorm:"-" json:"has_key_passphrase"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ServerInput is the request body for creating and updating servers. Empty secrets
+// keep the stored ones on update.
+type ServerInput struct {
+	Name              string   `json:"name"`
+	Host              string   `json:"host"`
+	Port              int      `json:"port"`
+	Username          string   `json:"username"`
+	AuthType          string   `json:"auth_type"`
+	Password          string   `json:"password"`
+	PrivateKey        string   `json:"private_key"`
+	KeyPassphrase     string   `json:"key_passphrase"`
+	KeyFilePath       string   `json:"key_file_path"`
+	AgentSocket       string   `json:"agent_socket"`
+	SecretCommand     string   `json:"secret_command"`
+	FallbackAuthTypes []string `json:"fallback_auth_types"`
+	Certificate       string   `json:"certificate"`
+	JumpHostID        *uint    `json:"jump_host_id"`
 }
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// User is a local account that can sign in to the web UI and API
+type User struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Username     string     `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash string     `gorm:"not null" json:"-"` // empty for users that sign in with single sign-on
+	OIDCSubject  *string    `gorm:"column:oidc_subject;uniqueIndex" json:"oidc_subject,omitempty"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	Roles []RoleAssignment `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"roles,omitempty"`
+}
+
+// Session is a signed-in browser session. Only a hash of the cookie token is stored.
+type Session struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index;constraint:OnDelete:CASCADE" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,80 @@
+package entity
+
+import "time"
+
+// Webhook events
+const (
+	WebhookEventBackupStarted       = "backup.started"
+	WebhookEventBackupCompleted     = "backup.completed"
+	WebhookEventBackupFailed        = "backup.failed"
+	WebhookEventConsecutiveFailures = "backup.consecutive_failures"
+	WebhookEventBackupRecovered     = "backup.recovered"
+	WebhookEventMissedBackup        = "backup.missed"
+	WebhookEventLowStorage          = "storage.low"
+	WebhookEventDailyDigest         = "digest.daily"
+	WebhookEventWeeklyDigest        = "digest.weekly"
+	// WebhookEventPing is only sent when testing an endpoint
+	WebhookEventPing = "ping"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending" // waiting for the next attempt
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // all attempts failed
+)
+
+// Webhook posts signed JSON payloads about backup events to an HTTP endpoint.
+// Secret is encrypted with the master key and never returned by the API.
+type Webhook struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Name   string `gorm:"not null" json:"name"`
+	URL    string `gorm:"not null" json:"url"`
+	Secret string `gorm:"not null" json:"-"`
+	// Events the endpoint receives; empty means all events
+	Events              []string  `gorm:"serializer:json" json:"events"`
+	BackupProfileID     *uint     `gorm:"constraint:OnDelete:CASCADE" json:"backup_profile_id,omitempty"`
+	ServerID            *uint     `gorm:"constraint:OnDelete:CASCADE" json:"server_id,omitempty"`
+	LowStorageThreshold int       `gorm:"default:10" json:"low_storage_threshold"` // percentage
+	Enabled             bool      `json:"enabled"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+
+	BackupProfile *BackupProfile `gorm:"foreignKey:BackupProfileID" json:"backup_profile,omitempty"`
+	Server        *Server        `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+
+	// PlainSecret is only returned when the webhook is created or its secret is replaced
+	PlainSecret string `gorm:"-" json:"secret,omitempty"`
+}
+
+// WebhookInput is used for creating and updating webhooks
+type WebhookInput struct {
+	Name                string   `json:"name"`
+	URL                 string   `json:"url"`
+	Secret              string   `json:"secret"` // generated on create and kept on update when empty
+	Events              []string `json:"events"`
+	BackupProfileID     *uint    `json:"backup_profile_id"`
+	ServerID            *uint    `json:"server_id"`
+	LowStorageThreshold int      `json:"low_storage_threshold"`
+	Enabled             *bool    `json:"enabled"` // defaults to true on create and is kept on update
+}
+
+// WebhookDelivery records sending one event to a webhook, including retries
+type WebhookDelivery struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	WebhookID uint   `gorm:"not null;index;constraint:OnDelete:CASCADE" json:"webhook_id"`
+	Event     string `gorm:"not null" json:"event"`
+	// EventID is the same for redeliveries, so receivers can recognize duplicates
+	EventID       string     `gorm:"not null;index" json:"event_id"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"type:text;not null" json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code,omitempty"`
+	ResponseBody  string     `gorm:"type:text" json:"response_body,omitempty"` // truncated
+	Error         string     `json:"error,omitempty"`
+	DurationMs    int64      `json:"duration_ms"` // of the last attempt
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	RedeliveryOf  *uint      `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
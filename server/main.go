@@ -5,8 +5,11 @@ import (
 	"flag"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"backapp-server/config"
 	"backapp-server/controller"
@@ -40,34 +43,100 @@ func main() {
 	port := flag.Int("port", 8080, "Port to run the server on")
 	dbPath := flag.String("db", "./app.db", "SQLite database path")
 	testMode := flag.Bool("test-mode", false, "Run in test mode with database reset endpoint")
+	masterKeyFile := flag.String("master-key-file", "", "File with the master key that encrypts stored credentials")
+	rotateKeyFile := flag.String("rotate-master-key", "", "Re-encrypt stored credentials with the key in this file (generated if missing) and exit")
+	sshIdleTimeout := flag.Duration("ssh-idle-timeout", 5*time.Minute, "Close pooled SSH connections after being idle this long")
+	sshMaxSessions := flag.Int("ssh-max-sessions", 8, "Maximum concurrent sessions per pooled SSH connection")
+	logLevel := flag.String("log-level", "info", "Minimum level of logged messages: debug, info, warn or error")
+	logFormat := flag.String("log-format", service.LogFormatText, "Log output format: text or json")
+	trustedProxies := flag.String("trusted-proxies", os.Getenv("BACKAPP_TRUSTED_PROXIES"), "Comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For header is trusted")
 	flag.Parse()
 	config.TestMode = *testMode
 
+	if err := service.InitLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Load the master key before the database so that stored credentials can be encrypted
+	if err := service.InitSecrets(*masterKeyFile, *dbPath); err != nil {
+		fatal("Failed to load master key", err)
+	}
+
 	// Initialize database via service layer
 	service.InitDB(*dbPath)
 
+	if *rotateKeyFile != "" {
+		count, err := service.RotateMasterKey(*rotateKeyFile)
+		if err != nil {
+			fatal("Failed to rotate master key", err)
+		}
+		slog.Info("Re-encrypted credentials of servers, webhooks and chat integrations. Start the server with this key from now on.", "credentials", count, "key_file", *rotateKeyFile)
+		return
+	}
+
+	// Share SSH connections between backups, the file explorer and connection tests
+	service.InitSSHPool(*sshIdleTimeout, *sshMaxSessions)
+
+	// Create the first user from the environment if configured
+	service.BootstrapAdminFromEnv()
+
+	// Enable single sign-on if configured
+	if err := service.InitOIDC(); err != nil {
+		fatal("Failed to configure single sign-on", err)
+	}
+
+	// Enable email notifications if configured
+	if err := service.InitEmail(); err != nil {
+		fatal("Failed to configure email notifications", err)
+	}
+
+	// Export traces of backup runs if configured
+	if err := service.InitTracing(); err != nil {
+		fatal("Failed to configure tracing", err)
+	}
+
 	// Initialize notification service
 	if err := service.InitNotificationService(); err != nil {
-		log.Printf("Warning: Failed to initialize notification service: %v", err)
+		slog.Warn("Failed to initialize notification service", "error", err)
 	}
 
 	// Initialize and load scheduled backups
 	scheduler := service.GetScheduler()
 	if err := scheduler.LoadAllSchedules(); err != nil {
-		log.Printf("Warning: Failed to load backup schedules: %v", err)
+		slog.Warn("Failed to load backup schedules", "error", err)
 	}
 
 	// Start retention cleanup scheduler
 	service.StartRetentionScheduler()
 
+	// Report scheduled backups that did not complete in time
+	service.StartWatchdog()
+
+	// Send daily and weekly digests
+	service.StartDigestScheduler()
+
+	// Deliver notifications held during quiet hours
+	service.StartQuietHours()
+
 	// Create a filesystem for embedded static files
 	staticFS, err := fs.Sub(embeddedStaticFiles, "static")
 	if err != nil {
-		log.Fatalf("Failed to create embedded static fs: %v", err)
+		fatal("Failed to create embedded static fs", err)
 	}
 
-	// Initialize gin router and set up routes via controller package
-	router := gin.Default()
+	// Initialize gin router and set up routes via controller package. Requests are logged
+	// with the structured logger instead of gin's logger.
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	// Client IPs in the audit and request logs come from X-Forwarded-For only behind a
+	// trusted proxy, as clients can set the header themselves
+	if err := router.SetTrustedProxies(splitList(*trustedProxies)); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+	router.Use(controller.RequestLogger())
 
 	// Add CORS middleware to allow requests from React frontend
 	router.Use(CORSMiddleware())
@@ -78,7 +147,7 @@ func main() {
 	// Serve static files from embedded filesystem (assets folder)
 	assetsFS, err := fs.Sub(staticFS, "assets")
 	if err != nil {
-		log.Fatalf("Failed to create assets fs: %v", err)
+		fatal("Failed to create assets fs", err)
 	}
 	router.StaticFS("/assets", http.FS(assetsFS))
 
@@ -115,12 +184,29 @@ func main() {
 	})
 
 	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("Server starting on %s...\n", addr)
+	slog.Info("Server starting", "address", addr)
 
 	if config.TestMode {
-		log.Println("\033[41;37m[WARNING] Server is running in TEST MODE, this will enable test endpoints that do cause high security risks\033[0m")
+		slog.Warn("Server is running in TEST MODE, this will enable test endpoints that do cause high security risks")
 	}
 	if err := router.Run(addr); err != nil {
-		log.Fatal(err)
+		fatal("Server stopped", err)
 	}
 }
+
+// splitList returns the non-empty items of a comma-separated list
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fatal logs an error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
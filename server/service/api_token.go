@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+// apiTokenPrefix marks BackApp API tokens, e.g. for secret scanners
+const apiTokenPrefix = "bkp_"
+
+// apiTokenLastUsedInterval limits how often last_used_at is written for a busy token
+const apiTokenLastUsedInterval = time.Minute
+
+var ErrTokenExpired = errors.New("API token has expired")
+
+func validTokenScope(scope string) bool {
+	switch scope {
+	case entity.TokenScopeReadOnly, entity.TokenScopeTriggerRuns, entity.TokenScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// ServiceListAPITokens lists the tokens of ownerID, or all tokens when ownerID is nil
+func ServiceListAPITokens(ownerID *uint) ([]entity.APIToken, error) {
+	var tokens []entity.APIToken
+	query := DB.Preload("User").Preload("BackupProfiles").Order("created_at desc")
+	if ownerID != nil {
+		query = query.Where("user_id = ?", *ownerID)
+	}
+	if err := query.Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// ServiceCreateAPIToken creates a token owned by userID. The plain token is set on the
+// returned entity and cannot be retrieved later.
+func ServiceCreateAPIToken(userID uint, input *entity.APITokenInput) (*entity.APIToken, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if !validTokenScope(input.Scope) {
+		return nil, fmt.Errorf("scope must be one of %s, %s or %s",
+			entity.TokenScopeReadOnly, entity.TokenScopeTriggerRuns, entity.TokenScopeAdmin)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+
+	var profiles []entity.BackupProfile
+	if len(input.ProfileIDs) > 0 {
+		if err := DB.Find(&profiles, input.ProfileIDs).Error; err != nil {
+			return nil, err
+		}
+		if len(profiles) != len(input.ProfileIDs) {
+			return nil, fmt.Errorf("unknown backup profile")
+		}
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	plain := apiTokenPrefix + secret
+
+	token := &entity.APIToken{
+		Name:               name,
+		UserID:             userID,
+		Scope:              input.Scope,
+		TokenPrefix:        plain[:len(apiTokenPrefix)+6],
+		TokenHash:          hashToken(plain),
+		ExpiresAt:          input.ExpiresAt,
+		RestrictToProfiles: len(profiles) > 0,
+		BackupProfiles:     profiles,
+	}
+	if err := DB.Create(token).Error; err != nil {
+		return nil, err
+	}
+	token.Token = plain
+	return token, nil
+}
+
+// ServiceDeleteAPIToken revokes a token of ownerID, or any token when ownerID is nil
+func ServiceDeleteAPIToken(id uint, ownerID *uint) error {
+	var token entity.APIToken
+	query := DB
+	if ownerID != nil {
+		query = query.Where("user_id = ?", *ownerID)
+	}
+	if err := query.First(&token, id).Error; err != nil {
+		return err
+	}
+	return DB.Select("BackupProfiles").Delete(&token).Error
+}
+
+// ServiceAuthenticateAPIToken returns the token and its user for a plain token and
+// records when it was used
+func ServiceAuthenticateAPIToken(plain string) (*entity.APIToken, error) {
+	var token entity.APIToken
+	err := DB.Preload("User").Preload("BackupProfiles").Where("token_hash = ?", hashToken(plain)).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	if token.User == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenLastUsedInterval {
+		if err := DB.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			slog.Error("Failed to update last use of API token", "api_token_id", token.ID, "error", err)
+		}
+		token.LastUsedAt = &now
+	}
+	return &token, nil
+}
+
+// TokenAllowsProfile reports whether a token may access a backup profile
+func TokenAllowsProfile(token *entity.APIToken, profileID uint) bool {
+	if !token.RestrictToProfiles {
+		return true
+	}
+	for _, profile := range token.BackupProfiles {
+		if profile.ID == profileID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAuditPageSize is the page size when none is requested
+	defaultAuditPageSize = 50
+	// maxAuditPageSize limits the page size of the audit log API
+	maxAuditPageSize = 500
+)
+
+// redactedValue replaces secrets in audit log changes
+const redactedValue = "[redacted]"
+
+// auditModels maps audit target types to their models
+var auditModels = map[string]func() interface{}{
+	entity.AuditTargetServer:                 func() interface{} { return &entity.Server{} },
+	entity.AuditTargetStorageLocation:        func() interface{} { return &entity.StorageLocation{} },
+	entity.AuditTargetNamingRule:             func() interface{} { return &entity.NamingRule{} },
+	entity.AuditTargetBackupProfile:          func() interface{} { return &entity.BackupProfile{} },
+	entity.AuditTargetCommand:                func() interface{} { return &entity.Command{} },
+	entity.AuditTargetFileRule:               func() interface{} { return &entity.FileRule{} },
+	entity.AuditTargetBackupRun:              func() interface{} { return &entity.BackupRun{} },
+	entity.AuditTargetBackupFile:             func() interface{} { return &entity.BackupFile{} },
+	entity.AuditTargetUser:                   func() interface{} { return &entity.User{} },
+	entity.AuditTargetRoleAssignment:         func() interface{} { return &entity.RoleAssignment{} },
+	entity.AuditTargetAPIToken:               func() interface{} { return &entity.APIToken{} },
+	entity.AuditTargetNotificationPreference: func() interface{} { return &entity.NotificationPreference{} },
+	entity.AuditTargetPushSubscription:       func() interface{} { return &entity.PushSubscription{} },
+	entity.AuditTargetEmailRecipient:         func() interface{} { return &entity.EmailRecipient{} },
+	entity.AuditTargetWebhook:                func() interface{} { return &entity.Webhook{} },
+	entity.AuditTargetChatIntegration:        func() interface{} { return &entity.ChatIntegration{} },
+}
+
+// auditSecretColumns are never written to the audit log, only whether they changed
+var auditSecretColumns = map[string]bool{
+	"password":         true,
+	"password_hash":    true,
+	"private_key_path": true,
+	"key_passphrase":   true,
+	"token_hash":       true,
+	"p256dh":           true,
+	"auth":             true,
+	"secret":           true,
+	"token":            true,
+	"url":              true,
+}
+
+// auditIgnoredColumns change on every save and would only add noise
+var auditIgnoredColumns = map[string]bool{
+	"updated_at": true,
+}
+
+// ServiceAuditSnapshot returns the stored columns of an audit target, including secrets.
+// It is only used to compute changes, which redact the secrets.
+func ServiceAuditSnapshot(targetType string, id uint) (map[string]interface{}, error) {
+	model, ok := auditModels[targetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit target type %q", targetType)
+	}
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(model()); err != nil {
+		return nil, err
+	}
+	// Reading the table instead of the model keeps columns with serializers raw, which
+	// cannot be scanned into a map
+	row := map[string]interface{}{}
+	if err := DB.Table(stmt.Schema.Table).Where("id = ?", id).Take(&row).Error; err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// AuditTargetName returns a readable name of a snapshot
+func AuditTargetName(snapshot map[string]interface{}) string {
+	for _, column := range []string{"name", "username", "remote_path", "command", "local_path", "endpoint", "address"} {
+		if value, ok := snapshot[column].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// AuditChanges compares two snapshots column by column. A nil snapshot stands for a
+// target that does not exist yet or anymore.
+func AuditChanges(before, after map[string]interface{}) map[string]entity.AuditChange {
+	changes := map[string]entity.AuditChange{}
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+	for column := range columns {
+		if auditIgnoredColumns[column] {
+			continue
+		}
+		oldValue, newValue := auditValue(before[column]), auditValue(after[column])
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if auditSecretColumns[column] {
+			changes[column] = entity.AuditChange{Before: redactSecret(oldValue), After: redactSecret(newValue)}
+		} else {
+			changes[column] = entity.AuditChange{Before: oldValue, After: newValue}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// auditValue normalizes a column value for comparison and JSON
+func auditValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// redactSecret hides a secret but keeps whether it was set
+func redactSecret(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return redactedValue
+}
+
+// ServiceRecordAudit appends an entry to the audit log
+func ServiceRecordAudit(entry *entity.AuditLog) error {
+	return DB.Create(entry).Error
+}
+
+// auditQuery applies a filter to the audit log, newest first
+func auditQuery(filter *entity.AuditLogFilter) *gorm.DB {
+	query := DB.Model(&entity.AuditLog{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query.Order("created_at DESC, id DESC")
+}
+
+// ServiceListAuditLogs returns a page of audit log entries and the total number of matches
+func ServiceListAuditLogs(filter *entity.AuditLogFilter) ([]entity.AuditLog, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultAuditPageSize
+	}
+	if filter.PageSize > maxAuditPageSize {
+		filter.PageSize = maxAuditPageSize
+	}
+	var total int64
+	if err := auditQuery(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []entity.AuditLog
+	err := auditQuery(filter).Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// ServiceExportAuditLogs writes all matching entries as JSON lines, newest first
+func ServiceExportAuditLogs(filter *entity.AuditLogFilter, w io.Writer) error {
+	rows, err := auditQuery(filter).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	for rows.Next() {
+		var entry entity.AuditLog
+		if err := DB.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := encoder.Encode(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
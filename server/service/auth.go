@@ -0,0 +1,333 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SessionDuration is how long a browser session stays valid after login
+const SessionDuration = 7 * 24 * time.Hour
+
+// minPasswordLength is the minimum length of user passwords
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrSetupCompleted     = errors.New("initial setup has already been completed")
+	ErrLastUser           = errors.New("the last user cannot be deleted")
+	ErrUsernameTaken      = errors.New("username is already taken")
+)
+
+// dummyPasswordHash is compared against when a user does not exist, so that login
+// takes the same time for unknown users and wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("backapp-dummy-password"), bcrypt.DefaultCost)
+
+func validateCredentials(username, password string) error {
+	if strings.TrimSpace(username) == "" {
+		return fmt.Errorf("username is required")
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// hashToken returns the stored representation of a session or API token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateToken returns a random URL-safe token with 256 bits of entropy
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ---- Users ----
+
+func ServiceListUsers() ([]entity.User, error) {
+	var users []entity.User
+	if err := DB.Preload("Roles.Server").Preload("Roles.BackupProfile").Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ServiceGetUser returns a user including their roles
+func ServiceGetUser(id uint) (*entity.User, error) {
+	var user entity.User
+	if err := DB.Preload("Roles.Server").Preload("Roles.BackupProfile").First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ServiceCreateUser creates a user with an optional global role
+func ServiceCreateUser(username, password, role string) (*entity.User, error) {
+	if err := validateCredentials(username, password); err != nil {
+		return nil, err
+	}
+	username = strings.TrimSpace(username)
+
+	var count int64
+	if err := DB.Model(&entity.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUsernameTaken
+	}
+
+	if role != "" && RoleLevel(role) == 0 {
+		return nil, fmt.Errorf("role must be one of %s, %s or %s", entity.RoleViewer, entity.RoleOperator, entity.RoleAdmin)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &entity.User{Username: username, PasswordHash: hash}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if role == "" {
+			return nil
+		}
+		return createGlobalRole(tx, user.ID, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ServiceGetUser(user.ID)
+}
+
+// ServiceUpdateUserPassword sets a new password and signs the user out everywhere
+func ServiceUpdateUserPassword(id uint, password string) (*entity.User, error) {
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	var user entity.User
+	if err := DB.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hash
+	if err := DB.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", user.ID).Delete(&entity.Session{}).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func ServiceDeleteUser(id uint) error {
+	var user entity.User
+	if err := DB.First(&user, id).Error; err != nil {
+		return err
+	}
+	var count int64
+	if err := DB.Model(&entity.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastUser
+	}
+	otherAdmins, err := countGlobalAdmins(user.ID)
+	if err != nil {
+		return err
+	}
+	if otherAdmins == 0 {
+		return ErrLastAdmin
+	}
+	if err := DB.Where("user_id = ?", user.ID).Delete(&entity.Session{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("user_id = ?", user.ID).Delete(&entity.RoleAssignment{}).Error; err != nil {
+		return err
+	}
+	var tokens []entity.APIToken
+	if err := DB.Where("user_id = ?", user.ID).Find(&tokens).Error; err != nil {
+		return err
+	}
+	for i := range tokens {
+		if err := DB.Select("BackupProfiles").Delete(&tokens[i]).Error; err != nil {
+			return err
+		}
+	}
+	return DB.Delete(&user).Error
+}
+
+// ServiceVerifyPassword checks the password of an existing user
+func ServiceVerifyPassword(id uint, password string) error {
+	var user entity.User
+	if err := DB.First(&user, id).Error; err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// ---- Initial setup ----
+
+// ServiceSetupRequired reports whether no user exists yet
+func ServiceSetupRequired() (bool, error) {
+	var count int64
+	if err := DB.Model(&entity.User{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// ServiceSetupAdmin creates the first user as global admin. It fails once any user exists.
+func ServiceSetupAdmin(username, password string) (*entity.User, error) {
+	if err := validateCredentials(username, password); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{Username: strings.TrimSpace(username), PasswordHash: hash}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSetupCompleted
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return createGlobalRole(tx, user.ID, entity.RoleAdmin)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// BootstrapAdminFromEnv creates the first user from BACKAPP_ADMIN_USERNAME (default "admin")
+// and BACKAPP_ADMIN_PASSWORD when no user exists yet. Without these variables the first
+// user is created through the setup screen of the web UI.
+func BootstrapAdminFromEnv() {
+	password := os.Getenv("BACKAPP_ADMIN_PASSWORD")
+	if password == "" {
+		return
+	}
+	username := os.Getenv("BACKAPP_ADMIN_USERNAME")
+	if username == "" {
+		username = "admin"
+	}
+
+	user, err := ServiceSetupAdmin(username, password)
+	if err != nil {
+		if err != ErrSetupCompleted {
+			slog.Warn("Failed to create initial admin user", "error", err)
+		}
+		return
+	}
+	slog.Info("Created initial admin user", "user", user.Username)
+}
+
+// ---- Sessions ----
+
+// ServiceLogin checks credentials and starts a new session. The returned token is
+// only known to the caller; the database keeps its hash.
+func ServiceLogin(username, password string) (string, *entity.User, error) {
+	var user entity.User
+	err := DB.Where("username = ?", strings.TrimSpace(username)).First(&user).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			return "", nil, ErrInvalidCredentials
+		}
+		return "", nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	token, err := startSession(&user)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &user, nil
+}
+
+// startSession creates a session for an authenticated user and returns its token
+func startSession(user *entity.User) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	session := &entity.Session{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(SessionDuration),
+	}
+	if err := DB.Create(session).Error; err != nil {
+		return "", err
+	}
+
+	user.LastLoginAt = &now
+	if err := DB.Model(user).Update("last_login_at", now).Error; err != nil {
+		slog.Error("Failed to update last login", "user", user.Username, "error", err)
+	}
+
+	// Drop expired sessions while we are at it
+	DB.Where("expires_at < ?", now).Delete(&entity.Session{})
+
+	return token, nil
+}
+
+// ServiceGetSessionUser returns the user of a valid session token
+func ServiceGetSessionUser(token string) (*entity.User, error) {
+	var session entity.Session
+	if err := DB.Preload("User").Where("token_hash = ?", hashToken(token)).First(&session).Error; err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		DB.Delete(&session)
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session.User, nil
+}
+
+// ServiceLogout ends the session of a token
+func ServiceLogout(token string) error {
+	return DB.Where("token_hash = ?", hashToken(token)).Delete(&entity.Session{}).Error
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+// roleLevels orders roles by privilege. Level 0 means no access.
+var roleLevels = map[string]int{
+	entity.RoleViewer:   1,
+	entity.RoleOperator: 2,
+	entity.RoleAdmin:    3,
+}
+
+// RoleLevel returns the privilege level of a role, 0 for unknown roles
+func RoleLevel(role string) int {
+	return roleLevels[role]
+}
+
+var ErrLastAdmin = errors.New("at least one user must remain a global admin")
+
+// Permissions holds the role assignments of a user, resolved for access checks
+type Permissions struct {
+	global         int
+	servers        map[uint]int
+	profiles       map[uint]int
+	profileServers map[uint]uint // server of each profile with a profile assignment
+}
+
+// ServiceLoadPermissions loads the role assignments of a user
+func ServiceLoadPermissions(userID uint) (*Permissions, error) {
+	var assignments []entity.RoleAssignment
+	if err := DB.Preload("BackupProfile").Where("user_id = ?", userID).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	p := &Permissions{
+		servers:        make(map[uint]int),
+		profiles:       make(map[uint]int),
+		profileServers: make(map[uint]uint),
+	}
+	for _, a := range assignments {
+		level := RoleLevel(a.Role)
+		switch {
+		case a.BackupProfileID != nil:
+			p.profiles[*a.BackupProfileID] = max(p.profiles[*a.BackupProfileID], level)
+			if a.BackupProfile != nil {
+				p.profileServers[*a.BackupProfileID] = a.BackupProfile.ServerID
+			}
+		case a.ServerID != nil:
+			p.servers[*a.ServerID] = max(p.servers[*a.ServerID], level)
+		default:
+			p.global = max(p.global, level)
+		}
+	}
+	return p, nil
+}
+
+// Global returns the level of the user's global role
+func (p *Permissions) Global() int {
+	return p.global
+}
+
+// Server returns the level of the user's role on a server
+func (p *Permissions) Server(serverID uint) int {
+	return max(p.global, p.servers[serverID])
+}
+
+// Profile returns the level of the user's role on a backup profile of a server
+func (p *Permissions) Profile(profileID, serverID uint) int {
+	return max(p.Server(serverID), p.profiles[profileID])
+}
+
+// SeesServer reports whether the user may see a server, either through a role on it
+// or on one of its backup profiles
+func (p *Permissions) SeesServer(serverID uint) bool {
+	if p.Server(serverID) > 0 {
+		return true
+	}
+	for profileID, profileServerID := range p.profileServers {
+		if profileServerID == serverID && p.profiles[profileID] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileLevels returns the level of the user's role on every backup profile
+func (p *Permissions) ProfileLevels() (map[uint]int, error) {
+	var profiles []entity.BackupProfile
+	if err := DB.Select("id", "server_id").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	levels := make(map[uint]int, len(profiles))
+	for _, profile := range profiles {
+		levels[profile.ID] = p.Profile(profile.ID, profile.ServerID)
+	}
+	return levels, nil
+}
+
+// Any reports whether the user has any role at all
+func (p *Permissions) Any() bool {
+	return p.global > 0 || len(p.servers) > 0 || len(p.profiles) > 0
+}
+
+// ---- Role assignments ----
+
+func ServiceListRoleAssignments(userID uint) ([]entity.RoleAssignment, error) {
+	var assignments []entity.RoleAssignment
+	if err := DB.Preload("Server").Preload("BackupProfile").Where("user_id = ?", userID).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func ServiceCreateRoleAssignment(userID uint, input *entity.RoleAssignmentInput) (*entity.RoleAssignment, error) {
+	if RoleLevel(input.Role) == 0 {
+		return nil, fmt.Errorf("role must be one of %s, %s or %s", entity.RoleViewer, entity.RoleOperator, entity.RoleAdmin)
+	}
+	if input.ServerID != nil && input.BackupProfileID != nil {
+		return nil, fmt.Errorf("a role applies either to a server or to a backup profile")
+	}
+	if err := DB.First(&entity.User{}, userID).Error; err != nil {
+		return nil, err
+	}
+	if input.ServerID != nil {
+		if err := DB.First(&entity.Server{}, *input.ServerID).Error; err != nil {
+			return nil, fmt.Errorf("unknown server")
+		}
+	}
+	if input.BackupProfileID != nil {
+		if err := DB.First(&entity.BackupProfile{}, *input.BackupProfileID).Error; err != nil {
+			return nil, fmt.Errorf("unknown backup profile")
+		}
+	}
+
+	assignment := &entity.RoleAssignment{
+		UserID:          userID,
+		Role:            input.Role,
+		ServerID:        input.ServerID,
+		BackupProfileID: input.BackupProfileID,
+	}
+	if err := DB.Create(assignment).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Preload("Server").Preload("BackupProfile").First(assignment, assignment.ID).Error; err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// ServiceDeleteRoleAssignment removes a role. The last global admin role cannot be removed.
+func ServiceDeleteRoleAssignment(userID, id uint) error {
+	var assignment entity.RoleAssignment
+	if err := DB.Where("user_id = ?", userID).First(&assignment, id).Error; err != nil {
+		return err
+	}
+	if isGlobalAdmin(&assignment) {
+		others, err := countGlobalAdmins(assignment.UserID)
+		if err != nil {
+			return err
+		}
+		if others == 0 {
+			return ErrLastAdmin
+		}
+	}
+	return DB.Delete(&assignment).Error
+}
+
+func isGlobalAdmin(a *entity.RoleAssignment) bool {
+	return a.Role == entity.RoleAdmin && a.ServerID == nil && a.BackupProfileID == nil
+}
+
+// countGlobalAdmins counts the users other than excludeUserID with a global admin role
+func countGlobalAdmins(excludeUserID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&entity.RoleAssignment{}).
+		Where("role = ? AND server_id IS NULL AND backup_profile_id IS NULL AND user_id <> ?", entity.RoleAdmin, excludeUserID).
+		Distinct("user_id").
+		Count(&count).Error
+	return count, err
+}
+
+func createGlobalRole(tx *gorm.DB, userID uint, role string) error {
+	return tx.Create(&entity.RoleAssignment{UserID: userID, Role: role}).Error
+}
+
+// migrateRoleAssignments makes all existing users global admins when roles are
+// introduced, so that upgrading does not lock anybody out
+func migrateRoleAssignments() {
+	var assignments int64
+	if err := DB.Model(&entity.RoleAssignment{}).Count(&assignments).Error; err != nil || assignments > 0 {
+		return
+	}
+	var users []entity.User
+	if err := DB.Find(&users).Error; err != nil {
+		return
+	}
+	for _, user := range users {
+		if err := createGlobalRole(DB, user.ID, entity.RoleAdmin); err != nil {
+			slog.Warn("Failed to grant admin role", "user", user.Username, "error", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"backapp-server/entity"
+)
+
+// ServiceDryRunBackupProfile connects to the profile's server and resolves every file rule
+// into the exact files a backup would transfer. No commands are executed and nothing is
+// written locally.
+func ServiceDryRunBackupProfile(id uint) (*entity.BackupDryRun, error) {
+	// Load the profile with credentials, it is never returned to the client
+	var profile entity.BackupProfile
+	if err := DB.Preload("Server").
+		Preload("StorageLocation").
+		Preload("NamingRule").
+		Preload("Commands").
+		Preload("FileRules").
+		First(&profile, id).Error; err != nil {
+		return nil, err
+	}
+
+	backupDir := filepath.Join(profile.StorageLocation.BasePath, NewBackupExecutor().generateBackupName(&profile))
+	result := &entity.BackupDryRun{
+		BackupProfileID:   profile.ID,
+		BackupPath:        backupDir,
+		Rules:             []entity.DryRunRule{},
+		Files:             []entity.DryRunFile{},
+		Commands:          profile.Commands,
+		StorageLocationID: profile.StorageLocationID,
+		Warnings:          []string{},
+	}
+	if result.Commands == nil {
+		result.Commands = []entity.Command{}
+	}
+	if len(profile.Commands) > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d pre/post commands were not executed; files they create or remove are not reflected", len(profile.Commands)))
+	}
+
+	sshClient, err := AcquireSSHClient(profile.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH client: %v", err)
+	}
+	defer sshClient.Close()
+
+	// runID 0 keeps the transfer service from logging to a backup run
+	transferService := NewFileTransferService(sshClient, backupDir, 0)
+	for _, rule := range profile.FileRules {
+		summary := entity.DryRunRule{
+			FileRuleID: rule.ID,
+			RemotePath: rule.RemotePath,
+		}
+		files, err := transferService.ListFiles(rule)
+		if err != nil {
+			summary.Error = err.Error()
+			result.Warnings = append(result.Warnings, fmt.Sprintf("File rule %s would fail: %v", rule.RemotePath, err))
+		}
+		for _, file := range files {
+			summary.FileCount++
+			summary.TotalSizeBytes += file.SizeBytes
+		}
+		result.Rules = append(result.Rules, summary)
+		result.Files = append(result.Files, files...)
+		result.TotalFiles += summary.FileCount
+		result.TotalSizeBytes += summary.TotalSizeBytes
+	}
+
+	total, free, err := statFilesystem(profile.StorageLocation.BasePath)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not determine free space at %s: %v", profile.StorageLocation.BasePath, err))
+		return result, nil
+	}
+	result.StorageTotalBytes = total
+	result.StorageFreeBytes = free
+	result.Fits = result.TotalSizeBytes <= free
+	if !result.Fits {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Backup needs %.2f MB but only %.2f MB are free at %s",
+			float64(result.TotalSizeBytes)/1024/1024, float64(free)/1024/1024, profile.StorageLocation.BasePath))
+	}
+
+	return result, nil
+}
+
+// statFilesystem returns total and available bytes of the filesystem that holds path.
+// If path does not exist yet, the nearest existing parent directory is used.
+func statFilesystem(path string) (int64, int64, error) {
+	dir := filepath.Clean(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	return int64(stat.Blocks) * int64(stat.Bsize), int64(stat.Bavail) * int64(stat.Bsize), nil
+}
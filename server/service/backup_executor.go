@@ -2,7 +2,7 @@ package service
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -28,10 +28,18 @@ func (e *BackupExecutor) logToDatabase(runID uint, level, message string) {
 		Message:     message,
 	}
 	if err := DB.Create(logEntry).Error; err != nil {
-		log.Printf("Failed to save log to database: %v", err)
+		slog.Error("Failed to save log to database", "run_id", runID, "error", err)
 	}
+	GetRunEventHub().PublishLog(logEntry)
 	// Also log to console
-	log.Printf("[%s] %s", level, message)
+	logRun(runID, level, message)
+}
+
+// setStage publishes the stage a run has entered to live subscribers
+func (e *BackupExecutor) setStage(runID uint, stage string) {
+	GetRunEventHub().UpdateProgress(runID, true, func(p *entity.BackupRunProgress) {
+		p.Stage = stage
+	})
 }
 
 // ExecuteBackup executes a backup profile
@@ -61,42 +69,57 @@ func (e *BackupExecutor) ExecuteBackup(profileID uint, allowDisabled bool) error
 	if err := DB.Create(run).Error; err != nil {
 		return fmt.Errorf("failed to create backup run: %v", err)
 	}
-
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Starting backup for profile: %s", profile.Name))
+	GetRunEventHub().PublishState(run)
+
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Starting backup for profile: %s", profile.Name))
+	span := TracingSvc.StartSpan("backup run")
+	span.SetAttribute("backapp.run.id", run.ID)
+	span.SetAttribute("backapp.profile.id", profile.ID)
+	span.SetAttribute("backapp.profile.name", profile.Name)
+	span.SetAttribute("backapp.server.name", profile.Server.Name)
+	if span != nil {
+		e.logToDatabase(run.ID, entity.LogLevelDebug, fmt.Sprintf("Trace ID: %s", span.TraceID()))
+	}
 
 	// Send notification for backup started
 	if NotificationSvc != nil {
-		go NotificationSvc.NotifyBackupStarted(profileID, profile.Name)
+		go NotificationSvc.NotifyBackupStarted(profileID, run.ID, profile.Name)
 	}
+	heartbeat := e.startHeartbeat(&profile, run.ID)
 
 	// Execute backup and update status
-	err := e.executeBackupInternal(&profile, run)
+	err := e.executeBackupInternal(&profile, run, span)
 
 	// Update run status
 	run.EndTime = time.Now()
 	duration := run.EndTime.Sub(run.StartTime)
+	// This run is not saved as finished yet, so it is not counted
+	previousFailures := GetConsecutiveFailureCount(profileID)
 	if err != nil {
 		run.Status = "failed"
 		run.ErrorMessage = err.Error()
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Backup failed: %v", err))
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Backup failed: %v", err))
+		heartbeat.finish(heartbeatFail, err.Error())
 
 		// Send failure notification
 		if NotificationSvc != nil {
-			go NotificationSvc.NotifyBackupFailed(profileID, profile.Name, err.Error())
+			go NotificationSvc.NotifyBackupFailed(profileID, run.ID, profile.Name, err.Error())
 
 			// Check for consecutive failures
-			failureCount := GetConsecutiveFailureCount(profileID)
+			failureCount := previousFailures + 1
 			if failureCount > 1 {
-				go NotificationSvc.NotifyConsecutiveFailures(profileID, profile.Name, failureCount)
+				go NotificationSvc.NotifyConsecutiveFailures(profileID, run.ID, profile.Name, failureCount)
 			}
 		}
 	} else {
 		run.Status = "completed"
-		e.logToDatabase(run.ID, "INFO", "Backup completed successfully")
+		e.logToDatabase(run.ID, entity.LogLevelInfo, "Backup completed successfully")
+		heartbeat.finish(heartbeatSuccess, fmt.Sprintf("Backup '%s' completed in %s\nRun: #%d\nDuration: %.0f seconds\nFiles: %d\nSize: %d bytes\n",
+			profile.Name, duration.Round(time.Second), run.ID, duration.Seconds(), run.TotalFiles, run.TotalSizeBytes))
 
 		// Send success notification
 		if NotificationSvc != nil {
-			go NotificationSvc.NotifyBackupSuccess(profileID, profile.Name, duration)
+			go NotificationSvc.NotifyBackupSuccess(profileID, run.ID, profile.Name, duration, previousFailures)
 		}
 
 		// Check storage usage and notify if low
@@ -105,67 +128,89 @@ func (e *BackupExecutor) ExecuteBackup(profileID uint, allowDisabled bool) error
 
 	// Update the run record - using Save with the full struct
 	if updateErr := DB.Save(run).Error; updateErr != nil {
-		log.Printf("Failed to update backup run status: %v", updateErr)
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Failed to update run status: %v", updateErr))
+		slog.Error("Failed to update backup run status", "run_id", run.ID, "error", updateErr)
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Failed to update run status: %v", updateErr))
 	} else {
-		e.logToDatabase(run.ID, "DEBUG", fmt.Sprintf("Run status updated to: %s", run.Status))
+		e.logToDatabase(run.ID, entity.LogLevelDebug, fmt.Sprintf("Run status updated to: %s", run.Status))
 	}
+	GetRunEventHub().PublishState(run)
+
+	span.SetAttribute("backapp.run.status", run.Status)
+	span.SetAttribute("backapp.files", run.TotalFiles)
+	span.SetAttribute("backapp.bytes", run.TotalSizeBytes)
+	span.SetError(err)
+	span.End()
 
 	return err
 }
 
 // executeBackupInternal performs the actual backup execution
-func (e *BackupExecutor) executeBackupInternal(profile *entity.BackupProfile, run *entity.BackupRun) error {
+func (e *BackupExecutor) executeBackupInternal(profile *entity.BackupProfile, run *entity.BackupRun, span *Span) error {
 	// Create SSH client
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Connecting to server: %s@%s:%d", profile.Server.Username, profile.Server.Host, profile.Server.Port))
-	sshClient, err := NewSSHClient(profile.Server)
+	e.setStage(run.ID, "connect")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Connecting to server: %s@%s:%d", profile.Server.Username, profile.Server.Host, profile.Server.Port))
+	connectSpan := span.StartChild("connect")
+	connectSpan.SetAttribute("server.address", profile.Server.Host)
+	connectSpan.SetAttribute("server.port", profile.Server.Port)
+	sshClient, err := AcquireSSHClient(profile.Server)
+	connectSpan.SetError(err)
+	connectSpan.End()
 	if err != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Failed to create SSH client: %v", err))
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Failed to create SSH client: %v", err))
 		return fmt.Errorf("failed to create SSH client: %v", err)
 	}
 	defer sshClient.Close()
-	e.logToDatabase(run.ID, "INFO", "SSH connection established")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, "SSH connection established")
 
 	// Execute pre-backup commands
-	e.logToDatabase(run.ID, "INFO", "Executing pre-backup commands")
-	if err := e.executeCommands(sshClient, profile.Commands, "pre", run.ID); err != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Pre-backup commands failed: %v", err))
+	e.setStage(run.ID, "pre")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, "Executing pre-backup commands")
+	if err := e.executeCommands(sshClient, profile.Commands, "pre", run.ID, span); err != nil {
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Pre-backup commands failed: %v", err))
 		return fmt.Errorf("pre-backup commands failed: %v", err)
 	}
 
 	// Generate backup directory name using naming rule
 	backupDirName := e.generateBackupName(profile)
 	backupDir := filepath.Join(profile.StorageLocation.BasePath, backupDirName)
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Backup directory: %s", backupDir))
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Backup directory: %s", backupDir))
 
 	// Create backup directory
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Failed to create backup directory: %v", err))
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Failed to create backup directory: %v", err))
 		return fmt.Errorf("failed to create backup directory: %v", err)
 	}
 	absBackupDir, absErr := filepath.Abs(backupDir)
 	if absErr != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Failed to get absolute path of backup directory: %v", absErr))
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Failed to get absolute path of backup directory: %v", absErr))
 	} else {
-		e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Absolute backup directory path: %s", absBackupDir))
+		e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Absolute backup directory path: %s", absBackupDir))
 	}
-	e.logToDatabase(run.ID, "INFO", "Backup directory created")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, "Backup directory created")
 
 	// Transfer files
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Starting file transfer (%d rules)", len(profile.FileRules)))
+	e.setStage(run.ID, "transfer")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Starting file transfer (%d rules)", len(profile.FileRules)))
+	transferSpan := span.StartChild("transfer")
 	transferService := NewFileTransferService(sshClient, backupDir, run.ID)
+	transferService.span = transferSpan
 	backupFiles, err := transferService.TransferFiles(profile.FileRules)
+	transferSpan.SetAttribute("backapp.rules", len(profile.FileRules))
+	transferSpan.SetAttribute("backapp.files", len(backupFiles))
+	transferSpan.SetAttribute("backapp.bytes", transferService.bytesDone)
+	transferSpan.SetError(err)
+	transferSpan.End()
 	if err != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("File transfer failed: %v", err))
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("File transfer failed: %v", err))
 		return fmt.Errorf("file transfer failed: %v", err)
 	}
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("File transfer completed: %d files", len(backupFiles)))
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("File transfer completed: %d files", len(backupFiles)))
 
 	// Save backup files to database
 	for i := range backupFiles {
 		backupFiles[i].BackupRunID = run.ID
 		if err := DB.Create(&backupFiles[i]).Error; err != nil {
-			log.Printf("Failed to save backup file record: %v", err)
+			slog.Error("Failed to save backup file record", "run_id", run.ID, "path", backupFiles[i].RemotePath, "error", err)
 		}
 	}
 
@@ -176,20 +221,35 @@ func (e *BackupExecutor) executeBackupInternal(profile *entity.BackupProfile, ru
 	}
 	run.TotalSizeBytes = totalSize
 	run.TotalFiles = len(backupFiles)
-	e.logToDatabase(run.ID, "INFO", fmt.Sprintf("Total size: %.2f MB, Total files: %d", float64(totalSize)/1024/1024, len(backupFiles)))
+	e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Total size: %.2f MB, Total files: %d", float64(totalSize)/1024/1024, len(backupFiles)))
 
 	// Execute post-backup commands
-	e.logToDatabase(run.ID, "INFO", "Executing post-backup commands")
-	if err := e.executeCommands(sshClient, profile.Commands, "post", run.ID); err != nil {
-		e.logToDatabase(run.ID, "ERROR", fmt.Sprintf("Post-backup commands failed: %v", err))
+	e.setStage(run.ID, "post")
+	e.logToDatabase(run.ID, entity.LogLevelInfo, "Executing post-backup commands")
+	if err := e.executeCommands(sshClient, profile.Commands, "post", run.ID, span); err != nil {
+		e.logToDatabase(run.ID, entity.LogLevelError, fmt.Sprintf("Post-backup commands failed: %v", err))
 		return fmt.Errorf("post-backup commands failed: %v", err)
 	}
 
+	// Delete expired runs of the profile now instead of at the next hourly cleanup
+	if profile.RetentionDays != nil && *profile.RetentionDays > 0 {
+		e.setStage(run.ID, "retention")
+		retentionSpan := span.StartChild("retention")
+		result := NewRetentionCleanup().cleanupProfile(profile)
+		retentionSpan.SetAttribute("backapp.retention.runs", result.runs)
+		retentionSpan.SetAttribute("backapp.retention.files", result.files)
+		retentionSpan.SetAttribute("backapp.retention.bytes", result.bytes)
+		retentionSpan.End()
+		if result.runs > 0 {
+			e.logToDatabase(run.ID, entity.LogLevelInfo, fmt.Sprintf("Retention deleted %d files (%.2f MB) of %d expired runs", result.files, float64(result.bytes)/1024/1024, result.runs))
+		}
+	}
+
 	return nil
 }
 
 // executeCommands executes commands in order for a specific stage (pre/post)
-func (e *BackupExecutor) executeCommands(sshClient *SSHClient, commands []entity.Command, stage string, runID uint) error {
+func (e *BackupExecutor) executeCommands(sshClient *SSHClient, commands []entity.Command, stage string, runID uint, span *Span) error {
 	// Filter commands by stage
 	var stageCommands []entity.Command
 	for _, cmd := range commands {
@@ -203,20 +263,30 @@ func (e *BackupExecutor) executeCommands(sshClient *SSHClient, commands []entity
 		return stageCommands[i].RunOrder < stageCommands[j].RunOrder
 	})
 
+	stageSpan := span.StartChild(stage)
+	stageSpan.SetAttribute("backapp.commands", len(stageCommands))
+	defer stageSpan.End()
+
 	// Execute commands in order
-	for _, cmd := range stageCommands {
+	for i, cmd := range stageCommands {
 		workDir := cmd.WorkingDirectory
 		if workDir == "" {
 			workDir = "/"
 		}
-		e.logToDatabase(runID, "INFO", fmt.Sprintf("Executing %s command in %s: %s", stage, workDir, cmd.Command))
+		e.logToDatabase(runID, entity.LogLevelInfo, fmt.Sprintf("Executing %s command in %s: %s", stage, workDir, cmd.Command))
+		commandSpan := stageSpan.StartChild("command")
+		commandSpan.SetAttribute("backapp.command.index", i+1)
+		commandSpan.SetAttribute("backapp.command", cmd.Command)
 		output, err := sshClient.RunCommandInDir(cmd.Command, workDir)
+		commandSpan.SetError(err)
+		commandSpan.End()
 		if err != nil {
-			e.logToDatabase(runID, "ERROR", fmt.Sprintf("Command failed: %s, error: %v", cmd.Command, err))
+			stageSpan.SetError(err)
+			e.logToDatabase(runID, entity.LogLevelError, fmt.Sprintf("Command failed: %s, error: %v", cmd.Command, err))
 			return fmt.Errorf("command '%s' failed: %v, output: %s", cmd.Command, err, output)
 		}
 		if output != "" {
-			e.logToDatabase(runID, "DEBUG", fmt.Sprintf("Command output: %s", output))
+			e.logToDatabase(runID, entity.LogLevelDebug, fmt.Sprintf("Command output: %s", output))
 		}
 	}
 
@@ -1,11 +1,31 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
 	"backapp-server/entity"
 
 	"gorm.io/gorm"
 )
 
+// ErrInvalidBackupProfile is wrapped by validation errors of backup profile input
+var ErrInvalidBackupProfile = errors.New("invalid backup profile")
+
+// validateBackupProfile checks and normalizes the optional settings of a profile
+func validateBackupProfile(input *entity.BackupProfile) error {
+	input.HeartbeatURL = strings.TrimSpace(input.HeartbeatURL)
+	if input.HeartbeatURL != "" {
+		target, err := url.Parse(input.HeartbeatURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("%w: heartbeat_url must be an http or https URL", ErrInvalidBackupProfile)
+		}
+	}
+	return nil
+}
+
 func ServiceListBackupProfiles() ([]entity.BackupProfile, error) {
 	var profiles []entity.BackupProfile
 	if err := DB.
@@ -24,6 +44,9 @@ func ServiceListBackupProfiles() ([]entity.BackupProfile, error) {
 }
 
 func ServiceCreateBackupProfile(input *entity.BackupProfile) (*entity.BackupProfile, error) {
+	if err := validateBackupProfile(input); err != nil {
+		return nil, err
+	}
 	if err := DB.Create(input).Error; err != nil {
 		return nil, err
 	}
@@ -51,12 +74,17 @@ func ServiceUpdateBackupProfile(id uint, input *entity.BackupProfile) (*entity.B
 	if err != nil {
 		return nil, err
 	}
+	if err := validateBackupProfile(input); err != nil {
+		return nil, err
+	}
 	profile.Name = input.Name
 	profile.ServerID = input.ServerID
 	profile.StorageLocationID = input.StorageLocationID
 	profile.NamingRuleID = input.NamingRuleID
 	profile.ScheduleCron = input.ScheduleCron
 	profile.RetentionDays = input.RetentionDays
+	profile.MissedBackupGraceMinutes = input.MissedBackupGraceMinutes
+	profile.HeartbeatURL = input.HeartbeatURL
 	profile.Enabled = input.Enabled
 	if err := DB.Save(profile).Error; err != nil {
 		return nil, err
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backapp-server/entity"
+)
+
+// chatProvider formats notifications for one chat or push service
+type chatProvider interface {
+	// validate checks the settings an integration needs, given its plain token
+	validate(integration *entity.ChatIntegration, token string) error
+	// request builds the HTTP request posting a notification
+	request(integration *entity.ChatIntegration, token string, notification *Notification) (*http.Request, error)
+}
+
+// chatProviders are the built-in chat providers by name
+var chatProviders = map[string]chatProvider{
+	entity.ChatProviderSlack:   slackProvider{},
+	entity.ChatProviderDiscord: discordProvider{},
+	entity.ChatProviderMatrix:  matrixProvider{},
+	entity.ChatProviderNtfy:    ntfyProvider{},
+	entity.ChatProviderGotify:  gotifyProvider{},
+}
+
+// newJSONRequest returns a request with a JSON body
+func newJSONRequest(method, target string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// chatEndpoint appends a path to the server URL of an integration
+func chatEndpoint(integration *entity.ChatIntegration, path string) string {
+	return strings.TrimRight(integration.URL, "/") + path
+}
+
+// chatHeadline is the title of chat messages
+func chatHeadline(notification *Notification) string {
+	if notification.Subject != "" {
+		return notification.Subject
+	}
+	return notification.Title
+}
+
+// chatSummary is the text of chat messages
+func chatSummary(notification *Notification) string {
+	if notification.Summary != "" {
+		return notification.Summary
+	}
+	return notification.Body
+}
+
+// truncate shortens s to at most limit characters
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
+
+// slackProvider posts attachments to a Slack incoming webhook
+type slackProvider struct{}
+
+func (slackProvider) validate(*entity.ChatIntegration, string) error { return nil }
+
+// slackEscape escapes the control characters of Slack message text
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (slackProvider) request(integration *entity.ChatIntegration, _ string, notification *Notification) (*http.Request, error) {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	fields := []field{}
+	for _, detail := range notification.Details {
+		fields = append(fields, field{Title: detail.Label, Value: slackEscape.Replace(detail.Value), Short: len(detail.Value) <= 40})
+	}
+	headline := slackEscape.Replace(chatHeadline(notification))
+	attachment := map[string]interface{}{
+		"fallback": headline,
+		"color":    severityColors[notification.Severity],
+		"title":    headline,
+		"text":     slackEscape.Replace(chatSummary(notification)),
+		"fields":   fields,
+		"footer":   "BackApp",
+		"ts":       time.Now().Unix(),
+	}
+	if notification.Link != "" {
+		attachment["title_link"] = notification.Link
+	}
+	body := map[string]interface{}{
+		"text":        headline,
+		"attachments": []interface{}{attachment},
+	}
+	if integration.Channel != "" {
+		body["channel"] = integration.Channel
+	}
+	return newJSONRequest(http.MethodPost, integration.URL, body)
+}
+
+// discordProvider posts embeds to a Discord webhook
+type discordProvider struct{}
+
+func (discordProvider) validate(*entity.ChatIntegration, string) error { return nil }
+
+func (discordProvider) request(integration *entity.ChatIntegration, _ string, notification *Notification) (*http.Request, error) {
+	type field struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+	fields := []field{}
+	for _, detail := range notification.Details {
+		if detail.Value == "" || len(fields) == 25 {
+			continue
+		}
+		fields = append(fields, field{Name: truncate(detail.Label, 256), Value: truncate(detail.Value, 1024), Inline: len(detail.Value) <= 40})
+	}
+	color, _ := strconv.ParseInt(strings.TrimPrefix(severityColors[notification.Severity], "#"), 16, 32)
+	embed := map[string]interface{}{
+		"title":       truncate(chatHeadline(notification), 256),
+		"description": truncate(chatSummary(notification), 4096),
+		"color":       color,
+		"fields":      fields,
+		"footer":      map[string]string{"text": "BackApp"},
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if notification.Link != "" {
+		embed["url"] = notification.Link
+	}
+	return newJSONRequest(http.MethodPost, integration.URL, map[string]interface{}{
+		"username": "BackApp",
+		"embeds":   []interface{}{embed},
+	})
+}
+
+// matrixProvider sends notices to a Matrix room through the client-server API
+type matrixProvider struct{}
+
+func (matrixProvider) validate(integration *entity.ChatIntegration, token string) error {
+	if !strings.HasPrefix(integration.Channel, "!") || !strings.Contains(integration.Channel, ":") {
+		return errors.New("channel must be a Matrix room ID like !room:example.org")
+	}
+	if token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (matrixProvider) request(integration *entity.ChatIntegration, token string, notification *Notification) (*http.Request, error) {
+	text := []string{chatHeadline(notification), chatSummary(notification)}
+	formatted := fmt.Sprintf(`<p><strong><font color="%s">%s</font></strong><br>%s</p>`,
+		severityColors[notification.Severity], html.EscapeString(chatHeadline(notification)), html.EscapeString(chatSummary(notification)))
+	if len(notification.Details) > 0 {
+		formatted += "<ul>"
+		for _, detail := range notification.Details {
+			text = append(text, detail.Label+": "+detail.Value)
+			formatted += fmt.Sprintf("<li><strong>%s:</strong> %s</li>", html.EscapeString(detail.Label), html.EscapeString(detail.Value))
+		}
+		formatted += "</ul>"
+	}
+	if notification.Link != "" {
+		text = append(text, notification.Link)
+		formatted += fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(notification.Link), html.EscapeString(notification.LinkLabel))
+	}
+
+	// The transaction ID makes retries by the homeserver idempotent
+	txnID := fmt.Sprintf("backapp-%d", time.Now().UnixNano())
+	target := chatEndpoint(integration, fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		url.PathEscape(integration.Channel), txnID))
+	req, err := newJSONRequest(http.MethodPut, target, map[string]string{
+		"msgtype":        "m.notice",
+		"body":           strings.Join(text, "\n"),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
+
+// ntfyProvider publishes to an ntfy topic
+type ntfyProvider struct{}
+
+func (ntfyProvider) validate(integration *entity.ChatIntegration, _ string) error {
+	if integration.Channel == "" {
+		return errors.New("channel must be the ntfy topic")
+	}
+	return nil
+}
+
+// ntfy priorities and tags, which ntfy shows as emojis
+var (
+	ntfyPriorities = map[string]int{SeverityInfo: 3, SeveritySuccess: 3, SeverityWarning: 4, SeverityFailure: 4}
+	ntfyTags       = map[string]string{SeverityInfo: "information_source", SeveritySuccess: "white_check_mark", SeverityWarning: "warning", SeverityFailure: "x"}
+)
+
+func (ntfyProvider) request(integration *entity.ChatIntegration, token string, notification *Notification) (*http.Request, error) {
+	lines := []string{chatSummary(notification)}
+	for _, detail := range notification.Details {
+		lines = append(lines, detail.Label+": "+detail.Value)
+	}
+	body := map[string]interface{}{
+		"topic":    integration.Channel,
+		"title":    chatHeadline(notification),
+		"message":  strings.Join(lines, "\n"),
+		"priority": ntfyPriorities[notification.Severity],
+		"tags":     []string{ntfyTags[notification.Severity]},
+	}
+	if notification.Link != "" {
+		body["click"] = notification.Link
+	}
+	// JSON messages are published to the root URL of the server
+	req, err := newJSONRequest(http.MethodPost, chatEndpoint(integration, "/"), body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// gotifyProvider creates Gotify messages with an application token
+type gotifyProvider struct{}
+
+func (gotifyProvider) validate(_ *entity.ChatIntegration, token string) error {
+	if token == "" {
+		return errors.New("token must be a Gotify application token")
+	}
+	return nil
+}
+
+// gotifyPriorities are Gotify priorities from 0 to 10; clients alert from 8
+var gotifyPriorities = map[string]int{SeverityInfo: 4, SeveritySuccess: 4, SeverityWarning: 6, SeverityFailure: 8}
+
+func (gotifyProvider) request(integration *entity.ChatIntegration, token string, notification *Notification) (*http.Request, error) {
+	lines := []string{chatSummary(notification), ""}
+	for _, detail := range notification.Details {
+		lines = append(lines, fmt.Sprintf("- **%s:** %s", detail.Label, detail.Value))
+	}
+	extras := map[string]interface{}{
+		"client::display": map[string]string{"contentType": "text/markdown"},
+	}
+	if notification.Link != "" {
+		lines = append(lines, "", fmt.Sprintf("[%s](%s)", notification.LinkLabel, notification.Link))
+		extras["client::notification"] = map[string]interface{}{"click": map[string]string{"url": notification.Link}}
+	}
+	req, err := newJSONRequest(http.MethodPost, chatEndpoint(integration, "/message"), map[string]interface{}{
+		"title":    chatHeadline(notification),
+		"message":  strings.TrimSpace(strings.Join(lines, "\n")),
+		"priority": gotifyPriorities[notification.Severity],
+		"extras":   extras,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Gotify-Key", token)
+	return req, nil
+}
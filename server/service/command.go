@@ -12,6 +12,14 @@ func ServiceListCommandsForProfile(profileID int) ([]entity.Command, error) {
 	return cmds, nil
 }
 
+func ServiceGetCommand(id uint) (*entity.Command, error) {
+	var cmd entity.Command
+	if err := DB.First(&cmd, id).Error; err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
 func ServiceCreateCommand(input *entity.Command) (*entity.Command, error) {
 	if err := DB.Create(input).Error; err != nil {
 		return nil, err
@@ -2,8 +2,10 @@ package service
 
 import (
 	"backapp-server/entity"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -13,21 +15,28 @@ var DB *gorm.DB
 
 func InitDB(dataSourceName string) {
 	var err error
-	DB, err = gorm.Open(sqlite.Open(dataSourceName), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(dataSourceName), &gorm.Config{Logger: newGormLogger()})
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		fatal("Failed to open database", err)
 	}
 
 	// Enable foreign key support for SQLite
 	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatalf("Failed to get raw database connection: %v", err)
+		fatal("Failed to get raw database connection", err)
 	}
 	_, err = sqlDB.Exec("PRAGMA foreign_keys = ON")
 	if err != nil {
-		log.Printf("Warning: Failed to enable foreign keys: %v", err)
+		slog.Warn("Failed to enable foreign keys", "error", err)
 	}
 
+	if err := migrateServerAuthConstraint(); err != nil {
+		fatal("Failed to migrate server auth types", err)
+	}
+
+	// Exclude patterns were separated by commas before include patterns were added
+	legacyPatterns := DB.Migrator().HasTable(&entity.FileRule{}) && !DB.Migrator().HasColumn(&entity.FileRule{}, "IncludePattern")
+
 	// Auto-migrate the schema
 	err = DB.AutoMigrate(
 		&entity.Server{},
@@ -40,22 +49,80 @@ func InitDB(dataSourceName string) {
 		&entity.BackupFile{},
 		&entity.BackupRunLog{},
 		&entity.PushSubscription{},
+		&entity.EmailRecipient{},
+		&entity.ChatIntegration{},
 		&entity.NotificationPreference{},
 		&entity.VAPIDKeys{},
+		&entity.Webhook{},
+		&entity.WebhookDelivery{},
+		&entity.User{},
+		&entity.Session{},
+		&entity.APIToken{},
+		&entity.RoleAssignment{},
+		&entity.AuditLog{},
 	)
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		fatal("Failed to migrate database", err)
 	}
+	migrateRoleAssignments()
+	if legacyPatterns {
+		migrateExcludePatterns()
+	}
+	encryptPlaintextSecrets()
+	migrateKeyFilePaths()
+	protectAuditLog()
 
 	// Initialize default storage locations and naming rules
 	initializeDefaults()
 }
 
+// migrateServerAuthConstraint replaces the check constraint of older versions, which
+// allowed fewer auth types. SQLite recreates the table to change it, which needs foreign
+// keys disabled while backup profiles reference the servers.
+func migrateServerAuthConstraint() error {
+	if !DB.Migrator().HasTable(&entity.Server{}) {
+		return nil
+	}
+	var legacy []string
+	for _, name := range []string{"chk_servers_auth_type", "chk_servers_auth_source"} {
+		if DB.Migrator().HasConstraint(&entity.Server{}, name) {
+			legacy = append(legacy, name)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	// The pragma applies to one connection, so run everything on the same one
+	return DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+		for _, name := range legacy {
+			if err := conn.Migrator().DropConstraint(&entity.Server{}, name); err != nil {
+				return err
+			}
+		}
+		return conn.Migrator().CreateConstraint(&entity.Server{}, "chk_servers_auth_methods")
+	})
+}
+
+// protectAuditLog makes the audit log append-only
+func protectAuditLog() {
+	for _, operation := range []string{"UPDATE", "DELETE"} {
+		trigger := fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS audit_logs_no_%s BEFORE %s ON audit_logs
+			BEGIN SELECT RAISE(ABORT, 'the audit log is append-only'); END`, strings.ToLower(operation), operation)
+		if err := DB.Exec(trigger).Error; err != nil {
+			fatal("Failed to protect the audit log", err)
+		}
+	}
+}
+
 func initializeDefaults() {
 	// Check if any storage locations exist
 	var storageCount int64
 	if err := DB.Model(&entity.StorageLocation{}).Count(&storageCount).Error; err != nil {
-		log.Printf("Error checking storage locations: %v", err)
+		slog.Error("Failed to check storage locations", "error", err)
 		return
 	}
 
@@ -73,9 +140,9 @@ func initializeDefaults() {
 		}
 		for _, loc := range defaultStorageLocations {
 			if err := DB.Create(&loc).Error; err != nil {
-				log.Printf("Error creating default storage location '%s': %v", loc.Name, err)
+				slog.Error("Failed to create default storage location", "storage_location", loc.Name, "error", err)
 			} else {
-				log.Printf("Created default storage location: %s", loc.Name)
+				slog.Info("Created default storage location", "storage_location", loc.Name)
 			}
 		}
 	}
@@ -83,7 +150,7 @@ func initializeDefaults() {
 	// Check if any naming rules exist
 	var ruleCount int64
 	if err := DB.Model(&entity.NamingRule{}).Count(&ruleCount).Error; err != nil {
-		log.Printf("Error checking naming rules: %v", err)
+		slog.Error("Failed to check naming rules", "error", err)
 		return
 	}
 
@@ -105,30 +172,55 @@ func initializeDefaults() {
 		}
 		for _, rule := range defaultNamingRules {
 			if err := DB.Create(&rule).Error; err != nil {
-				log.Printf("Error creating default naming rule '%s': %v", rule.Name, err)
+				slog.Error("Failed to create default naming rule", "naming_rule", rule.Name, "error", err)
 			} else {
-				log.Printf("Created default naming rule: %s", rule.Name)
+				slog.Info("Created default naming rule", "naming_rule", rule.Name)
 			}
 		}
 	}
 }
 
 func ResetDatabase() {
+	// Keep accounts, sessions and global roles so that the caller stays signed in
+	var users []entity.User
+	var sessions []entity.Session
+	var roles []entity.RoleAssignment
+	DB.Find(&users)
+	DB.Find(&sessions)
+	DB.Where("server_id IS NULL AND backup_profile_id IS NULL").Find(&roles)
+
+	// Server and profile IDs are reused after the reset
+	GetSSHPool().CloseAll()
+	GetWatchdog().Reset()
+	if NotificationSvc != nil {
+		NotificationSvc.ResetAlerts()
+	}
+
 	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatalf("Failed to get raw database connection: %v", err)
+		fatal("Failed to get raw database connection", err)
 	}
 
 	// Close the existing database connection
 	if err := sqlDB.Close(); err != nil {
-		log.Printf("Warning: Failed to close database connection: %v", err)
+		slog.Warn("Failed to close database connection", "error", err)
 	}
 
 	// Delete the existing database file
 	if err := os.Remove("app.db"); err != nil {
-		log.Printf("Warning: Failed to delete database file: %v", err)
+		slog.Warn("Failed to delete database file", "error", err)
 	}
 
 	// Re-initialize the database
 	InitDB("app.db")
+
+	if len(users) > 0 {
+		DB.Create(&users)
+	}
+	if len(sessions) > 0 {
+		DB.Create(&sessions)
+	}
+	if len(roles) > 0 {
+		DB.Create(&roles)
+	}
 }
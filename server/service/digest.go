@@ -0,0 +1,351 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// defaultDigestTime is when digests are sent without BACKAPP_DIGEST_TIME; weekly digests
+// are sent on Mondays
+const defaultDigestTime = "08:00"
+
+// digestNames name the digest frequencies in notifications
+var digestNames = map[string]string{
+	entity.DigestDaily:  "Daily",
+	entity.DigestWeekly: "Weekly",
+}
+
+// digestPeriods are how much time each digest frequency covers
+var digestPeriods = map[string]time.Duration{
+	entity.DigestDaily:  24 * time.Hour,
+	entity.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+// digestWebhookEvents are the webhook events of each digest frequency
+var digestWebhookEvents = map[string]string{
+	entity.DigestDaily:  entity.WebhookEventDailyDigest,
+	entity.DigestWeekly: entity.WebhookEventWeeklyDigest,
+}
+
+// digestScope is the profile or server a digest is limited to; neither means all profiles
+type digestScope struct {
+	BackupProfileID uint
+	ServerID        uint
+}
+
+// includes reports whether a preference or webhook with these scope IDs has the scope
+func (s digestScope) includes(backupProfileID, serverID *uint) bool {
+	return optionalID(backupProfileID) == s.BackupProfileID && optionalID(serverID) == s.ServerID
+}
+
+func optionalID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// StartDigestScheduler sends the daily and weekly digests at BACKAPP_DIGEST_TIME
+func StartDigestScheduler() {
+	at := strings.TrimSpace(os.Getenv("BACKAPP_DIGEST_TIME"))
+	if at == "" {
+		at = defaultDigestTime
+	}
+	sendAt, err := time.Parse("15:04", at)
+	if err != nil {
+		slog.Warn("BACKAPP_DIGEST_TIME is not HH:MM, using the default", "value", at, "default", defaultDigestTime)
+		sendAt, _ = time.Parse("15:04", defaultDigestTime)
+	}
+
+	c := cron.New()
+	c.AddFunc(fmt.Sprintf("%d %d * * *", sendAt.Minute(), sendAt.Hour()), func() {
+		NotificationSvc.SendDigests(entity.DigestDaily, time.Now())
+	})
+	c.AddFunc(fmt.Sprintf("%d %d * * 1", sendAt.Minute(), sendAt.Hour()), func() {
+		NotificationSvc.SendDigests(entity.DigestWeekly, time.Now())
+	})
+	c.Start()
+}
+
+// SendDigests sends the digests of a frequency to the preferences and webhooks that receive
+// them, one per scope, and returns the digests that were sent
+func (n *NotificationService) SendDigests(period string, now time.Time) []entity.Digest {
+	var prefs []entity.NotificationPreference
+	if err := DB.Where("digest_frequency = ?", period).Find(&prefs).Error; err != nil {
+		slog.Error("Failed to load digest preferences", "error", err)
+		return nil
+	}
+	var webhooks []entity.Webhook
+	if err := DB.Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		slog.Error("Failed to list webhooks", "error", err)
+	}
+
+	var scopes []digestScope
+	seen := make(map[digestScope]bool)
+	addScope := func(backupProfileID, serverID *uint) {
+		scope := digestScope{BackupProfileID: optionalID(backupProfileID), ServerID: optionalID(serverID)}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, pref := range prefs {
+		addScope(pref.BackupProfileID, pref.ServerID)
+	}
+	event := digestWebhookEvents[period]
+	for i := range webhooks {
+		if webhookSubscribed(&webhooks[i], event) {
+			addScope(webhooks[i].BackupProfileID, webhooks[i].ServerID)
+		}
+	}
+
+	sent := []entity.Digest{}
+	for _, scope := range scopes {
+		digest, err := ServiceBuildDigest(period, scope, now)
+		if err != nil {
+			slog.Error("Failed to build digest", "period", period, "error", err)
+			continue
+		}
+		if len(digest.Profiles) == 0 {
+			continue
+		}
+		sent = append(sent, *digest)
+
+		n.SendToAll(digestNotification(digest), func(pref *entity.NotificationPreference) bool {
+			return pref.DigestFrequency == period && scope.includes(pref.BackupProfileID, pref.ServerID)
+		})
+		n.SendWebhooks(event, digest, func(webhook *entity.Webhook) bool {
+			return scope.includes(webhook.BackupProfileID, webhook.ServerID)
+		})
+	}
+	return sent
+}
+
+// ServiceBuildDigest summarizes the profiles of a scope over the period before now
+func ServiceBuildDigest(period string, scope digestScope, now time.Time) (*entity.Digest, error) {
+	length, ok := digestPeriods[period]
+	if !ok {
+		return nil, fmt.Errorf("unknown digest period %q", period)
+	}
+	digest := &entity.Digest{Period: period, From: now.Add(-length), To: now, Profiles: []entity.DigestProfile{}}
+
+	query := DB.Preload("Server").Order("name")
+	switch {
+	case scope.BackupProfileID != 0:
+		digest.BackupProfileID = &scope.BackupProfileID
+		query = query.Where("id = ?", scope.BackupProfileID)
+	case scope.ServerID != 0:
+		digest.ServerID = &scope.ServerID
+		query = query.Where("server_id = ?", scope.ServerID)
+	}
+	var profiles []entity.BackupProfile
+	if err := query.Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	if scope.BackupProfileID != 0 && len(profiles) > 0 {
+		digest.Scope = profiles[0].Name
+	}
+	if scope.ServerID != 0 {
+		var server entity.Server
+		if err := DB.First(&server, scope.ServerID).Error; err == nil {
+			digest.Scope = server.Name
+		}
+	}
+
+	for i := range profiles {
+		summary, err := digestProfile(&profiles[i], digest.From, now, length)
+		if err != nil {
+			return nil, err
+		}
+		digest.Runs += summary.Runs
+		digest.Succeeded += summary.Succeeded
+		digest.Failed += summary.Failed
+		digest.TransferredBytes += summary.TransferredBytes
+		digest.StorageGrowthBytes += summary.StorageGrowthBytes
+		digest.Profiles = append(digest.Profiles, *summary)
+	}
+	return digest, nil
+}
+
+// digestProfile summarizes the runs and stored files of a profile between from and now
+func digestProfile(profile *entity.BackupProfile, from, now time.Time, length time.Duration) (*entity.DigestProfile, error) {
+	summary := &entity.DigestProfile{
+		BackupProfileID: profile.ID,
+		BackupProfile:   profile.Name,
+		ServerID:        profile.ServerID,
+	}
+	if profile.Server != nil {
+		summary.Server = profile.Server.Name
+	}
+
+	var runs []entity.BackupRun
+	if err := DB.Where("backup_profile_id = ? AND start_time >= ? AND start_time < ?", profile.ID, from, now).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		summary.Runs++
+		summary.TransferredBytes += run.TotalSizeBytes
+		switch run.Status {
+		case "completed":
+			summary.Succeeded++
+		case "failed":
+			summary.Failed++
+		}
+	}
+
+	files := func() *gorm.DB {
+		return DB.Model(&entity.BackupFile{}).
+			Joins("JOIN backup_runs ON backup_runs.id = backup_files.backup_run_id").
+			Where("backup_runs.backup_profile_id = ?", profile.ID).
+			Select("COALESCE(SUM(backup_files.size_bytes), 0)")
+	}
+	var added, removed int64
+	if err := files().Where("backup_files.created_at >= ? AND backup_files.created_at < ?", from, now).Scan(&added).Error; err != nil {
+		return nil, err
+	}
+	if err := files().Where("backup_files.deleted_at >= ? AND backup_files.deleted_at < ?", from, now).Scan(&removed).Error; err != nil {
+		return nil, err
+	}
+	if err := files().Where("backup_files.deleted = ?", false).Scan(&summary.StoredBytes).Error; err != nil {
+		return nil, err
+	}
+	summary.StorageGrowthBytes = added - removed
+
+	var lastSuccess entity.BackupRun
+	if err := DB.Where("backup_profile_id = ? AND status = ?", profile.ID, "completed").
+		Order("end_time DESC").Limit(1).Find(&lastSuccess).Error; err != nil {
+		return nil, err
+	}
+	if lastSuccess.ID != 0 {
+		summary.LastSuccessAt = &lastSuccess.EndTime
+	}
+	// Scheduled profiles are only overdue once the watchdog considers their backup missed
+	summary.NoRecentSuccess = profile.Enabled && (lastSuccess.ID == 0 || lastSuccess.EndTime.Before(from))
+	if summary.NoRecentSuccess && profile.ScheduleCron != "" && ServiceCheckMissedBackup(profile, now) == nil {
+		summary.NoRecentSuccess = false
+	}
+
+	// Runs whose retention cutoff passes before the next digest
+	if profile.RetentionDays != nil && *profile.RetentionDays > 0 {
+		cutoff := now.Add(length).AddDate(0, 0, -*profile.RetentionDays)
+		var expiring struct {
+			Count int
+			Bytes int64
+		}
+		if err := DB.Model(&entity.BackupRun{}).
+			Select("COUNT(*) AS count, COALESCE(SUM(total_size_bytes), 0) AS bytes").
+			Where("backup_profile_id = ? AND status = ? AND retention_cleaned_up = ? AND end_time < ?", profile.ID, "completed", false, cutoff).
+			Scan(&expiring).Error; err != nil {
+			return nil, err
+		}
+		summary.RetentionDeletions = expiring.Count
+		summary.RetentionDeletionBytes = expiring.Bytes
+	}
+	return summary, nil
+}
+
+// digestNotification formats a digest for the notifiers, with a line per profile
+func digestNotification(digest *entity.Digest) *Notification {
+	title := digestNames[digest.Period] + " Backup Digest"
+	if digest.Scope != "" {
+		title += " for " + digest.Scope
+	}
+	since := "day"
+	if digest.Period == entity.DigestWeekly {
+		since = "week"
+	}
+
+	var overdue []string
+	details := make([]NotificationDetail, 0, len(digest.Profiles))
+	for _, profile := range digest.Profiles {
+		parts := []string{
+			fmt.Sprintf("%s (%d succeeded, %d failed)", pluralize(profile.Runs, "run"), profile.Succeeded, profile.Failed),
+			formatBytes(profile.TransferredBytes) + " transferred",
+			fmt.Sprintf("%s stored (%s)", formatBytes(profile.StoredBytes), formatByteChange(profile.StorageGrowthBytes)),
+		}
+		if profile.NoRecentSuccess {
+			overdue = append(overdue, profile.BackupProfile)
+			lastSuccess := "never"
+			if profile.LastSuccessAt != nil {
+				lastSuccess = profile.LastSuccessAt.Local().Format(notificationTimeFormat)
+			}
+			parts = append(parts, "no recent successful backup, last: "+lastSuccess)
+		}
+		if profile.RetentionDeletions > 0 {
+			parts = append(parts, fmt.Sprintf("%s (%s) expire by the next digest", pluralize(profile.RetentionDeletions, "run"), formatBytes(profile.RetentionDeletionBytes)))
+		}
+		details = append(details, NotificationDetail{Label: profile.BackupProfile, Value: strings.Join(parts, ", ")})
+	}
+
+	body := fmt.Sprintf("%s in the last %s, %d failed, %s transferred", pluralize(digest.Runs, "run"), since, digest.Failed, formatBytes(digest.TransferredBytes))
+	summary := fmt.Sprintf("%s in the last %s: %d succeeded, %d failed. %s transferred, storage %s.",
+		pluralize(digest.Runs, "run"), since, digest.Succeeded, digest.Failed, formatBytes(digest.TransferredBytes), formatByteChange(digest.StorageGrowthBytes))
+	severity := SeveritySuccess
+	if digest.Failed > 0 {
+		severity = SeverityWarning
+	}
+	if len(overdue) > 0 {
+		severity = SeverityWarning
+		body += fmt.Sprintf(". No recent successful backup: %s", strings.Join(overdue, ", "))
+		summary += fmt.Sprintf(" No recent successful backup: %s.", strings.Join(overdue, ", "))
+	}
+
+	return &Notification{
+		Title: title,
+		Body:  body,
+		Tag:   "digest-" + digest.Period,
+		Data: map[string]string{
+			"type":   "digest",
+			"period": digest.Period,
+		},
+		Subject:   title,
+		Summary:   summary,
+		Severity:  severity,
+		Details:   details,
+		Link:      publicLink("/dashboard"),
+		LinkLabel: "Open dashboard",
+	}
+}
+
+// pluralize formats a count of a noun, e.g. 1 run or 2 runs
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// formatBytes formats a size with binary units, e.g. 1.5 GB
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit && bytes > -unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes)
+	exp := 0
+	for value >= unit*unit || value <= -unit*unit {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value/unit, "KMGTPE"[exp])
+}
+
+// formatByteChange formats a growth or shrinkage of storage
+func formatByteChange(bytes int64) string {
+	switch {
+	case bytes > 0:
+		return "grew by " + formatBytes(bytes)
+	case bytes < 0:
+		return "shrank by " + formatBytes(-bytes)
+	}
+	return "unchanged"
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// smtpTimeout bounds connecting to the mail server and sending one message
+const smtpTimeout = 30 * time.Second
+
+// SMTP transport security modes
+const (
+	SMTPTLSNone     = "none"
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+)
+
+//go:embed templates/email.html templates/email.txt
+var emailTemplateFiles embed.FS
+
+var (
+	emailHTMLTemplate = htmltemplate.Must(htmltemplate.ParseFS(emailTemplateFiles, "templates/email.html"))
+	emailTextTemplate = texttemplate.Must(texttemplate.ParseFS(emailTemplateFiles, "templates/email.txt"))
+)
+
+// SMTPConfig configures the mail server notifications are sent through
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // empty to send without authentication
+	Password string
+	From     *mail.Address
+	TLSMode  string
+	// InsecureSkipVerify accepts self-signed certificates of internal relays
+	InsecureSkipVerify bool
+}
+
+// EmailSender sends notification emails over SMTP
+type EmailSender struct {
+	config SMTPConfig
+}
+
+// EmailMessage is the content of a notification email, rendered as HTML and plain text
+type EmailMessage struct {
+	Subject string
+	Title   string
+	Summary string
+	// Color of the HTML header, e.g. red for failures
+	Color     string
+	Details   []NotificationDetail
+	LogTail   []string
+	Link      string
+	LinkLabel string
+}
+
+// EmailSvc is nil unless SMTP is configured
+var EmailSvc *EmailSender
+
+// InitEmail enables email notifications when BACKAPP_SMTP_HOST is set
+func InitEmail() error {
+	host := os.Getenv("BACKAPP_SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+
+	from, err := mail.ParseAddress(os.Getenv("BACKAPP_SMTP_FROM"))
+	if err != nil {
+		return fmt.Errorf("invalid BACKAPP_SMTP_FROM: %v", err)
+	}
+	tlsMode := envOrDefault("BACKAPP_SMTP_TLS", SMTPTLSStartTLS)
+	defaultPort := 587
+	switch tlsMode {
+	case SMTPTLSStartTLS:
+	case SMTPTLSImplicit:
+		defaultPort = 465
+	case SMTPTLSNone:
+		defaultPort = 25
+	default:
+		return fmt.Errorf("BACKAPP_SMTP_TLS must be one of %s, %s or %s", SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone)
+	}
+	port, err := strconv.Atoi(envOrDefault("BACKAPP_SMTP_PORT", strconv.Itoa(defaultPort)))
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid BACKAPP_SMTP_PORT")
+	}
+
+	EmailSvc = &EmailSender{config: SMTPConfig{
+		Host:               host,
+		Port:               port,
+		Username:           os.Getenv("BACKAPP_SMTP_USERNAME"),
+		Password:           os.Getenv("BACKAPP_SMTP_PASSWORD"),
+		From:               from,
+		TLSMode:            tlsMode,
+		InsecureSkipVerify: os.Getenv("BACKAPP_SMTP_TLS_SKIP_VERIFY") == "true",
+	}}
+	slog.Info("Email notifications enabled", "host", host, "port", port)
+	return nil
+}
+
+// From returns the sender address
+func (s *EmailSender) From() string {
+	return s.config.From.Address
+}
+
+// Send renders a message and delivers it to one recipient
+func (s *EmailSender) Send(to string, message *EmailMessage) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %v", err)
+	}
+	body, err := s.compose(recipient, message)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("mail server does not support authentication")
+		}
+		// PlainAuth refuses to send the password over unencrypted connections to other hosts
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return fmt.Errorf("authentication failed: %v", err)
+		}
+	}
+	if err := client.Mail(s.config.From.Address); err != nil {
+		return fmt.Errorf("sender rejected: %v", err)
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return fmt.Errorf("recipient rejected: %v", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %v", err)
+	}
+	return client.Quit()
+}
+
+// dial connects to the mail server and secures the connection as configured
+func (s *EmailSender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host, InsecureSkipVerify: s.config.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.config.TLSMode == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mail server %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to mail server %s: %v", addr, err)
+	}
+	if s.config.TLSMode == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("mail server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	return client, nil
+}
+
+// compose builds a multipart/alternative message with a plain text and an HTML part
+func (s *EmailSender) compose(to *mail.Address, message *EmailMessage) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := emailTextTemplate.Execute(&text, message); err != nil {
+		return nil, fmt.Errorf("failed to render email: %v", err)
+	}
+	if err := emailHTMLTemplate.Execute(&html, message); err != nil {
+		return nil, fmt.Errorf("failed to render email: %v", err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", s.config.From.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", emailMessageID(s.config.From.Address)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", header[0], header[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// emailMessageID returns a unique Message-ID in the domain of the sender
+func emailMessageID(from string) string {
+	domain := "backapp.local"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	random := make([]byte, 12)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
@@ -17,13 +17,9 @@ func ServiceListServerFiles(serverId uint, remotePath string) ([]entity.FileSyst
 		return nil, err
 	}
 
-	// For local servers, use local filesystem
-	if server.Host == "localhost" || server.Host == "127.0.0.1" {
-		return listLocalFiles(remotePath)
-	}
-
-	// For remote servers, use SSH
-	client, err := NewSSHClient(server)
+	// Servers are always browsed over SSH, also on localhost, so that browsing takes the
+	// server's credentials rather than the BackApp host's own access
+	client, err := AcquireSSHClient(server)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
@@ -73,42 +69,40 @@ func listLocalFiles(dirPath string) ([]entity.FileSystemEntry, error) {
 	return results, nil
 }
 
+// remoteListScript prints "<d|f> <size> <name>" NUL-terminated for every entry of the
+// directory passed as $1. Symlinks to directories are listed as directories. Only POSIX
+// sh, find and GNU or BSD stat are required on the remote side.
+const remoteListScript = `find "$1" -mindepth 1 -maxdepth 1 -exec sh -c '
+for f do
+	if [ -d "$f" ]; then printf "d 0 %s\0" "${f##*/}"; continue; fi
+	size=$(stat -L -c %s -- "$f" 2>/dev/null || stat -L -f %z -- "$f" 2>/dev/null || echo 0)
+	printf "f %s %s\0" "$size" "${f##*/}"
+done' sh {} +`
+
 func listRemoteFiles(client *SSHClient, remotePath string) ([]entity.FileSystemEntry, error) {
 	if remotePath == "" {
 		remotePath = "/home"
 	}
 
-	// Use ls command to list files
-	cmd := fmt.Sprintf("ls -la %s", remotePath)
-	output, err := client.RunCommand(cmd)
+	// List entries NUL-terminated so that any filename survives
+	cmd := shellJoin("sh", "-c", remoteListScript, "sh", remotePath)
+	entries, err := client.ListNul(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list remote files: %w", err)
 	}
 
 	var results []entity.FileSystemEntry
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		if line == "" || strings.HasPrefix(line, "total") {
-			continue
-		}
-
-		// Parse ls -la output: permissions links owner group size date... name
-		parts := strings.Fields(line)
-		if len(parts) < 9 {
-			continue
-		}
-
-		// Skip "." and ".."
-		name := parts[len(parts)-1]
-		if name == "." || name == ".." {
+	for _, entry := range entries {
+		// Each entry is "<d|f> <size> <name>"
+		parts := strings.SplitN(entry, " ", 3)
+		if len(parts) != 3 {
 			continue
 		}
-
-		isDir := strings.HasPrefix(parts[0], "d")
+		name := parts[2]
+		isDir := parts[0] == "d"
 		size := int64(0)
 		if !isDir {
-			fmt.Sscanf(parts[4], "%d", &size)
+			fmt.Sscanf(parts[1], "%d", &size)
 		}
 
 		path := remotePath
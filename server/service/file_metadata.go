@@ -0,0 +1,170 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+)
+
+// findPrintf makes GNU find print the metadata of each listed entry followed by its path
+// and link target, each NUL-terminated, so that listing needs no extra round trip per file
+const findPrintf = ` -printf '%y %s %m %U %G %T@\0%p\0%l\0'`
+
+// fileMetadata describes a remote entry as reported by find
+type fileMetadata struct {
+	fileType   string // file, symlink or dir
+	size       int64
+	mode       uint32 // permission bits including setuid, setgid and sticky
+	uid        int
+	gid        int
+	modTime    time.Time
+	linkTarget string
+}
+
+// remoteEntry is a remote path together with its metadata
+type remoteEntry struct {
+	path string
+	fileMetadata
+}
+
+// listRemote runs a find command that ends in findPrintf and returns the listed entries
+func (s *FileTransferService) listRemote(findCmd string) ([]remoteEntry, error) {
+	fields, err := s.sshClient.ListNul(findCmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields)%3 != 0 {
+		return nil, fmt.Errorf("unexpected find output: %d fields", len(fields))
+	}
+
+	entries := make([]remoteEntry, 0, len(fields)/3)
+	for i := 0; i < len(fields); i += 3 {
+		meta, err := parseFindMetadata(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected find output for %s: %v", fields[i+1], err)
+		}
+		if meta.fileType == "symlink" {
+			meta.linkTarget = fields[i+2]
+		}
+		entries = append(entries, remoteEntry{path: fields[i+1], fileMetadata: *meta})
+	}
+	return entries, nil
+}
+
+// parseFindMetadata parses "<type> <size> <octal mode> <uid> <gid> <mtime>"
+func parseFindMetadata(output string) (*fileMetadata, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("expected 6 fields, got %q", output)
+	}
+
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := strconv.ParseUint(fields[2], 8, 32)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	// %T@ prints fractional seconds, only whole seconds are kept
+	seconds, _, _ := strings.Cut(fields[5], ".")
+	mtime, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &fileMetadata{
+		size:    size,
+		mode:    uint32(mode) & 07777,
+		uid:     uid,
+		gid:     gid,
+		modTime: time.Unix(mtime, 0),
+	}
+	switch fields[0] {
+	case "f":
+		meta.fileType = "file"
+	case "l":
+		meta.fileType = "symlink"
+		meta.size = 0
+	case "d":
+		meta.fileType = "dir"
+		meta.size = 0
+	default:
+		return nil, fmt.Errorf("unsupported file type %q", fields[0])
+	}
+	return meta, nil
+}
+
+// applyMetadata reproduces the remote permissions and mtime of a transferred entry and,
+// when running as root, its ownership. Local copies always stay readable and writable by
+// the owner so that BackApp can serve and delete them; the exact mode is kept in the
+// database. Symlinks are not stored on disk. Failures are logged as warnings and do not
+// fail the backup.
+func (s *FileTransferService) applyMetadata(file remoteFile) {
+	if file.fileType == "symlink" {
+		return
+	}
+	// Ownership goes first, changing it clears the setuid and setgid bits
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(file.localPath, file.uid, file.gid); err != nil {
+			s.logToDatabase(entity.LogLevelWarning, fmt.Sprintf("Failed to set owner of %s: %v", file.localPath, err))
+		}
+	}
+
+	mode := unixPermToFileMode(file.mode) | 0600
+	if file.fileType == "dir" {
+		mode |= 0700
+	}
+	if err := os.Chmod(file.localPath, mode); err != nil {
+		s.logToDatabase(entity.LogLevelWarning, fmt.Sprintf("Failed to set mode of %s: %v", file.localPath, err))
+	}
+	if !file.modTime.IsZero() {
+		if err := os.Chtimes(file.localPath, file.modTime, file.modTime); err != nil {
+			s.logToDatabase(entity.LogLevelWarning, fmt.Sprintf("Failed to set mtime of %s: %v", file.localPath, err))
+		}
+	}
+}
+
+// unixPermToFileMode converts unix permission bits to an os.FileMode
+func unixPermToFileMode(mode uint32) os.FileMode {
+	fileMode := os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		fileMode |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		fileMode |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		fileMode |= os.ModeSticky
+	}
+	return fileMode
+}
+
+// BackupFileMode returns the remote mode of a backup file including its type bits,
+// e.g. for archive headers. Files recorded before metadata was captured default to 0644.
+func BackupFileMode(file *entity.BackupFile) os.FileMode {
+	mode := file.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	fileMode := unixPermToFileMode(mode)
+	switch file.FileType {
+	case "symlink":
+		fileMode |= os.ModeSymlink
+	case "dir":
+		fileMode |= os.ModeDir
+	}
+	return fileMode
+}
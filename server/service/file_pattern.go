@@ -0,0 +1,152 @@
+package service
+
+import (
+	"path"
+	"strings"
+)
+
+// pathPattern is a single gitignore-style pattern of a file rule.
+//
+// Supported syntax:
+//   - "*", "?" and "[...]" match within a single path segment
+//   - "**" matches any number of segments
+//   - a leading "/" or a "/" in the middle anchors the pattern to the rule's remote path,
+//     otherwise it matches at any depth (like "**/pattern")
+//   - a trailing "/" only matches directories
+//   - a leading "!" negates the pattern
+//   - lines starting with "#" are comments
+type pathPattern struct {
+	raw      string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	segments []string
+}
+
+// parsePatterns parses a pattern list with one pattern per line
+func parsePatterns(spec string) []pathPattern {
+	var patterns []pathPattern
+	for _, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pathPattern{raw: line}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		p.segments = strings.Split(line, "/")
+		if !p.anchored {
+			p.segments = append([]string{"**"}, p.segments...)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// matches reports whether the pattern matches relPath or one of its parent directories.
+// relPath is slash separated and relative to the rule's remote path.
+func (p pathPattern) matches(relPath string, isDir bool) bool {
+	segments := strings.Split(relPath, "/")
+	for i := 1; i <= len(segments); i++ {
+		// Every prefix except the full path is a parent directory
+		candidateIsDir := i < len(segments) || isDir
+		if p.dirOnly && !candidateIsDir {
+			continue
+		}
+		if matchSegments(p.segments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches glob segments against path segments, with "**" spanning any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// matchPatterns evaluates patterns in order; the last matching pattern wins, so a
+// negated pattern can undo an earlier match
+func matchPatterns(patterns []pathPattern, relPath string, isDir bool) bool {
+	matched := false
+	for _, p := range patterns {
+		if p.matches(relPath, isDir) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+// fileSelector decides which files of a file rule are transferred
+type fileSelector struct {
+	include []pathPattern
+	exclude []pathPattern
+}
+
+func newFileSelector(includePattern, excludePattern string) *fileSelector {
+	return &fileSelector{
+		include: parsePatterns(includePattern),
+		exclude: parsePatterns(excludePattern),
+	}
+}
+
+// selects reports whether an entry at relPath (relative to the rule's remote path) is transferred
+func (f *fileSelector) selects(relPath string, isDir bool) bool {
+	if len(f.include) > 0 && !matchPatterns(f.include, relPath, isDir) {
+		return false
+	}
+	return !matchPatterns(f.exclude, relPath, isDir)
+}
+
+// prunableNames returns exclude patterns that can be evaluated by the remote find using
+// -name: unanchored single segment patterns. Nothing can be pushed down if any pattern
+// is negated, since a later negation may re-include what an earlier pattern excludes.
+func (f *fileSelector) prunableNames() (dirNames []string, fileNames []string) {
+	for _, p := range f.exclude {
+		if p.negate {
+			return nil, nil
+		}
+	}
+	for _, p := range f.exclude {
+		if p.anchored || len(p.segments) != 2 || strings.Contains(p.segments[1], "**") {
+			continue
+		}
+		dirNames = append(dirNames, p.segments[1])
+		if !p.dirOnly {
+			fileNames = append(fileNames, p.segments[1])
+		}
+	}
+	return dirNames, fileNames
+}
@@ -1,6 +1,11 @@
 package service
 
-import "backapp-server/entity"
+import (
+	"log/slog"
+	"strings"
+
+	"backapp-server/entity"
+)
 
 func ServiceListFileRulesForProfile(profileID int) ([]entity.FileRule, error) {
 	var rules []entity.FileRule
@@ -10,6 +15,14 @@ func ServiceListFileRulesForProfile(profileID int) ([]entity.FileRule, error) {
 	return rules, nil
 }
 
+func ServiceGetFileRule(id uint) (*entity.FileRule, error) {
+	var rule entity.FileRule
+	if err := DB.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
 func ServiceCreateFileRule(input *entity.FileRule) (*entity.FileRule, error) {
 	if err := DB.Create(input).Error; err != nil {
 		return nil, err
@@ -24,7 +37,10 @@ func ServiceUpdateFileRule(id uint, input *entity.FileRule) (*entity.FileRule, e
 	}
 	rule.RemotePath = input.RemotePath
 	rule.Recursive = input.Recursive
+	rule.IncludePattern = input.IncludePattern
 	rule.ExcludePattern = input.ExcludePattern
+	rule.MaxFileSizeBytes = input.MaxFileSizeBytes
+	rule.ModifiedSince = input.ModifiedSince
 	if err := DB.Save(&rule).Error; err != nil {
 		return nil, err
 	}
@@ -34,3 +50,29 @@ func ServiceUpdateFileRule(id uint, input *entity.FileRule) (*entity.FileRule, e
 func ServiceDeleteFileRule(id uint) error {
 	return DB.Delete(&entity.FileRule{}, id).Error
 }
+
+// migrateExcludePatterns puts the comma-separated exclude patterns of older versions on
+// separate lines. It only runs on databases from before include patterns were added, as
+// commas are part of patterns since.
+func migrateExcludePatterns() {
+	var rules []entity.FileRule
+	if err := DB.Where("exclude_pattern LIKE ?", "%,%").Find(&rules).Error; err != nil {
+		slog.Error("Failed to load file rules for pattern migration", "error", err)
+		return
+	}
+	for _, rule := range rules {
+		var patterns []string
+		for _, pattern := range strings.Split(rule.ExcludePattern, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+		err := DB.Model(&entity.FileRule{}).Where("id = ?", rule.ID).Update("exclude_pattern", strings.Join(patterns, "\n")).Error
+		if err != nil {
+			slog.Error("Failed to migrate exclude patterns", "file_rule_id", rule.ID, "error", err)
+		}
+	}
+	if len(rules) > 0 {
+		slog.Info("Put comma-separated exclude patterns on separate lines", "file_rules", len(rules))
+	}
+}
@@ -2,8 +2,9 @@ package service
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -16,6 +17,13 @@ type FileTransferService struct {
 	sshClient *SSHClient
 	destDir   string
 	runID     uint
+	// span is the trace span of the transfer stage, if the run is traced
+	span *Span
+
+	// progress counters for the current transfer
+	startedAt time.Time
+	filesDone int
+	bytesDone int64
 }
 
 // NewFileTransferService creates a new file transfer service
@@ -27,8 +35,12 @@ func NewFileTransferService(sshClient *SSHClient, destDir string, runID uint) *F
 	}
 }
 
-// logToDatabase writes a log entry to the database
+// logToDatabase writes a log entry to the database and the process log. Services without a run
+// (such as dry runs) do not log.
 func (s *FileTransferService) logToDatabase(level, message string) {
+	if s.runID == 0 {
+		return
+	}
 	logEntry := &entity.BackupRunLog{
 		BackupRunID: s.runID,
 		Timestamp:   time.Now(),
@@ -36,8 +48,18 @@ func (s *FileTransferService) logToDatabase(level, message string) {
 		Message:     message,
 	}
 	if err := DB.Create(logEntry).Error; err != nil {
-		log.Printf("Failed to save log to database: %v", err)
+		slog.Error("Failed to save log to database", "run_id", s.runID, "error", err)
 	}
+	GetRunEventHub().PublishLog(logEntry)
+	logRun(s.runID, level, message)
+}
+
+// remoteFile is a single remote entry selected for transfer by a file rule
+type remoteFile struct {
+	ruleID     uint
+	remotePath string
+	localPath  string
+	fileMetadata
 }
 
 // TransferFiles transfers files according to file rules
@@ -46,220 +68,365 @@ func (s *FileTransferService) TransferFiles(fileRules []entity.FileRule) ([]enti
 
 	// Ensure destination directory exists
 	if err := os.MkdirAll(s.destDir, 0755); err != nil {
-		s.logToDatabase("ERROR", fmt.Sprintf("Failed to create destination directory: %v", err))
+		s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to create destination directory: %v", err))
 		return nil, fmt.Errorf("failed to create destination directory: %v", err)
 	}
 
+	// List all files first so that progress can be reported against known totals
+	ruleFiles := make([][]remoteFile, len(fileRules))
+	var filesTotal int
+	var bytesTotal int64
+	for i, rule := range fileRules {
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Listing files for rule %d/%d: %s", i+1, len(fileRules), rule.RemotePath))
+		listSpan := s.span.StartChild("list rule")
+		listSpan.SetAttribute("backapp.rule.path", rule.RemotePath)
+		files, err := s.listRuleFiles(rule)
+		listSpan.SetAttribute("backapp.files", len(files))
+		listSpan.SetError(err)
+		listSpan.End()
+		if err != nil {
+			s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to list files for rule %d: %v", rule.ID, err))
+			return nil, fmt.Errorf("failed to transfer files for rule %d: %v", rule.ID, err)
+		}
+		ruleFiles[i] = files
+		filesTotal += len(files)
+		for _, file := range files {
+			bytesTotal += file.size
+		}
+	}
+
+	s.startedAt = time.Now()
+	s.updateProgress(true, func(p *entity.BackupRunProgress) {
+		p.RuleCount = len(fileRules)
+		p.FilesTotal = filesTotal
+		p.BytesTotal = bytesTotal
+	})
+
 	for i, rule := range fileRules {
-		s.logToDatabase("INFO", fmt.Sprintf("Processing rule %d/%d: %s", i+1, len(fileRules), rule.RemotePath))
-		files, err := s.transferFileRule(rule)
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Processing rule %d/%d: %s", i+1, len(fileRules), rule.RemotePath))
+		s.updateProgress(true, func(p *entity.BackupRunProgress) {
+			p.RuleIndex = i + 1
+			p.CurrentRule = rule.RemotePath
+		})
+		ruleSpan := s.span.StartChild("transfer rule")
+		ruleSpan.SetAttribute("backapp.rule.path", rule.RemotePath)
+		bytesBefore := s.bytesDone
+		files, err := s.transferRemoteFiles(ruleFiles[i], ruleSpan)
+		ruleSpan.SetAttribute("backapp.files", len(files))
+		ruleSpan.SetAttribute("backapp.bytes", s.bytesDone-bytesBefore)
+		ruleSpan.SetError(err)
+		ruleSpan.End()
 		if err != nil {
-			s.logToDatabase("ERROR", fmt.Sprintf("Failed to transfer files for rule %d: %v", rule.ID, err))
+			s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to transfer files for rule %d: %v", rule.ID, err))
 			return nil, fmt.Errorf("failed to transfer files for rule %d: %v", rule.ID, err)
 		}
-		s.logToDatabase("INFO", fmt.Sprintf("Rule %d complete: transferred %d files", i+1, len(files)))
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Rule %d complete: transferred %d files", i+1, len(files)))
 		backupFiles = append(backupFiles, files...)
 	}
 
 	return backupFiles, nil
 }
 
-// transferFileRule transfers files for a single file rule
-func (s *FileTransferService) transferFileRule(rule entity.FileRule) ([]entity.BackupFile, error) {
-	s.logToDatabase("DEBUG", fmt.Sprintf("Checking remote path: %s", rule.RemotePath))
+// ListFiles resolves a file rule into the files a backup would transfer,
+// without downloading anything
+func (s *FileTransferService) ListFiles(rule entity.FileRule) ([]entity.DryRunFile, error) {
+	files, err := s.listRuleFiles(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.DryRunFile, 0, len(files))
+	for _, file := range files {
+		result = append(result, entity.DryRunFile{
+			FileRuleID: file.ruleID,
+			RemotePath: file.remotePath,
+			LocalPath:  file.localPath,
+			SizeBytes:  file.size,
+			FileType:   file.fileType,
+		})
+	}
+	return result, nil
+}
+
+// transferRemoteFiles downloads previously listed files, preserving their local layout
+func (s *FileTransferService) transferRemoteFiles(files []remoteFile, span *Span) ([]entity.BackupFile, error) {
+	var backupFiles []entity.BackupFile
+
+	for _, file := range files {
+		s.updateProgress(false, func(p *entity.BackupRunProgress) {
+			p.CurrentFile = file.remotePath
+		})
+
+		// Symlinks are only recorded with their target. Created on disk, later entries
+		// could be written through them to anywhere on this host.
+		if file.fileType != "symlink" {
+			if err := checkNoSymlinks(s.destDir, file.localPath); err != nil {
+				s.logToDatabase(entity.LogLevelError, err.Error())
+				return nil, err
+			}
+			if err := os.MkdirAll(filepath.Dir(file.localPath), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %v", err)
+			}
+		}
+
+		switch file.fileType {
+		case "symlink":
+			s.logToDatabase(entity.LogLevelDebug, fmt.Sprintf("Recording symlink: %s -> %s", file.remotePath, file.linkTarget))
+		case "dir":
+			s.logToDatabase(entity.LogLevelDebug, fmt.Sprintf("Creating empty directory: %s", file.remotePath))
+			if err := os.MkdirAll(file.localPath, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %v", err)
+			}
+		default:
+			s.logToDatabase(entity.LogLevelDebug, fmt.Sprintf("Transferring file: %s", file.remotePath))
+			fileSpan := span.StartFile(file.remotePath, file.size)
+			err := s.sshClient.CopyFileFromRemoteWithProgress(file.remotePath, file.localPath, func(written int64) {
+				s.updateProgress(false, func(p *entity.BackupRunProgress) {
+					s.setBytesDone(p, s.bytesDone+written)
+				})
+			})
+			fileSpan.SetError(err)
+			fileSpan.End()
+			if err != nil {
+				s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to copy file %s: %v", file.remotePath, err))
+				return nil, fmt.Errorf("failed to copy file %s: %v", file.remotePath, err)
+			}
+			s.logToDatabase(entity.LogLevelDebug, fmt.Sprintf("File transferred successfully: %s (%.2f KB)", filepath.Base(file.remotePath), float64(file.size)/1024))
+		}
+		s.applyMetadata(file)
+
+		s.bytesDone += file.size
+		s.filesDone++
+		s.updateProgress(true, func(p *entity.BackupRunProgress) {
+			p.FilesDone = s.filesDone
+			s.setBytesDone(p, s.bytesDone)
+		})
+
+		modTime := file.modTime
+		backupFiles = append(backupFiles, entity.BackupFile{
+			RemotePath: file.remotePath,
+			LocalPath:  file.localPath,
+			SizeBytes:  file.size,
+			FileSize:   file.size,
+			FileRuleID: file.ruleID,
+			FileType:   file.fileType,
+			Mode:       file.mode,
+			UID:        file.uid,
+			GID:        file.gid,
+			ModTime:    &modTime,
+			LinkTarget: file.linkTarget,
+		})
+	}
+
+	return backupFiles, nil
+}
+
+// checkNoSymlinks makes sure that localPath is within destDir and that none of its existing
+// components below destDir is a symlink, such as one created by older versions
+func checkNoSymlinks(destDir, localPath string) error {
+	rel, err := filepath.Rel(destDir, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside of the backup directory", localPath)
+	}
+	current := destDir
+	for _, component := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, component)
+		stat, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if stat.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through symlink %s", current)
+		}
+	}
+	return nil
+}
+
+// updateProgress publishes transfer progress for the run this service belongs to
+func (s *FileTransferService) updateProgress(force bool, fn func(p *entity.BackupRunProgress)) {
+	GetRunEventHub().UpdateProgress(s.runID, force, fn)
+}
+
+// setBytesDone updates transferred bytes along with throughput and ETA
+func (s *FileTransferService) setBytesDone(p *entity.BackupRunProgress, bytesDone int64) {
+	p.BytesDone = bytesDone
+	elapsed := time.Since(s.startedAt).Seconds()
+	if elapsed <= 0 || bytesDone <= 0 {
+		return
+	}
+	p.BytesPerSecond = float64(bytesDone) / elapsed
+	if remaining := p.BytesTotal - bytesDone; remaining > 0 {
+		p.ETASeconds = int64(float64(remaining) / p.BytesPerSecond)
+	} else {
+		p.ETASeconds = 0
+	}
+}
+
+// listRuleFiles resolves a single file rule into the remote files it selects
+func (s *FileTransferService) listRuleFiles(rule entity.FileRule) ([]remoteFile, error) {
+	s.logToDatabase(entity.LogLevelDebug, fmt.Sprintf("Checking remote path: %s", rule.RemotePath))
 	// Check if remote path exists and is a file or directory
-	checkCmd := fmt.Sprintf("test -e '%s' && echo exists || echo notfound", rule.RemotePath)
+	checkCmd := shellJoin("test", "-e", rule.RemotePath) + " && echo exists || echo notfound"
 	output, err := s.sshClient.RunCommand(checkCmd)
 	if err != nil || strings.TrimSpace(output) != "exists" {
-		s.logToDatabase("ERROR", fmt.Sprintf("Remote path does not exist: %s", rule.RemotePath))
+		s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Remote path does not exist: %s", rule.RemotePath))
 		return nil, fmt.Errorf("remote path does not exist: %s", rule.RemotePath)
 	}
 
 	// Check if it's a directory
-	isDirCmd := fmt.Sprintf("test -d '%s' && echo yes || echo no", rule.RemotePath)
+	isDirCmd := shellJoin("test", "-d", rule.RemotePath) + " && echo yes || echo no"
 	isDirOutput, err := s.sshClient.RunCommand(isDirCmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if path is directory: %v", err)
 	}
 
 	isDir := strings.TrimSpace(isDirOutput) == "yes"
+	selector := newFileSelector(rule.IncludePattern, rule.ExcludePattern)
 
 	if isDir {
 		if rule.Recursive {
-			return s.transferDirectory(rule)
+			return s.listDirectory(rule, selector)
 		}
 		// Non-recursive directory transfer
-		return s.transferDirectoryShallow(rule)
+		return s.listDirectoryShallow(rule, selector)
 	}
 
 	// Single file transfer
-	return s.transferSingleFile(rule)
-}
-
-// transferSingleFile transfers a single file
-func (s *FileTransferService) transferSingleFile(rule entity.FileRule) ([]entity.BackupFile, error) {
-	fileName := filepath.Base(rule.RemotePath)
-	localPath := filepath.Join(s.destDir, fileName)
-
-	s.logToDatabase("DEBUG", fmt.Sprintf("Transferring file: %s", rule.RemotePath))
-	// Get file size
-	sizeCmd := fmt.Sprintf("stat -c%%s '%s' 2>/dev/null || stat -f%%z '%s'", rule.RemotePath, rule.RemotePath)
-	sizeOutput, err := s.sshClient.RunCommand(sizeCmd)
+	if !selector.selects(path.Base(rule.RemotePath), false) {
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("File %s is excluded by the rule's patterns", rule.RemotePath))
+		return nil, nil
+	}
+	// Listing the file itself applies the size and age limits and reads its metadata
+	listCmd := fmt.Sprintf("find %s -maxdepth 0 \\( -type f -o -type l \\)%s", shellQuote(rule.RemotePath), findFilters(rule)) + findPrintf
+	entries, err := s.listRemote(listCmd)
 	if err != nil {
-		s.logToDatabase("ERROR", fmt.Sprintf("Failed to get file size for %s: %v", rule.RemotePath, err))
-		return nil, fmt.Errorf("failed to get file size: %v", err)
+		s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to read metadata of %s: %v", rule.RemotePath, err))
+		return nil, fmt.Errorf("failed to read file metadata: %v", err)
 	}
-
-	var fileSize int64
-	fmt.Sscanf(strings.TrimSpace(sizeOutput), "%d", &fileSize)
-
-	// Download file
-	if err := s.sshClient.CopyFileFromRemote(rule.RemotePath, localPath); err != nil {
-		s.logToDatabase("ERROR", fmt.Sprintf("Failed to copy file %s: %v", rule.RemotePath, err))
-		return nil, fmt.Errorf("failed to copy file: %v", err)
+	if len(entries) == 0 || exceedsMaxSize(rule, entries[0].size) {
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("File %s is skipped by the rule's size or age limits", rule.RemotePath))
+		return nil, nil
 	}
-	s.logToDatabase("DEBUG", fmt.Sprintf("File transferred successfully: %s (%.2f KB)", fileName, float64(fileSize)/1024))
+	return []remoteFile{newRemoteFile(rule, entries[0], filepath.Join(s.destDir, filepath.Base(rule.RemotePath)))}, nil
+}
 
-	backupFile := entity.BackupFile{
-		RemotePath: rule.RemotePath,
-		LocalPath:  localPath,
-		SizeBytes:  fileSize,
-		FileSize:   fileSize,
-		FileRuleID: rule.ID,
+// newRemoteFile returns a listed remote entry that will be stored at localPath
+func newRemoteFile(rule entity.FileRule, entry remoteEntry, localPath string) remoteFile {
+	return remoteFile{
+		ruleID:       rule.ID,
+		remotePath:   entry.path,
+		localPath:    localPath,
+		fileMetadata: entry.fileMetadata,
 	}
-
-	return []entity.BackupFile{backupFile}, nil
 }
 
-// transferDirectoryShallow transfers only files in the directory (non-recursive)
-func (s *FileTransferService) transferDirectoryShallow(rule entity.FileRule) ([]entity.BackupFile, error) {
-	// List files in directory (non-recursive)
-	listCmd := fmt.Sprintf("find '%s' -maxdepth 1 -type f", rule.RemotePath)
-	output, err := s.sshClient.RunCommand(listCmd)
+// listDirectoryShallow lists only files in the directory (non-recursive)
+func (s *FileTransferService) listDirectoryShallow(rule entity.FileRule, selector *fileSelector) ([]remoteFile, error) {
+	// List files in directory (non-recursive), letting find drop what it can
+	_, fileNames := selector.prunableNames()
+	listCmd := fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 \\( -type f -o -type l \\)%s%s", shellQuote(rule.RemotePath), findNameExclusions(fileNames), findFilters(rule)) + findPrintf
+	entries, err := s.listRemote(listCmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %v", err)
 	}
 
-	files := strings.Split(strings.TrimSpace(output), "\n")
-	var backupFiles []entity.BackupFile
-
-	for _, file := range files {
-		file = strings.TrimSpace(file)
-		if file == "" {
-			continue
-		}
-
-		if s.shouldExclude(file, rule.ExcludePattern) {
+	var files []remoteFile
+	excluded := 0
+	for _, entry := range entries {
+		if !selector.selects(path.Base(entry.path), false) || exceedsMaxSize(rule, entry.size) {
+			excluded++
 			continue
 		}
 
-		// Create a temporary rule for this single file
-		singleFileRule := entity.FileRule{
-			ID:         rule.ID,
-			RemotePath: file,
-		}
-
-		transferred, err := s.transferSingleFile(singleFileRule)
-		if err != nil {
-			return nil, fmt.Errorf("failed to transfer file %s: %v", file, err)
-		}
-
-		backupFiles = append(backupFiles, transferred...)
+		files = append(files, newRemoteFile(rule, entry, filepath.Join(s.destDir, filepath.Base(entry.path))))
+	}
+	if excluded > 0 {
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Excluded %d files by the rule's patterns and limits", excluded))
 	}
 
-	return backupFiles, nil
+	return files, nil
 }
 
-// transferDirectory transfers a directory recursively
-func (s *FileTransferService) transferDirectory(rule entity.FileRule) ([]entity.BackupFile, error) {
-	s.logToDatabase("INFO", fmt.Sprintf("Listing files in directory: %s", rule.RemotePath))
-	// Build find command with exclude pattern if provided
-	findCmd := fmt.Sprintf("find '%s' -type f", rule.RemotePath)
+// listDirectory lists a directory recursively
+func (s *FileTransferService) listDirectory(rule entity.FileRule, selector *fileSelector) ([]remoteFile, error) {
+	s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Listing files in directory: %s", rule.RemotePath))
+	// Build find command for files, symlinks and empty directories, pruning excluded
+	// directories and names where find can evaluate them
+	dirNames, fileNames := selector.prunableNames()
+	findCmd := "find " + shellQuote(rule.RemotePath) + " -mindepth 1"
+	if len(dirNames) > 0 {
+		findCmd += " \\( -type d \\(" + findNameAlternatives(dirNames) + " \\) -prune \\) -o"
+	}
+	findCmd += " \\( \\( -type f -o -type l \\)" + findNameExclusions(fileNames) + findFilters(rule) + " -o -type d -empty \\)" + findPrintf
 
-	output, err := s.sshClient.RunCommand(findCmd)
+	entries, err := s.listRemote(findCmd)
 	if err != nil {
-		s.logToDatabase("ERROR", fmt.Sprintf("Failed to list files in %s: %v", rule.RemotePath, err))
+		s.logToDatabase(entity.LogLevelError, fmt.Sprintf("Failed to list files in %s: %v", rule.RemotePath, err))
 		return nil, fmt.Errorf("failed to list files: %v", err)
 	}
 
-	files := strings.Split(strings.TrimSpace(output), "\n")
-	s.logToDatabase("INFO", fmt.Sprintf("Found %d files to transfer", len(files)))
-	var backupFiles []entity.BackupFile
-
-	for _, file := range files {
-		file = strings.TrimSpace(file)
-		if file == "" {
-			continue
-		}
-
-		if s.shouldExclude(file, rule.ExcludePattern) {
-			continue
-		}
+	s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Found %d files to transfer", len(entries)))
 
+	var files []remoteFile
+	excluded := 0
+	for _, entry := range entries {
 		// Preserve directory structure
-		relPath := strings.TrimPrefix(file, rule.RemotePath)
+		relPath := strings.TrimPrefix(entry.path, rule.RemotePath)
 		relPath = strings.TrimPrefix(relPath, "/")
-		localPath := filepath.Join(s.destDir, relPath)
 
-		// Create parent directory
-		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-			return nil, fmt.Errorf("failed to create directory: %v", err)
-		}
-
-		// Get file size
-		sizeCmd := fmt.Sprintf("stat -c%%s '%s' 2>/dev/null || stat -f%%z '%s'", file, file)
-		sizeOutput, err := s.sshClient.RunCommand(sizeCmd)
-		if err != nil {
-			continue // Skip files that can't be stat'd
+		if !selector.selects(relPath, entry.fileType == "dir") || exceedsMaxSize(rule, entry.size) {
+			excluded++
+			continue
 		}
 
-		var fileSize int64
-		fmt.Sscanf(strings.TrimSpace(sizeOutput), "%d", &fileSize)
+		files = append(files, newRemoteFile(rule, entry, filepath.Join(s.destDir, relPath)))
+	}
+	if excluded > 0 {
+		s.logToDatabase(entity.LogLevelInfo, fmt.Sprintf("Excluded %d files by the rule's patterns and limits", excluded))
+	}
 
-		// Download file
-		if err := s.sshClient.CopyFileFromRemote(file, localPath); err != nil {
-			return nil, fmt.Errorf("failed to copy file %s: %v", file, err)
-		}
+	return files, nil
+}
 
-		backupFile := entity.BackupFile{
-			RemotePath: file,
-			LocalPath:  localPath,
-			SizeBytes:  fileSize,
-			FileSize:   fileSize,
-			FileRuleID: rule.ID,
-		}
+// exceedsMaxSize reports whether a file is larger than the rule allows
+func exceedsMaxSize(rule entity.FileRule, size int64) bool {
+	return rule.MaxFileSizeBytes > 0 && size > rule.MaxFileSizeBytes
+}
 
-		backupFiles = append(backupFiles, backupFile)
+// findFilters returns find tests for the size and age limits of a rule
+func findFilters(rule entity.FileRule) string {
+	var filters strings.Builder
+	if rule.MaxFileSizeBytes > 0 {
+		// -size -Nc matches files smaller than N bytes
+		fmt.Fprintf(&filters, " -size -%dc", rule.MaxFileSizeBytes+1)
 	}
-
-	return backupFiles, nil
+	if rule.ModifiedSince != nil {
+		// -newermt @N matches files modified after the unix time N
+		fmt.Fprintf(&filters, " -newermt @%d", rule.ModifiedSince.Unix())
+	}
+	return filters.String()
 }
 
-// shouldExclude checks if a file should be excluded based on the pattern
-func (s *FileTransferService) shouldExclude(filePath, excludePattern string) bool {
-	if excludePattern == "" {
-		return false
+// findNameExclusions returns find tests that drop files with any of the given names
+func findNameExclusions(names []string) string {
+	var tests strings.Builder
+	for _, name := range names {
+		tests.WriteString(" ! -name " + shellQuote(name))
 	}
+	return tests.String()
+}
 
-	// Simple pattern matching - supports wildcards
-	patterns := strings.Split(excludePattern, ",")
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-		if pattern == "" {
-			continue
-		}
-
-		// Simple wildcard matching
-		matched, err := filepath.Match(pattern, filepath.Base(filePath))
-		if err == nil && matched {
-			return true
-		}
-
-		// Check if pattern is in the path
-		if strings.Contains(filePath, pattern) {
-			return true
+// findNameAlternatives returns find tests matching any of the given names
+func findNameAlternatives(names []string) string {
+	var tests strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			tests.WriteString(" -o")
 		}
+		tests.WriteString(" -name " + shellQuote(name))
 	}
-
-	return false
+	return tests.String()
 }
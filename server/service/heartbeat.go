@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+)
+
+const (
+	// heartbeatTimeout bounds a single ping
+	heartbeatTimeout = 10 * time.Second
+	// heartbeatBodyLimit is how much of an error message is sent with failure pings
+	heartbeatBodyLimit = 10000
+)
+
+// heartbeatRetryDelays are the waits before pinging again after an error
+var heartbeatRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second}
+
+var heartbeatClient = &http.Client{Timeout: heartbeatTimeout}
+
+// Heartbeat events; the start ping has no body
+const (
+	heartbeatStart   = "start"
+	heartbeatSuccess = "success"
+	heartbeatFail    = "fail"
+)
+
+// heartbeat pings the monitor of one run. The finish ping waits for the start ping, so the
+// monitor never sees a run start after it completed.
+type heartbeat struct {
+	executor *BackupExecutor
+	runID    uint
+	url      string
+	started  chan struct{}
+}
+
+// startHeartbeat sends the start ping of a run, or returns nil if the profile has no
+// heartbeat URL
+func (e *BackupExecutor) startHeartbeat(profile *entity.BackupProfile, runID uint) *heartbeat {
+	if profile.HeartbeatURL == "" {
+		return nil
+	}
+	h := &heartbeat{executor: e, runID: runID, url: profile.HeartbeatURL, started: make(chan struct{})}
+	go func() {
+		defer close(h.started)
+		h.ping(heartbeatStart, "")
+	}()
+	return h
+}
+
+// finish sends the success or failure ping of the run
+func (h *heartbeat) finish(event, body string) {
+	if h == nil {
+		return
+	}
+	go func() {
+		<-h.started
+		h.ping(event, body)
+	}()
+}
+
+// ping sends an event, retrying after network errors and server errors
+func (h *heartbeat) ping(event, body string) {
+	target, err := heartbeatURL(h.url, event)
+	if err != nil {
+		h.executor.logToDatabase(h.runID, entity.LogLevelWarning, fmt.Sprintf("Invalid heartbeat URL: %v", err))
+		return
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := sendHeartbeat(target, body)
+		if err == nil {
+			h.executor.logToDatabase(h.runID, entity.LogLevelDebug, fmt.Sprintf("Sent %s heartbeat", event))
+			return
+		}
+		if !retry || attempt >= len(heartbeatRetryDelays) {
+			h.executor.logToDatabase(h.runID, entity.LogLevelWarning, fmt.Sprintf("Failed to send %s heartbeat: %v", event, err))
+			return
+		}
+		time.Sleep(heartbeatRetryDelays[attempt])
+	}
+}
+
+// heartbeatURL returns the URL of an event: <url>/start, <url> or <url>/fail
+func heartbeatURL(base, event string) (string, error) {
+	target, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if event != heartbeatSuccess {
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/" + event
+		target.RawPath = ""
+	}
+	return target.String(), nil
+}
+
+// sendHeartbeat posts a ping and reports whether a failed one is worth retrying
+func sendHeartbeat(target, body string) (bool, error) {
+	if len(body) > heartbeatBodyLimit {
+		body = body[:heartbeatBodyLimit]
+	}
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "BackApp")
+	resp, err := heartbeatClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, fmt.Errorf("monitor returned %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm/logger"
+)
+
+// Log formats accepted by InitLogging
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+const redacted = "[REDACTED]"
+
+// secretLogKeys are attributes whose values are never logged
+var secretLogKeys = map[string]bool{
+	"password":      true,
+	"passphrase":    true,
+	"private_key":   true,
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"authorization": true,
+	"cookie":        true,
+	"master_key":    true,
+}
+
+// secretWords are the names of settings whose values are redacted from messages
+const secretWords = `(?:password|passwd|passphrase|secret|token|api[_-]?key|authorization)`
+
+var (
+	// secretAssignments matches "name=value" and "name: value", e.g. in command lines and
+	// environment variables
+	secretAssignments = regexp.MustCompile(`(?i)\b([\w.-]*` + secretWords + `[\w.-]*"?\s*[=:]\s*)((?:Bearer|Basic)\s+\S+|"[^"]*"|'[^']*'|[^\s,;&|]+)`)
+	// secretFlags matches "--name value" and "-name value" command line flags
+	secretFlags = regexp.MustCompile(`(?i)((?:^|\s)--?[\w-]*` + secretWords + `[\w-]*\s+)("[^"]*"|'[^']*'|[^\s-]\S*)`)
+	// urlPasswords matches the password of credentials in URLs
+	urlPasswords = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+)
+
+// InitLogging makes a structured logger with the level (debug, info, warn or error) and
+// format the default, which log.Printf also writes to
+func InitLogging(level, format string) error {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q, use debug, info, warn or error", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel, ReplaceAttr: redactLogAttr}
+
+	var handler slog.Handler
+	switch format {
+	case LogFormatText:
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("invalid log format %q, use %s or %s", format, LogFormatText, LogFormatJSON)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// redactLogAttr hides the values of secret attributes and secrets within messages and errors
+func redactLogAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSecretLogKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactSecrets(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactSecrets(err.Error()))
+		}
+	}
+	return a
+}
+
+func isSecretLogKey(key string) bool {
+	key = strings.ToLower(key)
+	if secretLogKeys[key] {
+		return true
+	}
+	for _, suffix := range []string{"_password", "_passphrase", "_secret", "_token"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets replaces the values of passwords, tokens and similar settings in text
+func redactSecrets(text string) string {
+	text = secretAssignments.ReplaceAllString(text, "${1}"+redacted)
+	text = secretFlags.ReplaceAllString(text, "${1}"+redacted)
+	return urlPasswords.ReplaceAllString(text, "${1}"+redacted+"@")
+}
+
+// logRun writes a message of a backup run, at the level of its run log, to the process log
+func logRun(runID uint, level, message string) {
+	slog.Log(context.Background(), runLogLevel(level), message, "run_id", runID)
+}
+
+// runLogLevel maps the levels of run logs to slog levels
+func runLogLevel(level string) slog.Level {
+	switch level {
+	case entity.LogLevelDebug:
+		return slog.LevelDebug
+	case entity.LogLevelWarning:
+		return slog.LevelWarn
+	case entity.LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// gormLogWriter writes database errors and slow queries to the process log
+type gormLogWriter struct{}
+
+func (gormLogWriter) Printf(format string, args ...any) {
+	slog.Warn(fmt.Sprintf(format, args...), "component", "database")
+}
+
+// newGormLogger logs database errors and slow queries without their parameters, which may
+// hold encrypted credentials
+func newGormLogger() logger.Interface {
+	return logger.New(gormLogWriter{}, logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  logger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
+}
+
+// fatal logs an error and exits, for errors the server cannot start without
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+)
+
+// runStatuses are the statuses reported by the run metrics
+var runStatuses = []string{"pending", "running", "completed", "failed"}
+
+// Metrics counts events that are not recorded in the database, like failed SSH
+// connections, and renders all metrics in the Prometheus text format
+type Metrics struct {
+	mu sync.Mutex
+	// sshConnectionErrors counts failed connections per server ID
+	sshConnectionErrors map[uint]uint64
+}
+
+var (
+	metrics     *Metrics
+	metricsOnce sync.Once
+)
+
+// GetMetrics returns the singleton metrics instance
+func GetMetrics() *Metrics {
+	metricsOnce.Do(func() {
+		metrics = &Metrics{sshConnectionErrors: make(map[uint]uint64)}
+	})
+	return metrics
+}
+
+// RecordSSHConnectionError counts a failed connection to a saved server
+func (m *Metrics) RecordSSHConnectionError(serverID uint) {
+	if serverID == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sshConnectionErrors[serverID]++
+}
+
+// metricsWriter writes metric families in the Prometheus text exposition format
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+// family writes the HELP and TYPE lines of a metric
+func (mw *metricsWriter) family(name, metricType, help string) {
+	mw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes one value; labels alternate between names and values
+func (mw *metricsWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	mw.printf("%s %s\n", b.String(), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (mw *metricsWriter) printf(format string, args ...interface{}) {
+	if mw.err == nil {
+		_, mw.err = fmt.Fprintf(mw.w, format, args...)
+	}
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// profileRunStats aggregates the runs of a profile with one status
+type profileRunStats struct {
+	BackupProfileID uint
+	Status          string
+	Runs            int64
+	Bytes           int64
+	Files           int64
+}
+
+// Write renders the metrics of backups, retention, storage, the scheduler and SSH
+// connections, mostly derived from the database
+func (m *Metrics) Write(w io.Writer) error {
+	mw := &metricsWriter{w: w}
+	if err := m.writeProfiles(mw); err != nil {
+		return err
+	}
+	if err := m.writeStorage(mw); err != nil {
+		return err
+	}
+	if err := m.writeScheduler(mw); err != nil {
+		return err
+	}
+	if err := m.writeSSH(mw); err != nil {
+		return err
+	}
+	return mw.err
+}
+
+func (m *Metrics) writeProfiles(mw *metricsWriter) error {
+	var profiles []entity.BackupProfile
+	if err := DB.Preload("Server").Order("id").Find(&profiles).Error; err != nil {
+		return err
+	}
+
+	var stats []profileRunStats
+	if err := DB.Model(&entity.BackupRun{}).
+		Select("backup_profile_id, status, COUNT(*) AS runs, COALESCE(SUM(total_size_bytes), 0) AS bytes, COALESCE(SUM(total_files), 0) AS files").
+		Group("backup_profile_id, status").
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+	byProfile := make(map[uint]map[string]profileRunStats)
+	for _, s := range stats {
+		if byProfile[s.BackupProfileID] == nil {
+			byProfile[s.BackupProfileID] = make(map[string]profileRunStats)
+		}
+		byProfile[s.BackupProfileID][s.Status] = s
+	}
+
+	var retention []struct {
+		BackupProfileID uint
+		Runs            int64
+		Bytes           int64
+	}
+	if err := DB.Model(&entity.BackupRun{}).
+		Select("backup_runs.backup_profile_id, COUNT(DISTINCT backup_runs.id) AS runs, COALESCE(SUM(CASE WHEN backup_files.deleted THEN backup_files.size_bytes ELSE 0 END), 0) AS bytes").
+		Joins("LEFT JOIN backup_files ON backup_files.backup_run_id = backup_runs.id").
+		Where("backup_runs.retention_cleaned_up = ?", true).
+		Group("backup_runs.backup_profile_id").
+		Scan(&retention).Error; err != nil {
+		return err
+	}
+	retentionRuns := make(map[uint]int64)
+	retentionBytes := make(map[uint]int64)
+	for _, r := range retention {
+		retentionRuns[r.BackupProfileID] = r.Runs
+		retentionBytes[r.BackupProfileID] = r.Bytes
+	}
+
+	lastRuns := make(map[uint]entity.BackupRun)
+	lastSuccess := make(map[uint]time.Time)
+	for _, profile := range profiles {
+		var run entity.BackupRun
+		if err := DB.Where("backup_profile_id = ?", profile.ID).Order("start_time DESC").Limit(1).Find(&run).Error; err != nil {
+			return err
+		}
+		if run.ID != 0 {
+			lastRuns[profile.ID] = run
+		}
+		var success entity.BackupRun
+		if err := DB.Where("backup_profile_id = ? AND status = ?", profile.ID, "completed").Order("end_time DESC").Limit(1).Find(&success).Error; err != nil {
+			return err
+		}
+		if success.ID != 0 {
+			lastSuccess[profile.ID] = success.EndTime
+		}
+	}
+
+	labels := func(profile *entity.BackupProfile, extra ...string) []string {
+		server := ""
+		if profile.Server != nil {
+			server = profile.Server.Name
+		}
+		return append([]string{"profile_id", strconv.FormatUint(uint64(profile.ID), 10), "profile", profile.Name, "server", server}, extra...)
+	}
+
+	mw.family("backapp_backup_profile_enabled", "gauge", "Whether the backup profile is enabled.")
+	for i := range profiles {
+		mw.sample("backapp_backup_profile_enabled", boolValue(profiles[i].Enabled), labels(&profiles[i])...)
+	}
+
+	mw.family("backapp_backup_last_success_timestamp_seconds", "gauge", "Unix time the last successful run of the profile ended; absent before the first one.")
+	for i := range profiles {
+		if at, ok := lastSuccess[profiles[i].ID]; ok {
+			mw.sample("backapp_backup_last_success_timestamp_seconds", float64(at.Unix()), labels(&profiles[i])...)
+		}
+	}
+
+	mw.family("backapp_backup_last_run_status", "gauge", "Status of the last run of the profile, 1 for the current status.")
+	for i := range profiles {
+		run, ok := lastRuns[profiles[i].ID]
+		if !ok {
+			continue
+		}
+		for _, status := range runStatuses {
+			mw.sample("backapp_backup_last_run_status", boolValue(run.Status == status), labels(&profiles[i], "status", status)...)
+		}
+	}
+
+	mw.family("backapp_backup_last_run_duration_seconds", "gauge", "Duration of the last finished run of the profile.")
+	for i := range profiles {
+		if run, ok := lastRuns[profiles[i].ID]; ok && runFinished(&run) {
+			mw.sample("backapp_backup_last_run_duration_seconds", run.EndTime.Sub(run.StartTime).Seconds(), labels(&profiles[i])...)
+		}
+	}
+
+	mw.family("backapp_backup_last_run_bytes", "gauge", "Bytes transferred by the last finished run of the profile.")
+	for i := range profiles {
+		if run, ok := lastRuns[profiles[i].ID]; ok && runFinished(&run) {
+			mw.sample("backapp_backup_last_run_bytes", float64(run.TotalSizeBytes), labels(&profiles[i])...)
+		}
+	}
+
+	mw.family("backapp_backup_last_run_files", "gauge", "Files transferred by the last finished run of the profile.")
+	for i := range profiles {
+		if run, ok := lastRuns[profiles[i].ID]; ok && runFinished(&run) {
+			mw.sample("backapp_backup_last_run_files", float64(run.TotalFiles), labels(&profiles[i])...)
+		}
+	}
+
+	mw.family("backapp_backup_runs_total", "counter", "Runs of the profile by status.")
+	for i := range profiles {
+		for _, status := range runStatuses {
+			mw.sample("backapp_backup_runs_total", float64(byProfile[profiles[i].ID][status].Runs), labels(&profiles[i], "status", status)...)
+		}
+	}
+
+	mw.family("backapp_backup_transferred_bytes_total", "counter", "Bytes transferred by all runs of the profile.")
+	for i := range profiles {
+		var bytes int64
+		for _, s := range byProfile[profiles[i].ID] {
+			bytes += s.Bytes
+		}
+		mw.sample("backapp_backup_transferred_bytes_total", float64(bytes), labels(&profiles[i])...)
+	}
+
+	mw.family("backapp_backup_transferred_files_total", "counter", "Files transferred by all runs of the profile.")
+	for i := range profiles {
+		var files int64
+		for _, s := range byProfile[profiles[i].ID] {
+			files += s.Files
+		}
+		mw.sample("backapp_backup_transferred_files_total", float64(files), labels(&profiles[i])...)
+	}
+
+	mw.family("backapp_retention_deleted_runs_total", "counter", "Runs of the profile deleted by the retention policy.")
+	for i := range profiles {
+		mw.sample("backapp_retention_deleted_runs_total", float64(retentionRuns[profiles[i].ID]), labels(&profiles[i])...)
+	}
+
+	mw.family("backapp_retention_deleted_bytes_total", "counter", "Bytes of the runs of the profile deleted by the retention policy.")
+	for i := range profiles {
+		mw.sample("backapp_retention_deleted_bytes_total", float64(retentionBytes[profiles[i].ID]), labels(&profiles[i])...)
+	}
+	return nil
+}
+
+func (m *Metrics) writeStorage(mw *metricsWriter) error {
+	usage, err := GetStorageUsage()
+	if err != nil {
+		return err
+	}
+	labels := func(location *entity.StorageUsage) []string {
+		return []string{"storage_location_id", strconv.FormatUint(uint64(location.StorageLocationID), 10), "storage_location", location.Name}
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(*entity.StorageUsage) int64
+	}{
+		{"backapp_storage_total_bytes", "Size of the filesystem of the storage location.", func(l *entity.StorageUsage) int64 { return l.TotalBytes }},
+		{"backapp_storage_used_bytes", "Used bytes of the filesystem of the storage location.", func(l *entity.StorageUsage) int64 { return l.UsedBytes }},
+		{"backapp_storage_free_bytes", "Bytes available on the filesystem of the storage location.", func(l *entity.StorageUsage) int64 { return l.FreeBytes }},
+		{"backapp_storage_backup_bytes", "Bytes of backups in the storage location.", func(l *entity.StorageUsage) int64 { return l.BackupSizeBytes }},
+	}
+	for _, gauge := range gauges {
+		mw.family(gauge.name, "gauge", gauge.help)
+		for i := range usage.Locations {
+			location := &usage.Locations[i]
+			// Locations whose filesystem could not be read have no size
+			if location.TotalBytes == 0 && gauge.name != "backapp_storage_backup_bytes" {
+				continue
+			}
+			mw.sample(gauge.name, float64(gauge.value(location)), labels(location)...)
+		}
+	}
+	return nil
+}
+
+func (m *Metrics) writeScheduler(mw *metricsWriter) error {
+	var inProgress int64
+	if err := DB.Model(&entity.BackupRun{}).Where("status IN ?", []string{"pending", "running"}).Count(&inProgress).Error; err != nil {
+		return err
+	}
+
+	mw.family("backapp_scheduler_jobs", "gauge", "Backup profiles with an active schedule.")
+	mw.sample("backapp_scheduler_jobs", float64(GetScheduler().JobCount()))
+	mw.family("backapp_scheduler_queue_depth", "gauge", "Backup runs that are pending or running.")
+	mw.sample("backapp_scheduler_queue_depth", float64(inProgress))
+	return nil
+}
+
+func (m *Metrics) writeSSH(mw *metricsWriter) error {
+	var servers []entity.Server
+	if err := DB.Select("id", "name").Order("id").Find(&servers).Error; err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mw.family("backapp_ssh_connection_errors_total", "counter", "Failed SSH connections to the server since BackApp started.")
+	for _, server := range servers {
+		mw.sample("backapp_ssh_connection_errors_total", float64(m.sshConnectionErrors[server.ID]),
+			"server_id", strconv.FormatUint(uint64(server.ID), 10), "server", server.Name)
+	}
+	return nil
+}
+
+// runFinished reports whether a run completed or failed
+func runFinished(run *entity.BackupRun) bool {
+	return run.Status == "completed" || run.Status == "failed"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
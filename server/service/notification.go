@@ -7,8 +7,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"backapp-server/entity"
@@ -19,11 +22,19 @@ import (
 // NotificationService handles push notifications
 type NotificationService struct {
 	vapidKeys *entity.VAPIDKeys
+	notifiers []Notifier
+	alerts    *alertTracker
+
+	heldMu sync.Mutex
+	// held are the notifications of preferences in their quiet hours
+	held map[uint][]heldNotification
 }
 
 // NewNotificationService creates a new notification service
 func NewNotificationService() *NotificationService {
-	return &NotificationService{}
+	n := &NotificationService{alerts: newAlertTracker(), held: make(map[uint][]heldNotification)}
+	n.notifiers = []Notifier{pushNotifier{n}, emailNotifier{n}, chatNotifier{n}}
+	return n
 }
 
 // Initialize sets up VAPID keys (creates new ones if not exist)
@@ -48,7 +59,7 @@ func (n *NotificationService) Initialize() error {
 			return fmt.Errorf("failed to save VAPID keys: %v", err)
 		}
 
-		log.Println("Generated new VAPID keys for push notifications")
+		slog.Info("Generated new VAPID keys for push notifications")
 	}
 
 	n.vapidKeys = &keys
@@ -93,18 +104,19 @@ func (n *NotificationService) SaveSubscription(endpoint, p256dh, auth, userAgent
 
 	// Create default notification preferences for this subscription
 	defaultPref := &entity.NotificationPreference{
-		SubscriptionID:              sub.ID,
+		SubscriptionID:              &sub.ID,
 		NotifyOnStart:               false,
 		NotifyOnSuccess:             false,
 		NotifyOnFailure:             true,
 		NotifyOnConsecutiveFailures: true,
 		ConsecutiveFailureThreshold: 3,
+		NotifyOnMissedBackup:        true,
 		NotifyOnLowStorage:          true,
 		LowStorageThreshold:         10,
 	}
 
 	if err := DB.Create(defaultPref).Error; err != nil {
-		log.Printf("Warning: failed to create default notification preferences: %v", err)
+		slog.Warn("Failed to create default notification preferences", "error", err)
 	}
 
 	return sub, nil
@@ -174,8 +186,12 @@ func (n *NotificationService) UpdatePreference(prefID uint, input *entity.Notifi
 	pref.NotifyOnFailure = input.NotifyOnFailure
 	pref.NotifyOnConsecutiveFailures = input.NotifyOnConsecutiveFailures
 	pref.ConsecutiveFailureThreshold = input.ConsecutiveFailureThreshold
+	pref.NotifyOnMissedBackup = input.NotifyOnMissedBackup
 	pref.NotifyOnLowStorage = input.NotifyOnLowStorage
 	pref.LowStorageThreshold = input.LowStorageThreshold
+	pref.DigestFrequency = input.DigestFrequency
+	pref.QuietHoursStart = input.QuietHoursStart
+	pref.QuietHoursEnd = input.QuietHoursEnd
 
 	if err := DB.Save(&pref).Error; err != nil {
 		return nil, err
@@ -187,7 +203,7 @@ func (n *NotificationService) UpdatePreference(prefID uint, input *entity.Notifi
 // CreatePreference creates a new notification preference for a subscription
 func (n *NotificationService) CreatePreference(subscriptionID uint, input *entity.NotificationPreferenceInput) (*entity.NotificationPreference, error) {
 	pref := &entity.NotificationPreference{
-		SubscriptionID:              subscriptionID,
+		SubscriptionID:              &subscriptionID,
 		BackupProfileID:             input.BackupProfileID,
 		ServerID:                    input.ServerID,
 		NotifyOnStart:               input.NotifyOnStart,
@@ -195,8 +211,12 @@ func (n *NotificationService) CreatePreference(subscriptionID uint, input *entit
 		NotifyOnFailure:             input.NotifyOnFailure,
 		NotifyOnConsecutiveFailures: input.NotifyOnConsecutiveFailures,
 		ConsecutiveFailureThreshold: input.ConsecutiveFailureThreshold,
+		NotifyOnMissedBackup:        input.NotifyOnMissedBackup,
 		NotifyOnLowStorage:          input.NotifyOnLowStorage,
 		LowStorageThreshold:         input.LowStorageThreshold,
+		DigestFrequency:             input.DigestFrequency,
+		QuietHoursStart:             input.QuietHoursStart,
+		QuietHoursEnd:               input.QuietHoursEnd,
 	}
 
 	if err := DB.Create(pref).Error; err != nil {
@@ -257,7 +277,7 @@ func (n *NotificationService) SendNotification(sub *entity.PushSubscription, pay
 
 	if resp.StatusCode == http.StatusGone {
 		// Subscription is no longer valid, remove it
-		log.Printf("Subscription expired, removing: %s", sub.Endpoint)
+		slog.Info("Push subscription expired, removing it", "endpoint", sub.Endpoint)
 		n.DeleteSubscription(sub.Endpoint)
 		return nil
 	}
@@ -269,139 +289,229 @@ func (n *NotificationService) SendNotification(sub *entity.PushSubscription, pay
 	return nil
 }
 
-// SendToAll sends a notification to all subscriptions that match the criteria
-func (n *NotificationService) SendToAll(payload *NotificationPayload, filterFunc func(*entity.NotificationPreference) bool) {
-	subs, err := n.ListSubscriptions()
-	if err != nil {
-		log.Printf("Failed to list subscriptions: %v", err)
+// SendToAll sends a notification through every notifier to the recipients whose
+// preferences match the criteria. Repeated alerts are skipped, and notifications other
+// than failures are held for preferences in their quiet hours.
+func (n *NotificationService) SendToAll(notification *Notification, filterFunc func(*entity.NotificationPreference) bool) {
+	var prefs []entity.NotificationPreference
+	if err := DB.Find(&prefs).Error; err != nil {
+		slog.Error("Failed to list notification preferences", "error", err)
 		return
 	}
 
-	for _, sub := range subs {
-		prefs, err := n.GetPreferences(sub.ID)
-		if err != nil {
+	now := time.Now()
+	send := make(map[uint]bool)
+	notified := make(map[string]bool)
+	var quiet []*entity.NotificationPreference
+	for i := range prefs {
+		pref := &prefs[i]
+		if !filterFunc(pref) {
+			if notification.AlertKey != "" {
+				n.alerts.clear(notification.AlertKey, preferenceRecipient(pref))
+			}
 			continue
 		}
-
-		// Check if any preference matches the filter
-		shouldSend := false
-		for _, pref := range prefs {
-			if filterFunc(&pref) {
-				shouldSend = true
-				break
-			}
+		if notification.AlertKey != "" && !n.alerts.allow(notification.AlertKey, preferenceRecipient(pref), now) {
+			continue
 		}
-
-		if shouldSend {
-			go func(s entity.PushSubscription) {
-				if err := n.SendNotification(&s, payload); err != nil {
-					log.Printf("Failed to send notification to %s: %v", s.Endpoint, err)
-				}
-			}(sub)
+		if notification.Severity != SeverityFailure && inQuietHours(pref, now) {
+			quiet = append(quiet, pref)
+			continue
 		}
+		send[pref.ID] = true
+		notified[notifiedRecipient(pref)] = true
 	}
-}
 
-// NotifyBackupStarted sends notification when a backup starts
-func (n *NotificationService) NotifyBackupStarted(profileID uint, profileName string) {
-	payload := &NotificationPayload{
-		Title: "Backup Started",
-		Body:  fmt.Sprintf("Backup '%s' has started", profileName),
-		Tag:   fmt.Sprintf("backup-started-%d", profileID),
-		Data: map[string]string{
-			"type":       "backup_started",
-			"profile_id": fmt.Sprintf("%d", profileID),
-		},
+	// Recipients get the notification once, now if any of their rules is not quiet
+	for _, pref := range quiet {
+		if recipient := notifiedRecipient(pref); !notified[recipient] {
+			notified[recipient] = true
+			n.hold(pref, notification, now)
+		}
 	}
+	if len(send) == 0 {
+		return
+	}
+	for _, notifier := range n.notifiers {
+		notifier.Notify(notification, func(pref *entity.NotificationPreference) bool { return send[pref.ID] })
+	}
+}
 
-	n.SendToAll(payload, func(pref *entity.NotificationPreference) bool {
-		if !pref.NotifyOnStart {
+// profilePreference matches preferences that enable an event for all backups, for the
+// profile or for the server of the profile
+func profilePreference(profileID uint, enabled func(*entity.NotificationPreference) bool) func(*entity.NotificationPreference) bool {
+	var profile entity.BackupProfile
+	DB.Select("id", "server_id").First(&profile, profileID)
+	return func(pref *entity.NotificationPreference) bool {
+		if !enabled(pref) {
 			return false
 		}
-		// Check if this is a global preference or specific to this profile
-		if pref.BackupProfileID == nil {
-			return true
+		if pref.BackupProfileID != nil {
+			return *pref.BackupProfileID == profileID
 		}
-		return *pref.BackupProfileID == profileID
-	})
+		if pref.ServerID != nil {
+			return *pref.ServerID == profile.ServerID
+		}
+		return true
+	}
 }
 
-// NotifyBackupSuccess sends notification when a backup succeeds
-func (n *NotificationService) NotifyBackupSuccess(profileID uint, profileName string, duration time.Duration) {
-	payload := &NotificationPayload{
-		Title: "Backup Completed",
-		Body:  fmt.Sprintf("Backup '%s' completed successfully in %s", profileName, duration.Round(time.Second)),
-		Tag:   fmt.Sprintf("backup-success-%d", profileID),
+// runNotification returns a notification about a run, linking to the run
+func runNotification(profileID, runID uint, notificationType string) *Notification {
+	return &Notification{
+		Tag: fmt.Sprintf("%s-%d", strings.ReplaceAll(notificationType, "_", "-"), profileID),
 		Data: map[string]string{
-			"type":       "backup_success",
+			"type":       notificationType,
 			"profile_id": fmt.Sprintf("%d", profileID),
+			"run_id":     fmt.Sprintf("%d", runID),
 		},
+		Details:   []NotificationDetail{{Label: "Run", Value: fmt.Sprintf("#%d", runID)}},
+		RunID:     runID,
+		Link:      publicLink(fmt.Sprintf("/backup-runs/%d", runID)),
+		LinkLabel: "View backup run",
 	}
+}
 
-	n.SendToAll(payload, func(pref *entity.NotificationPreference) bool {
-		if !pref.NotifyOnSuccess {
-			return false
-		}
-		if pref.BackupProfileID == nil {
-			return true
+// NotifyBackupStarted sends notification when a backup starts
+func (n *NotificationService) NotifyBackupStarted(profileID, runID uint, profileName string) {
+	notification := runNotification(profileID, runID, "backup_started")
+	notification.Title = "Backup Started"
+	notification.Body = fmt.Sprintf("Backup '%s' has started", profileName)
+	notification.Subject = fmt.Sprintf("Backup '%s' started", profileName)
+	notification.Summary = fmt.Sprintf("The backup profile '%s' has started a backup.", profileName)
+	notification.Severity = SeverityInfo
+
+	n.SendToAll(notification, profilePreference(profileID, func(pref *entity.NotificationPreference) bool { return pref.NotifyOnStart }))
+	data := runWebhookData(profileID, runID, "running")
+	n.SendWebhooks(entity.WebhookEventBackupStarted, data, runWebhook(data))
+}
+
+// NotifyBackupSuccess sends notification when a backup succeeds. After failed runs, it
+// also tells the recipients of the failure alerts that the profile recovered.
+func (n *NotificationService) NotifyBackupSuccess(profileID, runID uint, profileName string, duration time.Duration, previousFailures int) {
+	notification := runNotification(profileID, runID, "backup_success")
+	notification.Title = "Backup Completed"
+	notification.Body = fmt.Sprintf("Backup '%s' completed successfully in %s", profileName, duration.Round(time.Second))
+	notification.Subject = fmt.Sprintf("Backup '%s' completed", profileName)
+	notification.Summary = fmt.Sprintf("The backup profile '%s' completed successfully.", profileName)
+	notification.Severity = SeveritySuccess
+	notification.Details = append(notification.Details, NotificationDetail{Label: "Duration", Value: duration.Round(time.Second).String()})
+	enabled := func(pref *entity.NotificationPreference) bool { return pref.NotifyOnSuccess }
+
+	if previousFailures > 0 {
+		n.alerts.resolve(consecutiveFailuresAlert(profileID))
+		notification = runNotification(profileID, runID, "backup_recovered")
+		notification.Data["failure_count"] = fmt.Sprintf("%d", previousFailures)
+		notification.Title = "Backup Recovered"
+		notification.Body = fmt.Sprintf("Backup '%s' completed successfully after %s", profileName, pluralize(previousFailures, "failed run"))
+		notification.Subject = fmt.Sprintf("Backup '%s' recovered", profileName)
+		notification.Summary = fmt.Sprintf("The backup profile '%s' completed successfully after %s.", profileName, pluralize(previousFailures, "failed run"))
+		notification.Severity = SeveritySuccess
+		notification.Details = append(notification.Details,
+			NotificationDetail{Label: "Duration", Value: duration.Round(time.Second).String()},
+			NotificationDetail{Label: "Failed runs before", Value: fmt.Sprintf("%d", previousFailures)})
+		enabled = func(pref *entity.NotificationPreference) bool {
+			return pref.NotifyOnSuccess || pref.NotifyOnFailure ||
+				(pref.NotifyOnConsecutiveFailures && previousFailures >= pref.ConsecutiveFailureThreshold)
 		}
-		return *pref.BackupProfileID == profileID
-	})
+	}
+
+	n.SendToAll(notification, profilePreference(profileID, enabled))
+	data := runWebhookData(profileID, runID, "completed")
+	data.DurationSeconds = duration.Seconds()
+	n.SendWebhooks(entity.WebhookEventBackupCompleted, data, runWebhook(data))
+	if previousFailures > 0 {
+		recovered := *data
+		recovered.FailureCount = previousFailures
+		n.SendWebhooks(entity.WebhookEventBackupRecovered, &recovered, runWebhook(&recovered))
+	}
 }
 
 // NotifyBackupFailed sends notification when a backup fails
-func (n *NotificationService) NotifyBackupFailed(profileID uint, profileName string, errorMsg string) {
-	payload := &NotificationPayload{
-		Title: "Backup Failed",
-		Body:  fmt.Sprintf("Backup '%s' failed: %s", profileName, errorMsg),
-		Tag:   fmt.Sprintf("backup-failed-%d", profileID),
-		Data: map[string]string{
-			"type":       "backup_failed",
-			"profile_id": fmt.Sprintf("%d", profileID),
-		},
-	}
+func (n *NotificationService) NotifyBackupFailed(profileID, runID uint, profileName string, errorMsg string) {
+	notification := runNotification(profileID, runID, "backup_failed")
+	notification.Title = "Backup Failed"
+	notification.Body = fmt.Sprintf("Backup '%s' failed: %s", profileName, errorMsg)
+	notification.Subject = fmt.Sprintf("Backup '%s' failed", profileName)
+	notification.Summary = fmt.Sprintf("The backup profile '%s' failed.", profileName)
+	notification.Severity = SeverityFailure
+	notification.Details = append(notification.Details, NotificationDetail{Label: "Error", Value: errorMsg})
+
+	n.SendToAll(notification, profilePreference(profileID, func(pref *entity.NotificationPreference) bool { return pref.NotifyOnFailure }))
+	data := runWebhookData(profileID, runID, "failed")
+	data.Error = errorMsg
+	n.SendWebhooks(entity.WebhookEventBackupFailed, data, runWebhook(data))
+}
 
-	n.SendToAll(payload, func(pref *entity.NotificationPreference) bool {
-		if !pref.NotifyOnFailure {
-			return false
-		}
-		if pref.BackupProfileID == nil {
-			return true
-		}
-		return *pref.BackupProfileID == profileID
+// NotifyConsecutiveFailures sends notification when a backup has failed multiple times
+func (n *NotificationService) NotifyConsecutiveFailures(profileID, runID uint, profileName string, failureCount int) {
+	notification := runNotification(profileID, runID, "consecutive_failures")
+	notification.Tag = fmt.Sprintf("backup-consecutive-failures-%d", profileID)
+	notification.Data["failure_count"] = fmt.Sprintf("%d", failureCount)
+	notification.Title = "Multiple Backup Failures"
+	notification.Body = fmt.Sprintf("Backup '%s' has failed %d times in a row", profileName, failureCount)
+	notification.Subject = fmt.Sprintf("Backup '%s' failed %d times in a row", profileName, failureCount)
+	notification.Summary = fmt.Sprintf("The backup profile '%s' has failed %d times in a row.", profileName, failureCount)
+	notification.Severity = SeverityFailure
+	notification.Details = append(notification.Details, NotificationDetail{Label: "Failures", Value: fmt.Sprintf("%d", failureCount)})
+	notification.AlertKey = consecutiveFailuresAlert(profileID)
+
+	n.SendToAll(notification, profilePreference(profileID, func(pref *entity.NotificationPreference) bool {
+		return pref.NotifyOnConsecutiveFailures && failureCount >= pref.ConsecutiveFailureThreshold
+	}))
+	data := runWebhookData(profileID, runID, "failed")
+	data.FailureCount = failureCount
+	now := time.Now()
+	matches := runWebhook(data)
+	n.SendWebhooks(entity.WebhookEventConsecutiveFailures, data, func(webhook *entity.Webhook) bool {
+		return matches(webhook) && n.alerts.allow(notification.AlertKey, webhookRecipient(webhook), now)
 	})
 }
 
-// NotifyConsecutiveFailures sends notification when a backup has failed multiple times
-func (n *NotificationService) NotifyConsecutiveFailures(profileID uint, profileName string, failureCount int) {
-	payload := &NotificationPayload{
-		Title: "Multiple Backup Failures",
-		Body:  fmt.Sprintf("Backup '%s' has failed %d times in a row", profileName, failureCount),
-		Tag:   fmt.Sprintf("backup-consecutive-failures-%d", profileID),
+// NotifyMissedBackup sends notification when a scheduled backup did not complete in time
+func (n *NotificationService) NotifyMissedBackup(missed *MissedBackup) {
+	lastSuccess := "never"
+	if missed.LastSuccessAt != nil {
+		lastSuccess = missed.LastSuccessAt.Local().Format(notificationTimeFormat)
+	}
+	notification := &Notification{
+		Title: "Missed Backup",
+		Body:  fmt.Sprintf("Backup '%s' did not complete as scheduled: %s", missed.BackupProfile, missed.Reason),
+		Tag:   fmt.Sprintf("backup-missed-%d", missed.BackupProfileID),
 		Data: map[string]string{
-			"type":          "consecutive_failures",
-			"profile_id":    fmt.Sprintf("%d", profileID),
-			"failure_count": fmt.Sprintf("%d", failureCount),
+			"type":       "missed_backup",
+			"profile_id": fmt.Sprintf("%d", missed.BackupProfileID),
+		},
+		Subject:  fmt.Sprintf("Backup '%s' missed its schedule", missed.BackupProfile),
+		Summary:  fmt.Sprintf("The backup profile '%s' has no successful run since its scheduled time: %s.", missed.BackupProfile, missed.Reason),
+		Severity: SeverityFailure,
+		Details: []NotificationDetail{
+			{Label: "Schedule", Value: missed.ScheduleCron},
+			{Label: "Last successful run", Value: lastSuccess},
 		},
+		Link:      publicLink(fmt.Sprintf("/backup-profiles/%d", missed.BackupProfileID)),
+		LinkLabel: "View backup profile",
+	}
+	if missed.ExpectedAt != nil {
+		notification.Details = append(notification.Details, NotificationDetail{Label: "Expected", Value: missed.ExpectedAt.Local().Format(notificationTimeFormat)})
 	}
 
-	n.SendToAll(payload, func(pref *entity.NotificationPreference) bool {
-		if !pref.NotifyOnConsecutiveFailures {
-			return false
-		}
-		if failureCount < pref.ConsecutiveFailureThreshold {
-			return false
+	n.SendToAll(notification, profilePreference(missed.BackupProfileID, func(pref *entity.NotificationPreference) bool { return pref.NotifyOnMissedBackup }))
+	data := missedWebhookData(missed)
+	n.SendWebhooks(entity.WebhookEventMissedBackup, data, func(webhook *entity.Webhook) bool {
+		if webhook.BackupProfileID != nil {
+			return *webhook.BackupProfileID == data.BackupProfileID
 		}
-		if pref.BackupProfileID == nil {
-			return true
+		if webhook.ServerID != nil {
+			return *webhook.ServerID == data.ServerID
 		}
-		return *pref.BackupProfileID == profileID
+		return true
 	})
 }
 
 // NotifyLowStorage sends notification when storage is running low
 func (n *NotificationService) NotifyLowStorage(locationName string, freePercent float64) {
-	payload := &NotificationPayload{
+	notification := &Notification{
 		Title: "Low Storage Warning",
 		Body:  fmt.Sprintf("Storage location '%s' has only %.1f%% free space remaining", locationName, freePercent),
 		Tag:   "low-storage-warning",
@@ -410,14 +520,40 @@ func (n *NotificationService) NotifyLowStorage(locationName string, freePercent
 			"location":     locationName,
 			"free_percent": fmt.Sprintf("%.1f", freePercent),
 		},
+		Subject:  fmt.Sprintf("Low storage on '%s'", locationName),
+		Summary:  fmt.Sprintf("The storage location '%s' is running out of space.", locationName),
+		Severity: SeverityWarning,
+		Details: []NotificationDetail{
+			{Label: "Storage location", Value: locationName},
+			{Label: "Free space", Value: fmt.Sprintf("%.1f%%", freePercent)},
+		},
+		Link:      publicLink("/storage-locations"),
+		LinkLabel: "View storage locations",
+		AlertKey:  "low-storage-" + locationName,
 	}
 
-	n.SendToAll(payload, func(pref *entity.NotificationPreference) bool {
-		if !pref.NotifyOnLowStorage {
+	n.SendToAll(notification, func(pref *entity.NotificationPreference) bool {
+		// Storage alerts are not about a profile or server
+		if !pref.NotifyOnLowStorage || pref.BackupProfileID != nil || pref.ServerID != nil {
 			return false
 		}
 		return freePercent < float64(pref.LowStorageThreshold)
 	})
+	n.SendWebhooks(entity.WebhookEventLowStorage, &webhookStorageData{
+		StorageLocation: locationName,
+		FreePercent:     freePercent,
+		URL:             publicLink("/storage-locations"),
+	}, func(webhook *entity.Webhook) bool {
+		// Storage alerts are not about a profile or server
+		if webhook.BackupProfileID != nil || webhook.ServerID != nil {
+			return false
+		}
+		if freePercent >= float64(webhook.LowStorageThreshold) {
+			n.alerts.clear(notification.AlertKey, webhookRecipient(webhook))
+			return false
+		}
+		return n.alerts.allow(notification.AlertKey, webhookRecipient(webhook), time.Now())
+	})
 }
 
 // generateVAPIDKeys generates a new ECDSA P-256 key pair for VAPID
@@ -441,10 +577,11 @@ func generateVAPIDKeys() (string, string, error) {
 	return privateKeyB64, publicKeyB64, nil
 }
 
-// GetConsecutiveFailureCount returns the number of consecutive failures for a profile
+// GetConsecutiveFailureCount returns the number of consecutive failures for a profile,
+// ignoring runs that did not finish yet
 func GetConsecutiveFailureCount(profileID uint) int {
 	var runs []entity.BackupRun
-	if err := DB.Where("backup_profile_id = ?", profileID).
+	if err := DB.Where("backup_profile_id = ? AND status IN ?", profileID, []string{"completed", "failed"}).
 		Order("start_time DESC").
 		Limit(10).
 		Find(&runs).Error; err != nil {
@@ -466,8 +603,23 @@ func GetConsecutiveFailureCount(profileID uint) int {
 // Global notification service instance
 var NotificationSvc *NotificationService
 
+// publicURL is where users reach BackApp, used for links in notifications
+var publicURL = strings.TrimSuffix(os.Getenv("BACKAPP_PUBLIC_URL"), "/")
+
+// notificationTimeFormat formats times shown in notifications
+const notificationTimeFormat = "2006-01-02 15:04 MST"
+
+// publicLink returns the absolute URL of a page, or nothing without BACKAPP_PUBLIC_URL
+func publicLink(path string) string {
+	if publicURL == "" {
+		return ""
+	}
+	return publicURL + path
+}
+
 // InitNotificationService initializes the global notification service
 func InitNotificationService() error {
 	NotificationSvc = NewNotificationService()
+	go NotificationSvc.resumeWebhookDeliveries()
 	return NotificationSvc.Initialize()
 }
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+)
+
+const (
+	// alertRepeatInterval is how long a recipient is not alerted again about an ongoing
+	// condition, e.g. a profile that keeps failing
+	alertRepeatInterval = 24 * time.Hour
+	// quietHoursInterval is how often held notifications are checked for delivery
+	quietHoursInterval = time.Minute
+)
+
+// alertTracker remembers which recipients were alerted about ongoing conditions
+type alertTracker struct {
+	mu sync.Mutex
+	// sent maps alert keys to when each recipient was last alerted
+	sent map[string]map[string]time.Time
+}
+
+func newAlertTracker() *alertTracker {
+	return &alertTracker{sent: make(map[string]map[string]time.Time)}
+}
+
+// allow reports whether a recipient may be alerted now and records the alert if so
+func (t *alertTracker) allow(key, recipient string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.sent[key][recipient]; ok && now.Sub(last) < alertRepeatInterval {
+		return false
+	}
+	if t.sent[key] == nil {
+		t.sent[key] = make(map[string]time.Time)
+	}
+	t.sent[key][recipient] = now
+	return true
+}
+
+// clear forgets the alert of a recipient the condition no longer applies to
+func (t *alertTracker) clear(key, recipient string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sent[key], recipient)
+}
+
+// resolve forgets an alert for all recipients once its condition ended
+func (t *alertTracker) resolve(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sent, key)
+}
+
+func (t *alertTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = make(map[string]map[string]time.Time)
+}
+
+// consecutiveFailuresAlert is the alert key of a profile failing repeatedly
+func consecutiveFailuresAlert(profileID uint) string {
+	return fmt.Sprintf("consecutive-failures-%d", profileID)
+}
+
+// preferenceRecipient and webhookRecipient name alerted recipients in the alert tracker
+func preferenceRecipient(pref *entity.NotificationPreference) string {
+	return fmt.Sprintf("preference-%d", pref.ID)
+}
+
+func webhookRecipient(webhook *entity.Webhook) string {
+	return fmt.Sprintf("webhook-%d", webhook.ID)
+}
+
+// notifiedRecipient identifies who a preference notifies, e.g. an email recipient, so
+// that quiet hours of one rule do not delay what another rule already delivered
+func notifiedRecipient(pref *entity.NotificationPreference) string {
+	switch {
+	case pref.SubscriptionID != nil:
+		return fmt.Sprintf("push-%d", *pref.SubscriptionID)
+	case pref.EmailRecipientID != nil:
+		return fmt.Sprintf("email-%d", *pref.EmailRecipientID)
+	case pref.ChatIntegrationID != nil:
+		return fmt.Sprintf("chat-%d", *pref.ChatIntegrationID)
+	}
+	return fmt.Sprintf("preference-%d", pref.ID)
+}
+
+// inQuietHours reports whether the time is within the quiet hours of a preference. Quiet
+// hours ending before they start span midnight, e.g. 22:00 to 07:00.
+func inQuietHours(pref *entity.NotificationPreference, now time.Time) bool {
+	if pref.QuietHoursStart == "" || pref.QuietHoursEnd == "" {
+		return false
+	}
+	start, err := time.Parse("15:04", pref.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", pref.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	now = now.Local()
+	minute := now.Hour()*60 + now.Minute()
+	from, until := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= until {
+		return minute >= from && minute < until
+	}
+	return minute >= from || minute < until
+}
+
+// heldNotification is a notification held back by quiet hours
+type heldNotification struct {
+	notification *Notification
+	at           time.Time
+}
+
+// HeldBatch describes the notifications delivered together to a preference after its
+// quiet hours
+type HeldBatch struct {
+	PreferenceID  uint     `json:"preference_id"`
+	Notifications []string `json:"notifications"` // subjects
+}
+
+// hold keeps a notification for a preference until its quiet hours end
+func (n *NotificationService) hold(pref *entity.NotificationPreference, notification *Notification, now time.Time) {
+	n.heldMu.Lock()
+	defer n.heldMu.Unlock()
+	n.held[pref.ID] = append(n.held[pref.ID], heldNotification{notification: notification, at: now})
+}
+
+// ResetAlerts forgets sent alerts and drops held notifications
+func (n *NotificationService) ResetAlerts() {
+	n.alerts.reset()
+	n.heldMu.Lock()
+	defer n.heldMu.Unlock()
+	n.held = make(map[uint][]heldNotification)
+}
+
+// StartQuietHours delivers held notifications every minute once quiet hours ended
+func StartQuietHours() {
+	go func() {
+		ticker := time.NewTicker(quietHoursInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			if NotificationSvc != nil {
+				NotificationSvc.DeliverHeld(now)
+			}
+		}
+	}()
+}
+
+// DeliverHeld sends the notifications held for preferences whose quiet hours ended at the
+// given time, one message per preference, and returns what was sent
+func (n *NotificationService) DeliverHeld(now time.Time) []HeldBatch {
+	due := make(map[uint][]heldNotification)
+	n.heldMu.Lock()
+	for prefID, held := range n.held {
+		pref, err := n.GetPreference(prefID)
+		if err != nil {
+			// The rule was deleted
+			delete(n.held, prefID)
+			continue
+		}
+		if inQuietHours(pref, now) {
+			continue
+		}
+		due[prefID] = held
+		delete(n.held, prefID)
+	}
+	n.heldMu.Unlock()
+
+	prefIDs := make([]uint, 0, len(due))
+	for prefID := range due {
+		prefIDs = append(prefIDs, prefID)
+	}
+	sort.Slice(prefIDs, func(i, j int) bool { return prefIDs[i] < prefIDs[j] })
+
+	batches := []HeldBatch{}
+	for _, prefID := range prefIDs {
+		held := due[prefID]
+		batch := HeldBatch{PreferenceID: prefID}
+		for _, h := range held {
+			batch.Notifications = append(batch.Notifications, h.notification.Subject)
+		}
+		notification := heldNotificationsSummary(held)
+		for _, notifier := range n.notifiers {
+			notifier.Notify(notification, func(pref *entity.NotificationPreference) bool { return pref.ID == prefID })
+		}
+		batches = append(batches, batch)
+	}
+	if len(batches) > 0 {
+		slog.Info("Delivered notifications held during quiet hours", "rules", len(batches))
+	}
+	return batches
+}
+
+// heldNotificationsSummary returns a single held notification as is and lists several
+func heldNotificationsSummary(held []heldNotification) *Notification {
+	if len(held) == 1 {
+		return held[0].notification
+	}
+
+	severity := SeverityInfo
+	subjects := make([]string, 0, len(held))
+	details := make([]NotificationDetail, 0, len(held))
+	for _, h := range held {
+		if h.notification.Severity == SeverityWarning {
+			severity = SeverityWarning
+		}
+		subjects = append(subjects, h.notification.Subject)
+		details = append(details, NotificationDetail{Label: h.at.Local().Format(notificationTimeFormat), Value: h.notification.Subject})
+	}
+	title := pluralize(len(held), "notification") + " during quiet hours"
+	return &Notification{
+		Title: title,
+		Body:  strings.Join(subjects, "\n"),
+		Tag:   "quiet-hours",
+		Data: map[string]string{
+			"type":  "quiet_hours",
+			"count": fmt.Sprintf("%d", len(held)),
+		},
+		Subject:   title,
+		Summary:   "These notifications were held back during quiet hours.",
+		Severity:  severity,
+		Details:   details,
+		Link:      publicLink("/dashboard"),
+		LinkLabel: "Open dashboard",
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// chatTimeout bounds sending a single chat message
+	chatTimeout = 15 * time.Second
+	// chatErrorLimit is how much of an error response is included in errors
+	chatErrorLimit = 512
+)
+
+var chatClient = &http.Client{Timeout: chatTimeout}
+
+// ErrInvalidChatIntegration is wrapped by validation errors of chat integration input
+var ErrInvalidChatIntegration = errors.New("invalid chat integration")
+
+// ListChatIntegrations returns all chat integrations with their preferences
+func (n *NotificationService) ListChatIntegrations() ([]entity.ChatIntegration, error) {
+	var integrations []entity.ChatIntegration
+	err := DB.Preload("Preferences.BackupProfile").Preload("Preferences.Server").Order("name").Find(&integrations).Error
+	for i := range integrations {
+		describeChatIntegration(&integrations[i])
+	}
+	return integrations, err
+}
+
+// GetChatIntegration returns a chat integration by ID
+func (n *NotificationService) GetChatIntegration(id uint) (*entity.ChatIntegration, error) {
+	var integration entity.ChatIntegration
+	if err := DB.First(&integration, id).Error; err != nil {
+		return nil, err
+	}
+	describeChatIntegration(&integration)
+	return &integration, nil
+}
+
+// describeChatIntegration sets the fields shown in place of the encrypted URL and token
+func describeChatIntegration(integration *entity.ChatIntegration) {
+	integration.HasToken = integration.Token != ""
+	integration.MaskedURL = ""
+	if plain, err := decryptSecret(integration.URL); err == nil {
+		if target, err := url.Parse(plain); err == nil {
+			integration.MaskedURL = maskURL(target)
+		}
+	}
+}
+
+// maskURL returns the scheme and host of a URL, hiding credentials in its path and query
+func maskURL(target *url.URL) string {
+	masked := target.Scheme + "://" + target.Host
+	if strings.Trim(target.Path, "/") != "" || target.RawQuery != "" {
+		masked += "/…"
+	}
+	return masked
+}
+
+// CreateChatIntegration adds a chat integration with the same default preferences as
+// new push subscriptions
+func (n *NotificationService) CreateChatIntegration(input *entity.ChatIntegrationInput) (*entity.ChatIntegration, error) {
+	integration := &entity.ChatIntegration{Enabled: true}
+	if err := applyChatIntegrationInput(integration, input); err != nil {
+		return nil, err
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(integration).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.NotificationPreference{
+			ChatIntegrationID:           &integration.ID,
+			NotifyOnFailure:             true,
+			NotifyOnConsecutiveFailures: true,
+			ConsecutiveFailureThreshold: 3,
+			NotifyOnMissedBackup:        true,
+			NotifyOnLowStorage:          true,
+			LowStorageThreshold:         10,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return integration, nil
+}
+
+// UpdateChatIntegration changes a chat integration; its URL and token are only replaced
+// when the input has them
+func (n *NotificationService) UpdateChatIntegration(id uint, input *entity.ChatIntegrationInput) (*entity.ChatIntegration, error) {
+	integration, err := n.GetChatIntegration(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyChatIntegrationInput(integration, input); err != nil {
+		return nil, err
+	}
+	if err := DB.Save(integration).Error; err != nil {
+		return nil, err
+	}
+	return integration, nil
+}
+
+// applyChatIntegrationInput validates input and copies it to a chat integration
+func applyChatIntegrationInput(integration *entity.ChatIntegration, input *entity.ChatIntegrationInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidChatIntegration)
+	}
+	provider, ok := chatProviders[input.Provider]
+	if !ok {
+		return fmt.Errorf("%w: unknown provider %q", ErrInvalidChatIntegration, input.Provider)
+	}
+	var err error
+	storedURL := integration.URL
+	rawURL := strings.TrimSpace(input.URL)
+	if rawURL == "" && storedURL != "" {
+		if rawURL, err = decryptSecret(storedURL); err != nil {
+			return fmt.Errorf("failed to decrypt url: %v", err)
+		}
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidChatIntegration)
+	}
+
+	token := input.Token
+	if token == "" && integration.Token != "" {
+		if token, err = decryptSecret(integration.Token); err != nil {
+			return fmt.Errorf("failed to decrypt token: %v", err)
+		}
+	}
+	integration.Name = name
+	integration.Provider = input.Provider
+	integration.URL = target.String()
+	integration.Channel = strings.TrimSpace(input.Channel)
+	if err := provider.validate(integration, token); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChatIntegration, err)
+	}
+	if input.Enabled != nil {
+		integration.Enabled = *input.Enabled
+	}
+	if strings.TrimSpace(input.URL) == "" {
+		integration.URL = storedURL
+	} else if integration.URL, err = encryptSecret(integration.URL); err != nil {
+		return err
+	}
+	if input.Token != "" {
+		encrypted, err := encryptSecret(input.Token)
+		if err != nil {
+			return err
+		}
+		integration.Token = encrypted
+	}
+	describeChatIntegration(integration)
+	return nil
+}
+
+// DeleteChatIntegration removes a chat integration and its preferences
+func (n *NotificationService) DeleteChatIntegration(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.ChatIntegration{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("chat_integration_id = ?", id).Delete(&entity.NotificationPreference{}).Error
+	})
+}
+
+// CreateChatIntegrationPreference adds a preference to a chat integration
+func (n *NotificationService) CreateChatIntegrationPreference(integrationID uint, input *entity.NotificationPreferenceInput) (*entity.NotificationPreference, error) {
+	pref := &entity.NotificationPreference{
+		ChatIntegrationID:           &integrationID,
+		BackupProfileID:             input.BackupProfileID,
+		ServerID:                    input.ServerID,
+		NotifyOnStart:               input.NotifyOnStart,
+		NotifyOnSuccess:             input.NotifyOnSuccess,
+		NotifyOnFailure:             input.NotifyOnFailure,
+		NotifyOnConsecutiveFailures: input.NotifyOnConsecutiveFailures,
+		ConsecutiveFailureThreshold: input.ConsecutiveFailureThreshold,
+		NotifyOnMissedBackup:        input.NotifyOnMissedBackup,
+		NotifyOnLowStorage:          input.NotifyOnLowStorage,
+		LowStorageThreshold:         input.LowStorageThreshold,
+		DigestFrequency:             input.DigestFrequency,
+		QuietHoursStart:             input.QuietHoursStart,
+		QuietHoursEnd:               input.QuietHoursEnd,
+	}
+	if err := DB.Create(pref).Error; err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+// SendTestChat sends a test message through a chat integration and reports errors
+func (n *NotificationService) SendTestChat(integration *entity.ChatIntegration) error {
+	return sendChat(integration, &Notification{
+		Title:     "Test Notification",
+		Body:      "This is a test notification from BackApp",
+		Subject:   "Test Notification",
+		Summary:   "This is a test notification from BackApp. Chat notifications are working.",
+		Severity:  SeverityInfo,
+		Link:      publicLink("/notifications"),
+		LinkLabel: "Notification settings",
+	})
+}
+
+// chatNotifier posts to the enabled chat integrations
+type chatNotifier struct {
+	service *NotificationService
+}
+
+func (c chatNotifier) Notify(notification *Notification, filterFunc func(*entity.NotificationPreference) bool) {
+	integrations, err := c.service.ListChatIntegrations()
+	if err != nil {
+		slog.Error("Failed to list chat integrations", "error", err)
+		return
+	}
+
+	for _, integration := range integrations {
+		if !integration.Enabled || !matchesAny(integration.Preferences, filterFunc) {
+			continue
+		}
+		go func(i entity.ChatIntegration) {
+			if err := sendChat(&i, notification); err != nil {
+				slog.Error("Failed to send chat notification", "provider", i.Provider, "integration", i.Name, "error", err)
+			}
+		}(integration)
+	}
+}
+
+// sendChat formats a notification for the provider of an integration and sends it
+func sendChat(integration *entity.ChatIntegration, notification *Notification) error {
+	provider, ok := chatProviders[integration.Provider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", integration.Provider)
+	}
+	token, err := decryptSecret(integration.Token)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt token: %v", err)
+	}
+	plain := *integration
+	if plain.URL, err = decryptSecret(integration.URL); err != nil {
+		return fmt.Errorf("failed to decrypt url: %v", err)
+	}
+
+	req, err := provider.request(&plain, token, notification)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "BackApp")
+	resp, err := chatClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, chatErrorLimit))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := strings.TrimSpace(string(bytes.ToValidUTF8(body, nil)))
+		if message == "" {
+			return fmt.Errorf("%s returned %s", integration.Provider, resp.Status)
+		}
+		return fmt.Errorf("%s returned %s: %s", integration.Provider, resp.Status, message)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+// emailLogTailLines is how many lines of the run log notification emails include
+const emailLogTailLines = 20
+
+var (
+	ErrEmailNotConfigured  = errors.New("email notifications are not configured, set BACKAPP_SMTP_HOST")
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrEmailRecipientTaken = errors.New("email address is already a recipient")
+)
+
+// ListEmailRecipients returns all email recipients with their preferences
+func (n *NotificationService) ListEmailRecipients() ([]entity.EmailRecipient, error) {
+	var recipients []entity.EmailRecipient
+	err := DB.Preload("Preferences.BackupProfile").Preload("Preferences.Server").Order("address").Find(&recipients).Error
+	return recipients, err
+}
+
+// GetEmailRecipient returns an email recipient by ID
+func (n *NotificationService) GetEmailRecipient(id uint) (*entity.EmailRecipient, error) {
+	var recipient entity.EmailRecipient
+	if err := DB.First(&recipient, id).Error; err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// CreateEmailRecipient adds a recipient with the same default preferences as new push subscriptions
+func (n *NotificationService) CreateEmailRecipient(address string) (*entity.EmailRecipient, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, ErrInvalidEmail
+	}
+
+	recipient := &entity.EmailRecipient{Address: strings.ToLower(parsed.Address)}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&entity.EmailRecipient{}).Where("address = ?", recipient.Address).Count(&count)
+		if count > 0 {
+			return ErrEmailRecipientTaken
+		}
+		if err := tx.Create(recipient).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.NotificationPreference{
+			EmailRecipientID:            &recipient.ID,
+			NotifyOnFailure:             true,
+			NotifyOnConsecutiveFailures: true,
+			ConsecutiveFailureThreshold: 3,
+			NotifyOnMissedBackup:        true,
+			NotifyOnLowStorage:          true,
+			LowStorageThreshold:         10,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recipient, nil
+}
+
+// DeleteEmailRecipient removes a recipient and its preferences
+func (n *NotificationService) DeleteEmailRecipient(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.EmailRecipient{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("email_recipient_id = ?", id).Delete(&entity.NotificationPreference{}).Error
+	})
+}
+
+// CreateEmailRecipientPreference adds a preference to an email recipient
+func (n *NotificationService) CreateEmailRecipientPreference(recipientID uint, input *entity.NotificationPreferenceInput) (*entity.NotificationPreference, error) {
+	pref := &entity.NotificationPreference{
+		EmailRecipientID:            &recipientID,
+		BackupProfileID:             input.BackupProfileID,
+		ServerID:                    input.ServerID,
+		NotifyOnStart:               input.NotifyOnStart,
+		NotifyOnSuccess:             input.NotifyOnSuccess,
+		NotifyOnFailure:             input.NotifyOnFailure,
+		NotifyOnConsecutiveFailures: input.NotifyOnConsecutiveFailures,
+		ConsecutiveFailureThreshold: input.ConsecutiveFailureThreshold,
+		NotifyOnMissedBackup:        input.NotifyOnMissedBackup,
+		NotifyOnLowStorage:          input.NotifyOnLowStorage,
+		LowStorageThreshold:         input.LowStorageThreshold,
+		DigestFrequency:             input.DigestFrequency,
+		QuietHoursStart:             input.QuietHoursStart,
+		QuietHoursEnd:               input.QuietHoursEnd,
+	}
+	if err := DB.Create(pref).Error; err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+// GetPreference returns a notification preference by ID
+func (n *NotificationService) GetPreference(prefID uint) (*entity.NotificationPreference, error) {
+	var pref entity.NotificationPreference
+	if err := DB.First(&pref, prefID).Error; err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// SendTestEmail sends a test message to a recipient and reports delivery errors
+func (n *NotificationService) SendTestEmail(recipient *entity.EmailRecipient) error {
+	if EmailSvc == nil {
+		return ErrEmailNotConfigured
+	}
+	return EmailSvc.Send(recipient.Address, &EmailMessage{
+		Subject:   "Test Notification",
+		Title:     "Test Notification",
+		Summary:   "This is a test notification from BackApp. Email notifications are working.",
+		Color:     severityColors[SeverityInfo],
+		Link:      publicLink("/notifications"),
+		LinkLabel: "Notification settings",
+	})
+}
+
+// runLogTail returns the last lines of a run log, without debug messages
+func runLogTail(runID uint, lines int) []string {
+	var logs []entity.BackupRunLog
+	if err := DB.Where("backup_run_id = ? AND level <> ?", runID, entity.LogLevelDebug).
+		Order("timestamp DESC, id DESC").Limit(lines).Find(&logs).Error; err != nil {
+		return nil
+	}
+	tail := make([]string, len(logs))
+	for i, entry := range logs {
+		tail[len(logs)-1-i] = fmt.Sprintf("%s %-7s %s", entry.Timestamp.Format("15:04:05"), entry.Level, entry.Message)
+	}
+	return tail
+}
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 15 * time.Second
+	// webhookResponseLimit is how much of a response body is kept in the delivery log
+	webhookResponseLimit = 2048
+	// webhookDeliveryHistory is the number of deliveries kept per webhook
+	webhookDeliveryHistory = 200
+)
+
+// webhookRetryDelays are the waits before retrying a failed delivery
+var webhookRetryDelays = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute, 30 * time.Minute}
+
+// webhookEvents are the events webhooks can subscribe to
+var webhookEvents = map[string]bool{
+	entity.WebhookEventBackupStarted:       true,
+	entity.WebhookEventBackupCompleted:     true,
+	entity.WebhookEventBackupFailed:        true,
+	entity.WebhookEventConsecutiveFailures: true,
+	entity.WebhookEventBackupRecovered:     true,
+	entity.WebhookEventMissedBackup:        true,
+	entity.WebhookEventDailyDigest:         true,
+	entity.WebhookEventWeeklyDigest:        true,
+	entity.WebhookEventLowStorage:          true,
+}
+
+// webhookClient does not follow redirects, which would turn the POST into a GET
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ErrInvalidWebhook is wrapped by validation errors of webhook input
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// webhookRunData is the data of backup events
+type webhookRunData struct {
+	BackupRunID     uint    `json:"backup_run_id"`
+	BackupProfileID uint    `json:"backup_profile_id"`
+	BackupProfile   string  `json:"backup_profile"`
+	ServerID        uint    `json:"server_id"`
+	Server          string  `json:"server"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	FailureCount    int     `json:"failure_count,omitempty"`
+	URL             string  `json:"url,omitempty"`
+}
+
+// webhookMissedData is the data of missed backup events
+type webhookMissedData struct {
+	BackupProfileID uint       `json:"backup_profile_id"`
+	BackupProfile   string     `json:"backup_profile"`
+	ServerID        uint       `json:"server_id"`
+	Server          string     `json:"server"`
+	ScheduleCron    string     `json:"schedule_cron"`
+	ExpectedAt      *time.Time `json:"expected_at,omitempty"`
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	Reason          string     `json:"reason"`
+	URL             string     `json:"url,omitempty"`
+}
+
+// webhookStorageData is the data of storage events
+type webhookStorageData struct {
+	StorageLocation string  `json:"storage_location"`
+	FreePercent     float64 `json:"free_percent"`
+	URL             string  `json:"url,omitempty"`
+}
+
+// ListWebhooks returns all webhooks
+func (n *NotificationService) ListWebhooks() ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := DB.Preload("BackupProfile").Preload("Server").Order("name").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetWebhook returns a webhook by ID
+func (n *NotificationService) GetWebhook(id uint) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	if err := DB.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook adds a webhook. Without a secret in the input one is generated; the
+// plain secret is returned once in the PlainSecret field.
+func (n *NotificationService) CreateWebhook(input *entity.WebhookInput) (*entity.Webhook, error) {
+	webhook := &entity.Webhook{Enabled: true}
+	if input.Secret == "" {
+		secret, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		input.Secret = secret
+	}
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, err
+	}
+	if err := DB.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	webhook.PlainSecret = input.Secret
+	return webhook, nil
+}
+
+// UpdateWebhook changes a webhook; its secret is only replaced when the input has one
+func (n *NotificationService) UpdateWebhook(id uint, input *entity.WebhookInput) (*entity.Webhook, error) {
+	webhook, err := n.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, err
+	}
+	if err := DB.Save(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// applyWebhookInput validates input and copies it to a webhook
+func applyWebhookInput(webhook *entity.Webhook, input *entity.WebhookInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidWebhook)
+	}
+	target, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidWebhook)
+	}
+	events := []string{}
+	for _, event := range input.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		events = append(events, event)
+	}
+	if input.BackupProfileID != nil && input.ServerID != nil {
+		return fmt.Errorf("%w: select either a backup profile or a server", ErrInvalidWebhook)
+	}
+	if input.LowStorageThreshold < 0 || input.LowStorageThreshold > 100 {
+		return fmt.Errorf("%w: low_storage_threshold must be between 0 and 100", ErrInvalidWebhook)
+	}
+
+	webhook.Name = name
+	webhook.URL = target.String()
+	webhook.Events = events
+	webhook.BackupProfileID = input.BackupProfileID
+	webhook.ServerID = input.ServerID
+	webhook.LowStorageThreshold = input.LowStorageThreshold
+	if webhook.LowStorageThreshold == 0 {
+		webhook.LowStorageThreshold = 10
+	}
+	if input.Enabled != nil {
+		webhook.Enabled = *input.Enabled
+	}
+	if input.Secret != "" {
+		encrypted, err := encryptSecret(input.Secret)
+		if err != nil {
+			return err
+		}
+		webhook.Secret = encrypted
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (n *NotificationService) DeleteWebhook(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&entity.WebhookDelivery{}).Error
+	})
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook, newest first
+func (n *NotificationService) ListWebhookDeliveries(webhookID uint) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := DB.Where("webhook_id = ?", webhookID).Order("id DESC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetWebhookDelivery returns a delivery of a webhook
+func (n *NotificationService) GetWebhookDelivery(webhookID, deliveryID uint) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if err := DB.Where("webhook_id = ?", webhookID).First(&delivery, deliveryID).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RedeliverWebhook sends the payload of a delivery again, with the same event ID
+func (n *NotificationService) RedeliverWebhook(delivery *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	return n.deliverWebhook(&entity.WebhookDelivery{
+		WebhookID:    delivery.WebhookID,
+		Event:        delivery.Event,
+		EventID:      delivery.EventID,
+		Payload:      delivery.Payload,
+		RedeliveryOf: &delivery.ID,
+	})
+}
+
+// SendTestWebhook sends a ping event to a webhook and returns the delivery
+func (n *NotificationService) SendTestWebhook(webhook *entity.Webhook) (*entity.WebhookDelivery, error) {
+	eventID, payload, err := newWebhookPayload(entity.WebhookEventPing, map[string]interface{}{"webhook_id": webhook.ID, "name": webhook.Name})
+	if err != nil {
+		return nil, err
+	}
+	return n.deliverWebhook(&entity.WebhookDelivery{
+		WebhookID: webhook.ID,
+		Event:     entity.WebhookEventPing,
+		EventID:   eventID,
+		Payload:   payload,
+	})
+}
+
+// SendWebhooks delivers an event to all enabled webhooks subscribed to it that match
+func (n *NotificationService) SendWebhooks(event string, data interface{}, match func(*entity.Webhook) bool) {
+	var webhooks []entity.Webhook
+	if err := DB.Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		slog.Error("Failed to list webhooks", "error", err)
+		return
+	}
+
+	var eventID, payload string
+	for _, webhook := range webhooks {
+		if !webhookSubscribed(&webhook, event) || !match(&webhook) {
+			continue
+		}
+		if payload == "" {
+			var err error
+			if eventID, payload, err = newWebhookPayload(event, data); err != nil {
+				slog.Error("Failed to build webhook payload", "event", event, "error", err)
+				return
+			}
+		}
+		go func(webhookID uint) {
+			_, err := n.deliverWebhook(&entity.WebhookDelivery{
+				WebhookID: webhookID,
+				Event:     event,
+				EventID:   eventID,
+				Payload:   payload,
+			})
+			if err != nil {
+				slog.Error("Failed to deliver webhook", "webhook_id", webhookID, "error", err)
+			}
+		}(webhook.ID)
+	}
+}
+
+// webhookSubscribed reports whether a webhook receives an event
+func webhookSubscribed(webhook *entity.Webhook, event string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// runWebhook matches webhooks for all backups and those scoped to the run's profile or server
+func runWebhook(data *webhookRunData) func(*entity.Webhook) bool {
+	return func(webhook *entity.Webhook) bool {
+		if webhook.BackupProfileID != nil {
+			return *webhook.BackupProfileID == data.BackupProfileID
+		}
+		if webhook.ServerID != nil {
+			return *webhook.ServerID == data.ServerID
+		}
+		return true
+	}
+}
+
+// runWebhookData describes a run for webhook payloads
+func runWebhookData(profileID, runID uint, status string) *webhookRunData {
+	data := &webhookRunData{
+		BackupRunID:     runID,
+		BackupProfileID: profileID,
+		Status:          status,
+		URL:             publicLink(fmt.Sprintf("/backup-runs/%d", runID)),
+	}
+	var profile entity.BackupProfile
+	if err := DB.Preload("Server").First(&profile, profileID).Error; err == nil {
+		data.BackupProfile = profile.Name
+		data.ServerID = profile.ServerID
+		if profile.Server != nil {
+			data.Server = profile.Server.Name
+		}
+	}
+	return data
+}
+
+// missedWebhookData describes a missed backup for webhook payloads
+func missedWebhookData(missed *MissedBackup) *webhookMissedData {
+	data := &webhookMissedData{
+		BackupProfileID: missed.BackupProfileID,
+		BackupProfile:   missed.BackupProfile,
+		ScheduleCron:    missed.ScheduleCron,
+		ExpectedAt:      missed.ExpectedAt,
+		LastSuccessAt:   missed.LastSuccessAt,
+		Reason:          missed.Reason,
+		URL:             publicLink(fmt.Sprintf("/backup-profiles/%d", missed.BackupProfileID)),
+	}
+	var profile entity.BackupProfile
+	if err := DB.Preload("Server").First(&profile, missed.BackupProfileID).Error; err == nil {
+		data.ServerID = profile.ServerID
+		if profile.Server != nil {
+			data.Server = profile.Server.Name
+		}
+	}
+	return data
+}
+
+// newWebhookPayload returns a new event ID and the JSON payload of an event
+func newWebhookPayload(event string, data interface{}) (string, string, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	eventID := "evt_" + hex.EncodeToString(random)
+	payload, err := json.Marshal(WebhookPayload{ID: eventID, Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return "", "", err
+	}
+	return eventID, string(payload), nil
+}
+
+// deliverWebhook records a delivery and makes its first attempt
+func (n *NotificationService) deliverWebhook(delivery *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	now := time.Now()
+	delivery.Status = entity.WebhookDeliveryPending
+	delivery.NextAttemptAt = &now
+	if err := DB.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	pruneWebhookDeliveries(delivery.WebhookID)
+	return n.attemptWebhookDelivery(delivery.ID), nil
+}
+
+// attemptWebhookDelivery posts a pending delivery and schedules a retry if it fails.
+// It returns the updated delivery, or nil if it is no longer due.
+func (n *NotificationService) attemptWebhookDelivery(id uint) *entity.WebhookDelivery {
+	var delivery entity.WebhookDelivery
+	if err := DB.First(&delivery, id).Error; err != nil {
+		return nil
+	}
+	// Timers of deliveries removed by a database reset may fire for a reused ID
+	if delivery.Status != entity.WebhookDeliveryPending || delivery.NextAttemptAt == nil || time.Now().Before(*delivery.NextAttemptAt) {
+		return nil
+	}
+	var webhook entity.Webhook
+	if err := DB.First(&webhook, delivery.WebhookID).Error; err != nil {
+		return nil
+	}
+
+	start := time.Now()
+	code, body, err := postWebhook(&webhook, &delivery)
+	delivery.Attempts++
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+	delivery.Error = ""
+	delivery.NextAttemptAt = nil
+
+	var retryIn time.Duration
+	switch {
+	case err == nil:
+		delivery.Status = entity.WebhookDeliverySucceeded
+	case delivery.Attempts > len(webhookRetryDelays):
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Error = err.Error()
+		retryIn = webhookRetryDelays[delivery.Attempts-1]
+		next := time.Now().Add(retryIn)
+		delivery.NextAttemptAt = &next
+	}
+	if err := DB.Save(&delivery).Error; err != nil {
+		slog.Error("Failed to save webhook delivery", "delivery_id", delivery.ID, "error", err)
+		return &delivery
+	}
+	if delivery.Status == entity.WebhookDeliveryPending {
+		time.AfterFunc(retryIn, func() { n.attemptWebhookDelivery(id) })
+	}
+	return &delivery
+}
+
+// postWebhook sends a delivery and returns the response code and the start of the body.
+// Responses other than 2xx are errors.
+func postWebhook(webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, string, error) {
+	secret, err := decryptSecret(webhook.Secret)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BackApp-Webhook")
+	req.Header.Set("X-BackApp-Event", delivery.Event)
+	req.Header.Set("X-BackApp-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-BackApp-Timestamp", timestamp)
+	req.Header.Set("X-BackApp-Signature", "sha256="+webhookSignature(secret, timestamp, delivery.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	// Drain the rest so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(bytes.ToValidUTF8(body, nil)), fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, string(bytes.ToValidUTF8(body, nil)), nil
+}
+
+// webhookSignature signs the timestamp and payload of a delivery, so receivers can check
+// its origin and reject replays
+func webhookSignature(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pruneWebhookDeliveries keeps the newest deliveries of a webhook
+func pruneWebhookDeliveries(webhookID uint) {
+	err := DB.Where("webhook_id = ? AND id NOT IN (?)", webhookID,
+		DB.Model(&entity.WebhookDelivery{}).Select("id").Where("webhook_id = ?", webhookID).
+			Order("id DESC").Limit(webhookDeliveryHistory)).
+		Delete(&entity.WebhookDelivery{}).Error
+	if err != nil {
+		slog.Error("Failed to prune webhook deliveries", "webhook_id", webhookID, "error", err)
+	}
+}
+
+// resumeWebhookDeliveries schedules the retries of deliveries pending before a restart
+func (n *NotificationService) resumeWebhookDeliveries() {
+	var deliveries []entity.WebhookDelivery
+	if err := DB.Where("status = ?", entity.WebhookDeliveryPending).Find(&deliveries).Error; err != nil {
+		slog.Error("Failed to load pending webhook deliveries", "error", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		id := delivery.ID
+		if delivery.NextAttemptAt == nil {
+			now := time.Now()
+			DB.Model(&delivery).Update("next_attempt_at", &now)
+			delivery.NextAttemptAt = &now
+		}
+		time.AfterFunc(time.Until(*delivery.NextAttemptAt), func() { n.attemptWebhookDelivery(id) })
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"log/slog"
+
+	"backapp-server/entity"
+)
+
+// Notification severities, shown as colors and priorities
+const (
+	SeverityInfo    = "info"
+	SeveritySuccess = "success"
+	SeverityFailure = "failure"
+	SeverityWarning = "warning"
+)
+
+// severityColors are the colors of notification severities
+var severityColors = map[string]string{
+	SeverityInfo:    "#1976d2",
+	SeveritySuccess: "#2e7d32",
+	SeverityFailure: "#c62828",
+	SeverityWarning: "#ed6c02",
+}
+
+// Notification is sent through every notifier. Push notifications show Title and Body;
+// emails and chat messages show Subject, Summary and Details.
+type Notification struct {
+	Title    string
+	Body     string
+	Tag      string
+	Data     map[string]string
+	Subject  string
+	Summary  string
+	Severity string
+	Details  []NotificationDetail
+	// RunID attaches the tail of the run log to emails
+	RunID     uint
+	Link      string
+	LinkLabel string
+	// AlertKey names an ongoing condition, e.g. low storage on a location. Each recipient is
+	// alerted about it once per alertRepeatInterval while it lasts.
+	AlertKey string
+}
+
+// NotificationDetail is a labeled value listed in a notification
+type NotificationDetail struct {
+	Label string
+	Value string
+}
+
+// Notifier delivers notifications through one channel to the recipients whose
+// preferences match the filter
+type Notifier interface {
+	Notify(notification *Notification, filterFunc func(*entity.NotificationPreference) bool)
+}
+
+// matchesAny reports whether any of the preferences matches the filter
+func matchesAny(prefs []entity.NotificationPreference, filterFunc func(*entity.NotificationPreference) bool) bool {
+	for i := range prefs {
+		if filterFunc(&prefs[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// pushNotifier sends browser push notifications
+type pushNotifier struct {
+	service *NotificationService
+}
+
+func (p pushNotifier) Notify(notification *Notification, filterFunc func(*entity.NotificationPreference) bool) {
+	subs, err := p.service.ListSubscriptions()
+	if err != nil {
+		slog.Error("Failed to list push subscriptions", "error", err)
+		return
+	}
+
+	payload := &NotificationPayload{
+		Title: notification.Title,
+		Body:  notification.Body,
+		Tag:   notification.Tag,
+		Data:  notification.Data,
+	}
+	for _, sub := range subs {
+		prefs, err := p.service.GetPreferences(sub.ID)
+		if err != nil || !matchesAny(prefs, filterFunc) {
+			continue
+		}
+		go func(s entity.PushSubscription) {
+			if err := p.service.SendNotification(&s, payload); err != nil {
+				slog.Error("Failed to send push notification", "endpoint", s.Endpoint, "error", err)
+			}
+		}(sub)
+	}
+}
+
+// emailNotifier emails the email recipients
+type emailNotifier struct {
+	service *NotificationService
+}
+
+func (e emailNotifier) Notify(notification *Notification, filterFunc func(*entity.NotificationPreference) bool) {
+	if EmailSvc == nil {
+		return
+	}
+	recipients, err := e.service.ListEmailRecipients()
+	if err != nil {
+		slog.Error("Failed to list email recipients", "error", err)
+		return
+	}
+
+	message := &EmailMessage{
+		Subject:   notification.Subject,
+		Title:     notification.Subject,
+		Summary:   notification.Summary,
+		Color:     severityColors[notification.Severity],
+		Details:   notification.Details,
+		Link:      notification.Link,
+		LinkLabel: notification.LinkLabel,
+	}
+	if notification.RunID != 0 {
+		message.LogTail = runLogTail(notification.RunID, emailLogTailLines)
+	}
+	for _, recipient := range recipients {
+		if !matchesAny(recipient.Preferences, filterFunc) {
+			continue
+		}
+		go func(address string) {
+			if err := EmailSvc.Send(address, message); err != nil {
+				slog.Error("Failed to send notification email", "address", address, "error", err)
+			}
+		}(recipient.Address)
+	}
+}
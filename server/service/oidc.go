@@ -0,0 +1,564 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// oidcLoginTimeout is how long a user has to complete the login at the provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// oidcJWKSRefreshInterval limits how often the signing keys are refetched for unknown key IDs
+const oidcJWKSRefreshInterval = time.Minute
+
+var ErrOIDCUsernameTaken = errors.New("a local user with this username already exists")
+
+// OIDCRoleMapping grants Role to members of Group, globally or for a server or profile
+type OIDCRoleMapping struct {
+	Group           string
+	Role            string
+	ServerID        *uint
+	BackupProfileID *uint
+}
+
+// OIDCConfig configures single sign-on with an OpenID Connect provider
+type OIDCConfig struct {
+	ProviderName  string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string // empty for public clients, which rely on PKCE alone
+	RedirectURL   string // empty to derive it from the request
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMappings  []OIDCRoleMapping
+}
+
+// OIDCService implements the authorization code flow with PKCE
+type OIDCService struct {
+	config     OIDCConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+	keysAt    time.Time
+	pending   map[string]*oidcPendingLogin // state -> login in progress
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcPendingLogin struct {
+	verifier    string
+	nonce       string
+	redirectURL string
+	expiresAt   time.Time
+}
+
+// OIDCSvc is nil unless single sign-on is configured
+var OIDCSvc *OIDCService
+
+// InitOIDC enables single sign-on when BACKAPP_OIDC_ISSUER and BACKAPP_OIDC_CLIENT_ID are set.
+// The provider is contacted on the first login, so it does not need to be up at startup.
+func InitOIDC() error {
+	issuer := os.Getenv("BACKAPP_OIDC_ISSUER")
+	clientID := os.Getenv("BACKAPP_OIDC_CLIENT_ID")
+	if issuer == "" || clientID == "" {
+		return nil
+	}
+
+	mappings, err := parseOIDCRoleMappings(os.Getenv("BACKAPP_OIDC_ROLE_MAPPING"))
+	if err != nil {
+		return fmt.Errorf("invalid BACKAPP_OIDC_ROLE_MAPPING: %v", err)
+	}
+	scopes := strings.Fields(os.Getenv("BACKAPP_OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	OIDCSvc = NewOIDCService(OIDCConfig{
+		ProviderName:  envOrDefault("BACKAPP_OIDC_PROVIDER_NAME", "SSO"),
+		Issuer:        strings.TrimSuffix(issuer, "/"),
+		ClientID:      clientID,
+		ClientSecret:  os.Getenv("BACKAPP_OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("BACKAPP_OIDC_REDIRECT_URL"),
+		Scopes:        scopes,
+		UsernameClaim: envOrDefault("BACKAPP_OIDC_USERNAME_CLAIM", "preferred_username"),
+		GroupsClaim:   envOrDefault("BACKAPP_OIDC_GROUPS_CLAIM", "groups"),
+		RoleMappings:  mappings,
+	})
+	slog.Info("OpenID Connect single sign-on enabled", "issuer", issuer)
+	return nil
+}
+
+func NewOIDCService(config OIDCConfig) *OIDCService {
+	return &OIDCService{
+		config:     config,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		pending:    make(map[string]*oidcPendingLogin),
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseOIDCRoleMappings parses "group=role[@server:ID|@profile:ID]" entries separated by commas,
+// e.g. "backapp-admins=admin,web-team=operator@server:2"
+func parseOIDCRoleMappings(spec string) ([]OIDCRoleMapping, error) {
+	var mappings []OIDCRoleMapping
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, rest, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("expected group=role in %q", entry)
+		}
+		mapping := OIDCRoleMapping{Group: strings.TrimSpace(group)}
+
+		role, target, scoped := strings.Cut(rest, "@")
+		mapping.Role = strings.TrimSpace(role)
+		if RoleLevel(mapping.Role) == 0 {
+			return nil, fmt.Errorf("unknown role %q", mapping.Role)
+		}
+		if scoped {
+			kind, idStr, _ := strings.Cut(target, ":")
+			id, err := strconv.ParseUint(idStr, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid id in %q", entry)
+			}
+			targetID := uint(id)
+			switch kind {
+			case "server":
+				mapping.ServerID = &targetID
+			case "profile":
+				mapping.BackupProfileID = &targetID
+			default:
+				return nil, fmt.Errorf("expected @server:ID or @profile:ID in %q", entry)
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// ProviderName returns the name shown on the login button
+func (s *OIDCService) ProviderName() string {
+	return s.config.ProviderName
+}
+
+// RedirectURL returns the configured callback URL, or fallback when none is configured
+func (s *OIDCService) RedirectURL(fallback string) string {
+	if s.config.RedirectURL != "" {
+		return s.config.RedirectURL
+	}
+	return fallback
+}
+
+func (s *OIDCService) getDiscovery() (*oidcDiscovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := s.getJSON(s.config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID provider: %v", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != s.config.Issuer {
+		return nil, fmt.Errorf("provider reports issuer %q, expected %q", discovery.Issuer, s.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("provider metadata is incomplete")
+	}
+	s.discovery = &discovery
+	return s.discovery, nil
+}
+
+func (s *OIDCService) getJSON(endpoint string, target interface{}) error {
+	resp, err := s.httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// AuthorizationURL starts a login and returns the provider URL to redirect the browser to
+// and the state that identifies the login
+func (s *OIDCService) AuthorizationURL(redirectURL string) (string, string, error) {
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	s.mu.Lock()
+	now := time.Now()
+	for key, login := range s.pending {
+		if now.After(login.expiresAt) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = &oidcPendingLogin{
+		verifier:    verifier,
+		nonce:       nonce,
+		redirectURL: redirectURL,
+		expiresAt:   now.Add(oidcLoginTimeout),
+	}
+	s.mu.Unlock()
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.config.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(s.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), state, nil
+}
+
+// CompleteLogin exchanges the authorization code, verifies the ID token, provisions the
+// user and their roles, and starts a session
+func (s *OIDCService) CompleteLogin(state, code string) (string, *entity.User, error) {
+	s.mu.Lock()
+	login := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if login == nil || time.Now().After(login.expiresAt) {
+		return "", nil, fmt.Errorf("login expired or was started elsewhere, please try again")
+	}
+
+	rawIDToken, err := s.exchangeCode(code, login)
+	if err != nil {
+		return "", nil, err
+	}
+	claims, err := s.verifyIDToken(rawIDToken, login.nonce)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	user, err := s.provisionUser(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := startSession(user)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+func (s *OIDCService) exchangeCode(code string, login *oidcPendingLogin) (string, error) {
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {login.redirectURL},
+		"client_id":     {s.config.ClientID},
+		"code_verifier": {login.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token response contains no ID token")
+	}
+	return body.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, s.signingKey,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(s.config.Issuer),
+		jwt.WithAudience(s.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("nonce mismatch")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("missing subject")
+	}
+	return claims, nil
+}
+
+// signingKey looks up the provider key for a token, refetching the key set for unknown key IDs
+func (s *OIDCService) signingKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	stale := time.Since(s.keysAt) > oidcJWKSRefreshInterval
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale && s.keys != nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.fetchKeys(discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.keysAt = time.Now()
+	s.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Providers with a single key may omit the key ID
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *OIDCService) fetchKeys(jwksURI string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+// provisionUser finds or creates the user of an ID token and syncs their roles from its groups
+func (s *OIDCService) provisionUser(claims jwt.MapClaims) (*entity.User, error) {
+	subject := claims["sub"].(string)
+	username, _ := claims[s.config.UsernameClaim].(string)
+	if username == "" {
+		username = subject
+	}
+	roles := s.rolesForGroups(claimStrings(claims[s.config.GroupsClaim]))
+
+	var user entity.User
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("oidc_subject = ?", subject).First(&user).Error
+		if err == gorm.ErrRecordNotFound {
+			// Local accounts are never taken over by a provider account of the same name
+			var count int64
+			if err := tx.Model(&entity.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrOIDCUsernameTaken
+			}
+			user = entity.User{Username: username, OIDCSubject: &subject}
+			err = tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ? AND source = ?", user.ID, entity.RoleSourceOIDC).Delete(&entity.RoleAssignment{}).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if !roleTargetExists(tx, &role) {
+				slog.Warn("Skipping OIDC role mapping to a deleted server or backup profile")
+				continue
+			}
+			role.UserID = user.ID
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// rolesForGroups returns the role assignments granted by the mappings for a user's groups
+func (s *OIDCService) rolesForGroups(groups []string) []entity.RoleAssignment {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		// Keycloak reports group paths such as "/web-team"
+		member[strings.TrimPrefix(group, "/")] = true
+	}
+
+	var roles []entity.RoleAssignment
+	for _, mapping := range s.config.RoleMappings {
+		if !member[strings.TrimPrefix(mapping.Group, "/")] {
+			continue
+		}
+		roles = append(roles, entity.RoleAssignment{
+			Role:            mapping.Role,
+			ServerID:        mapping.ServerID,
+			BackupProfileID: mapping.BackupProfileID,
+			Source:          entity.RoleSourceOIDC,
+		})
+	}
+	return roles
+}
+
+func roleTargetExists(tx *gorm.DB, role *entity.RoleAssignment) bool {
+	switch {
+	case role.ServerID != nil:
+		return tx.First(&entity.Server{}, *role.ServerID).Error == nil
+	case role.BackupProfileID != nil:
+		return tx.First(&entity.BackupProfile{}, *role.BackupProfileID).Error == nil
+	}
+	return true
+}
+
+// claimStrings reads a claim holding a string or a list of strings
+func claimStrings(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package service
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"backapp-server/entity"
@@ -10,6 +10,13 @@ import (
 // RetentionCleanup handles automatic deletion of old backup files
 type RetentionCleanup struct{}
 
+// retentionResult counts what a cleanup deleted
+type retentionResult struct {
+	runs  int
+	files int
+	bytes int64
+}
+
 // NewRetentionCleanup creates a new retention cleanup service
 func NewRetentionCleanup() *RetentionCleanup {
 	return &RetentionCleanup{}
@@ -17,11 +24,11 @@ func NewRetentionCleanup() *RetentionCleanup {
 
 // RunCleanup checks all backup profiles and deletes files older than retention period
 func (r *RetentionCleanup) RunCleanup() {
-	log.Println("Starting retention cleanup...")
+	slog.Info("Starting retention cleanup")
 
 	var profiles []entity.BackupProfile
 	if err := DB.Find(&profiles).Error; err != nil {
-		log.Printf("Failed to load backup profiles for retention cleanup: %v", err)
+		slog.Error("Failed to load backup profiles for retention cleanup", "error", err)
 		return
 	}
 
@@ -34,16 +41,17 @@ func (r *RetentionCleanup) RunCleanup() {
 		r.cleanupProfile(&profile)
 	}
 
-	log.Println("Retention cleanup completed")
+	slog.Info("Retention cleanup completed")
 }
 
-// cleanupProfile deletes old backup files for a specific profile
-func (r *RetentionCleanup) cleanupProfile(profile *entity.BackupProfile) {
+// cleanupProfile deletes old backup files for a specific profile and counts what it deleted
+func (r *RetentionCleanup) cleanupProfile(profile *entity.BackupProfile) retentionResult {
+	var result retentionResult
 	retentionDays := *profile.RetentionDays
 	cutoffTime := time.Now().AddDate(0, 0, -retentionDays)
 
-	log.Printf("Cleaning up profile %s (ID: %d) - retention: %d days, cutoff: %s",
-		profile.Name, profile.ID, retentionDays, cutoffTime.Format(time.RFC3339))
+	slog.Debug("Cleaning up profile", "profile_id", profile.ID, "profile", profile.Name,
+		"retention_days", retentionDays, "cutoff", cutoffTime.Format(time.RFC3339))
 
 	// Find backup runs older than the retention period that haven't been cleaned up yet
 	var oldRuns []entity.BackupRun
@@ -51,25 +59,30 @@ func (r *RetentionCleanup) cleanupProfile(profile *entity.BackupProfile) {
 		profile.ID, cutoffTime, "completed", false).
 		Preload("BackupFiles").
 		Find(&oldRuns).Error; err != nil {
-		log.Printf("Failed to find old backup runs for profile %d: %v", profile.ID, err)
-		return
+		slog.Error("Failed to find old backup runs", "profile_id", profile.ID, "error", err)
+		return result
 	}
 
 	if len(oldRuns) == 0 {
-		log.Printf("No old backup runs found for profile %d", profile.ID)
-		return
+		slog.Debug("No old backup runs found", "profile_id", profile.ID)
+		return result
 	}
 
-	log.Printf("Found %d old backup runs to clean up for profile %d", len(oldRuns), profile.ID)
+	slog.Info("Found old backup runs to clean up", "profile_id", profile.ID, "runs", len(oldRuns))
 
 	for _, run := range oldRuns {
-		r.cleanupRun(&run)
+		files, bytes := r.cleanupRun(&run)
+		result.runs++
+		result.files += files
+		result.bytes += bytes
 	}
+	return result
 }
 
 // cleanupRun deletes all files associated with a backup run using existing service function
-func (r *RetentionCleanup) cleanupRun(run *entity.BackupRun) {
-	log.Printf("Cleaning up backup run %d (ended: %s)", run.ID, run.EndTime.Format(time.RFC3339))
+// and returns how many files and bytes it deleted
+func (r *RetentionCleanup) cleanupRun(run *entity.BackupRun) (int, int64) {
+	slog.Debug("Cleaning up backup run", "run_id", run.ID, "ended", run.EndTime.Format(time.RFC3339))
 
 	deletedFiles := 0
 	deletedBytes := int64(0)
@@ -81,7 +94,7 @@ func (r *RetentionCleanup) cleanupRun(run *entity.BackupRun) {
 
 		// Use existing service function to delete the file
 		if err := ServiceDeleteBackupFile(file.ID); err != nil {
-			log.Printf("Failed to delete backup file %d: %v", file.ID, err)
+			slog.Error("Failed to delete backup file", "run_id", run.ID, "backup_file_id", file.ID, "error", err)
 			continue
 		}
 
@@ -91,11 +104,11 @@ func (r *RetentionCleanup) cleanupRun(run *entity.BackupRun) {
 
 	// Mark the run as cleaned up
 	if err := DB.Model(run).Update("retention_cleaned_up", true).Error; err != nil {
-		log.Printf("Failed to mark backup run %d as cleaned up: %v", run.ID, err)
+		slog.Error("Failed to mark backup run as cleaned up", "run_id", run.ID, "error", err)
 	}
 
-	log.Printf("Deleted %d files (%.2f MB) from backup run %d",
-		deletedFiles, float64(deletedBytes)/(1024*1024), run.ID)
+	slog.Info("Deleted files of backup run", "run_id", run.ID, "files", deletedFiles, "bytes", deletedBytes)
+	return deletedFiles, deletedBytes
 }
 
 // StartRetentionScheduler starts a goroutine that runs retention cleanup periodically
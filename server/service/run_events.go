@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+)
+
+const (
+	// progressInterval limits how often byte-level progress is pushed to subscribers
+	progressInterval = 500 * time.Millisecond
+	// stateSendTimeout bounds how long publishing a state event waits for full subscribers
+	stateSendTimeout = 2 * time.Second
+)
+
+// RunEventHub fans out live backup run events to SSE subscribers
+type RunEventHub struct {
+	mu          sync.RWMutex
+	runSubs     map[uint]map[chan entity.BackupRunEvent]struct{} // runID -> subscribers
+	globalSubs  map[chan entity.BackupRunEvent]struct{}
+	progress    map[uint]*entity.BackupRunProgress
+	lastPublish map[uint]time.Time
+}
+
+var (
+	runEventHub     *RunEventHub
+	runEventHubOnce sync.Once
+)
+
+// GetRunEventHub returns the singleton event hub instance
+func GetRunEventHub() *RunEventHub {
+	runEventHubOnce.Do(func() {
+		runEventHub = &RunEventHub{
+			runSubs:     make(map[uint]map[chan entity.BackupRunEvent]struct{}),
+			globalSubs:  make(map[chan entity.BackupRunEvent]struct{}),
+			progress:    make(map[uint]*entity.BackupRunProgress),
+			lastPublish: make(map[uint]time.Time),
+		}
+	})
+	return runEventHub
+}
+
+// Subscribe registers a listener for log, progress and state events of a single run.
+// The returned function must be called to unsubscribe.
+func (h *RunEventHub) Subscribe(runID uint) (<-chan entity.BackupRunEvent, func()) {
+	ch := make(chan entity.BackupRunEvent, 256)
+
+	h.mu.Lock()
+	if h.runSubs[runID] == nil {
+		h.runSubs[runID] = make(map[chan entity.BackupRunEvent]struct{})
+	}
+	h.runSubs[runID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.runSubs[runID], ch)
+		if len(h.runSubs[runID]) == 0 {
+			delete(h.runSubs, runID)
+		}
+	}
+}
+
+// SubscribeAll registers a listener for state changes of all runs.
+// The returned function must be called to unsubscribe.
+func (h *RunEventHub) SubscribeAll() (<-chan entity.BackupRunEvent, func()) {
+	ch := make(chan entity.BackupRunEvent, 64)
+
+	h.mu.Lock()
+	h.globalSubs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.globalSubs, ch)
+	}
+}
+
+// Publish sends an event to all subscribers of its run. State events are also
+// sent to global subscribers. Slow subscribers drop log and progress events instead of
+// blocking the backup; state events, which end run streams, wait for them up to
+// stateSendTimeout. Sending happens outside the lock so that a slow subscriber does not
+// hold up subscribing and unsubscribing.
+func (h *RunEventHub) Publish(event entity.BackupRunEvent) {
+	h.mu.RLock()
+	subscribers := make([]chan entity.BackupRunEvent, 0, len(h.runSubs[event.BackupRunID]))
+	for ch := range h.runSubs[event.BackupRunID] {
+		subscribers = append(subscribers, ch)
+	}
+	if event.Type == "state" {
+		for ch := range h.globalSubs {
+			subscribers = append(subscribers, ch)
+		}
+	}
+	h.mu.RUnlock()
+
+	var timeout <-chan struct{}
+	if event.Type == "state" {
+		ctx, cancel := context.WithTimeout(context.Background(), stateSendTimeout)
+		defer cancel()
+		timeout = ctx.Done()
+	}
+	send := func(ch chan entity.BackupRunEvent) {
+		select {
+		case ch <- event:
+			return
+		default:
+		}
+		if timeout == nil {
+			return
+		}
+		select {
+		case ch <- event:
+		case <-timeout:
+		}
+	}
+
+	for _, ch := range subscribers {
+		send(ch)
+	}
+}
+
+// PublishLog sends a freshly written log entry to the run's subscribers
+func (h *RunEventHub) PublishLog(logEntry *entity.BackupRunLog) {
+	h.Publish(entity.BackupRunEvent{
+		Type:        "log",
+		BackupRunID: logEntry.BackupRunID,
+		Log:         logEntry,
+	})
+}
+
+// PublishState sends the current status of a run to run and global subscribers.
+// Progress tracking for the run is dropped once it is no longer running.
+func (h *RunEventHub) PublishState(run *entity.BackupRun) {
+	if run.Status != "running" {
+		h.mu.Lock()
+		delete(h.progress, run.ID)
+		delete(h.lastPublish, run.ID)
+		h.mu.Unlock()
+	}
+
+	snapshot := *run
+	snapshot.BackupFiles = nil
+	h.Publish(entity.BackupRunEvent{
+		Type:        "state",
+		BackupRunID: run.ID,
+		Run:         &snapshot,
+	})
+}
+
+// UpdateProgress applies fn to the progress snapshot of a run and publishes the result.
+// Updates are rate limited unless force is set, so that byte-level progress does not
+// flood subscribers.
+func (h *RunEventHub) UpdateProgress(runID uint, force bool, fn func(p *entity.BackupRunProgress)) {
+	if runID == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	p, ok := h.progress[runID]
+	if !ok {
+		p = &entity.BackupRunProgress{BackupRunID: runID}
+		h.progress[runID] = p
+	}
+	fn(p)
+	now := time.Now()
+	p.UpdatedAt = now
+	if !force && now.Sub(h.lastPublish[runID]) < progressInterval {
+		h.mu.Unlock()
+		return
+	}
+	h.lastPublish[runID] = now
+	snapshot := *p
+	h.mu.Unlock()
+
+	h.Publish(entity.BackupRunEvent{
+		Type:        "progress",
+		BackupRunID: runID,
+		Progress:    &snapshot,
+	})
+}
+
+// LatestProgress returns the last known progress of a running backup, if any
+func (h *RunEventHub) LatestProgress(runID uint) *entity.BackupRunProgress {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	p, ok := h.progress[runID]
+	if !ok {
+		return nil
+	}
+	snapshot := *p
+	return &snapshot
+}
@@ -1,7 +1,7 @@
 package service
 
 import (
-	"log"
+	"log/slog"
 	"sync"
 
 	"backapp-server/entity"
@@ -53,10 +53,10 @@ func (s *BackupScheduler) ScheduleProfile(profile *entity.BackupProfile) error {
 
 	// Add new schedule
 	entryID, err := s.cron.AddFunc(profile.ScheduleCron, func() {
-		log.Printf("Running scheduled backup for profile %d: %s", profile.ID, profile.Name)
+		slog.Info("Running scheduled backup", "profile_id", profile.ID, "profile", profile.Name)
 		// Scheduled jobs must respect the enabled flag (allowDisabled=false)
 		if err := s.executor.ExecuteBackup(profile.ID, false); err != nil {
-			log.Printf("Scheduled backup failed for profile %d: %v", profile.ID, err)
+			slog.Error("Scheduled backup failed", "profile_id", profile.ID, "error", err)
 		}
 	})
 
@@ -65,7 +65,7 @@ func (s *BackupScheduler) ScheduleProfile(profile *entity.BackupProfile) error {
 	}
 
 	s.jobs[profile.ID] = entryID
-	log.Printf("Scheduled backup profile %d (%s) with cron: %s", profile.ID, profile.Name, profile.ScheduleCron)
+	slog.Info("Scheduled backup profile", "profile_id", profile.ID, "profile", profile.Name, "cron", profile.ScheduleCron)
 
 	return nil
 }
@@ -78,7 +78,7 @@ func (s *BackupScheduler) UnscheduleProfile(profileID uint) {
 	if entryID, exists := s.jobs[profileID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.jobs, profileID)
-		log.Printf("Unscheduled backup profile %d", profileID)
+		slog.Info("Unscheduled backup profile", "profile_id", profileID)
 	}
 }
 
@@ -91,14 +91,21 @@ func (s *BackupScheduler) LoadAllSchedules() error {
 
 	for i := range profiles {
 		if err := s.ScheduleProfile(&profiles[i]); err != nil {
-			log.Printf("Failed to schedule profile %d: %v", profiles[i].ID, err)
+			slog.Error("Failed to schedule backup profile", "profile_id", profiles[i].ID, "error", err)
 		}
 	}
 
-	log.Printf("Loaded %d scheduled backup profiles", len(profiles))
+	slog.Info("Loaded scheduled backup profiles", "profiles", len(profiles))
 	return nil
 }
 
+// JobCount returns the number of scheduled profiles
+func (s *BackupScheduler) JobCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.jobs)
+}
+
 // Stop stops the scheduler
 func (s *BackupScheduler) Stop() {
 	s.cron.Stop()
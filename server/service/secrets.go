@@ -0,0 +1,345 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"backapp-server/entity"
+
+	"gorm.io/gorm"
+)
+
+// secretPrefix marks values encrypted with the master key. Values without it are
+// plaintext from before encryption was introduced.
+const secretPrefix = "enc:v1:"
+
+// masterKeySize is the size of the AES-256 master key in bytes
+const masterKeySize = 32
+
+// secretCipher encrypts stored credentials; set by InitSecrets
+var secretCipher cipher.AEAD
+
+var ErrMasterKeyMismatch = errors.New("stored credentials cannot be decrypted with the configured master key")
+
+// InitSecrets loads the master key that encrypts stored credentials from keyFile,
+// BACKAPP_MASTER_KEY or BACKAPP_MASTER_KEY_FILE, in that order. Without any of them a
+// key file is generated next to the database on first start.
+func InitSecrets(keyFile, dbPath string) error {
+	key, source, err := loadMasterKey(keyFile, dbPath)
+	if err != nil {
+		return err
+	}
+	aead, err := newSecretCipher(key)
+	if err != nil {
+		return err
+	}
+	secretCipher = aead
+	slog.Info("Loaded master key", "source", source)
+	return nil
+}
+
+func loadMasterKey(keyFile, dbPath string) ([]byte, string, error) {
+	if keyFile != "" {
+		key, err := readMasterKeyFile(keyFile)
+		return key, keyFile, err
+	}
+	if value := os.Getenv("BACKAPP_MASTER_KEY"); value != "" {
+		key, err := parseMasterKey(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid BACKAPP_MASTER_KEY: %v", err)
+		}
+		return key, "BACKAPP_MASTER_KEY", nil
+	}
+	if path := os.Getenv("BACKAPP_MASTER_KEY_FILE"); path != "" {
+		key, err := readMasterKeyFile(path)
+		return key, path, err
+	}
+
+	path := filepath.Join(filepath.Dir(dbPath), "master.key")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		slog.Warn("No master key configured, generating one. Keep it out of database backups.", "path", path)
+	}
+	key, err := readOrCreateMasterKeyFile(path)
+	return key, path, err
+}
+
+// parseMasterKey accepts 32 bytes encoded as base64 or hex
+func parseMasterKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(value); err == nil && len(key) == masterKeySize {
+			return key, nil
+		}
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == masterKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("expected %d random bytes encoded as base64 or hex, e.g. from 'openssl rand -base64 %d'", masterKeySize, masterKeySize)
+}
+
+func readMasterKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %v", err)
+	}
+	key, err := parseMasterKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key file %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// readOrCreateMasterKeyFile reads a key file, writing a new random key first if it does not exist
+func readOrCreateMasterKeyFile(path string) ([]byte, error) {
+	if _, err := os.Stat(path); err == nil {
+		return readMasterKeyFile(path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create master key file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		return nil, fmt.Errorf("failed to write master key file: %v", err)
+	}
+	return key, nil
+}
+
+func newSecretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSecret(aead cipher.AEAD, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openSecret(aead cipher.AEAD, value string) (string, error) {
+	if !strings.HasPrefix(value, secretPrefix) {
+		return value, nil
+	}
+	if aead == nil {
+		return "", fmt.Errorf("no master key loaded")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMasterKeyMismatch
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrMasterKeyMismatch
+	}
+	return string(plaintext), nil
+}
+
+// encryptSecret encrypts a credential for storage; empty values stay empty
+func encryptSecret(plaintext string) (string, error) {
+	if secretCipher == nil {
+		return "", fmt.Errorf("no master key loaded")
+	}
+	return sealSecret(secretCipher, plaintext)
+}
+
+// decryptSecret returns the plaintext of a stored credential
+func decryptSecret(value string) (string, error) {
+	return openSecret(secretCipher, value)
+}
+
+// encryptPlaintextSecrets encrypts credentials stored before encryption was introduced
+func encryptPlaintextSecrets() {
+	if secretCipher == nil {
+		return
+	}
+	var servers []entity.Server
+	if err := DB.Find(&servers).Error; err != nil {
+		slog.Error("Failed to load servers for credential encryption", "error", err)
+		return
+	}
+	count := 0
+	for _, server := range servers {
+		updates := map[string]interface{}{}
+		for column, value := range map[string]string{"password": server.Password, "private_key_path": server.PrivateKeyPath} {
+			if value == "" || strings.HasPrefix(value, secretPrefix) {
+				continue
+			}
+			encrypted, err := encryptSecret(value)
+			if err != nil {
+				slog.Error("Failed to encrypt credentials", "server_id", server.ID, "error", err)
+				return
+			}
+			updates[column] = encrypted
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := DB.Model(&entity.Server{}).Where("id = ?", server.ID).Updates(updates).Error; err != nil {
+			slog.Error("Failed to encrypt credentials", "server_id", server.ID, "error", err)
+			return
+		}
+		count++
+	}
+	if count > 0 {
+		slog.Info("Encrypted stored credentials", "servers", count)
+	}
+
+	// Chat webhook URLs are encrypted since they grant posting to a channel
+	var integrations []entity.ChatIntegration
+	if err := DB.Find(&integrations).Error; err != nil {
+		slog.Error("Failed to load chat integrations for URL encryption", "error", err)
+		return
+	}
+	count = 0
+	for _, integration := range integrations {
+		if integration.URL == "" || strings.HasPrefix(integration.URL, secretPrefix) {
+			continue
+		}
+		encrypted, err := encryptSecret(integration.URL)
+		if err == nil {
+			err = DB.Model(&entity.ChatIntegration{}).Where("id = ?", integration.ID).Update("url", encrypted).Error
+		}
+		if err != nil {
+			slog.Error("Failed to encrypt chat integration URL", "chat_integration_id", integration.ID, "error", err)
+			return
+		}
+		count++
+	}
+	if count > 0 {
+		slog.Info("Encrypted stored chat integration URLs", "chat_integrations", count)
+	}
+}
+
+// migrateKeyFilePaths moves key file paths, which older versions stored as private keys, to
+// the key file auth type, so private keys are only ever used as key content
+func migrateKeyFilePaths() {
+	var servers []entity.Server
+	if err := DB.Find(&servers).Error; err != nil {
+		slog.Error("Failed to load servers for key path migration", "error", err)
+		return
+	}
+	for _, server := range servers {
+		if !hasAuthType(&server, entity.AuthTypeKey) {
+			continue
+		}
+		privateKey, err := decryptSecret(server.PrivateKeyPath)
+		// Keys are multi-line, so a single line is the path of a key file
+		if err != nil || privateKey == "" || strings.Contains(strings.TrimSpace(privateKey), "\n") {
+			continue
+		}
+		path := strings.TrimSpace(privateKey)
+		if server.KeyFilePath != "" && server.KeyFilePath != path {
+			slog.Warn("Server has a private key path and a different key file, not migrating it", "server_id", server.ID)
+			continue
+		}
+
+		authTypes := []string{}
+		for _, authType := range append([]string{server.AuthType}, server.FallbackAuthTypes...) {
+			if authType == entity.AuthTypeKey {
+				authType = entity.AuthTypeKeyFile
+			}
+			if !slices.Contains(authTypes, authType) {
+				authTypes = append(authTypes, authType)
+			}
+		}
+		server.AuthType = authTypes[0]
+		server.FallbackAuthTypes = authTypes[1:]
+		server.KeyFilePath = path
+		server.PrivateKeyPath = ""
+		err = DB.Model(&entity.Server{}).Where("id = ?", server.ID).
+			Select("auth_type", "fallback_auth_types", "key_file_path", "private_key_path").Updates(&server).Error
+		if err != nil {
+			slog.Error("Failed to migrate private key path", "server_id", server.ID, "error", err)
+			continue
+		}
+		slog.Info("Migrated private key path to key file", "server_id", server.ID, "key_file", path)
+	}
+}
+
+// encryptedColumns lists the columns encrypted with the master key
+var encryptedColumns = []struct {
+	name    string
+	model   interface{}
+	columns []string
+}{
+	{"server", &entity.Server{}, []string{"password", "private_key_path", "key_passphrase"}},
+	{"webhook", &entity.Webhook{}, []string{"secret"}},
+	{"chat integration", &entity.ChatIntegration{}, []string{"url", "token"}},
+}
+
+// RotateMasterKey re-encrypts all stored credentials with the key in newKeyFile, which
+// is generated if it does not exist. It returns the number of records updated. The
+// server must be started with the new key afterwards.
+func RotateMasterKey(newKeyFile string) (int, error) {
+	if secretCipher == nil {
+		return 0, fmt.Errorf("no master key loaded")
+	}
+	key, err := readOrCreateMasterKeyFile(newKeyFile)
+	if err != nil {
+		return 0, err
+	}
+	newCipher, err := newSecretCipher(key)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range encryptedColumns {
+			var rows []map[string]interface{}
+			if err := tx.Model(table.model).Select(append([]string{"id"}, table.columns...)).Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				updates := map[string]interface{}{}
+				for _, column := range table.columns {
+					value, _ := row[column].(string)
+					plaintext, err := decryptSecret(value)
+					if err != nil {
+						return fmt.Errorf("%s %v: %v", table.name, row["id"], err)
+					}
+					if updates[column], err = sealSecret(newCipher, plaintext); err != nil {
+						return err
+					}
+				}
+				if err := tx.Model(table.model).Where("id = ?", row["id"]).Updates(updates).Error; err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	secretCipher = newCipher
+	return count, nil
+}
@@ -1,6 +1,15 @@
 package service
 
-import "backapp-server/entity"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"backapp-server/entity"
+)
+
+// ErrInvalidServer is wrapped by validation errors of server input
+var ErrInvalidServer = errors.New("invalid server")
 
 // internal helpers
 
@@ -9,21 +18,121 @@ func sanitizeServer(s *entity.Server) *entity.Server {
 		return nil
 	}
 	copy := *s
+	copy.HasPassword = s.Password != ""
+	copy.HasPrivateKey = s.PrivateKeyPath != ""
+	copy.HasKeyPassphrase = s.KeyPassphrase != ""
 	copy.PrivateKeyPath = ""
 	copy.Password = ""
+	copy.KeyPassphrase = ""
 	return &copy
 }
 
 func sanitizeServers(list []entity.Server) []entity.Server {
 	out := make([]entity.Server, len(list))
 	for i := range list {
-		out[i] = list[i]
-		out[i].PrivateKeyPath = ""
-		out[i].Password = ""
+		out[i] = *sanitizeServer(&list[i])
 	}
 	return out
 }
 
+// applyServerInput validates an input and copies it into server, encrypting new
+// secrets. Empty secrets keep the stored ones.
+func applyServerInput(server *entity.Server, input *entity.ServerInput) error {
+	if input.Name == "" || input.Host == "" || input.Username == "" {
+		return fmt.Errorf("%w: name, host and username are required", ErrInvalidServer)
+	}
+	// Without an auth type updates keep the current one, new servers default to a key
+	authType := input.AuthType
+	if authType == "" {
+		authType = server.AuthType
+	}
+	if authType == "" {
+		authType = entity.AuthTypeKey
+	}
+	seen := map[string]bool{}
+	usesKey := false
+	for _, t := range append([]string{authType}, input.FallbackAuthTypes...) {
+		if seen[t] {
+			return fmt.Errorf("%w: auth type %q is listed twice", ErrInvalidServer, t)
+		}
+		seen[t] = true
+		if err := validateAuthType(t, input); err != nil {
+			return err
+		}
+		usesKey = usesKey || t == entity.AuthTypeKey || t == entity.AuthTypeKeyFile ||
+			t == entity.AuthTypeKeyCommand || t == entity.AuthTypeAgent
+	}
+	// Keys are multi-line, a single line is most likely the path of a key file
+	if input.PrivateKey != "" && !strings.Contains(strings.TrimSpace(input.PrivateKey), "\n") {
+		return fmt.Errorf("%w: private_key must be the content of a key, use key_file auth for a key file on the host", ErrInvalidServer)
+	}
+	if strings.TrimSpace(input.Certificate) != "" {
+		if !usesKey {
+			return fmt.Errorf("%w: a certificate needs a key based auth type", ErrInvalidServer)
+		}
+		if _, err := parseUserCertificate([]byte(input.Certificate)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidServer, err)
+		}
+	}
+
+	server.Name = input.Name
+	server.Host = input.Host
+	server.Port = input.Port
+	if server.Port == 0 {
+		server.Port = 22
+	}
+	server.Username = input.Username
+	server.AuthType = authType
+	server.KeyFilePath = input.KeyFilePath
+	server.AgentSocket = input.AgentSocket
+	server.SecretCommand = input.SecretCommand
+	server.FallbackAuthTypes = input.FallbackAuthTypes
+	server.Certificate = strings.TrimSpace(input.Certificate)
+	if input.JumpHostID != nil {
+		if err := validateJumpHost(server.ID, *input.JumpHostID); err != nil {
+			return err
+		}
+	}
+	server.JumpHostID = input.JumpHostID
+
+	for _, secret := range []struct {
+		value  string
+		target *string
+	}{
+		{input.Password, &server.Password},
+		{input.PrivateKey, &server.PrivateKeyPath},
+		{input.KeyPassphrase, &server.KeyPassphrase},
+	} {
+		if secret.value == "" {
+			continue
+		}
+		encrypted, err := encryptSecret(secret.value)
+		if err != nil {
+			return err
+		}
+		*secret.target = encrypted
+	}
+	return nil
+}
+
+// validateAuthType checks that an auth type is known and has its settings
+func validateAuthType(authType string, input *entity.ServerInput) error {
+	switch authType {
+	case entity.AuthTypePassword, entity.AuthTypeKey, entity.AuthTypeAgent, entity.AuthTypeKeyboardInteractive:
+	case entity.AuthTypeKeyFile:
+		if input.KeyFilePath == "" {
+			return fmt.Errorf("%w: key_file_path is required for key_file auth", ErrInvalidServer)
+		}
+	case entity.AuthTypePasswordCommand, entity.AuthTypeKeyCommand:
+		if input.SecretCommand == "" {
+			return fmt.Errorf("%w: secret_command is required for %s auth", ErrInvalidServer, authType)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported auth_type %q", ErrInvalidServer, authType)
+	}
+	return nil
+}
+
 // low-level accessors (may return sensitive fields)
 
 func GetServerByID(id uint) (*entity.Server, error) {
@@ -60,20 +169,10 @@ func ServiceGetServer(id uint) (*entity.Server, error) {
 	return sanitizeServer(server), nil
 }
 
-func ServiceCreateServerFromJSON(input *entity.Server) (*entity.Server, error) {
-	server := &entity.Server{
-		Name:     input.Name,
-		Host:     input.Host,
-		Port:     input.Port,
-		Username: input.Username,
-		AuthType: input.AuthType,
-		Password: input.Password,
-	}
-	if server.Port == 0 {
-		server.Port = 22
-	}
-	if server.AuthType == "" {
-		server.AuthType = "key"
+func ServiceCreateServerFromJSON(input *entity.ServerInput) (*entity.Server, error) {
+	server := &entity.Server{}
+	if err := applyServerInput(server, input); err != nil {
+		return nil, err
 	}
 	if err := DB.Create(server).Error; err != nil {
 		return nil, err
@@ -81,47 +180,27 @@ func ServiceCreateServerFromJSON(input *entity.Server) (*entity.Server, error) {
 	return sanitizeServer(server), nil
 }
 
-func ServiceCreateServerWithKey(name, host string, port int, username string, keyContent []byte) (*entity.Server, error) {
-	server := &entity.Server{
-		Name:     name,
-		Host:     host,
-		Port:     port,
-		Username: username,
-		AuthType: "key",
-	}
-	if server.Port == 0 {
-		server.Port = 22
-	}
-	if err := DB.Create(server).Error; err != nil {
-		return nil, err
-	}
-	server.PrivateKeyPath = string(keyContent)
-	if err := DB.Save(server).Error; err != nil {
-		return nil, err
+// ServiceTestServerInput tests the connection of a server before it is saved
+func ServiceTestServerInput(input *entity.ServerInput) error {
+	server := &entity.Server{}
+	if err := applyServerInput(server, input); err != nil {
+		return err
 	}
-	return sanitizeServer(server), nil
+	return TestSSHConnectionUsingServer(server)
 }
 
-func ServiceUpdateServer(id uint, input *entity.Server) (*entity.Server, error) {
+func ServiceUpdateServer(id uint, input *entity.ServerInput) (*entity.Server, error) {
 	server, err := GetServerByID(id)
 	if err != nil {
 		return nil, err
 	}
-	server.Name = input.Name
-	server.Host = input.Host
-	server.Port = input.Port
-	server.Username = input.Username
-	server.AuthType = input.AuthType
-	// Only update password if a new one is provided (non-empty)
-	if input.Password != "" {
-		server.Password = input.Password
-	}
-	if server.Port == 0 {
-		server.Port = 22
+	if err := applyServerInput(server, input); err != nil {
+		return nil, err
 	}
 	if err := DB.Save(server).Error; err != nil {
 		return nil, err
 	}
+	GetSSHPool().Invalidate(server.ID)
 	return sanitizeServer(server), nil
 }
 
@@ -176,6 +255,15 @@ func ServiceDeleteServer(id uint) error {
 		return err
 	}
 
+	// Servers connecting through this one would lose their route
+	var dependents int64
+	if err := DB.Model(&entity.Server{}).Where("jump_host_id = ?", id).Count(&dependents).Error; err != nil {
+		return err
+	}
+	if dependents > 0 {
+		return fmt.Errorf("%w by %d other server(s)", ErrServerIsJumpHost, dependents)
+	}
+
 	// Get all backup profiles for this server
 	var profiles []entity.BackupProfile
 	if err := DB.Where("server_id = ?", id).Find(&profiles).Error; err != nil {
@@ -216,5 +304,6 @@ func ServiceDeleteServer(id uint) error {
 	}
 
 	// Finally, delete the server
+	GetSSHPool().Invalidate(id)
 	return DB.Delete(&entity.Server{}, id).Error
 }
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"backapp-server/entity"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// secretCommandTimeout limits how long a secret command may take
+const secretCommandTimeout = 30 * time.Second
+
+// sshCredential is what a single auth type resolves to. SSH tries every method name
+// only once, so credentials of the same method are merged before connecting.
+type sshCredential struct {
+	method   string // "publickey", "password" or "keyboard-interactive"
+	signers  func() ([]ssh.Signer, error)
+	password string
+}
+
+// sshAuthMethods resolves the credentials of a server right before connecting, starting
+// with its auth type and followed by its fallbacks. The returned function releases agent
+// connections once the handshake is done.
+func sshAuthMethods(server *entity.Server) ([]ssh.AuthMethod, func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+
+	var credentials []sshCredential
+	var firstErr error
+	for _, authType := range append([]string{server.AuthType}, server.FallbackAuthTypes...) {
+		credential, done, err := resolveCredential(server, authType)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if len(server.FallbackAuthTypes) > 0 {
+				slog.Warn("Skipping SSH auth method", "auth_type", authType, "server", server.Name, "error", err)
+			}
+			continue
+		}
+		releases = append(releases, done)
+		credentials = append(credentials, credential)
+	}
+	if len(credentials) == 0 {
+		return nil, release, firstErr
+	}
+	return mergeCredentials(credentials), release, nil
+}
+
+// resolveCredential reads the credential of one auth type of a server
+func resolveCredential(server *entity.Server, authType string) (sshCredential, func(), error) {
+	release := func() {}
+
+	switch authType {
+	case entity.AuthTypePassword, entity.AuthTypeKeyboardInteractive:
+		password, err := decryptSecret(server.Password)
+		if err != nil {
+			return sshCredential{}, release, fmt.Errorf("failed to decrypt password: %v", err)
+		}
+		if password == "" {
+			return sshCredential{}, release, fmt.Errorf("server has no password configured")
+		}
+		method := "password"
+		if authType == entity.AuthTypeKeyboardInteractive {
+			method = "keyboard-interactive"
+		}
+		return sshCredential{method: method, password: password}, release, nil
+
+	case entity.AuthTypeKey:
+		privateKey, err := decryptSecret(server.PrivateKeyPath)
+		if err != nil {
+			return sshCredential{}, release, fmt.Errorf("failed to decrypt private key: %v", err)
+		}
+		if privateKey == "" {
+			return sshCredential{}, release, fmt.Errorf("server has no private key configured")
+		}
+		return keyCredential(server, []byte(privateKey), "")
+
+	case entity.AuthTypeKeyFile:
+		keyData, err := os.ReadFile(server.KeyFilePath)
+		if err != nil {
+			return sshCredential{}, release, fmt.Errorf("failed to read private key file: %v", err)
+		}
+		// OpenSSH picks up a certificate next to the key, which allows renewing it on disk
+		return keyCredential(server, keyData, server.KeyFilePath+"-cert.pub")
+
+	case entity.AuthTypeAgent:
+		socket := server.AgentSocket
+		if socket == "" {
+			socket = os.Getenv("SSH_AUTH_SOCK")
+		}
+		if socket == "" {
+			return sshCredential{}, release, fmt.Errorf("no SSH agent configured and SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return sshCredential{}, release, fmt.Errorf("failed to connect to SSH agent: %v", err)
+		}
+		cert, err := serverCertificate(server, "")
+		if err != nil {
+			conn.Close()
+			return sshCredential{}, release, err
+		}
+		client := agent.NewClient(conn)
+		signers := func() ([]ssh.Signer, error) {
+			signers, err := client.Signers()
+			if err != nil || cert == nil {
+				return signers, err
+			}
+			// Present the certificate with the agent key it belongs to
+			for _, signer := range signers {
+				if bytes.Equal(signer.PublicKey().Marshal(), cert.Key.Marshal()) {
+					certSigner, err := ssh.NewCertSigner(cert, signer)
+					if err != nil {
+						return nil, err
+					}
+					return append([]ssh.Signer{certSigner}, signers...), nil
+				}
+			}
+			return signers, nil
+		}
+		return sshCredential{method: "publickey", signers: signers}, func() { conn.Close() }, nil
+
+	case entity.AuthTypePasswordCommand:
+		output, err := runSecretCommand(server.SecretCommand)
+		if err != nil {
+			return sshCredential{}, release, err
+		}
+		// Password managers end their output with a newline
+		return sshCredential{method: "password", password: strings.TrimRight(output, "\r\n")}, release, nil
+
+	case entity.AuthTypeKeyCommand:
+		output, err := runSecretCommand(server.SecretCommand)
+		if err != nil {
+			return sshCredential{}, release, err
+		}
+		return keyCredential(server, []byte(output), "")
+
+	default:
+		return sshCredential{}, release, fmt.Errorf("unsupported auth_type: %s", authType)
+	}
+}
+
+// mergeCredentials turns credentials into auth methods in the order their methods first
+// appear. Keys are offered one after another, passwords are retried with the next one.
+func mergeCredentials(credentials []sshCredential) []ssh.AuthMethod {
+	var order []string
+	byMethod := map[string][]sshCredential{}
+	for _, credential := range credentials {
+		if _, ok := byMethod[credential.method]; !ok {
+			order = append(order, credential.method)
+		}
+		byMethod[credential.method] = append(byMethod[credential.method], credential)
+	}
+
+	var methods []ssh.AuthMethod
+	for _, method := range order {
+		group := byMethod[method]
+		switch method {
+		case "publickey":
+			methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				var all []ssh.Signer
+				for _, credential := range group {
+					signers, err := credential.signers()
+					if err != nil {
+						return nil, err
+					}
+					all = append(all, signers...)
+				}
+				return all, nil
+			}))
+		case "password":
+			next := nextPassword(group)
+			methods = append(methods, ssh.RetryableAuthMethod(ssh.PasswordCallback(func() (string, error) {
+				return next(), nil
+			}), len(group)))
+		case "keyboard-interactive":
+			next := nextPassword(group)
+			methods = append(methods, ssh.RetryableAuthMethod(ssh.KeyboardInteractive(
+				func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+					// Static passwords answer every prompt; an empty round only shows instructions
+					if len(questions) == 0 {
+						return nil, nil
+					}
+					password := next()
+					answers := make([]string, len(questions))
+					for i := range answers {
+						answers[i] = password
+					}
+					return answers, nil
+				}), len(group)))
+		}
+	}
+	return methods
+}
+
+// nextPassword returns the passwords of credentials one per call, repeating the last one
+func nextPassword(credentials []sshCredential) func() string {
+	i := 0
+	return func() string {
+		password := credentials[i].password
+		if i < len(credentials)-1 {
+			i++
+		}
+		return password
+	}
+}
+
+// keyCredential parses a private key, decrypting it with the server's passphrase if it is
+// encrypted, and pairs it with the server's certificate or the one in certFile
+func keyCredential(server *entity.Server, keyData []byte, certFile string) (sshCredential, func(), error) {
+	release := func() {}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase, decryptErr := decryptSecret(server.KeyPassphrase)
+		if decryptErr != nil {
+			return sshCredential{}, release, fmt.Errorf("failed to decrypt key passphrase: %v", decryptErr)
+		}
+		if passphrase == "" {
+			return sshCredential{}, release, fmt.Errorf("private key is encrypted but no passphrase is configured")
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
+	}
+	if err != nil {
+		return sshCredential{}, release, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	signers := []ssh.Signer{signer}
+	cert, err := serverCertificate(server, certFile)
+	if err != nil {
+		return sshCredential{}, release, err
+	}
+	if cert != nil {
+		certSigner, err := ssh.NewCertSigner(cert, signer)
+		if err != nil {
+			return sshCredential{}, release, fmt.Errorf("certificate does not match the private key: %v", err)
+		}
+		// Servers that do not trust the CA may still accept the plain key
+		signers = []ssh.Signer{certSigner, signer}
+	}
+	return sshCredential{method: "publickey", signers: func() ([]ssh.Signer, error) { return signers, nil }}, release, nil
+}
+
+// serverCertificate returns the stored certificate of a server, or the one in certFile if
+// that exists. It returns nil without a certificate.
+func serverCertificate(server *entity.Server, certFile string) (*ssh.Certificate, error) {
+	data := []byte(server.Certificate)
+	if len(bytes.TrimSpace(data)) == 0 && certFile != "" {
+		fileData, err := os.ReadFile(certFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate file: %v", err)
+		}
+		data = fileData
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	return parseUserCertificate(data)
+}
+
+// parseUserCertificate parses an OpenSSH user certificate such as id_ed25519-cert.pub
+func parseUserCertificate(data []byte) (*ssh.Certificate, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse certificate: %s is a public key, not a certificate", key.Type())
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("failed to parse certificate: not a user certificate")
+	}
+	return cert, nil
+}
+
+// runSecretCommand runs a secret provider command such as "pass show servers/web" or
+// "vault kv get -field=password secret/web" and returns its output
+func runSecretCommand(command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("server has no secret command configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("secret command timed out after %s", secretCommandTimeout)
+		}
+		return "", fmt.Errorf("secret command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if strings.TrimSpace(stdout.String()) == "" {
+		return "", fmt.Errorf("secret command printed nothing")
+	}
+	return stdout.String(), nil
+}
+
+// usesHostCredentials reports whether an auth type takes credentials from the BackApp
+// host itself rather than from the database
+func usesHostCredentials(authType string) bool {
+	switch authType {
+	case entity.AuthTypeKeyFile, entity.AuthTypeAgent, entity.AuthTypePasswordCommand, entity.AuthTypeKeyCommand:
+		return true
+	}
+	return false
+}
+
+// HostCredentialsChanged reports whether an update configures credentials that are taken
+// from the BackApp host. These reach beyond the server, so only global admins may set them.
+func HostCredentialsChanged(server *entity.Server, input *entity.ServerInput) bool {
+	usesHost := false
+	for _, authType := range append([]string{input.AuthType}, input.FallbackAuthTypes...) {
+		if usesHostCredentials(authType) && !hasAuthType(server, authType) {
+			return true
+		}
+		usesHost = usesHost || usesHostCredentials(authType)
+	}
+	if !usesHost {
+		return false
+	}
+	return server.KeyFilePath != input.KeyFilePath ||
+		server.AgentSocket != input.AgentSocket ||
+		server.SecretCommand != input.SecretCommand
+}
+
+// hasAuthType reports whether a server uses an auth type, either first or as fallback
+func hasAuthType(server *entity.Server, authType string) bool {
+	if server.AuthType == authType {
+		return true
+	}
+	for _, fallback := range server.FallbackAuthTypes {
+		if fallback == authType {
+			return true
+		}
+	}
+	return false
+}
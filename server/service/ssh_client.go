@@ -3,9 +3,10 @@ package service
 import (
 	"fmt"
 	"io"
-	"log"
-	"net"
+	"log/slog"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"backapp-server/entity"
@@ -18,79 +19,141 @@ type SSHClient struct {
 	client *ssh.Client
 	config *ssh.ClientConfig
 	addr   string
+	// jumps are the connections to the jump hosts, first hop first
+	jumps []*ssh.Client
+	// sessions limits the open sessions of a pooled connection
+	sessions chan struct{}
+	// release hands a pooled connection back instead of closing it
+	release func()
 }
 
-// NewSSHClient creates a new SSH client for a server
+// NewSSHClient creates a new SSH client for a server, connecting through its jump hosts.
+// Failed connections are counted in the metrics.
 func NewSSHClient(server *entity.Server) (*SSHClient, error) {
-	var config *ssh.ClientConfig
-
-	switch server.AuthType {
-	case "key":
-		var keyData []byte
-		var err error
-
-		// Try reading as file first
-		if stat, statErr := os.Stat(server.PrivateKeyPath); statErr == nil && !stat.IsDir() {
-			keyData, err = os.ReadFile(server.PrivateKeyPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read private key file: %v", err)
-			}
-		} else {
-			// Treat as key content
-			keyData = []byte(server.PrivateKeyPath)
-		}
+	client, err := dialSSH(server)
+	if err != nil {
+		GetMetrics().RecordSSHConnectionError(server.ID)
+	}
+	return client, err
+}
 
-		signer, err := ssh.ParsePrivateKey(keyData)
+// dialSSH connects to a server through its jump hosts
+func dialSSH(server *entity.Server) (*SSHClient, error) {
+	chain, err := jumpHostChain(server)
+	if err != nil {
+		return nil, err
+	}
+
+	var jumps []*ssh.Client
+	closeJumps := func() {
+		for i := len(jumps) - 1; i >= 0; i-- {
+			jumps[i].Close()
+		}
+	}
+	for i, hop := range chain {
+		auth, release, err := sshAuthMethods(hop)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse private key: %v", err)
+			closeJumps()
+			if len(chain) == 1 {
+				return nil, err
+			}
+			return nil, hopError(chain, i, err)
 		}
 
-		config = &ssh.ClientConfig{
-			User: server.Username,
-			Auth: []ssh.AuthMethod{
-				ssh.PublicKeys(signer),
-			},
+		config := &ssh.ClientConfig{
+			User:            hop.Username,
+			Auth:            auth,
 			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 			Timeout:         30 * time.Second,
 		}
+		address := serverAddress(hop)
 
-	case "password":
-		config = &ssh.ClientConfig{
-			User: server.Username,
-			Auth: []ssh.AuthMethod{
-				ssh.Password(server.Password),
-			},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			Timeout:         30 * time.Second,
+		var client *ssh.Client
+		if i == 0 {
+			client, err = ssh.Dial("tcp", address, config)
+		} else {
+			client, err = dialThrough(jumps[i-1], address, config)
+		}
+		release()
+		if err != nil {
+			closeJumps()
+			if len(chain) == 1 {
+				return nil, fmt.Errorf("SSH connection failed: %v", err)
+			}
+			return nil, hopError(chain, i, err)
 		}
 
-	default:
-		return nil, fmt.Errorf("unsupported auth_type: %s", server.AuthType)
+		if i == len(chain)-1 {
+			return &SSHClient{
+				client: client,
+				config: config,
+				addr:   address,
+				jumps:  jumps,
+			}, nil
+		}
+		jumps = append(jumps, client)
 	}
+	return nil, fmt.Errorf("no server to connect to")
+}
 
-	// Build address with correct port
-	address := server.Host
-	if _, _, err := net.SplitHostPort(server.Host); err != nil {
-		// hostname doesn't contain port, add it
-		port := server.Port
-		if port == 0 {
-			port = 22
-		}
-		address = net.JoinHostPort(server.Host, fmt.Sprintf("%d", port))
+// shellSafe matches words that a POSIX shell passes through unchanged
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s as a single word for a POSIX shell. Any byte except NUL
+// survives quoting, including quotes, spaces, newlines and glob characters.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
 	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		return nil, fmt.Errorf("SSH connection failed: %v", err)
+// shellJoin quotes each argument and joins them into a command line
+func shellJoin(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
 	}
+	return strings.Join(quoted, " ")
+}
 
-	return &SSHClient{
-		client: client,
-		config: config,
-		addr:   address,
+// newSession opens a session, waiting while a pooled connection has its maximum number
+// of sessions open. The returned function closes the session.
+func (c *SSHClient) newSession() (*ssh.Session, func(), error) {
+	if c.sessions != nil {
+		c.sessions <- struct{}{}
+	}
+	free := func() {
+		if c.sessions != nil {
+			<-c.sessions
+		}
+	}
+	session, err := c.client.NewSession()
+	if err != nil {
+		free()
+		return nil, nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	return session, func() {
+		session.Close()
+		free()
 	}, nil
 }
 
+// ping sends a keepalive request and waits for the reply
+func (c *SSHClient) ping(timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("keepalive timed out after %s", timeout)
+	}
+}
+
 // RunCommand executes a command on the remote server
 func (c *SSHClient) RunCommand(cmd string) (string, error) {
 	return c.RunCommandInDir(cmd, "")
@@ -98,16 +161,16 @@ func (c *SSHClient) RunCommand(cmd string) (string, error) {
 
 // RunCommandInDir executes a command on the remote server in a specific directory
 func (c *SSHClient) RunCommandInDir(cmd string, workingDir string) (string, error) {
-	session, err := c.client.NewSession()
+	session, closeSession, err := c.newSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %v", err)
+		return "", err
 	}
-	defer session.Close()
+	defer closeSession()
 
 	// If working directory is specified and not root, prepend cd command
 	fullCmd := cmd
 	if workingDir != "" && workingDir != "/" {
-		fullCmd = fmt.Sprintf("cd '%s' && %s", workingDir, cmd)
+		fullCmd = fmt.Sprintf("cd -- %s && %s", shellQuote(workingDir), cmd)
 	}
 
 	output, err := session.CombinedOutput(fullCmd)
@@ -118,28 +181,83 @@ func (c *SSHClient) RunCommandInDir(cmd string, workingDir string) (string, erro
 	return string(output), nil
 }
 
+// Output executes a command and returns its standard output only. Standard error is
+// reported as part of the error so that it cannot corrupt machine-readable output.
+func (c *SSHClient) Output(cmd string) (string, error) {
+	session, closeSession, err := c.newSession()
+	if err != nil {
+		return "", err
+	}
+	defer closeSession()
+
+	var stdout, stderr strings.Builder
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(cmd); err != nil {
+		return stdout.String(), fmt.Errorf("command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// ListNul executes a command that prints NUL-terminated entries, such as find -print0,
+// and returns the entries. Unlike newline-separated output this is safe for any filename.
+func (c *SSHClient) ListNul(cmd string) ([]string, error) {
+	output, err := c.Output(cmd)
+	if err != nil {
+		return nil, err
+	}
+	entries := strings.Split(output, "\x00")
+	// The output ends with a terminator, which leaves an empty last entry
+	if entries[len(entries)-1] == "" {
+		entries = entries[:len(entries)-1]
+	}
+	return entries, nil
+}
+
 // CopyFileFromRemote downloads a file from the remote server using SCP
 func (c *SSHClient) CopyFileFromRemote(remotePath, localPath string) error {
-	log.Printf("Starting file copy from remote: %s to local: %s", remotePath, localPath)
+	return c.CopyFileFromRemoteWithProgress(remotePath, localPath, nil)
+}
+
+// CopyFileFromRemoteWithProgress downloads a file and reports the number of bytes
+// written so far for this file to onProgress (which may be nil)
+func (c *SSHClient) CopyFileFromRemoteWithProgress(remotePath, localPath string, onProgress func(written int64)) error {
+	slog.Debug("Starting file copy from remote", "remote_path", remotePath, "local_path", localPath)
 
 	// Try simple cat method first (more reliable)
-	err := c.copyFileUsingCat(remotePath, localPath)
+	err := c.copyFileUsingCat(remotePath, localPath, onProgress)
 	if err == nil {
-		log.Printf("File copied successfully using cat method")
+		slog.Debug("File copied successfully using cat method", "remote_path", remotePath)
 		return nil
 	}
 
-	log.Printf("Cat method failed: %v, falling back to SCP", err)
-	return c.copyFileUsingSCP(remotePath, localPath)
+	slog.Debug("Cat method failed, falling back to SCP", "remote_path", remotePath, "error", err)
+	return c.copyFileUsingSCP(remotePath, localPath, onProgress)
+}
+
+// progressWriter counts bytes written through it and reports the running total
+type progressWriter struct {
+	w          io.Writer
+	written    int64
+	onProgress func(written int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.onProgress != nil {
+		p.onProgress(p.written)
+	}
+	return n, err
 }
 
 // copyFileUsingCat downloads a file using cat (simpler and more reliable)
-func (c *SSHClient) copyFileUsingCat(remotePath, localPath string) error {
-	session, err := c.client.NewSession()
+func (c *SSHClient) copyFileUsingCat(remotePath, localPath string, onProgress func(written int64)) error {
+	session, closeSession, err := c.newSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %v", err)
+		return err
 	}
-	defer session.Close()
+	defer closeSession()
 
 	// Create local file
 	localFile, err := os.Create(localPath)
@@ -155,12 +273,12 @@ func (c *SSHClient) copyFileUsingCat(remotePath, localPath string) error {
 	}
 
 	// Start cat command
-	if err := session.Start(fmt.Sprintf("cat '%s'", remotePath)); err != nil {
+	if err := session.Start(shellJoin("cat", "--", remotePath)); err != nil {
 		return fmt.Errorf("failed to start cat: %v", err)
 	}
 
 	// Copy content to local file
-	if _, err := io.Copy(localFile, stdout); err != nil {
+	if _, err := io.Copy(&progressWriter{w: localFile, onProgress: onProgress}, stdout); err != nil {
 		return fmt.Errorf("failed to copy file content: %v", err)
 	}
 
@@ -173,12 +291,12 @@ func (c *SSHClient) copyFileUsingCat(remotePath, localPath string) error {
 }
 
 // copyFileUsingSCP downloads a file from the remote server using SCP
-func (c *SSHClient) copyFileUsingSCP(remotePath, localPath string) error {
-	session, err := c.client.NewSession()
+func (c *SSHClient) copyFileUsingSCP(remotePath, localPath string, onProgress func(written int64)) error {
+	session, closeSession, err := c.newSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %v", err)
+		return err
 	}
-	defer session.Close()
+	defer closeSession()
 
 	// Create local file
 	localFile, err := os.Create(localPath)
@@ -198,7 +316,7 @@ func (c *SSHClient) copyFileUsingSCP(remotePath, localPath string) error {
 		return fmt.Errorf("failed to get stdin pipe: %v", err)
 	}
 
-	if err := session.Start("scp -f " + remotePath); err != nil {
+	if err := session.Start(shellJoin("scp", "-f", "--", remotePath)); err != nil {
 		return fmt.Errorf("failed to start scp: %v", err)
 	}
 
@@ -220,7 +338,7 @@ func (c *SSHClient) copyFileUsingSCP(remotePath, localPath string) error {
 	}
 
 	// Read file content
-	if _, err := io.Copy(localFile, stdout); err != nil {
+	if _, err := io.Copy(&progressWriter{w: localFile, onProgress: onProgress}, stdout); err != nil {
 		return fmt.Errorf("failed to copy file: %v", err)
 	}
 
@@ -240,10 +358,18 @@ func (c *SSHClient) copyFileUsingSCP(remotePath, localPath string) error {
 	return nil
 }
 
-// Close closes the SSH connection
+// Close closes the SSH connection, or hands it back to the pool
 func (c *SSHClient) Close() error {
+	if c.release != nil {
+		c.release()
+		return nil
+	}
+	var err error
 	if c.client != nil {
-		return c.client.Close()
+		err = c.client.Close()
 	}
-	return nil
+	for i := len(c.jumps) - 1; i >= 0; i-- {
+		c.jumps[i].Close()
+	}
+	return err
 }
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"backapp-server/entity"
+
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// maxJumpHosts limits the length of a jump host chain
+const maxJumpHosts = 8
+
+// ErrServerIsJumpHost is returned when deleting a server that other servers connect through
+var ErrServerIsJumpHost = errors.New("server is used as jump host")
+
+// serverAddress returns the host:port of a server
+func serverAddress(server *entity.Server) string {
+	// Hosts may already contain a port
+	if _, _, err := net.SplitHostPort(server.Host); err == nil {
+		return server.Host
+	}
+	port := server.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(server.Host, strconv.Itoa(port))
+}
+
+// jumpHostChain returns the servers to connect through in order, ending with server itself
+func jumpHostChain(server *entity.Server) ([]*entity.Server, error) {
+	chain := []*entity.Server{server}
+	seen := map[uint]bool{server.ID: true}
+	for next := server.JumpHostID; next != nil; next = chain[0].JumpHostID {
+		if seen[*next] {
+			return nil, fmt.Errorf("jump host chain of %q contains a loop", server.Name)
+		}
+		if len(chain) > maxJumpHosts {
+			return nil, fmt.Errorf("jump host chain of %q is longer than %d hops", server.Name, maxJumpHosts)
+		}
+		jump, err := GetServerByID(*next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jump host %d of %q: %v", *next, chain[0].Name, err)
+		}
+		seen[jump.ID] = true
+		chain = append([]*entity.Server{jump}, chain...)
+	}
+	return chain, nil
+}
+
+// validateJumpHost makes sure a jump host exists and does not lead back to the server
+func validateJumpHost(serverID uint, jumpHostID uint) error {
+	next := &jumpHostID
+	for depth := 0; next != nil; depth++ {
+		if serverID != 0 && *next == serverID {
+			return fmt.Errorf("%w: jump host chain leads back to this server", ErrInvalidServer)
+		}
+		if depth >= maxJumpHosts {
+			return fmt.Errorf("%w: jump host chain is longer than %d hops", ErrInvalidServer, maxJumpHosts)
+		}
+		jump, err := GetServerByID(*next)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: jump host %d not found", ErrInvalidServer, *next)
+		}
+		if err != nil {
+			return err
+		}
+		next = jump.JumpHostID
+	}
+	return nil
+}
+
+// dialThrough opens an SSH connection to address tunneled through an established connection
+func dialThrough(jump *ssh.Client, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := jump.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		conn  ssh.Conn
+		chans <-chan ssh.NewChannel
+		reqs  <-chan *ssh.Request
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+		done <- result{c, chans, reqs, err}
+	}()
+
+	// Tunneled connections ignore config.Timeout, so the handshake is bounded here
+	select {
+	case r := <-done:
+		if r.err != nil {
+			conn.Close()
+			return nil, r.err
+		}
+		return ssh.NewClient(r.conn, r.chans, r.reqs), nil
+	case <-time.After(config.Timeout):
+		conn.Close()
+		return nil, fmt.Errorf("handshake timed out after %s", config.Timeout)
+	}
+}
+
+// hopError names the hop of a jump host chain that failed
+func hopError(chain []*entity.Server, i int, err error) error {
+	hop := chain[i]
+	if i == len(chain)-1 {
+		via := chain[i-1]
+		return fmt.Errorf("SSH connection to %q (%s) via jump host %q failed: %v",
+			hop.Name, serverAddress(hop), via.Name, err)
+	}
+	return fmt.Errorf("SSH connection to jump host %q (%s, hop %d of %d) failed: %v",
+		hop.Name, serverAddress(hop), i+1, len(chain)-1, err)
+}
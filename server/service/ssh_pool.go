@@ -0,0 +1,253 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+)
+
+const (
+	// defaultSSHIdleTimeout closes pooled connections that were not used for this long
+	defaultSSHIdleTimeout = 5 * time.Minute
+	// defaultSSHMaxSessions stays below the MaxSessions default of OpenSSH (10)
+	defaultSSHMaxSessions = 8
+	// sshKeepaliveInterval is how often pooled connections are checked
+	sshKeepaliveInterval = 30 * time.Second
+	// sshHealthCheckTimeout bounds a keepalive round trip
+	sshHealthCheckTimeout = 10 * time.Second
+)
+
+// SSHPool shares one SSH connection per server between backups, the file explorer and
+// connection tests. Connections are kept alive while in use and closed once idle.
+type SSHPool struct {
+	idleTimeout time.Duration
+	maxSessions int
+	conns       map[uint]*pooledSSHConn
+	mu          sync.Mutex
+}
+
+// pooledSSHConn is a connection of the pool and its bookkeeping
+type pooledSSHConn struct {
+	client      *SSHClient
+	fingerprint string
+	// chain holds the IDs of the jump hosts, whose changes also invalidate the connection
+	chain    []uint
+	users    int
+	lastUsed time.Time
+	// broken connections are closed once their last user releases them
+	broken bool
+}
+
+var (
+	sshPool     *SSHPool
+	sshPoolOnce sync.Once
+)
+
+// InitSSHPool configures the shared SSH connection pool and starts its keepalives
+func InitSSHPool(idleTimeout time.Duration, maxSessions int) {
+	sshPoolOnce.Do(func() {
+		if idleTimeout <= 0 {
+			idleTimeout = defaultSSHIdleTimeout
+		}
+		if maxSessions <= 0 {
+			maxSessions = defaultSSHMaxSessions
+		}
+		sshPool = &SSHPool{
+			idleTimeout: idleTimeout,
+			maxSessions: maxSessions,
+			conns:       make(map[uint]*pooledSSHConn),
+		}
+		go sshPool.maintain()
+	})
+}
+
+// GetSSHPool returns the shared SSH connection pool
+func GetSSHPool() *SSHPool {
+	InitSSHPool(defaultSSHIdleTimeout, defaultSSHMaxSessions)
+	return sshPool
+}
+
+// AcquireSSHClient returns a pooled connection to a server. Closing the returned client
+// hands the connection back to the pool.
+func AcquireSSHClient(server *entity.Server) (*SSHClient, error) {
+	return GetSSHPool().Acquire(server)
+}
+
+// Acquire returns a healthy connection to a server, dialing one if needed. Servers that
+// are not saved yet get a connection of their own.
+func (p *SSHPool) Acquire(server *entity.Server) (*SSHClient, error) {
+	if server.ID == 0 {
+		return NewSSHClient(server)
+	}
+	fingerprint := sshFingerprint(server)
+
+	p.mu.Lock()
+	conn := p.conns[server.ID]
+	if conn != nil && (conn.broken || conn.fingerprint != fingerprint) {
+		p.retire(server.ID, conn)
+		conn = nil
+	}
+	if conn != nil {
+		conn.users++
+		p.mu.Unlock()
+		// Connections dropped by the server or the network are replaced transparently
+		if err := conn.client.ping(sshHealthCheckTimeout); err == nil {
+			return p.lease(server.ID, conn), nil
+		}
+		slog.Info("SSH connection is unhealthy, reconnecting", "server", server.Name)
+		p.mu.Lock()
+		conn.users--
+		conn.broken = true
+		p.retire(server.ID, conn)
+	}
+	p.mu.Unlock()
+
+	client, err := NewSSHClient(server)
+	if err != nil {
+		return nil, err
+	}
+	client.sessions = make(chan struct{}, p.maxSessions)
+	chain, err := jumpHostChain(server)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	conn = &pooledSSHConn{client: client, fingerprint: fingerprint, users: 1}
+	for _, hop := range chain[:len(chain)-1] {
+		conn.chain = append(conn.chain, hop.ID)
+	}
+
+	p.mu.Lock()
+	if existing := p.conns[server.ID]; existing != nil && !existing.broken && existing.fingerprint == fingerprint {
+		// Another caller connected meanwhile, so use that connection
+		existing.users++
+		p.mu.Unlock()
+		client.Close()
+		return p.lease(server.ID, existing), nil
+	} else if existing != nil {
+		p.retire(server.ID, existing)
+	}
+	p.conns[server.ID] = conn
+	p.mu.Unlock()
+	slog.Debug("Opened pooled SSH connection", "server", server.Name)
+	return p.lease(server.ID, conn), nil
+}
+
+// lease wraps a pooled connection so that closing it releases the connection
+func (p *SSHPool) lease(serverID uint, conn *pooledSSHConn) *SSHClient {
+	var once sync.Once
+	return &SSHClient{
+		client:   conn.client.client,
+		config:   conn.client.config,
+		addr:     conn.client.addr,
+		sessions: conn.client.sessions,
+		release: func() {
+			once.Do(func() { p.release(serverID, conn) })
+		},
+	}
+}
+
+// release hands a connection back to the pool
+func (p *SSHPool) release(serverID uint, conn *pooledSSHConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn.users--
+	conn.lastUsed = time.Now()
+	if conn.broken && conn.users == 0 {
+		conn.client.Close()
+	}
+}
+
+// retire removes a connection from the pool, closing it unless it is still in use. The
+// caller must hold the lock.
+func (p *SSHPool) retire(serverID uint, conn *pooledSSHConn) {
+	if p.conns[serverID] == conn {
+		delete(p.conns, serverID)
+	}
+	conn.broken = true
+	if conn.users == 0 {
+		conn.client.Close()
+	}
+}
+
+// Invalidate drops the connections of a server and of servers reached through it, e.g.
+// after its settings changed
+func (p *SSHPool) Invalidate(serverID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, conn := range p.conns {
+		if id == serverID || containsUint(conn.chain, serverID) {
+			p.retire(id, conn)
+		}
+	}
+}
+
+// CloseAll drops every pooled connection
+func (p *SSHPool) CloseAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, conn := range p.conns {
+		p.retire(id, conn)
+	}
+}
+
+// maintain closes idle connections and sends keepalives on the others, so that NAT
+// gateways and firewalls do not drop long backups
+func (p *SSHPool) maintain() {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		var alive []*pooledSSHConn
+		for id, conn := range p.conns {
+			if conn.users == 0 && time.Since(conn.lastUsed) > p.idleTimeout {
+				p.retire(id, conn)
+				continue
+			}
+			alive = append(alive, conn)
+		}
+		p.mu.Unlock()
+
+		for _, conn := range alive {
+			if err := conn.client.ping(sshHealthCheckTimeout); err != nil {
+				slog.Info("Pooled SSH connection failed keepalive", "address", conn.client.addr, "error", err)
+				p.mu.Lock()
+				for id, c := range p.conns {
+					if c == conn {
+						p.retire(id, conn)
+					}
+				}
+				p.mu.Unlock()
+			}
+		}
+	}
+}
+
+// sshFingerprint summarizes the connection settings of a server, so that a pooled
+// connection is not reused after they changed
+func sshFingerprint(server *entity.Server) string {
+	jumpHost := ""
+	if server.JumpHostID != nil {
+		jumpHost = fmt.Sprint(*server.JumpHostID)
+	}
+	return strings.Join([]string{
+		server.Host, fmt.Sprint(server.Port), server.Username, server.AuthType,
+		server.Password, server.PrivateKeyPath, server.KeyPassphrase, server.KeyFilePath,
+		server.AgentSocket, server.SecretCommand, strings.Join(server.FallbackAuthTypes, ","),
+		server.Certificate, jumpHost,
+	}, "\x00")
+}
+
+// containsUint reports whether list contains id
+func containsUint(list []uint, id uint) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tracingExportInterval is how often ended spans are sent when no run finishes
+	tracingExportInterval = 5 * time.Second
+	// tracingMaxPending bounds the spans kept while the collector is unreachable
+	tracingMaxPending = 10000
+	// tracingTimeout bounds a single export request
+	tracingTimeout = 10 * time.Second
+)
+
+// OTLP status codes and span kinds
+const (
+	otlpStatusError  = 2
+	otlpKindInternal = 1
+)
+
+// TracingSvc is nil unless an OTLP endpoint is configured
+var TracingSvc *Tracer
+
+// Tracer records spans of backup runs and exports them to an OpenTelemetry collector with
+// OTLP over HTTP, encoded as JSON
+type Tracer struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	// fileMinBytes is the size from which files get their own span; 0 disables file spans
+	fileMinBytes int64
+	client       *http.Client
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+	flush   chan struct{}
+}
+
+// Span is a timed operation of a trace. All methods accept a nil span, which records nothing,
+// so callers do not need to check whether tracing is enabled.
+type Span struct {
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes []spanAttribute
+	errMessage string
+	failed     bool
+}
+
+type spanAttribute struct {
+	key   string
+	value any
+}
+
+// InitTracing enables tracing if an OTLP endpoint is set in the standard OpenTelemetry
+// environment variables
+func InitTracing() error {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if parsed, err := url.Parse(endpoint); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+
+	protocol := envOrDefault("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	if protocol != "" && protocol != "http/json" {
+		return fmt.Errorf("OTLP protocol %q is not supported, use http/json", protocol)
+	}
+	headers, err := parseOTLPHeaders(envOrDefault("OTEL_EXPORTER_OTLP_TRACES_HEADERS", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	if err != nil {
+		return fmt.Errorf("invalid OTLP headers: %v", err)
+	}
+	var fileMinBytes int64
+	if value := os.Getenv("BACKAPP_TRACE_FILE_MIN_BYTES"); value != "" {
+		fileMinBytes, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || fileMinBytes < 0 {
+			return fmt.Errorf("invalid BACKAPP_TRACE_FILE_MIN_BYTES")
+		}
+	}
+
+	TracingSvc = &Tracer{
+		endpoint:     endpoint,
+		headers:      headers,
+		serviceName:  envOrDefault("OTEL_SERVICE_NAME", "backapp"),
+		fileMinBytes: fileMinBytes,
+		client:       &http.Client{Timeout: tracingTimeout},
+		flush:        make(chan struct{}, 1),
+	}
+	go TracingSvc.exportLoop()
+	slog.Info("Tracing enabled", "endpoint", endpoint)
+	return nil
+}
+
+// parseOTLPHeaders parses "key=value" pairs separated by commas with URL encoded values
+func parseOTLPHeaders(spec string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%q is not key=value", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		headers[strings.TrimSpace(key)] = decoded
+	}
+	return headers, nil
+}
+
+// StartSpan starts the root span of a new trace
+func (t *Tracer) StartSpan(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now()}
+	rand.Read(span.traceID[:])
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// StartChild starts a span within this one
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	child := &Span{tracer: s.tracer, traceID: s.traceID, parentID: s.spanID, name: name, start: time.Now()}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// StartFile starts a child span for a file at least BACKAPP_TRACE_FILE_MIN_BYTES large, or
+// returns nil for smaller files
+func (s *Span) StartFile(path string, size int64) *Span {
+	if s == nil || s.tracer.fileMinBytes == 0 || size < s.tracer.fileMinBytes {
+		return nil
+	}
+	span := s.StartChild("file")
+	span.SetAttribute("file.path", path)
+	span.SetAttribute("file.size", size)
+	return span
+}
+
+// TraceID returns the hex trace ID, as shown by tracing backends
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetAttribute records a string, bool, integer or float attribute. Secrets in strings are
+// redacted like in the process log, as traces leave the host.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	if text, ok := value.(string); ok {
+		value = redactSecrets(text)
+	}
+	if isSecretLogKey(key) {
+		value = redacted
+	}
+	s.attributes = append(s.attributes, spanAttribute{key: key, value: value})
+}
+
+// SetError marks the span as failed, with the redacted error message
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.failed = true
+	s.errMessage = redactSecrets(err.Error())
+}
+
+// End finishes the span and queues it for export. Root spans are exported right away.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	t := s.tracer
+	t.mu.Lock()
+	if len(t.pending) < tracingMaxPending {
+		t.pending = append(t.pending, s)
+	} else {
+		t.dropped++
+	}
+	t.mu.Unlock()
+
+	if s.parentID == [8]byte{} {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// exportLoop sends ended spans periodically and after each trace
+func (t *Tracer) exportLoop() {
+	ticker := time.NewTicker(tracingExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		}
+		t.export()
+	}
+}
+
+// export sends the pending spans. Spans of a failed export are dropped rather than retried,
+// so an unreachable collector does not hold up runs or grow memory.
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans := t.pending
+	dropped := t.dropped
+	t.pending = nil
+	t.dropped = 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		slog.Warn("Dropped spans while the trace queue was full", "spans", dropped)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.send(spans); err != nil {
+		slog.Warn("Failed to export spans", "spans", len(spans), "error", err)
+	}
+}
+
+func (t *Tracer) send(spans []*Span) error {
+	body, err := json.Marshal(t.otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BackApp")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ---- OTLP JSON encoding ----
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+func (t *Tracer) otlpRequest(spans []*Span) map[string]any {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		item := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		}
+		if span.parentID != [8]byte{} {
+			item.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		for _, attribute := range span.attributes {
+			item.Attributes = append(item.Attributes, otlpKeyValue{Key: attribute.key, Value: otlpValue(attribute.value)})
+		}
+		if span.failed {
+			item.Status = otlpStatus{Code: otlpStatusError, Message: span.errMessage}
+		}
+		encoded = append(encoded, item)
+	}
+
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpKeyValue{{Key: "service.name", Value: otlpValue(t.serviceName)}},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "backapp"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+// otlpValue encodes an attribute value; 64-bit integers are strings in OTLP JSON
+func otlpValue(value any) map[string]any {
+	switch v := value.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case uint:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"backapp-server/entity"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// defaultMissedBackupGrace is the grace period of profiles without their own
+	defaultMissedBackupGrace = 60 * time.Minute
+	// watchdogInterval is how often the watchdog checks the schedules
+	watchdogInterval = time.Minute
+)
+
+// MissedBackup describes a scheduled profile without a successful run in its expected window
+type MissedBackup struct {
+	BackupProfileID uint       `json:"backup_profile_id"`
+	BackupProfile   string     `json:"backup_profile"`
+	ScheduleCron    string     `json:"schedule_cron"`
+	ExpectedAt      *time.Time `json:"expected_at,omitempty"` // unset when the schedule is invalid
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	Reason          string     `json:"reason"`
+}
+
+// BackupWatchdog reports scheduled profiles whose backups stopped completing, e.g. because
+// the server was down, the schedule is invalid or the profile was disabled by mistake
+type BackupWatchdog struct {
+	mu sync.Mutex
+	// alerted holds the missed run each profile was last reported for, so every missed
+	// run is only reported once
+	alerted map[uint]string
+}
+
+var (
+	watchdog     *BackupWatchdog
+	watchdogOnce sync.Once
+)
+
+// GetWatchdog returns the singleton watchdog instance
+func GetWatchdog() *BackupWatchdog {
+	watchdogOnce.Do(func() {
+		watchdog = &BackupWatchdog{alerted: make(map[uint]string)}
+	})
+	return watchdog
+}
+
+// StartWatchdog checks the schedules every minute
+func StartWatchdog() {
+	w := GetWatchdog()
+	go func() {
+		ticker := time.NewTicker(watchdogInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			w.Check(now)
+		}
+	}()
+}
+
+// Reset forgets which missed backups were reported
+func (w *BackupWatchdog) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.alerted = make(map[uint]string)
+}
+
+// Check reports profiles that missed a backup at the given time and returns all of them
+func (w *BackupWatchdog) Check(now time.Time) []MissedBackup {
+	var profiles []entity.BackupProfile
+	if err := DB.Where("schedule_cron <> ''").Find(&profiles).Error; err != nil {
+		slog.Error("Failed to load backup profiles for the watchdog", "error", err)
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	missed := []MissedBackup{}
+	checked := make(map[uint]bool)
+	for i := range profiles {
+		profile := &profiles[i]
+		checked[profile.ID] = true
+		result := ServiceCheckMissedBackup(profile, now)
+		if result == nil {
+			delete(w.alerted, profile.ID)
+			continue
+		}
+		missed = append(missed, *result)
+
+		key := result.ScheduleCron
+		if result.ExpectedAt != nil {
+			key = result.ExpectedAt.UTC().Format(time.RFC3339)
+		}
+		if w.alerted[profile.ID] == key {
+			continue
+		}
+		w.alerted[profile.ID] = key
+		slog.Warn("Missed backup", "profile_id", profile.ID, "profile", profile.Name, "reason", result.Reason)
+		if NotificationSvc != nil {
+			NotificationSvc.NotifyMissedBackup(result)
+		}
+	}
+	for profileID := range w.alerted {
+		if !checked[profileID] {
+			delete(w.alerted, profileID)
+		}
+	}
+	return missed
+}
+
+// ServiceCheckMissedBackup returns the missed backup of a scheduled profile at the given
+// time, or nil when its last scheduled run completed in time or is not due yet
+func ServiceCheckMissedBackup(profile *entity.BackupProfile, now time.Time) *MissedBackup {
+	grace := defaultMissedBackupGrace
+	if profile.MissedBackupGraceMinutes != nil {
+		if *profile.MissedBackupGraceMinutes <= 0 {
+			return nil
+		}
+		grace = time.Duration(*profile.MissedBackupGraceMinutes) * time.Minute
+	}
+	missed := &MissedBackup{
+		BackupProfileID: profile.ID,
+		BackupProfile:   profile.Name,
+		ScheduleCron:    profile.ScheduleCron,
+	}
+
+	// The scheduler parses schedules the same way and skips invalid ones
+	schedule, err := cron.ParseStandard(profile.ScheduleCron)
+	if err != nil {
+		missed.Reason = fmt.Sprintf("the schedule %q is invalid: %v", profile.ScheduleCron, err)
+		return missed
+	}
+
+	// Expect a run at the first scheduled time after the last success
+	since := profile.CreatedAt
+	var lastSuccess entity.BackupRun
+	if err := DB.Where("backup_profile_id = ? AND status = ?", profile.ID, "completed").
+		Order("end_time DESC").Limit(1).Find(&lastSuccess).Error; err == nil && lastSuccess.ID != 0 {
+		missed.LastSuccessAt = &lastSuccess.EndTime
+		if lastSuccess.EndTime.After(since) {
+			since = lastSuccess.EndTime
+		}
+	}
+	// Like the scheduler, interpret schedules in the local time zone
+	expected := schedule.Next(since.In(time.Local))
+	deadline := expected.Add(grace)
+	if expected.IsZero() || now.Before(deadline) {
+		return nil
+	}
+	missed.ExpectedAt = &expected
+	missed.Deadline = &deadline
+
+	var lastRun entity.BackupRun
+	DB.Where("backup_profile_id = ?", profile.ID).Order("start_time DESC").Limit(1).Find(&lastRun)
+	switch {
+	case !profile.Enabled:
+		missed.Reason = "the profile is disabled"
+	case lastRun.ID == 0 || lastRun.StartTime.Before(expected):
+		missed.Reason = "no run started since the scheduled time"
+	case lastRun.Status == "running":
+		missed.Reason = fmt.Sprintf("run #%d is still running", lastRun.ID)
+	case lastRun.ErrorMessage != "":
+		missed.Reason = fmt.Sprintf("run #%d %s: %s", lastRun.ID, lastRun.Status, lastRun.ErrorMessage)
+	default:
+		missed.Reason = fmt.Sprintf("run #%d %s", lastRun.ID, lastRun.Status)
+	}
+	return missed
+}
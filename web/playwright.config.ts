@@ -36,9 +36,15 @@ export default defineConfig({
 
   /* Configure projects for major browsers */
   projects: [
+    /* Signs in once and stores the session cookie for all other tests */
+    {
+      name: 'setup',
+      testMatch: /auth\.setup\.ts/,
+    },
     {
       name: 'chromium',
-      use: { ...devices['Desktop Chrome'] },
+      use: { ...devices['Desktop Chrome'], storageState: 'playwright/.auth/admin.json' },
+      dependencies: ['setup'],
     },
 
     // {
@@ -78,5 +84,21 @@ export default defineConfig({
     url: 'http://localhost:8081',
     reuseExistingServer: !process.env.CI,
     timeout: 120 * 1000,
+    env: {
+      /* Single sign-on against the fake provider started by tests/auth/oidc.spec.ts */
+      BACKAPP_OIDC_ISSUER: 'http://127.0.0.1:2250',
+      BACKAPP_OIDC_CLIENT_ID: 'backapp',
+      BACKAPP_OIDC_PROVIDER_NAME: 'Keycloak',
+      BACKAPP_OIDC_ROLE_MAPPING: 'backapp-admins=admin,backapp-viewers=viewer',
+      /* Email via the fake SMTP server started by tests/notifications/email-notifications.spec.ts */
+      BACKAPP_SMTP_HOST: '127.0.0.1',
+      BACKAPP_SMTP_PORT: '2255',
+      BACKAPP_SMTP_TLS: 'none',
+      BACKAPP_SMTP_FROM: 'BackApp <backapp@example.com>',
+      BACKAPP_PUBLIC_URL: 'http://localhost:8081',
+      /* Traces to the fake collector started by tests/backup-runs/tracing.spec.ts */
+      OTEL_EXPORTER_OTLP_ENDPOINT: 'http://127.0.0.1:2277',
+      BACKAPP_TRACE_FILE_MIN_BYTES: '1000',
+    },
   },
 });
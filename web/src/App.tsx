@@ -1,4 +1,8 @@
+import { Box, CircularProgress } from '@mui/material';
+import { useEffect, useState } from 'react';
 import { BrowserRouter as Router, Routes, Route, Navigate } from 'react-router-dom';
+import { authApi } from './api';
+import { UNAUTHORIZED_EVENT } from './api/client';
 import { Layout } from './components/common';
 import Dashboard from './pages/Dashboard.tsx';
 import Servers from './pages/Servers.tsx';
@@ -10,12 +14,68 @@ import StorageLocations from './pages/StorageLocations.tsx';
 import NamingRules from './pages/NamingRules.tsx';
 import Backups from './pages/Backups.tsx';
 import NotificationSettings from './pages/NotificationSettings.tsx';
+import Users from './pages/Users.tsx';
+import ApiTokens from './pages/ApiTokens.tsx';
+import AuditLog from './pages/AuditLog.tsx';
+import Login from './pages/Login.tsx';
+import type { AuthStatus, User } from './types';
 import './App.css';
 
 function App() {
+  // undefined while the session is being checked, null when signed out
+  const [user, setUser] = useState<User | null | undefined>(undefined);
+  const [authStatus, setAuthStatus] = useState<AuthStatus>({ setup_required: false, oidc_enabled: false });
+
+  const checkSession = async () => {
+    try {
+      setUser(await authApi.me());
+    } catch {
+      try {
+        setAuthStatus(await authApi.status());
+      } catch (error) {
+        console.error('Error loading auth status:', error);
+      }
+      setUser(null);
+    }
+  };
+
+  useEffect(() => {
+    checkSession();
+    const handleUnauthorized = () => setUser(null);
+    window.addEventListener(UNAUTHORIZED_EVENT, handleUnauthorized);
+    return () => window.removeEventListener(UNAUTHORIZED_EVENT, handleUnauthorized);
+  }, []);
+
+  const handleLogout = async () => {
+    try {
+      await authApi.logout();
+    } catch (error) {
+      console.error('Error logging out:', error);
+    }
+    setAuthStatus((status) => ({ ...status, setup_required: false }));
+    setUser(null);
+  };
+
+  const handleLoggedIn = (loggedIn: User) => {
+    setAuthStatus((status) => ({ ...status, setup_required: false }));
+    setUser(loggedIn);
+  };
+
+  if (user === undefined) {
+    return (
+      <Box display="flex" justifyContent="center" alignItems="center" minHeight="100vh">
+        <CircularProgress />
+      </Box>
+    );
+  }
+
+  if (user === null) {
+    return <Login status={authStatus} onLoggedIn={handleLoggedIn} />;
+  }
+
   return (
     <Router>
-      <Layout>
+      <Layout user={user} onLogout={handleLogout}>
         <Routes>
           <Route path="/" element={<Navigate to="/dashboard" replace />} />
           <Route path="/dashboard" element={<Dashboard />} />
@@ -27,7 +87,10 @@ function App() {
           <Route path="/backups" element={<Backups />} />
           <Route path="/storage-locations" element={<StorageLocations />} />
           <Route path="/naming-rules" element={<NamingRules />} />
-          <Route path="/notifications" element={<NotificationSettings />} />
+          <Route path="/notifications" element={<NotificationSettings user={user} />} />
+          <Route path="/users" element={<Users />} />
+          <Route path="/api-tokens" element={<ApiTokens />} />
+          <Route path="/audit-log" element={<AuditLog />} />
         </Routes>
       </Layout>
     </Router>
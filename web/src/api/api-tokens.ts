@@ -0,0 +1,24 @@
+import type { ApiToken, ApiTokenCreateInput } from '../types/api-token';
+import { fetchJSON, fetchWithoutResponse } from './client';
+
+export const apiTokenApi = {
+  async list(): Promise<ApiToken[]> {
+    return fetchJSON<ApiToken[]>('/api-tokens');
+  },
+
+  async create(data: ApiTokenCreateInput): Promise<ApiToken> {
+    return fetchJSON<ApiToken>('/api-tokens', {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(data),
+    });
+  },
+
+  async delete(id: number): Promise<boolean> {
+    return fetchWithoutResponse(`/api-tokens/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
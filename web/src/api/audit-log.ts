@@ -0,0 +1,26 @@
+import type { AuditLogFilter, AuditLogPage } from '../types/audit-log';
+import { fetchJSON } from './client';
+
+const filterQuery = (filter: AuditLogFilter) => {
+  const query = new URLSearchParams();
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      query.set(key, String(value));
+    }
+  });
+  return query;
+};
+
+export const auditLogApi = {
+  async list(filter: AuditLogFilter, page: number, pageSize: number): Promise<AuditLogPage> {
+    const query = filterQuery(filter);
+    query.set('page', page.toString());
+    query.set('page_size', pageSize.toString());
+    return fetchJSON<AuditLogPage>(`/audit-log?${query.toString()}`);
+  },
+
+  getExportUrl(filter: AuditLogFilter): string {
+    const qs = filterQuery(filter).toString();
+    return qs ? `/api/v1/audit-log/export?${qs}` : '/api/v1/audit-log/export';
+  },
+};
@@ -0,0 +1,90 @@
+import type { AuthStatus, Role, RoleAssignment, RoleAssignmentInput, User } from '../types';
+import { fetchJSON, fetchWithoutResponse } from './client';
+
+const API_BASE_URL = '/api/v1';
+
+async function postCredentials(endpoint: string, username: string, password: string): Promise<User> {
+  // Not using fetchJSON: a failed login must not be reported as an expired session
+  const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ username, password }),
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || `HTTP error! status: ${response.status}`);
+  }
+  return data;
+}
+
+export const authApi = {
+  async status(): Promise<AuthStatus> {
+    return fetchJSON<AuthStatus>('/auth/status');
+  },
+
+  async me(): Promise<User> {
+    return fetchJSON<User>('/auth/me');
+  },
+
+  async login(username: string, password: string): Promise<User> {
+    return postCredentials('/auth/login', username, password);
+  },
+
+  async setup(username: string, password: string): Promise<User> {
+    return postCredentials('/auth/setup', username, password);
+  },
+
+  async logout(): Promise<boolean> {
+    return fetchWithoutResponse('/auth/logout', { method: 'POST' });
+  },
+
+  async changePassword(currentPassword: string, newPassword: string): Promise<User> {
+    return fetchJSON<User>('/auth/password', {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+    });
+  },
+};
+
+export const userApi = {
+  async list(): Promise<User[]> {
+    return fetchJSON<User[]>('/users');
+  },
+
+  async create(username: string, password: string, role?: Role): Promise<User> {
+    return fetchJSON<User>('/users', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ username, password, role }),
+    });
+  },
+
+  async setPassword(id: number, password: string): Promise<User> {
+    return fetchJSON<User>(`/users/${id}`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ password }),
+    });
+  },
+
+  async delete(id: number): Promise<boolean> {
+    return fetchWithoutResponse(`/users/${id}`, {
+      method: 'DELETE',
+    });
+  },
+
+  async addRole(userId: number, data: RoleAssignmentInput): Promise<RoleAssignment> {
+    return fetchJSON<RoleAssignment>(`/users/${userId}/roles`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(data),
+    });
+  },
+
+  async removeRole(userId: number, roleId: number): Promise<boolean> {
+    return fetchWithoutResponse(`/users/${userId}/roles/${roleId}`, {
+      method: 'DELETE',
+    });
+  },
+};
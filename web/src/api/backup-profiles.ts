@@ -1,4 +1,4 @@
-import type { BackupProfile, BackupProfileCreateInput, BackupProfileUpdateInput } from '../types/backup-profile';
+import type { BackupDryRun, BackupProfile, BackupProfileCreateInput, BackupProfileUpdateInput } from '../types/backup-profile';
 import { fetchJSON, fetchWithoutResponse } from './client';
 
 export const backupProfileApi = {
@@ -48,8 +48,8 @@ export const backupProfileApi = {
     });
   },
 
-  async dryRun(id: number): Promise<{ message: string; commands: string[]; files: string[] }> {
-    return fetchJSON<{ message: string; commands: string[]; files: string[] }>(`/backup-profiles/${id}/dry-run`, {
+  async dryRun(id: number): Promise<BackupDryRun> {
+    return fetchJSON<BackupDryRun>(`/backup-profiles/${id}/dry-run`, {
       method: 'POST',
     });
   },
@@ -1,17 +1,25 @@
 const API_BASE_URL = '/api/v1';
 
-export async function fetchJSON<T>(endpoint: string, options?: RequestInit): Promise<T> {
-  const response = await fetch(`${API_BASE_URL}${endpoint}`, options);
+/** Fired when the API rejects a request because the session is missing or expired */
+export const UNAUTHORIZED_EVENT = 'backapp:unauthorized';
+
+function checkResponse(response: Response) {
+  if (response.status === 401) {
+    window.dispatchEvent(new Event(UNAUTHORIZED_EVENT));
+  }
   if (!response.ok) {
     throw new Error(`HTTP error! status: ${response.status}`);
   }
+}
+
+export async function fetchJSON<T>(endpoint: string, options?: RequestInit): Promise<T> {
+  const response = await fetch(`${API_BASE_URL}${endpoint}`, options);
+  checkResponse(response);
   return response.json();
 }
 
 export async function fetchWithoutResponse(endpoint: string, options?: RequestInit): Promise<boolean> {
   const response = await fetch(`${API_BASE_URL}${endpoint}`, options);
-  if (!response.ok) {
-    throw new Error(`HTTP error! status: ${response.status}`);
-  }
+  checkResponse(response);
   return response.ok;
 }
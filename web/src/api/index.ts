@@ -7,4 +7,7 @@ export { backupProfileApi } from './backup-profiles';
 export { backupRunApi, backupFileApi } from './backup-runs';
 export { fileExplorerApi } from './file-explorer';
 export { notificationApi, storageUsageApi, formatBytes } from './notifications';
-export type { PushSubscription, NotificationPreference, NotificationPreferenceInput, StorageUsage, TotalStorageUsage } from './notifications';
+export type { PushSubscription, DigestFrequency, NotificationPreference, NotificationPreferenceInput, EmailRecipient, EmailStatus, ChatIntegration, ChatIntegrationInput, ChatProvider, Webhook, WebhookInput, WebhookEvent, WebhookDelivery, WebhookDeliveryStatus, StorageUsage, TotalStorageUsage } from './notifications';
+export { authApi, userApi } from './auth';
+export { apiTokenApi } from './api-tokens';
+export { auditLogApi } from './audit-log';
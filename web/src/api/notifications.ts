@@ -9,9 +9,14 @@ export interface PushSubscription {
   created_at: string;
 }
 
+/** Empty sends no digest */
+export type DigestFrequency = '' | 'daily' | 'weekly';
+
 export interface NotificationPreference {
   id: number;
-  subscription_id: number;
+  subscription_id?: number;
+  email_recipient_id?: number;
+  chat_integration_id?: number;
   backup_profile_id?: number;
   server_id?: number;
   notify_on_start: boolean;
@@ -19,8 +24,13 @@ export interface NotificationPreference {
   notify_on_failure: boolean;
   notify_on_consecutive_failures: boolean;
   consecutive_failure_threshold: number;
+  notify_on_missed_backup: boolean;
   notify_on_low_storage: boolean;
   low_storage_threshold: number;
+  digest_frequency: DigestFrequency;
+  /** HH:MM in server time; notifications other than failures wait until the end */
+  quiet_hours_start: string;
+  quiet_hours_end: string;
   backup_profile?: {
     id: number;
     name: string;
@@ -39,8 +49,120 @@ export interface NotificationPreferenceInput {
   notify_on_failure: boolean;
   notify_on_consecutive_failures: boolean;
   consecutive_failure_threshold: number;
+  notify_on_missed_backup: boolean;
   notify_on_low_storage: boolean;
   low_storage_threshold: number;
+  digest_frequency: DigestFrequency;
+  quiet_hours_start: string;
+  quiet_hours_end: string;
+}
+
+export interface EmailRecipient {
+  id: number;
+  address: string;
+  created_at: string;
+  preferences?: NotificationPreference[];
+}
+
+export interface EmailStatus {
+  enabled: boolean;
+  /** Sender address, set when email notifications are enabled */
+  from?: string;
+}
+
+export type ChatProvider = 'slack' | 'discord' | 'matrix' | 'ntfy' | 'gotify';
+
+export interface ChatIntegration {
+  id: number;
+  name: string;
+  provider: ChatProvider;
+  /** Scheme and host of the URL, which is never returned as it may be a credential */
+  masked_url: string;
+  /** Matrix room ID or ntfy topic */
+  channel: string;
+  has_token: boolean;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+  preferences?: NotificationPreference[];
+}
+
+export interface ChatIntegrationInput {
+  name: string;
+  provider: ChatProvider;
+  /** Incoming webhook URL for Slack and Discord, the server URL otherwise; kept on update when empty */
+  url?: string;
+  channel: string;
+  /** Kept on update when empty */
+  token?: string;
+  enabled: boolean;
+}
+
+export type WebhookEvent =
+  | 'backup.started'
+  | 'backup.completed'
+  | 'backup.failed'
+  | 'backup.consecutive_failures'
+  | 'backup.recovered'
+  | 'backup.missed'
+  | 'storage.low'
+  | 'digest.daily'
+  | 'digest.weekly';
+
+export interface Webhook {
+  id: number;
+  name: string;
+  url: string;
+  /** Empty means all events */
+  events: WebhookEvent[];
+  backup_profile_id?: number;
+  server_id?: number;
+  low_storage_threshold: number;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+  backup_profile?: {
+    id: number;
+    name: string;
+  };
+  server?: {
+    id: number;
+    name: string;
+  };
+  /** Only returned when the webhook is created */
+  secret?: string;
+}
+
+export interface WebhookInput {
+  name: string;
+  url: string;
+  /** Generated on create and kept on update when empty */
+  secret?: string;
+  events: WebhookEvent[];
+  backup_profile_id?: number;
+  server_id?: number;
+  low_storage_threshold: number;
+  enabled: boolean;
+}
+
+export type WebhookDeliveryStatus = 'pending' | 'succeeded' | 'failed';
+
+export interface WebhookDelivery {
+  id: number;
+  webhook_id: number;
+  event: WebhookEvent | 'ping';
+  event_id: string;
+  payload: string;
+  status: WebhookDeliveryStatus;
+  attempts: number;
+  response_code?: number;
+  response_body?: string;
+  error?: string;
+  duration_ms: number;
+  next_attempt_at?: string;
+  redelivery_of?: number;
+  created_at: string;
+  updated_at: string;
 }
 
 export interface StorageUsage {
@@ -123,6 +245,124 @@ export const notificationApi = {
     fetchJSON(`/notifications/test?endpoint=${encodeURIComponent(endpoint)}`, {
       method: 'POST',
     }),
+
+  // Whether email notifications are configured on the server
+  getEmailStatus: (): Promise<EmailStatus> =>
+    fetchJSON('/notifications/email'),
+
+  // List email recipients with their preferences
+  listEmailRecipients: (): Promise<EmailRecipient[]> =>
+    fetchJSON('/notifications/email-recipients'),
+
+  // Add an email recipient, which starts with default preferences
+  createEmailRecipient: (address: string): Promise<EmailRecipient> =>
+    fetchJSON('/notifications/email-recipients', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ address }),
+    }),
+
+  // Remove an email recipient
+  deleteEmailRecipient: (id: number): Promise<boolean> =>
+    fetchWithoutResponse(`/notifications/email-recipients/${id}`, {
+      method: 'DELETE',
+    }),
+
+  // Add a preference to an email recipient
+  createEmailRecipientPreference: (id: number, preference: NotificationPreferenceInput): Promise<NotificationPreference> =>
+    fetchJSON(`/notifications/email-recipients/${id}/preferences`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(preference),
+    }),
+
+  // Send a test email to a recipient
+  sendTestEmail: (id: number): Promise<void> =>
+    fetchJSON(`/notifications/email-recipients/${id}/test`, {
+      method: 'POST',
+    }),
+
+  // List chat integrations with their preferences
+  listChatIntegrations: (): Promise<ChatIntegration[]> =>
+    fetchJSON('/notifications/chat-integrations'),
+
+  // Add a chat integration, which starts with default preferences
+  createChatIntegration: (integration: ChatIntegrationInput): Promise<ChatIntegration> =>
+    fetchJSON('/notifications/chat-integrations', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(integration),
+    }),
+
+  // Update a chat integration
+  updateChatIntegration: (id: number, integration: ChatIntegrationInput): Promise<ChatIntegration> =>
+    fetchJSON(`/notifications/chat-integrations/${id}`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(integration),
+    }),
+
+  // Remove a chat integration
+  deleteChatIntegration: (id: number): Promise<boolean> =>
+    fetchWithoutResponse(`/notifications/chat-integrations/${id}`, {
+      method: 'DELETE',
+    }),
+
+  // Add a preference to a chat integration
+  createChatIntegrationPreference: (id: number, preference: NotificationPreferenceInput): Promise<NotificationPreference> =>
+    fetchJSON(`/notifications/chat-integrations/${id}/preferences`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(preference),
+    }),
+
+  // Send a test message through a chat integration
+  sendTestChat: (id: number): Promise<void> =>
+    fetchJSON(`/notifications/chat-integrations/${id}/test`, {
+      method: 'POST',
+    }),
+
+  // List webhooks
+  listWebhooks: (): Promise<Webhook[]> =>
+    fetchJSON('/notifications/webhooks'),
+
+  // Add a webhook; the response contains its secret
+  createWebhook: (webhook: WebhookInput): Promise<Webhook> =>
+    fetchJSON('/notifications/webhooks', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(webhook),
+    }),
+
+  // Update a webhook
+  updateWebhook: (id: number, webhook: WebhookInput): Promise<Webhook> =>
+    fetchJSON(`/notifications/webhooks/${id}`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(webhook),
+    }),
+
+  // Remove a webhook and its delivery log
+  deleteWebhook: (id: number): Promise<boolean> =>
+    fetchWithoutResponse(`/notifications/webhooks/${id}`, {
+      method: 'DELETE',
+    }),
+
+  // Get the delivery log of a webhook, newest first
+  listWebhookDeliveries: (id: number): Promise<WebhookDelivery[]> =>
+    fetchJSON(`/notifications/webhooks/${id}/deliveries`),
+
+  // Send a logged delivery again
+  redeliverWebhook: (id: number, deliveryId: number): Promise<WebhookDelivery> =>
+    fetchJSON(`/notifications/webhooks/${id}/deliveries/${deliveryId}/redeliver`, {
+      method: 'POST',
+    }),
+
+  // Send a ping event to a webhook
+  sendTestWebhook: (id: number): Promise<WebhookDelivery> =>
+    fetchJSON(`/notifications/webhooks/${id}/test`, {
+      method: 'POST',
+    }),
 };
 
 export const storageUsageApi = {
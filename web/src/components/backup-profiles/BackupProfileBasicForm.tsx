@@ -88,6 +88,40 @@ function BackupProfileBasicForm({
         Number of days to keep backup files. Leave empty or 0 to keep forever.
       </FormHelperText>
 
+      {formData.schedule_cron && (
+        <>
+          <TextField
+            fullWidth
+            label="Missed Backup Alert After (minutes)"
+            type="number"
+            value={formData.missed_backup_grace_minutes ?? ''}
+            onChange={(e) => {
+              const value = e.target.value;
+              handleChange('missed_backup_grace_minutes' as keyof BackupProfile, value === '' ? null : parseInt(value, 10));
+            }}
+            inputProps={{ min: 0, 'data-testid': 'input-missed-backup-grace' }}
+            size="small"
+          />
+          <FormHelperText sx={{ mt: -1.5, ml: 1.5 }}>
+            Alert when a scheduled backup has not completed this long after its scheduled time. Leave empty for 60 minutes, 0 turns missed backup alerts off.
+          </FormHelperText>
+        </>
+      )}
+
+      <TextField
+        fullWidth
+        label="Heartbeat URL"
+        type="url"
+        value={formData.heartbeat_url || ''}
+        onChange={(e) => handleChange('heartbeat_url' as keyof BackupProfile, e.target.value)}
+        placeholder="https://hc-ping.com/your-check-uuid"
+        inputProps={{ 'data-testid': 'input-heartbeat-url' }}
+        size="small"
+      />
+      <FormHelperText sx={{ mt: -1.5, ml: 1.5 }}>
+        Pinged at /start when a run starts, on success and at /fail with the error, like Healthchecks.io expects. Optional.
+      </FormHelperText>
+
       <FormControlLabel
         control={
           <Checkbox
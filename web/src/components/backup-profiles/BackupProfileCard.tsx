@@ -66,6 +66,9 @@ function BackupProfileCard({ profile, onRefresh }: BackupProfileCardProps) {
           storage_location_id: profile.storage_location_id,
           naming_rule_id: profile.naming_rule_id,
           schedule_cron: profile.schedule_cron,
+          retention_days: profile.retention_days,
+          missed_backup_grace_minutes: profile.missed_backup_grace_minutes,
+          heartbeat_url: profile.heartbeat_url,
           enabled,
         });
         profile.name = editedName.trim();
@@ -88,6 +91,9 @@ function BackupProfileCard({ profile, onRefresh }: BackupProfileCardProps) {
         storage_location_id: profile.storage_location_id,
         naming_rule_id: profile.naming_rule_id,
         schedule_cron: profile.schedule_cron,
+        retention_days: profile.retention_days,
+        missed_backup_grace_minutes: profile.missed_backup_grace_minutes,
+        heartbeat_url: profile.heartbeat_url,
         enabled: checked,
       });
       profile.enabled = checked;
@@ -71,6 +71,8 @@ function BackupProfileFormDialog({
         naming_rule_id: profileData.naming_rule_id,
         schedule_cron: profileData.schedule_cron,
         retention_days: profileData.retention_days,
+        missed_backup_grace_minutes: profileData.missed_backup_grace_minutes,
+        heartbeat_url: profileData.heartbeat_url,
         enabled: profileData.enabled || false,
       };
 
@@ -4,6 +4,7 @@ import {
   Error as ErrorIcon,
   History as HistoryIcon,
   Label as LabelIcon,
+  MonitorHeart as HeartbeatIcon,
   HourglassEmpty as RunningIcon,
   Schedule as ScheduleIcon,
   Storage as StorageIcon,
@@ -128,6 +129,14 @@ function BackupProfileInfoGrid({ profile }: BackupProfileInfoGridProps) {
                 Next run: <strong>{profile.enabled ? nextRunTime.toLocaleString() : 'Disabled'}</strong>
               </Typography>
             )}
+            <Typography variant="body2" color="text.secondary" sx={{ pl: 3 }}>
+              Missed backup alert:{' '}
+              <strong>
+                {profile.missed_backup_grace_minutes === 0
+                  ? 'Off'
+                  : `after ${profile.missed_backup_grace_minutes ?? 60} minutes`}
+              </strong>
+            </Typography>
           </Box>
         </Grid>
       )}
@@ -144,6 +153,16 @@ function BackupProfileInfoGrid({ profile }: BackupProfileInfoGridProps) {
           </Typography>
         </Box>
       </Grid>
+      {profile.heartbeat_url && (
+        <Grid size={{ xs: 12 }}>
+          <Box display="flex" alignItems="center" gap={1} sx={{ flexWrap: 'wrap' }}>
+            <HeartbeatIcon fontSize="small" color="action" />
+            <Typography variant="body2" color="text.secondary" sx={{ wordBreak: 'break-all' }}>
+              Heartbeat: <strong>{profile.heartbeat_url}</strong>
+            </Typography>
+          </Box>
+        </Grid>
+      )}
     </Grid>
   );
 }
@@ -265,8 +265,10 @@ function ConfigureProfileDialog({ open, profileId, onClose }: ConfigureProfileDi
                       name="exclude_pattern"
                       label="Exclude Pattern"
                       fullWidth
-                      placeholder="*.log,node_modules,*.tmp"
-                      helperText="Comma-separated patterns to exclude"
+                      placeholder={'*.log\nnode_modules/\n!keep.log'}
+                      helperText="Gitignore-style patterns, one per line"
+                      multiline
+                      minRows={2}
                     />
                     <Box display="flex" gap={1}>
                       <Button type="submit" variant="contained" size="small">
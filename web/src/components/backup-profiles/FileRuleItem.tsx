@@ -4,6 +4,7 @@ import { useState } from 'react';
 import { fileRuleApi } from '../../api';
 import type { FileRule } from '../../types';
 import PathPickerField from '../common/PathPickerField';
+import { formatDate } from '../../utils/format';
 
 interface FileRuleItemProps {
   fileRule: FileRule;
@@ -32,6 +33,9 @@ function FileRuleItem({ fileRule, profileId, onFileRuleChanged, serverId }: File
         remote_path: editedPath.trim(),
         recursive: editedRecursive,
         exclude_pattern: editedPattern.trim() || undefined,
+        include_pattern: fileRule.include_pattern,
+        max_file_size_bytes: fileRule.max_file_size_bytes,
+        modified_since: fileRule.modified_since,
       });
       setIsEditing(false);
       onFileRuleChanged?.();
@@ -100,7 +104,9 @@ function FileRuleItem({ fileRule, profileId, onFileRuleChanged, serverId }: File
               label="Exclude Pattern"
               value={editedPattern}
               onChange={(e) => setEditedPattern(e.target.value)}
-              placeholder="*.tmp,*.cache (optional)"
+              placeholder={'*.tmp\n*.cache/ (optional, one per line)'}
+              multiline
+              minRows={2}
               variant="outlined"
             />
           </Stack>
@@ -122,6 +128,14 @@ function FileRuleItem({ fileRule, profileId, onFileRuleChanged, serverId }: File
                 variant="outlined"
                 sx={{ height: 20, fontSize: '0.7rem' }}
               />
+              {fileRule.include_pattern && (
+                <Chip
+                  label={`Include: ${fileRule.include_pattern}`}
+                  size="small"
+                  variant="outlined"
+                  sx={{ height: 20, fontSize: '0.7rem' }}
+                />
+              )}
               {fileRule.exclude_pattern && (
                 <Chip
                   label={`Exclude: ${fileRule.exclude_pattern}`}
@@ -130,6 +144,22 @@ function FileRuleItem({ fileRule, profileId, onFileRuleChanged, serverId }: File
                   sx={{ height: 20, fontSize: '0.7rem' }}
                 />
               )}
+              {!!fileRule.max_file_size_bytes && (
+                <Chip
+                  label={`Max size: ${fileRule.max_file_size_bytes} B`}
+                  size="small"
+                  variant="outlined"
+                  sx={{ height: 20, fontSize: '0.7rem' }}
+                />
+              )}
+              {!!fileRule.modified_since && (
+                <Chip
+                  label={`Modified since ${formatDate(fileRule.modified_since)}`}
+                  size="small"
+                  variant="outlined"
+                  sx={{ height: 20, fontSize: '0.7rem' }}
+                />
+              )}
             </Box>
           </>
         )}
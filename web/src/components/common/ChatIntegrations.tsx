@@ -0,0 +1,375 @@
+import AddIcon from '@mui/icons-material/Add';
+import ChatIcon from '@mui/icons-material/Chat';
+import DeleteIcon from '@mui/icons-material/Delete';
+import EditIcon from '@mui/icons-material/Edit';
+import SendIcon from '@mui/icons-material/Send';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Chip,
+  CircularProgress,
+  FormControl,
+  IconButton,
+  InputLabel,
+  MenuItem,
+  Paper,
+  Select,
+  Stack,
+  Switch,
+  TextField,
+  Tooltip,
+  Typography,
+} from '@mui/material';
+import { useEffect, useState } from 'react';
+import { notificationApi, type ChatIntegration, type ChatIntegrationInput, type ChatProvider } from '../../api';
+import type { BackupProfile, Server } from '../../types';
+import { PreferenceRules } from './PreferenceRules';
+
+interface ProviderFields {
+  label: string;
+  urlLabel: string;
+  urlPlaceholder: string;
+  /** Label of the channel field, if the provider needs one */
+  channelLabel?: string;
+  channelPlaceholder?: string;
+  /** Label of the token field, if the provider uses one */
+  tokenLabel?: string;
+  tokenRequired?: boolean;
+}
+
+const providers: Record<ChatProvider, ProviderFields> = {
+  slack: {
+    label: 'Slack',
+    urlLabel: 'Incoming webhook URL',
+    urlPlaceholder: 'https://hooks.slack.com/services/…',
+  },
+  discord: {
+    label: 'Discord',
+    urlLabel: 'Webhook URL',
+    urlPlaceholder: 'https://discord.com/api/webhooks/…',
+  },
+  matrix: {
+    label: 'Matrix',
+    urlLabel: 'Homeserver URL',
+    urlPlaceholder: 'https://matrix.example.org',
+    channelLabel: 'Room ID',
+    channelPlaceholder: '!abcdef:example.org',
+    tokenLabel: 'Access token',
+    tokenRequired: true,
+  },
+  ntfy: {
+    label: 'ntfy',
+    urlLabel: 'Server URL',
+    urlPlaceholder: 'https://ntfy.sh',
+    channelLabel: 'Topic',
+    channelPlaceholder: 'backups',
+    tokenLabel: 'Access token (optional)',
+  },
+  gotify: {
+    label: 'Gotify',
+    urlLabel: 'Server URL',
+    urlPlaceholder: 'https://gotify.example.org',
+    tokenLabel: 'Application token',
+    tokenRequired: true,
+  },
+};
+
+const emptyForm: ChatIntegrationInput = {
+  name: '',
+  provider: 'slack',
+  url: '',
+  channel: '',
+  token: '',
+  enabled: true,
+};
+
+interface ChatIntegrationsProps {
+  servers: Server[];
+  profiles: BackupProfile[];
+}
+
+/** Chat integrations and their notification rules, managed by global admins */
+export function ChatIntegrations({ servers, profiles }: ChatIntegrationsProps) {
+  const [integrations, setIntegrations] = useState<ChatIntegration[]>([]);
+  const [showForm, setShowForm] = useState(false);
+  const [editing, setEditing] = useState<ChatIntegration | null>(null);
+  const [form, setForm] = useState<ChatIntegrationInput>(emptyForm);
+  const [error, setError] = useState<string | null>(null);
+  const [message, setMessage] = useState<string | null>(null);
+  const [busyId, setBusyId] = useState<number | null>(null);
+  const [ruleFormFor, setRuleFormFor] = useState<number | null>(null);
+
+  useEffect(() => {
+    loadIntegrations();
+  }, []);
+
+  const loadIntegrations = async () => {
+    try {
+      setIntegrations((await notificationApi.listChatIntegrations()) || []);
+    } catch (err) {
+      console.error('Error loading chat integrations:', err);
+    }
+  };
+
+  const openCreateForm = () => {
+    setEditing(null);
+    setForm(emptyForm);
+    setShowForm(!showForm || editing !== null);
+  };
+
+  const openEditForm = (integration: ChatIntegration) => {
+    setEditing(integration);
+    setForm({
+      name: integration.name,
+      provider: integration.provider,
+      url: '',
+      channel: integration.channel,
+      token: '',
+      enabled: integration.enabled,
+    });
+    setShowForm(true);
+  };
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setError(null);
+    try {
+      if (editing === null) {
+        await notificationApi.createChatIntegration(form);
+      } else {
+        await notificationApi.updateChatIntegration(editing.id, form);
+      }
+      setShowForm(false);
+      setEditing(null);
+      loadIntegrations();
+    } catch (err) {
+      console.error('Error saving chat integration:', err);
+      setError(err instanceof Error ? err.message : 'Failed to save chat integration');
+    }
+  };
+
+  const handleToggleEnabled = async (integration: ChatIntegration, enabled: boolean) => {
+    try {
+      await notificationApi.updateChatIntegration(integration.id, {
+        name: integration.name,
+        provider: integration.provider,
+        channel: integration.channel,
+        enabled,
+      });
+      loadIntegrations();
+    } catch (err) {
+      console.error('Error updating chat integration:', err);
+    }
+  };
+
+  const handleDelete = async (integration: ChatIntegration) => {
+    if (!confirm(`Stop sending notifications to "${integration.name}"?`)) return;
+    try {
+      await notificationApi.deleteChatIntegration(integration.id);
+      loadIntegrations();
+    } catch (err) {
+      console.error('Error deleting chat integration:', err);
+    }
+  };
+
+  const handleSendTest = async (integration: ChatIntegration) => {
+    setBusyId(integration.id);
+    setError(null);
+    setMessage(null);
+    try {
+      await notificationApi.sendTestChat(integration.id);
+      setMessage(`Test message sent to ${integration.name}`);
+    } catch (err) {
+      console.error('Error sending test message:', err);
+      setError(`Failed to send a test message to ${integration.name}. Check the URL, channel and token.`);
+    } finally {
+      setBusyId(null);
+    }
+  };
+
+  const fields = providers[form.provider];
+
+  return (
+    <Card sx={{ mb: 3 }} data-testid="chat-integrations">
+      <CardContent>
+        <Box display="flex" justifyContent="space-between" alignItems="center" gap={1} mb={2}>
+          <Box display="flex" alignItems="center" gap={1}>
+            <ChatIcon color="action" />
+            <Typography variant="h6">Chat Integrations</Typography>
+          </Box>
+          <Button size="small" variant="contained" startIcon={<AddIcon />} onClick={openCreateForm} data-testid="add-chat-integration-btn">
+            Add Integration
+          </Button>
+        </Box>
+
+        <Typography variant="body2" color="text.secondary" mb={2}>
+          Post notifications to Slack, Discord, Matrix, ntfy or Gotify, formatted for each service.
+        </Typography>
+
+        {error && (
+          <Alert severity="error" sx={{ mb: 2 }} onClose={() => setError(null)}>
+            {error}
+          </Alert>
+        )}
+        {message && (
+          <Alert severity="success" sx={{ mb: 2 }} onClose={() => setMessage(null)}>
+            {message}
+          </Alert>
+        )}
+
+        {showForm && (
+          <Paper variant="outlined" sx={{ p: 2, mb: 2 }}>
+            <form onSubmit={handleSubmit}>
+              <Stack spacing={2}>
+                <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2}>
+                  <FormControl size="small" sx={{ minWidth: 160 }}>
+                    <InputLabel>Provider</InputLabel>
+                    <Select
+                      value={form.provider}
+                      label="Provider"
+                      onChange={(e) => setForm({ ...form, provider: e.target.value as ChatProvider })}
+                      data-testid="select-chat-provider"
+                    >
+                      {(Object.keys(providers) as ChatProvider[]).map((provider) => (
+                        <MenuItem key={provider} value={provider}>
+                          {providers[provider].label}
+                        </MenuItem>
+                      ))}
+                    </Select>
+                  </FormControl>
+                  <TextField
+                    label="Name"
+                    size="small"
+                    required
+                    value={form.name}
+                    onChange={(e) => setForm({ ...form, name: e.target.value })}
+                    inputProps={{ 'data-testid': 'input-chat-name' }}
+                  />
+                </Stack>
+                <TextField
+                  label={fields.urlLabel}
+                  type="url"
+                  size="small"
+                  required={editing === null}
+                  fullWidth
+                  value={form.url}
+                  onChange={(e) => setForm({ ...form, url: e.target.value })}
+                  placeholder={editing === null ? fields.urlPlaceholder : editing.masked_url}
+                  helperText={editing !== null ? 'Leave empty to keep the current URL' : undefined}
+                  inputProps={{ 'data-testid': 'input-chat-url' }}
+                />
+                {(fields.channelLabel || fields.tokenLabel) && (
+                  <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2}>
+                    {fields.channelLabel && (
+                      <TextField
+                        label={fields.channelLabel}
+                        size="small"
+                        required
+                        value={form.channel}
+                        onChange={(e) => setForm({ ...form, channel: e.target.value })}
+                        placeholder={fields.channelPlaceholder}
+                        inputProps={{ 'data-testid': 'input-chat-channel' }}
+                      />
+                    )}
+                    {fields.tokenLabel && (
+                      <TextField
+                        label={fields.tokenLabel}
+                        type="password"
+                        size="small"
+                        required={fields.tokenRequired && !editing?.has_token}
+                        value={form.token}
+                        onChange={(e) => setForm({ ...form, token: e.target.value })}
+                        helperText={editing?.has_token ? 'Leave empty to keep the current token' : undefined}
+                        inputProps={{ 'data-testid': 'input-chat-token' }}
+                      />
+                    )}
+                  </Stack>
+                )}
+                <Box display="flex" gap={1}>
+                  <Button type="submit" variant="contained" size="small" data-testid="save-chat-integration-btn">
+                    {editing === null ? 'Create' : 'Save'}
+                  </Button>
+                  <Button size="small" onClick={() => setShowForm(false)}>
+                    Cancel
+                  </Button>
+                </Box>
+              </Stack>
+            </form>
+          </Paper>
+        )}
+
+        {integrations.length === 0 ? (
+          <Typography color="text.secondary" py={2}>
+            No chat integrations yet.
+          </Typography>
+        ) : (
+          integrations.map((integration) => (
+            <Paper key={integration.id} variant="outlined" sx={{ p: 2, mb: 2 }} data-testid={`chat-integration-${integration.id}`}>
+              <Box display="flex" justifyContent="space-between" alignItems="center" flexWrap="wrap" gap={1} mb={1}>
+                <Box minWidth={0}>
+                  <Box display="flex" alignItems="center" gap={1}>
+                    <Typography fontWeight="medium">{integration.name}</Typography>
+                    <Chip label={providers[integration.provider]?.label ?? integration.provider} size="small" variant="outlined" />
+                  </Box>
+                  <Typography variant="body2" color="text.secondary" sx={{ wordBreak: 'break-all' }}>
+                    {integration.masked_url}
+                    {integration.channel && ` · ${integration.channel}`}
+                  </Typography>
+                </Box>
+                <Box display="flex" alignItems="center" gap={1}>
+                  <Switch
+                    size="small"
+                    checked={integration.enabled}
+                    onChange={(e) => handleToggleEnabled(integration, e.target.checked)}
+                    inputProps={{ 'aria-label': `${integration.name} enabled` }}
+                  />
+                  <Button
+                    size="small"
+                    startIcon={<AddIcon />}
+                    onClick={() => setRuleFormFor(ruleFormFor === integration.id ? null : integration.id)}
+                  >
+                    Add Rule
+                  </Button>
+                  <Button
+                    size="small"
+                    variant="outlined"
+                    startIcon={busyId === integration.id ? <CircularProgress size={16} /> : <SendIcon />}
+                    onClick={() => handleSendTest(integration)}
+                    disabled={busyId === integration.id}
+                    data-testid={`send-test-chat-${integration.id}`}
+                  >
+                    Send Test
+                  </Button>
+                  <Tooltip title="Edit integration">
+                    <IconButton size="small" onClick={() => openEditForm(integration)} aria-label={`edit ${integration.name}`}>
+                      <EditIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                  <Tooltip title="Delete integration">
+                    <IconButton size="small" color="error" onClick={() => handleDelete(integration)} aria-label={`delete ${integration.name}`}>
+                      <DeleteIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                </Box>
+              </Box>
+
+              <PreferenceRules
+                name={integration.name}
+                preferences={integration.preferences ?? []}
+                servers={servers}
+                profiles={profiles}
+                adding={ruleFormFor === integration.id}
+                onAdd={(preference) => notificationApi.createChatIntegrationPreference(integration.id, preference)}
+                onAdded={() => setRuleFormFor(null)}
+                onChange={loadIntegrations}
+              />
+            </Paper>
+          ))
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -0,0 +1,185 @@
+import AddIcon from '@mui/icons-material/Add';
+import DeleteIcon from '@mui/icons-material/Delete';
+import EmailIcon from '@mui/icons-material/Email';
+import SendIcon from '@mui/icons-material/Send';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  CircularProgress,
+  IconButton,
+  Paper,
+  Stack,
+  TextField,
+  Tooltip,
+  Typography,
+} from '@mui/material';
+import { useEffect, useState } from 'react';
+import { notificationApi, type EmailRecipient, type EmailStatus } from '../../api';
+import type { BackupProfile, Server } from '../../types';
+import { PreferenceRules } from './PreferenceRules';
+
+interface EmailRecipientsProps {
+  servers: Server[];
+  profiles: BackupProfile[];
+}
+
+/** Email recipients and their notification rules, managed by global admins */
+export function EmailRecipients({ servers, profiles }: EmailRecipientsProps) {
+  const [status, setStatus] = useState<EmailStatus | null>(null);
+  const [recipients, setRecipients] = useState<EmailRecipient[]>([]);
+  const [address, setAddress] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [message, setMessage] = useState<string | null>(null);
+  const [sendingTo, setSendingTo] = useState<number | null>(null);
+  const [ruleFormFor, setRuleFormFor] = useState<number | null>(null);
+
+  useEffect(() => {
+    notificationApi.getEmailStatus().then(setStatus).catch((err) => console.error('Error loading email status:', err));
+    loadRecipients();
+  }, []);
+
+  const loadRecipients = async () => {
+    try {
+      setRecipients((await notificationApi.listEmailRecipients()) || []);
+    } catch (err) {
+      console.error('Error loading email recipients:', err);
+    }
+  };
+
+  const handleAddRecipient = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setError(null);
+    try {
+      await notificationApi.createEmailRecipient(address);
+      setAddress('');
+      loadRecipients();
+    } catch (err) {
+      console.error('Error adding email recipient:', err);
+      setError('Failed to add recipient. Check the address and that it is not added already.');
+    }
+  };
+
+  const handleDeleteRecipient = async (recipient: EmailRecipient) => {
+    if (!confirm(`Stop sending notifications to ${recipient.address}?`)) return;
+    try {
+      await notificationApi.deleteEmailRecipient(recipient.id);
+      loadRecipients();
+    } catch (err) {
+      console.error('Error deleting email recipient:', err);
+    }
+  };
+
+  const handleSendTest = async (recipient: EmailRecipient) => {
+    setSendingTo(recipient.id);
+    setError(null);
+    setMessage(null);
+    try {
+      await notificationApi.sendTestEmail(recipient.id);
+      setMessage(`Test email sent to ${recipient.address}`);
+    } catch (err) {
+      console.error('Error sending test email:', err);
+      setError(`Failed to send a test email to ${recipient.address}. Check the server log for the SMTP error.`);
+    } finally {
+      setSendingTo(null);
+    }
+  };
+
+  return (
+    <Card sx={{ mb: 3 }} data-testid="email-recipients">
+      <CardContent>
+        <Box display="flex" alignItems="center" gap={1} mb={2}>
+          <EmailIcon color="action" />
+          <Typography variant="h6">Email Notifications</Typography>
+        </Box>
+
+        {status && !status.enabled && (
+          <Alert severity="warning" sx={{ mb: 2 }}>
+            Email notifications are not configured. Set <code>BACKAPP_SMTP_HOST</code> and <code>BACKAPP_SMTP_FROM</code> to
+            send emails to the recipients below.
+          </Alert>
+        )}
+        {status?.enabled && (
+          <Typography variant="body2" color="text.secondary" mb={2}>
+            Emails are sent from {status.from}.
+          </Typography>
+        )}
+        {error && (
+          <Alert severity="error" sx={{ mb: 2 }} onClose={() => setError(null)}>
+            {error}
+          </Alert>
+        )}
+        {message && (
+          <Alert severity="success" sx={{ mb: 2 }} onClose={() => setMessage(null)}>
+            {message}
+          </Alert>
+        )}
+
+        <form onSubmit={handleAddRecipient}>
+          <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2} mb={2}>
+            <TextField
+              label="Email address"
+              type="email"
+              size="small"
+              required
+              value={address}
+              onChange={(e) => setAddress(e.target.value)}
+              inputProps={{ 'data-testid': 'input-email-recipient' }}
+              sx={{ minWidth: 280 }}
+            />
+            <Button type="submit" variant="contained" startIcon={<AddIcon />} data-testid="add-email-recipient-btn">
+              Add Recipient
+            </Button>
+          </Stack>
+        </form>
+
+        {recipients.length === 0 ? (
+          <Typography color="text.secondary" py={2}>
+            No email recipients yet.
+          </Typography>
+        ) : (
+          recipients.map((recipient) => (
+            <Paper key={recipient.id} variant="outlined" sx={{ p: 2, mb: 2 }} data-testid={`email-recipient-${recipient.id}`}>
+              <Box display="flex" justifyContent="space-between" alignItems="center" flexWrap="wrap" gap={1} mb={1}>
+                <Typography fontWeight="medium">{recipient.address}</Typography>
+                <Box display="flex" gap={1}>
+                  <Button size="small" startIcon={<AddIcon />} onClick={() => setRuleFormFor(ruleFormFor === recipient.id ? null : recipient.id)}>
+                    Add Rule
+                  </Button>
+                  <Button
+                    size="small"
+                    variant="outlined"
+                    startIcon={sendingTo === recipient.id ? <CircularProgress size={16} /> : <SendIcon />}
+                    onClick={() => handleSendTest(recipient)}
+                    disabled={!status?.enabled || sendingTo === recipient.id}
+                    data-testid={`send-test-email-${recipient.id}`}
+                  >
+                    Send Test
+                  </Button>
+                  <Tooltip title="Remove recipient">
+                    <IconButton size="small" color="error" onClick={() => handleDeleteRecipient(recipient)} aria-label={`remove ${recipient.address}`}>
+                      <DeleteIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                </Box>
+              </Box>
+
+              <PreferenceRules
+                name={recipient.address}
+                preferences={recipient.preferences ?? []}
+                servers={servers}
+                profiles={profiles}
+                adding={ruleFormFor === recipient.id}
+                onAdd={(preference) => notificationApi.createEmailRecipientPreference(recipient.id, preference)}
+                onAdded={() => setRuleFormFor(null)}
+                onChange={loadRecipients}
+              />
+            </Paper>
+          ))
+        )}
+      </CardContent>
+    </Card>
+  );
+}
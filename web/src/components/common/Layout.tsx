@@ -1,11 +1,15 @@
 import BackupIcon from '@mui/icons-material/Backup';
 import ComputerIcon from '@mui/icons-material/Computer';
 import DashboardIcon from '@mui/icons-material/Dashboard';
+import HistoryIcon from '@mui/icons-material/History';
 import LabelIcon from '@mui/icons-material/Label';
+import LogoutIcon from '@mui/icons-material/Logout';
 import MenuIcon from '@mui/icons-material/Menu';
 import NotificationsIcon from '@mui/icons-material/Notifications';
+import PeopleIcon from '@mui/icons-material/People';
 import PlayArrowIcon from '@mui/icons-material/PlayArrow';
 import StorageIcon from '@mui/icons-material/Storage';
+import VpnKeyIcon from '@mui/icons-material/VpnKey';
 import {
   AppBar,
   Box,
@@ -24,14 +28,18 @@ import {
 } from '@mui/material';
 import { ReactNode, useState } from 'react';
 import { Link, useLocation } from 'react-router-dom';
+import type { User } from '../../types';
+import { isGlobalAdmin } from '../../utils/roles';
 
 const drawerWidth = 240;
 
 interface LayoutProps {
   children: ReactNode;
+  user?: User;
+  onLogout?: () => void;
 }
 
-function Layout({ children }: LayoutProps) {
+function Layout({ children, user, onLogout }: LayoutProps) {
   const location = useLocation();
   const theme = useTheme();
   const isMobile = useMediaQuery(theme.breakpoints.down('md'));
@@ -53,6 +61,9 @@ function Layout({ children }: LayoutProps) {
     { path: '/storage-locations', label: 'Storage Locations', icon: <StorageIcon /> },
     { path: '/naming-rules', label: 'Naming Rules', icon: <LabelIcon /> },
     { path: '/notifications', label: 'Notifications', icon: <NotificationsIcon /> },
+    ...(user && isGlobalAdmin(user) ? [{ path: '/users', label: 'Users', icon: <PeopleIcon /> }] : []),
+    { path: '/api-tokens', label: 'API Tokens', icon: <VpnKeyIcon /> },
+    ...(user && isGlobalAdmin(user) ? [{ path: '/audit-log', label: 'Audit Log', icon: <HistoryIcon /> }] : []),
   ];
 
   const getPageTitle = () => {
@@ -65,6 +76,9 @@ function Layout({ children }: LayoutProps) {
       '/storage-locations': 'Storage Locations',
       '/naming-rules': 'Naming Rules',
       '/notifications': 'Notifications',
+      '/users': 'Users',
+      '/api-tokens': 'API Tokens',
+      '/audit-log': 'Audit Log',
     };
 
     if (location.pathname.startsWith('/backup-profiles/')) {
@@ -175,9 +189,19 @@ function Layout({ children }: LayoutProps) {
               <MenuIcon />
             </IconButton>
           )}
-          <Typography variant="h6" noWrap component="div">
+          <Typography variant="h6" noWrap component="div" sx={{ flexGrow: 1 }}>
             {getPageTitle()}
           </Typography>
+          {user && (
+            <Box display="flex" alignItems="center" gap={1}>
+              <Typography variant="body2" color="text.secondary" data-testid="current-username">
+                {user.username}
+              </Typography>
+              <IconButton color="inherit" aria-label="log out" onClick={onLogout} data-testid="logout-btn">
+                <LogoutIcon />
+              </IconButton>
+            </Box>
+          )}
         </Toolbar>
       </AppBar>
 
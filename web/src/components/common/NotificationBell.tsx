@@ -148,8 +148,12 @@ export function NotificationBell({
           notify_on_failure: true,
           notify_on_consecutive_failures: true,
           consecutive_failure_threshold: 3,
+          notify_on_missed_backup: true,
           notify_on_low_storage: !profileId && !serverId,
           low_storage_threshold: 10,
+          digest_frequency: '',
+          quiet_hours_start: '',
+          quiet_hours_end: '',
         });
       }
 
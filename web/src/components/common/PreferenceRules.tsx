@@ -0,0 +1,263 @@
+import DeleteIcon from '@mui/icons-material/Delete';
+import {
+  Button,
+  Chip,
+  FormControl,
+  IconButton,
+  InputLabel,
+  MenuItem,
+  Select,
+  Stack,
+  Switch,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TableRow,
+  Tooltip,
+} from '@mui/material';
+import { useState } from 'react';
+import { notificationApi, type DigestFrequency, type NotificationPreference, type NotificationPreferenceInput } from '../../api';
+import type { BackupProfile, Server } from '../../types';
+import { QuietHoursField } from './QuietHoursField';
+
+type Scope = 'global' | 'profile' | 'server';
+
+type EventToggle =
+  | 'notify_on_start'
+  | 'notify_on_success'
+  | 'notify_on_failure'
+  | 'notify_on_consecutive_failures'
+  | 'notify_on_missed_backup'
+  | 'notify_on_low_storage';
+
+const eventColumns: { key: EventToggle; label: string }[] = [
+  { key: 'notify_on_start', label: 'Start' },
+  { key: 'notify_on_success', label: 'Success' },
+  { key: 'notify_on_failure', label: 'Failure' },
+  { key: 'notify_on_consecutive_failures', label: 'Consecutive' },
+  { key: 'notify_on_missed_backup', label: 'Missed' },
+  { key: 'notify_on_low_storage', label: 'Low Storage' },
+];
+
+const defaultPreference: NotificationPreferenceInput = {
+  notify_on_start: false,
+  notify_on_success: false,
+  notify_on_failure: true,
+  notify_on_consecutive_failures: true,
+  consecutive_failure_threshold: 3,
+  notify_on_missed_backup: true,
+  notify_on_low_storage: false,
+  low_storage_threshold: 10,
+  digest_frequency: '',
+  quiet_hours_start: '',
+  quiet_hours_end: '',
+};
+
+const preferenceInput = (pref: NotificationPreference): NotificationPreferenceInput => ({
+  backup_profile_id: pref.backup_profile_id,
+  server_id: pref.server_id,
+  notify_on_start: pref.notify_on_start,
+  notify_on_success: pref.notify_on_success,
+  notify_on_failure: pref.notify_on_failure,
+  notify_on_consecutive_failures: pref.notify_on_consecutive_failures,
+  consecutive_failure_threshold: pref.consecutive_failure_threshold,
+  notify_on_missed_backup: pref.notify_on_missed_backup,
+  notify_on_low_storage: pref.notify_on_low_storage,
+  low_storage_threshold: pref.low_storage_threshold,
+  digest_frequency: pref.digest_frequency,
+  quiet_hours_start: pref.quiet_hours_start,
+  quiet_hours_end: pref.quiet_hours_end,
+});
+
+interface PreferenceRulesProps {
+  /** Prefixes the labels of the switches, e.g. the email address */
+  name: string;
+  preferences: NotificationPreference[];
+  servers: Server[];
+  profiles: BackupProfile[];
+  /** Shows the form for a new rule */
+  adding: boolean;
+  onAdd: (preference: NotificationPreferenceInput) => Promise<unknown>;
+  onAdded: () => void;
+  /** Called after a rule was added, changed or deleted */
+  onChange: () => void;
+}
+
+/** Notification rules of a shared channel like an email recipient or a chat integration */
+export function PreferenceRules({ name, preferences, servers, profiles, adding, onAdd, onAdded, onChange }: PreferenceRulesProps) {
+  const [ruleScope, setRuleScope] = useState<Scope>('global');
+  const [ruleTargetId, setRuleTargetId] = useState<number | ''>('');
+
+  const handleToggle = async (pref: NotificationPreference, key: EventToggle, checked: boolean) => {
+    try {
+      await notificationApi.updatePreference(pref.id, { ...preferenceInput(pref), [key]: checked });
+      onChange();
+    } catch (err) {
+      console.error('Error updating preference:', err);
+    }
+  };
+
+  const handleDigestChange = async (pref: NotificationPreference, digestFrequency: DigestFrequency) => {
+    try {
+      await notificationApi.updatePreference(pref.id, { ...preferenceInput(pref), digest_frequency: digestFrequency });
+      onChange();
+    } catch (err) {
+      console.error('Error updating preference:', err);
+    }
+  };
+
+  const handleQuietHoursChange = async (pref: NotificationPreference, start: string, end: string) => {
+    try {
+      await notificationApi.updatePreference(pref.id, { ...preferenceInput(pref), quiet_hours_start: start, quiet_hours_end: end });
+      onChange();
+    } catch (err) {
+      console.error('Error updating preference:', err);
+    }
+  };
+
+  const handleDeletePreference = async (pref: NotificationPreference) => {
+    try {
+      await notificationApi.deletePreference(pref.id);
+      onChange();
+    } catch (err) {
+      console.error('Error deleting preference:', err);
+    }
+  };
+
+  const handleAddRule = async () => {
+    if (ruleScope !== 'global' && ruleTargetId === '') return;
+    try {
+      await onAdd({
+        ...defaultPreference,
+        backup_profile_id: ruleScope === 'profile' ? (ruleTargetId as number) : undefined,
+        server_id: ruleScope === 'server' ? (ruleTargetId as number) : undefined,
+      });
+      setRuleScope('global');
+      setRuleTargetId('');
+      onAdded();
+      onChange();
+    } catch (err) {
+      console.error('Error adding rule:', err);
+    }
+  };
+
+  const scopeChip = (pref: NotificationPreference) => {
+    if (pref.backup_profile_id) {
+      return <Chip label={pref.backup_profile?.name || `Profile ${pref.backup_profile_id}`} size="small" color="primary" variant="outlined" />;
+    }
+    if (pref.server_id) {
+      return <Chip label={pref.server?.name || `Server ${pref.server_id}`} size="small" color="secondary" variant="outlined" />;
+    }
+    return <Chip label="Global" size="small" />;
+  };
+
+  return (
+    <>
+      {adding && (
+        <Stack direction={{ xs: 'column', sm: 'row' }} spacing={1} mb={1}>
+          <FormControl size="small" sx={{ minWidth: 180 }}>
+            <InputLabel>Scope</InputLabel>
+            <Select
+              value={ruleScope}
+              label="Scope"
+              onChange={(e) => {
+                setRuleScope(e.target.value as Scope);
+                setRuleTargetId('');
+              }}
+            >
+              <MenuItem value="global">All Backups (Global)</MenuItem>
+              <MenuItem value="profile">Specific Profile</MenuItem>
+              <MenuItem value="server">Specific Server</MenuItem>
+            </Select>
+          </FormControl>
+          {ruleScope !== 'global' && (
+            <FormControl size="small" sx={{ minWidth: 180 }}>
+              <InputLabel>{ruleScope === 'profile' ? 'Backup Profile' : 'Server'}</InputLabel>
+              <Select
+                value={ruleTargetId}
+                label={ruleScope === 'profile' ? 'Backup Profile' : 'Server'}
+                onChange={(e) => setRuleTargetId(e.target.value as number)}
+              >
+                {(ruleScope === 'profile' ? profiles : servers).map((target) => (
+                  <MenuItem key={target.id} value={target.id}>
+                    {target.name}
+                  </MenuItem>
+                ))}
+              </Select>
+            </FormControl>
+          )}
+          <Button variant="contained" size="small" onClick={handleAddRule} disabled={ruleScope !== 'global' && ruleTargetId === ''}>
+            Add
+          </Button>
+        </Stack>
+      )}
+
+      <TableContainer>
+        <Table size="small">
+          <TableHead>
+            <TableRow>
+              <TableCell>Scope</TableCell>
+              {eventColumns.map((column) => (
+                <TableCell key={column.key}>{column.label}</TableCell>
+              ))}
+              <TableCell>Digest</TableCell>
+              <TableCell>Quiet Hours</TableCell>
+              <TableCell align="right" />
+            </TableRow>
+          </TableHead>
+          <TableBody>
+            {preferences.map((pref) => (
+              <TableRow key={pref.id}>
+                <TableCell>{scopeChip(pref)}</TableCell>
+                {eventColumns.map((column) => (
+                  <TableCell key={column.key}>
+                    {/* Storage alerts are not about a profile or server */}
+                    {(column.key !== 'notify_on_low_storage' || (!pref.backup_profile_id && !pref.server_id)) && (
+                      <Switch
+                        size="small"
+                        checked={pref[column.key]}
+                        onChange={(e) => handleToggle(pref, column.key, e.target.checked)}
+                        inputProps={{ 'aria-label': `${name} ${column.label}` }}
+                      />
+                    )}
+                  </TableCell>
+                ))}
+                <TableCell>
+                  <Select
+                    size="small"
+                    variant="standard"
+                    value={pref.digest_frequency || ''}
+                    onChange={(e) => handleDigestChange(pref, e.target.value as DigestFrequency)}
+                    displayEmpty
+                    inputProps={{ 'aria-label': `${name} Digest` }}
+                  >
+                    <MenuItem value="">Off</MenuItem>
+                    <MenuItem value="daily">Daily</MenuItem>
+                    <MenuItem value="weekly">Weekly</MenuItem>
+                  </Select>
+                </TableCell>
+                <TableCell>
+                  <QuietHoursField
+                    name={name}
+                    start={pref.quiet_hours_start || ''}
+                    end={pref.quiet_hours_end || ''}
+                    onChange={(start, end) => handleQuietHoursChange(pref, start, end)}
+                  />
+                </TableCell>
+                <TableCell align="right">
+                  <Tooltip title="Delete rule">
+                    <IconButton size="small" onClick={() => handleDeletePreference(pref)} color="error">
+                      <DeleteIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                </TableCell>
+              </TableRow>
+            ))}
+          </TableBody>
+        </Table>
+      </TableContainer>
+    </>
+  );
+}
@@ -0,0 +1,57 @@
+import { Stack, TextField, Typography } from '@mui/material';
+import { useEffect, useState } from 'react';
+
+interface QuietHoursFieldProps {
+  /** Prefixes the labels of the time fields */
+  name: string;
+  start: string;
+  end: string;
+  /** Called when both times are set to different values or both are cleared */
+  onChange: (start: string, end: string) => void;
+}
+
+/** Start and end of the quiet hours of a notification rule, saved when a field loses focus */
+export function QuietHoursField({ name, start, end, onChange }: QuietHoursFieldProps) {
+  const [from, setFrom] = useState(start);
+  const [until, setUntil] = useState(end);
+
+  useEffect(() => {
+    setFrom(start);
+    setUntil(end);
+  }, [start, end]);
+
+  const handleBlur = () => {
+    if (from === start && until === end) return;
+    if ((from === '' && until === '') || (from !== '' && until !== '' && from !== until)) {
+      onChange(from, until);
+    }
+  };
+
+  return (
+    <Stack direction="row" spacing={0.5} alignItems="center">
+      <TextField
+        type="time"
+        size="small"
+        variant="standard"
+        value={from}
+        onChange={(e) => setFrom(e.target.value)}
+        onBlur={handleBlur}
+        inputProps={{ 'aria-label': `${name} Quiet hours from` }}
+        sx={{ width: 110 }}
+      />
+      <Typography variant="body2" color="text.secondary">
+        –
+      </Typography>
+      <TextField
+        type="time"
+        size="small"
+        variant="standard"
+        value={until}
+        onChange={(e) => setUntil(e.target.value)}
+        onBlur={handleBlur}
+        inputProps={{ 'aria-label': `${name} Quiet hours until` }}
+        sx={{ width: 110 }}
+      />
+    </Stack>
+  );
+}
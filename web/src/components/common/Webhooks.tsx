@@ -0,0 +1,519 @@
+import AddIcon from '@mui/icons-material/Add';
+import ContentCopyIcon from '@mui/icons-material/ContentCopy';
+import DeleteIcon from '@mui/icons-material/Delete';
+import EditIcon from '@mui/icons-material/Edit';
+import HistoryIcon from '@mui/icons-material/History';
+import ReplayIcon from '@mui/icons-material/Replay';
+import SendIcon from '@mui/icons-material/Send';
+import WebhookIcon from '@mui/icons-material/Webhook';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Checkbox,
+  Chip,
+  CircularProgress,
+  FormControl,
+  FormControlLabel,
+  FormGroup,
+  IconButton,
+  InputLabel,
+  MenuItem,
+  Paper,
+  Select,
+  Stack,
+  Switch,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TableRow,
+  TextField,
+  Tooltip,
+  Typography,
+} from '@mui/material';
+import { useEffect, useState } from 'react';
+import {
+  notificationApi,
+  type Webhook,
+  type WebhookDelivery,
+  type WebhookDeliveryStatus,
+  type WebhookEvent,
+  type WebhookInput,
+} from '../../api';
+import type { BackupProfile, Server } from '../../types';
+import { formatDate } from '../../utils/format';
+
+type Scope = 'global' | 'profile' | 'server';
+
+const events: { value: WebhookEvent; label: string }[] = [
+  { value: 'backup.started', label: 'Backup started' },
+  { value: 'backup.completed', label: 'Backup completed' },
+  { value: 'backup.failed', label: 'Backup failed' },
+  { value: 'backup.consecutive_failures', label: 'Consecutive failures' },
+  { value: 'backup.recovered', label: 'Backup recovered' },
+  { value: 'backup.missed', label: 'Missed backup' },
+  { value: 'storage.low', label: 'Low storage' },
+  { value: 'digest.daily', label: 'Daily digest' },
+  { value: 'digest.weekly', label: 'Weekly digest' },
+];
+
+const statusColors: Record<WebhookDeliveryStatus, 'success' | 'warning' | 'error'> = {
+  succeeded: 'success',
+  pending: 'warning',
+  failed: 'error',
+};
+
+const emptyForm: WebhookInput = {
+  name: '',
+  url: '',
+  secret: '',
+  events: [],
+  low_storage_threshold: 10,
+  enabled: true,
+};
+
+interface WebhooksProps {
+  servers: Server[];
+  profiles: BackupProfile[];
+}
+
+/** Webhook endpoints and their delivery logs, managed by global admins */
+export function Webhooks({ servers, profiles }: WebhooksProps) {
+  const [webhooks, setWebhooks] = useState<Webhook[]>([]);
+  const [showForm, setShowForm] = useState(false);
+  const [editingId, setEditingId] = useState<number | null>(null);
+  const [form, setForm] = useState<WebhookInput>(emptyForm);
+  const [scope, setScope] = useState<Scope>('global');
+  const [createdSecret, setCreatedSecret] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [deliveriesFor, setDeliveriesFor] = useState<number | null>(null);
+  const [deliveries, setDeliveries] = useState<WebhookDelivery[]>([]);
+  const [busyId, setBusyId] = useState<number | null>(null);
+
+  useEffect(() => {
+    loadWebhooks();
+  }, []);
+
+  const loadWebhooks = async () => {
+    try {
+      setWebhooks((await notificationApi.listWebhooks()) || []);
+    } catch (err) {
+      console.error('Error loading webhooks:', err);
+    }
+  };
+
+  const loadDeliveries = async (webhookId: number) => {
+    try {
+      setDeliveries((await notificationApi.listWebhookDeliveries(webhookId)) || []);
+    } catch (err) {
+      console.error('Error loading webhook deliveries:', err);
+    }
+  };
+
+  const openCreateForm = () => {
+    setEditingId(null);
+    setForm(emptyForm);
+    setScope('global');
+    setShowForm(!showForm || editingId !== null);
+  };
+
+  const openEditForm = (webhook: Webhook) => {
+    setEditingId(webhook.id);
+    setForm({
+      name: webhook.name,
+      url: webhook.url,
+      secret: '',
+      events: webhook.events ?? [],
+      backup_profile_id: webhook.backup_profile_id,
+      server_id: webhook.server_id,
+      low_storage_threshold: webhook.low_storage_threshold,
+      enabled: webhook.enabled,
+    });
+    setScope(webhook.backup_profile_id ? 'profile' : webhook.server_id ? 'server' : 'global');
+    setShowForm(true);
+  };
+
+  const toggleEvent = (event: WebhookEvent, checked: boolean) => {
+    setForm({ ...form, events: checked ? [...form.events, event] : form.events.filter((e) => e !== event) });
+  };
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setError(null);
+    const input: WebhookInput = {
+      ...form,
+      backup_profile_id: scope === 'profile' ? form.backup_profile_id : undefined,
+      server_id: scope === 'server' ? form.server_id : undefined,
+    };
+    try {
+      if (editingId === null) {
+        const webhook = await notificationApi.createWebhook(input);
+        setCreatedSecret(webhook.secret ?? null);
+      } else {
+        await notificationApi.updateWebhook(editingId, input);
+      }
+      setShowForm(false);
+      setEditingId(null);
+      loadWebhooks();
+    } catch (err) {
+      console.error('Error saving webhook:', err);
+      setError(err instanceof Error ? err.message : 'Failed to save webhook');
+    }
+  };
+
+  const handleToggleEnabled = async (webhook: Webhook, enabled: boolean) => {
+    try {
+      await notificationApi.updateWebhook(webhook.id, {
+        name: webhook.name,
+        url: webhook.url,
+        events: webhook.events ?? [],
+        backup_profile_id: webhook.backup_profile_id,
+        server_id: webhook.server_id,
+        low_storage_threshold: webhook.low_storage_threshold,
+        enabled,
+      });
+      loadWebhooks();
+    } catch (err) {
+      console.error('Error updating webhook:', err);
+    }
+  };
+
+  const handleDelete = async (webhook: Webhook) => {
+    if (!confirm(`Delete the webhook "${webhook.name}" and its delivery log?`)) return;
+    try {
+      await notificationApi.deleteWebhook(webhook.id);
+      if (deliveriesFor === webhook.id) setDeliveriesFor(null);
+      loadWebhooks();
+    } catch (err) {
+      console.error('Error deleting webhook:', err);
+    }
+  };
+
+  const toggleDeliveries = (webhook: Webhook) => {
+    if (deliveriesFor === webhook.id) {
+      setDeliveriesFor(null);
+      return;
+    }
+    setDeliveries([]);
+    setDeliveriesFor(webhook.id);
+    loadDeliveries(webhook.id);
+  };
+
+  const handleSendTest = async (webhook: Webhook) => {
+    setBusyId(webhook.id);
+    try {
+      await notificationApi.sendTestWebhook(webhook.id);
+      setDeliveriesFor(webhook.id);
+      loadDeliveries(webhook.id);
+    } catch (err) {
+      console.error('Error sending test webhook:', err);
+    } finally {
+      setBusyId(null);
+    }
+  };
+
+  const handleRedeliver = async (delivery: WebhookDelivery) => {
+    try {
+      await notificationApi.redeliverWebhook(delivery.webhook_id, delivery.id);
+      loadDeliveries(delivery.webhook_id);
+    } catch (err) {
+      console.error('Error redelivering webhook:', err);
+    }
+  };
+
+  const scopeChip = (webhook: Webhook) => {
+    if (webhook.backup_profile_id) {
+      return <Chip label={webhook.backup_profile?.name || `Profile ${webhook.backup_profile_id}`} size="small" color="primary" variant="outlined" />;
+    }
+    if (webhook.server_id) {
+      return <Chip label={webhook.server?.name || `Server ${webhook.server_id}`} size="small" color="secondary" variant="outlined" />;
+    }
+    return <Chip label="Global" size="small" />;
+  };
+
+  return (
+    <Card sx={{ mb: 3 }} data-testid="webhooks">
+      <CardContent>
+        <Box display="flex" justifyContent="space-between" alignItems="center" gap={1} mb={2}>
+          <Box display="flex" alignItems="center" gap={1}>
+            <WebhookIcon color="action" />
+            <Typography variant="h6">Webhooks</Typography>
+          </Box>
+          <Button size="small" variant="contained" startIcon={<AddIcon />} onClick={openCreateForm} data-testid="add-webhook-btn">
+            Add Webhook
+          </Button>
+        </Box>
+
+        <Typography variant="body2" color="text.secondary" mb={2}>
+          Webhooks receive a signed JSON payload for each event. Verify the <code>X-BackApp-Signature</code> header, an
+          HMAC-SHA256 of the <code>X-BackApp-Timestamp</code> header, a dot and the body.
+        </Typography>
+
+        {createdSecret && (
+          <Alert
+            severity="success"
+            sx={{ mb: 2, wordBreak: 'break-all' }}
+            onClose={() => setCreatedSecret(null)}
+            action={
+              <IconButton aria-label="copy secret" size="small" onClick={() => navigator.clipboard.writeText(createdSecret)}>
+                <ContentCopyIcon fontSize="small" />
+              </IconButton>
+            }
+          >
+            Copy the signing secret now, it will not be shown again.
+            <Box component="code" display="block" mt={1} data-testid="created-webhook-secret">
+              {createdSecret}
+            </Box>
+          </Alert>
+        )}
+        {error && (
+          <Alert severity="error" sx={{ mb: 2 }} onClose={() => setError(null)}>
+            {error}
+          </Alert>
+        )}
+
+        {showForm && (
+          <Paper variant="outlined" sx={{ p: 2, mb: 2 }}>
+            <form onSubmit={handleSubmit}>
+              <Stack spacing={2}>
+                <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2}>
+                  <TextField
+                    label="Name"
+                    size="small"
+                    required
+                    value={form.name}
+                    onChange={(e) => setForm({ ...form, name: e.target.value })}
+                    inputProps={{ 'data-testid': 'input-webhook-name' }}
+                  />
+                  <TextField
+                    label="URL"
+                    type="url"
+                    size="small"
+                    required
+                    fullWidth
+                    value={form.url}
+                    onChange={(e) => setForm({ ...form, url: e.target.value })}
+                    placeholder="https://hooks.example.com/backapp"
+                    inputProps={{ 'data-testid': 'input-webhook-url' }}
+                  />
+                </Stack>
+                <TextField
+                  label="Secret"
+                  size="small"
+                  value={form.secret}
+                  onChange={(e) => setForm({ ...form, secret: e.target.value })}
+                  helperText={editingId === null ? 'Leave empty to generate a secret' : 'Leave empty to keep the current secret'}
+                />
+                <Box>
+                  <Typography variant="subtitle2">Events</Typography>
+                  <FormGroup row>
+                    {events.map((event) => (
+                      <FormControlLabel
+                        key={event.value}
+                        label={event.label}
+                        control={
+                          <Checkbox
+                            size="small"
+                            checked={form.events.includes(event.value)}
+                            onChange={(e) => toggleEvent(event.value, e.target.checked)}
+                          />
+                        }
+                      />
+                    ))}
+                  </FormGroup>
+                  <Typography variant="caption" color="text.secondary">
+                    Select no events to receive all of them.
+                  </Typography>
+                </Box>
+                <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2}>
+                  <FormControl size="small" sx={{ minWidth: 180 }}>
+                    <InputLabel>Scope</InputLabel>
+                    <Select value={scope} label="Scope" onChange={(e) => setScope(e.target.value as Scope)}>
+                      <MenuItem value="global">All Backups (Global)</MenuItem>
+                      <MenuItem value="profile">Specific Profile</MenuItem>
+                      <MenuItem value="server">Specific Server</MenuItem>
+                    </Select>
+                  </FormControl>
+                  {scope === 'profile' && (
+                    <FormControl size="small" sx={{ minWidth: 180 }} required>
+                      <InputLabel>Backup Profile</InputLabel>
+                      <Select
+                        value={form.backup_profile_id ?? ''}
+                        label="Backup Profile"
+                        onChange={(e) => setForm({ ...form, backup_profile_id: e.target.value as number })}
+                      >
+                        {profiles.map((profile) => (
+                          <MenuItem key={profile.id} value={profile.id}>
+                            {profile.name}
+                          </MenuItem>
+                        ))}
+                      </Select>
+                    </FormControl>
+                  )}
+                  {scope === 'server' && (
+                    <FormControl size="small" sx={{ minWidth: 180 }} required>
+                      <InputLabel>Server</InputLabel>
+                      <Select
+                        value={form.server_id ?? ''}
+                        label="Server"
+                        onChange={(e) => setForm({ ...form, server_id: e.target.value as number })}
+                      >
+                        {servers.map((server) => (
+                          <MenuItem key={server.id} value={server.id}>
+                            {server.name}
+                          </MenuItem>
+                        ))}
+                      </Select>
+                    </FormControl>
+                  )}
+                  {scope === 'global' && (
+                    <TextField
+                      label="Low storage below (%)"
+                      type="number"
+                      size="small"
+                      value={form.low_storage_threshold}
+                      onChange={(e) => setForm({ ...form, low_storage_threshold: parseInt(e.target.value, 10) || 0 })}
+                      inputProps={{ min: 1, max: 100 }}
+                    />
+                  )}
+                </Stack>
+                <Box display="flex" gap={1}>
+                  <Button type="submit" variant="contained" size="small" data-testid="save-webhook-btn">
+                    {editingId === null ? 'Create' : 'Save'}
+                  </Button>
+                  <Button size="small" onClick={() => setShowForm(false)}>
+                    Cancel
+                  </Button>
+                </Box>
+              </Stack>
+            </form>
+          </Paper>
+        )}
+
+        {webhooks.length === 0 ? (
+          <Typography color="text.secondary" py={2}>
+            No webhooks yet.
+          </Typography>
+        ) : (
+          webhooks.map((webhook) => (
+            <Paper key={webhook.id} variant="outlined" sx={{ p: 2, mb: 2 }} data-testid={`webhook-${webhook.id}`}>
+              <Box display="flex" justifyContent="space-between" alignItems="center" flexWrap="wrap" gap={1}>
+                <Box minWidth={0}>
+                  <Box display="flex" alignItems="center" gap={1}>
+                    <Typography fontWeight="medium">{webhook.name}</Typography>
+                    {scopeChip(webhook)}
+                  </Box>
+                  <Typography variant="body2" color="text.secondary" sx={{ wordBreak: 'break-all' }}>
+                    {webhook.url}
+                  </Typography>
+                  <Box display="flex" gap={0.5} flexWrap="wrap" mt={0.5}>
+                    {(webhook.events?.length ? webhook.events : ['all events']).map((event) => (
+                      <Chip key={event} label={event} size="small" variant="outlined" />
+                    ))}
+                  </Box>
+                </Box>
+                <Box display="flex" alignItems="center" gap={1}>
+                  <Switch
+                    size="small"
+                    checked={webhook.enabled}
+                    onChange={(e) => handleToggleEnabled(webhook, e.target.checked)}
+                    inputProps={{ 'aria-label': `${webhook.name} enabled` }}
+                  />
+                  <Button
+                    size="small"
+                    variant="outlined"
+                    startIcon={busyId === webhook.id ? <CircularProgress size={16} /> : <SendIcon />}
+                    onClick={() => handleSendTest(webhook)}
+                    disabled={busyId === webhook.id}
+                    data-testid={`send-test-webhook-${webhook.id}`}
+                  >
+                    Send Test
+                  </Button>
+                  <Button size="small" startIcon={<HistoryIcon />} onClick={() => toggleDeliveries(webhook)}>
+                    Deliveries
+                  </Button>
+                  <Tooltip title="Edit webhook">
+                    <IconButton size="small" onClick={() => openEditForm(webhook)} aria-label={`edit ${webhook.name}`}>
+                      <EditIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                  <Tooltip title="Delete webhook">
+                    <IconButton size="small" color="error" onClick={() => handleDelete(webhook)} aria-label={`delete ${webhook.name}`}>
+                      <DeleteIcon fontSize="small" />
+                    </IconButton>
+                  </Tooltip>
+                </Box>
+              </Box>
+
+              {deliveriesFor === webhook.id && (
+                <TableContainer sx={{ mt: 2 }}>
+                  <Table size="small" data-testid={`webhook-deliveries-${webhook.id}`}>
+                    <TableHead>
+                      <TableRow>
+                        <TableCell>Time</TableCell>
+                        <TableCell>Event</TableCell>
+                        <TableCell>Status</TableCell>
+                        <TableCell>Response</TableCell>
+                        <TableCell>Attempts</TableCell>
+                        <TableCell align="right" />
+                      </TableRow>
+                    </TableHead>
+                    <TableBody>
+                      {deliveries.length === 0 && (
+                        <TableRow>
+                          <TableCell colSpan={6} align="center">
+                            <Typography color="text.secondary" variant="body2">
+                              No deliveries yet
+                            </Typography>
+                          </TableCell>
+                        </TableRow>
+                      )}
+                      {deliveries.map((delivery) => (
+                        <TableRow key={delivery.id} data-testid={`webhook-delivery-${delivery.id}`}>
+                          <TableCell>{formatDate(delivery.created_at)}</TableCell>
+                          <TableCell>
+                            {delivery.event}
+                            {delivery.redelivery_of && (
+                              <Typography component="span" variant="caption" color="text.secondary">
+                                {' '}
+                                redelivery of #{delivery.redelivery_of}
+                              </Typography>
+                            )}
+                          </TableCell>
+                          <TableCell>
+                            <Tooltip title={delivery.next_attempt_at ? `Next attempt ${formatDate(delivery.next_attempt_at)}` : ''}>
+                              <Chip label={delivery.status} size="small" color={statusColors[delivery.status]} />
+                            </Tooltip>
+                          </TableCell>
+                          <TableCell>
+                            <Tooltip title={delivery.error || delivery.response_body || ''}>
+                              <span>
+                                {delivery.response_code || '—'} · {delivery.duration_ms} ms
+                              </span>
+                            </Tooltip>
+                          </TableCell>
+                          <TableCell>{delivery.attempts}</TableCell>
+                          <TableCell align="right">
+                            <Tooltip title="Redeliver">
+                              <IconButton size="small" onClick={() => handleRedeliver(delivery)} aria-label={`redeliver ${delivery.id}`}>
+                                <ReplayIcon fontSize="small" />
+                              </IconButton>
+                            </Tooltip>
+                          </TableCell>
+                        </TableRow>
+                      ))}
+                    </TableBody>
+                  </Table>
+                </TableContainer>
+              )}
+            </Paper>
+          ))
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -4,4 +4,6 @@ export { PrerequisitesAlert } from './PrerequisitesAlert';
 export { default as TabPanel } from './TabPanel';
 export { default as DestructiveActionDialog, type DestructiveAction, type DestructiveActionDialogProps } from './DestructiveActionDialog';
 export { NotificationBell } from './NotificationBell';
+export { EmailRecipients } from './EmailRecipients';
+export { Webhooks } from './Webhooks';
 export { StorageWidget } from './StorageWidget';
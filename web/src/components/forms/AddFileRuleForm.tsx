@@ -47,7 +47,9 @@ function AddFileRuleForm({ formData, onFormDataChange, onAdd, serverId, onCancel
                 label="Exclude Pattern"
                 value={formData.exclude_pattern}
                 onChange={(e) => onFormDataChange({ ...formData, exclude_pattern: e.target.value })}
-                placeholder="*.tmp,*.cache (optional)"
+                placeholder={'*.tmp\n*.cache/ (optional, one per line)'}
+                multiline
+                minRows={2}
                 size="small"
               />
               <Stack direction="row" gap={1}>
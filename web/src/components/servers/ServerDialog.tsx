@@ -10,29 +10,48 @@ import {
   FormControl,
   FormControlLabel,
   FormLabel,
+  MenuItem,
   Radio,
   RadioGroup,
   TextField,
   Typography,
 } from '@mui/material';
 import { useEffect, useState } from 'react';
-import type { Server } from '../../types';
+import type { Server, ServerAuthType } from '../../types';
+
+const AUTH_TYPES: { value: ServerAuthType; label: string }[] = [
+  { value: 'key', label: 'SSH Key' },
+  { value: 'password', label: 'Password' },
+  { value: 'key_file', label: 'Key File on Host' },
+  { value: 'agent', label: 'SSH Agent' },
+  { value: 'password_command', label: 'Password Command' },
+  { value: 'key_command', label: 'Key Command' },
+  { value: 'keyboard_interactive', label: 'Keyboard-Interactive' },
+];
+
+const KEY_AUTH_TYPES: ServerAuthType[] = ['key', 'key_file', 'key_command', 'agent'];
 
 interface ServerDialogProps {
   open: boolean;
   onClose: () => void;
   onSubmit: (formData: FormData) => Promise<void>;
   server?: Server;
+  /** Servers that can be selected as jump host */
+  servers?: Server[];
 }
 
-function ServerDialog({ open, onClose, onSubmit, server }: ServerDialogProps) {
-  const [authType, setAuthType] = useState<'key' | 'password'>('key');
+function ServerDialog({ open, onClose, onSubmit, server, servers = [] }: ServerDialogProps) {
+  const [authType, setAuthType] = useState<ServerAuthType>('key');
+  const [fallbacks, setFallbacks] = useState<ServerAuthType[]>([]);
   const [selectedFile, setSelectedFile] = useState<File | null>(null);
   const isEditMode = !!server;
+  // Settings are shown for the auth type and all fallbacks
+  const uses = (...types: ServerAuthType[]) => types.includes(authType) || fallbacks.some((f) => types.includes(f));
 
   useEffect(() => {
     if (server) {
-      setAuthType(server.auth_type === 'password' ? 'password' : 'key');
+      setAuthType(server.auth_type);
+      setFallbacks(server.fallback_auth_types || []);
     }
   }, [server]);
 
@@ -43,6 +62,7 @@ function ServerDialog({ open, onClose, onSubmit, server }: ServerDialogProps) {
     if (!isEditMode) {
       e.currentTarget.reset();
       setAuthType('key');
+      setFallbacks([]);
     }
     setSelectedFile(null);
   };
@@ -106,28 +126,70 @@ function ServerDialog({ open, onClose, onSubmit, server }: ServerDialogProps) {
             defaultValue={server?.username || ''}
             data-testid="input-username"
           />
+          <TextField
+            select
+            fullWidth
+            label="Jump Host"
+            name="jump_host_id"
+            margin="normal"
+            defaultValue={server?.jump_host_id ?? ''}
+            helperText="Connect through another server, e.g. a bastion host"
+            data-testid="input-jump-host"
+          >
+            <MenuItem value="">None</MenuItem>
+            {servers
+              .filter((candidate) => candidate.id !== server?.id)
+              .map((candidate) => (
+                <MenuItem key={candidate.id} value={candidate.id} data-testid={`jump-host-option-${candidate.id}`}>
+                  {candidate.name} ({candidate.host}:{candidate.port})
+                </MenuItem>
+              ))}
+          </TextField>
           <FormControl component="fieldset" margin="normal">
             <FormLabel component="legend">Authentication Type</FormLabel>
             <RadioGroup
               row
               name="auth_type"
               value={authType}
-              onChange={(e) => setAuthType(e.target.value as 'key' | 'password')}
+              onChange={(e) => {
+                const value = e.target.value as ServerAuthType;
+                setAuthType(value);
+                setFallbacks((current) => current.filter((fallback) => fallback !== value));
+              }}
             >
-              <FormControlLabel
-                value="key"
-                control={<Radio data-testid="auth-type-key" />}
-                label="SSH Key"
-              />
-              <FormControlLabel
-                value="password"
-                control={<Radio data-testid="auth-type-password" />}
-                label="Password"
-              />
+              {AUTH_TYPES.map((type) => (
+                <FormControlLabel
+                  key={type.value}
+                  value={type.value}
+                  control={<Radio data-testid={`auth-type-${type.value.replace('_', '-')}`} />}
+                  label={type.label}
+                />
+              ))}
             </RadioGroup>
           </FormControl>
+          <TextField
+            select
+            fullWidth
+            label="Fallback Methods (Optional)"
+            name="fallback_auth_types"
+            margin="normal"
+            value={fallbacks}
+            onChange={(e) => {
+              const value = e.target.value as unknown as ServerAuthType[] | string;
+              setFallbacks(typeof value === 'string' ? (value.split(',') as ServerAuthType[]) : value);
+            }}
+            SelectProps={{ multiple: true }}
+            helperText="Tried in order when the server rejects the authentication type"
+            data-testid="input-fallback-auth-types"
+          >
+            {AUTH_TYPES.filter((type) => type.value !== authType).map((type) => (
+              <MenuItem key={type.value} value={type.value} data-testid={`fallback-option-${type.value}`}>
+                {type.label}
+              </MenuItem>
+            ))}
+          </TextField>
 
-          {authType === 'key' && (
+          {uses('key') && (
             <Box mt={2} data-testid="keyfile-input">
               <Button variant="outlined" component="label" fullWidth startIcon={<AttachFileIcon />}>
                 {selectedFile ? 'Change SSH Private Key' : isEditMode ? 'Upload New SSH Private Key (Optional)' : 'Upload SSH Private Key'}
@@ -151,12 +213,93 @@ function ServerDialog({ open, onClose, onSubmit, server }: ServerDialogProps) {
                 </Box>
               )}
               <Typography variant="caption" color="text.secondary" display="block" mt={1}>
-                {isEditMode ? 'Leave empty to keep the existing SSH key' : 'Upload your SSH private key file'}
+                {isEditMode && server?.has_private_key
+                  ? 'Leave empty to keep the stored SSH key'
+                  : 'Upload your SSH private key file'}
               </Typography>
             </Box>
           )}
 
-          {authType === 'password' && (
+          {uses('key_file') && (
+            <TextField
+              fullWidth
+              label="Private Key Path"
+              name="key_file_path"
+              required
+              placeholder="/home/backapp/.ssh/id_ed25519"
+              margin="normal"
+              defaultValue={server?.key_file_path || ''}
+              helperText="Read from the BackApp host on every connection"
+              data-testid="input-key-file-path"
+            />
+          )}
+
+          {uses('agent') && (
+            <TextField
+              fullWidth
+              label="Agent Socket (Optional)"
+              name="agent_socket"
+              placeholder="/run/user/1000/ssh-agent.socket"
+              margin="normal"
+              defaultValue={server?.agent_socket || ''}
+              helperText="Defaults to SSH_AUTH_SOCK of the BackApp process"
+              data-testid="input-agent-socket"
+            />
+          )}
+
+          {uses('password_command', 'key_command') && (
+            <TextField
+              fullWidth
+              label="Secret Command"
+              name="secret_command"
+              required
+              placeholder={uses('password_command') ? 'pass show servers/web' : 'vault kv get -field=key secret/web'}
+              margin="normal"
+              defaultValue={server?.secret_command || ''}
+              helperText={`Runs on the BackApp host before every connection and prints the ${
+                uses('password_command') ? 'password' : 'private key'
+              }`}
+              data-testid="input-secret-command"
+            />
+          )}
+
+          {uses(...KEY_AUTH_TYPES) && (
+            <TextField
+              fullWidth
+              multiline
+              minRows={2}
+              label="Certificate (Optional)"
+              name="certificate"
+              placeholder="ssh-ed25519-cert-v01@openssh.com AAAA..."
+              margin="normal"
+              defaultValue={server?.certificate || ''}
+              helperText={
+                uses('key_file')
+                  ? 'OpenSSH user certificate; defaults to the -cert.pub file next to the key file'
+                  : 'OpenSSH user certificate signed by your CA'
+              }
+              data-testid="input-certificate"
+            />
+          )}
+
+          {uses('key', 'key_file', 'key_command') && (
+            <TextField
+              fullWidth
+              label="Key Passphrase (Optional)"
+              name="key_passphrase"
+              type="password"
+              autoComplete="new-password"
+              margin="normal"
+              helperText={
+                isEditMode && server?.has_key_passphrase
+                  ? 'Leave empty to keep the stored passphrase'
+                  : 'Only needed for encrypted private keys'
+              }
+              data-testid="input-key-passphrase"
+            />
+          )}
+
+          {uses('password', 'keyboard_interactive') && (
             <Box mt={2} data-testid="password-input">
               <TextField
                 fullWidth
@@ -167,9 +310,9 @@ function ServerDialog({ open, onClose, onSubmit, server }: ServerDialogProps) {
                 placeholder={isEditMode ? 'Leave empty to keep existing password' : undefined}
                 data-testid="input-password"
               />
-              {isEditMode && (
+              {isEditMode && server?.has_password && (
                 <Typography variant="caption" color="text.secondary" display="block" mt={1}>
-                  Leave empty to keep the existing password
+                  Leave empty to keep the stored password
                 </Typography>
               )}
             </Box>
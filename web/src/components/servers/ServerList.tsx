@@ -60,6 +60,11 @@ function ServerList({ servers, testingConnection, onTestConnection, onDeleteServ
                 <TableCell data-testid="server-name">{server.name}</TableCell>
                 <TableCell data-testid="server-host">
                   {server.host}:{server.port}
+                  {server.jump_host_id && (
+                    <Typography variant="caption" color="text.secondary" display="block" data-testid="server-jump-host">
+                      via {servers.find((s) => s.id === server.jump_host_id)?.name ?? `server ${server.jump_host_id}`}
+                    </Typography>
+                  )}
                 </TableCell>
                 <TableCell data-testid="server-username" sx={{ display: { xs: 'none', sm: 'table-cell' } }}>{server.username}</TableCell>
                 <TableCell>
@@ -0,0 +1,251 @@
+import { Add as AddIcon, ContentCopy as ContentCopyIcon, Delete as DeleteIcon } from '@mui/icons-material';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Chip,
+  CircularProgress,
+  FormControl,
+  IconButton,
+  InputLabel,
+  List,
+  ListItem,
+  ListItemText,
+  MenuItem,
+  Select,
+  Stack,
+  TextField,
+  Typography,
+} from '@mui/material';
+import { useEffect, useState } from 'react';
+import { apiTokenApi, backupProfileApi } from '../api';
+import type { ApiToken, ApiTokenScope, BackupProfile } from '../types';
+
+const scopeLabels: Record<ApiTokenScope, string> = {
+  'read-only': 'Read only',
+  'trigger-runs': 'Trigger runs',
+  admin: 'Admin',
+};
+
+function ApiTokens() {
+  const [tokens, setTokens] = useState<ApiToken[]>([]);
+  const [profiles, setProfiles] = useState<BackupProfile[]>([]);
+  const [showForm, setShowForm] = useState(false);
+  const [loading, setLoading] = useState(true);
+  const [scope, setScope] = useState<ApiTokenScope>('read-only');
+  const [profileIds, setProfileIds] = useState<number[]>([]);
+  const [expiresInDays, setExpiresInDays] = useState<number>(90);
+  const [createdToken, setCreatedToken] = useState<string | null>(null);
+
+  useEffect(() => {
+    loadData();
+  }, []);
+
+  const loadData = async () => {
+    try {
+      const [tokensData, profilesData] = await Promise.all([apiTokenApi.list(), backupProfileApi.list()]);
+      setTokens(tokensData || []);
+      setProfiles(profilesData || []);
+    } catch (error) {
+      console.error('Error loading API tokens:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    const formData = new FormData(e.currentTarget);
+    try {
+      const token = await apiTokenApi.create({
+        name: formData.get('name') as string,
+        scope,
+        profile_ids: profileIds,
+        expires_at: expiresInDays > 0 ? new Date(Date.now() + expiresInDays * 24 * 60 * 60 * 1000).toISOString() : undefined,
+      });
+      setCreatedToken(token.token ?? null);
+      setShowForm(false);
+      setScope('read-only');
+      setProfileIds([]);
+      setExpiresInDays(90);
+      loadData();
+    } catch (error) {
+      console.error('Error creating API token:', error);
+      alert('Failed to create API token');
+    }
+  };
+
+  const handleDelete = async (token: ApiToken) => {
+    if (!confirm(`Revoke the token "${token.name}"? Scripts using it will stop working.`)) return;
+    try {
+      await apiTokenApi.delete(token.id);
+      loadData();
+    } catch (error) {
+      console.error('Error revoking API token:', error);
+    }
+  };
+
+  const describeUsage = (token: ApiToken) => {
+    const parts = [`${token.token_prefix}…`];
+    if (token.user) parts.push(`created by ${token.user.username}`);
+    parts.push(token.last_used_at ? `last used ${new Date(token.last_used_at).toLocaleString()}` : 'never used');
+    if (token.expires_at) {
+      const expired = new Date(token.expires_at) < new Date();
+      parts.push(`${expired ? 'expired' : 'expires'} ${new Date(token.expires_at).toLocaleDateString()}`);
+    }
+    return parts.join(' · ');
+  };
+
+  if (loading) {
+    return (
+      <Box display="flex" justifyContent="center" alignItems="center" py={12}>
+        <CircularProgress />
+      </Box>
+    );
+  }
+
+  return (
+    <Box>
+      <Box
+        display="flex"
+        flexDirection={{ xs: 'column', sm: 'row' }}
+        justifyContent="space-between"
+        alignItems={{ xs: 'stretch', sm: 'center' }}
+        gap={2}
+        mb={3}
+      >
+        <Typography variant="h5" component="h3">
+          API Tokens
+        </Typography>
+        <Button
+          variant="contained"
+          startIcon={<AddIcon />}
+          onClick={() => setShowForm(!showForm)}
+          data-testid="add-api-token-btn"
+          fullWidth
+          sx={{ maxWidth: { sm: 'fit-content' } }}
+        >
+          Add Token
+        </Button>
+      </Box>
+
+      {createdToken && (
+        <Alert
+          severity="success"
+          sx={{ mb: 2, wordBreak: 'break-all' }}
+          onClose={() => setCreatedToken(null)}
+          action={
+            <IconButton aria-label="copy token" size="small" onClick={() => navigator.clipboard.writeText(createdToken)}>
+              <ContentCopyIcon fontSize="small" />
+            </IconButton>
+          }
+        >
+          Copy the token now, it will not be shown again. Send it as <code>Authorization: Bearer &lt;token&gt;</code>.
+          <Box component="code" display="block" mt={1} data-testid="created-api-token">
+            {createdToken}
+          </Box>
+        </Alert>
+      )}
+
+      <Card>
+        <CardContent>
+          {showForm && (
+            <form onSubmit={handleSubmit}>
+              <Stack spacing={2} mb={2}>
+                <TextField name="name" label="Name" required size="small" placeholder="e.g. CI pipeline" />
+                <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2}>
+                  <FormControl size="small" fullWidth>
+                    <InputLabel>Scope</InputLabel>
+                    <Select value={scope} label="Scope" onChange={(e) => setScope(e.target.value as ApiTokenScope)}>
+                      <MenuItem value="read-only">Read only</MenuItem>
+                      <MenuItem value="trigger-runs">Read and trigger runs</MenuItem>
+                      <MenuItem value="admin">Admin</MenuItem>
+                    </Select>
+                  </FormControl>
+                  <FormControl size="small" fullWidth>
+                    <InputLabel>Expires</InputLabel>
+                    <Select value={expiresInDays} label="Expires" onChange={(e) => setExpiresInDays(e.target.value as number)}>
+                      <MenuItem value={30}>In 30 days</MenuItem>
+                      <MenuItem value={90}>In 90 days</MenuItem>
+                      <MenuItem value={365}>In 1 year</MenuItem>
+                      <MenuItem value={0}>Never</MenuItem>
+                    </Select>
+                  </FormControl>
+                </Stack>
+                <FormControl size="small" fullWidth>
+                  <InputLabel>Backup Profiles</InputLabel>
+                  <Select
+                    multiple
+                    value={profileIds}
+                    label="Backup Profiles"
+                    onChange={(e) => setProfileIds(e.target.value as number[])}
+                    renderValue={(selected) =>
+                      profiles
+                        .filter((p) => selected.includes(p.id))
+                        .map((p) => p.name)
+                        .join(', ')
+                    }
+                  >
+                    {profiles.map((p) => (
+                      <MenuItem key={p.id} value={p.id}>
+                        {p.name}
+                      </MenuItem>
+                    ))}
+                  </Select>
+                </FormControl>
+                <Typography variant="caption" color="text.secondary">
+                  Leave the profiles empty to allow all profiles. Tokens restricted to profiles can only access those
+                  profiles, their runs and backup files.
+                </Typography>
+                <Box>
+                  <Button type="submit" variant="contained" size="small">
+                    Create
+                  </Button>
+                </Box>
+              </Stack>
+            </form>
+          )}
+
+          {tokens.length === 0 ? (
+            <Typography color="text.secondary">No API tokens yet.</Typography>
+          ) : (
+            <List>
+              {tokens.map((token) => (
+                <ListItem
+                  key={token.id}
+                  divider
+                  secondaryAction={
+                    <IconButton aria-label="revoke" onClick={() => handleDelete(token)}>
+                      <DeleteIcon />
+                    </IconButton>
+                  }
+                >
+                  <ListItemText
+                    primary={
+                      <Box display="flex" alignItems="center" gap={1} flexWrap="wrap">
+                        <span>{token.name}</span>
+                        <Chip label={scopeLabels[token.scope] ?? token.scope} size="small" />
+                        {token.restrict_to_profiles && (
+                          <Chip
+                            label={(token.backup_profiles ?? []).map((p) => p.name).join(', ') || 'No profiles'}
+                            size="small"
+                            variant="outlined"
+                          />
+                        )}
+                      </Box>
+                    }
+                    secondary={describeUsage(token)}
+                  />
+                </ListItem>
+              ))}
+            </List>
+          )}
+        </CardContent>
+      </Card>
+    </Box>
+  );
+}
+
+export default ApiTokens;
@@ -0,0 +1,312 @@
+import { Download as DownloadIcon, KeyboardArrowDown, KeyboardArrowUp } from '@mui/icons-material';
+import {
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Chip,
+  CircularProgress,
+  Collapse,
+  FormControl,
+  IconButton,
+  InputLabel,
+  MenuItem,
+  Paper,
+  Select,
+  Stack,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TablePagination,
+  TableRow,
+  TextField,
+  Typography,
+} from '@mui/material';
+import { Fragment, useEffect, useState } from 'react';
+import { auditLogApi } from '../api';
+import type { AuditAction, AuditLogEntry, AuditLogFilter, AuditTargetType } from '../types';
+import { formatDate } from '../utils/format';
+
+const actions: AuditAction[] = ['create', 'update', 'delete', 'run', 'dry_run', 'download'];
+
+const targetTypes: AuditTargetType[] = [
+  'server',
+  'storage_location',
+  'naming_rule',
+  'backup_profile',
+  'command',
+  'file_rule',
+  'backup_run',
+  'backup_file',
+  'user',
+  'role_assignment',
+  'api_token',
+  'notification_preference',
+  'push_subscription',
+  'email_recipient',
+  'webhook',
+  'chat_integration',
+];
+
+const humanize = (value: string) => value.replace(/_/g, ' ');
+
+const actionColors: Record<AuditAction, 'success' | 'info' | 'error' | 'primary' | 'default'> = {
+  create: 'success',
+  update: 'info',
+  delete: 'error',
+  run: 'primary',
+  dry_run: 'primary',
+  download: 'default',
+};
+
+const formatValue = (value: unknown) => {
+  if (value === null || value === undefined) return '—';
+  return typeof value === 'string' ? value : JSON.stringify(value);
+};
+
+// datetime-local inputs have no time zone, so they are read as local time
+const toRFC3339 = (value: string) => (value ? new Date(value).toISOString() : undefined);
+
+function AuditLog() {
+  const [entries, setEntries] = useState<AuditLogEntry[]>([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(0);
+  const [rowsPerPage, setRowsPerPage] = useState(25);
+  const [actor, setActor] = useState('');
+  const [action, setAction] = useState<AuditAction | ''>('');
+  const [targetType, setTargetType] = useState<AuditTargetType | ''>('');
+  const [from, setFrom] = useState('');
+  const [to, setTo] = useState('');
+  const [expandedId, setExpandedId] = useState<number | null>(null);
+  const [loading, setLoading] = useState(true);
+
+  const filter: AuditLogFilter = {
+    actor: actor || undefined,
+    action: action || undefined,
+    target_type: targetType || undefined,
+    from: toRFC3339(from),
+    to: toRFC3339(to),
+  };
+
+  useEffect(() => {
+    auditLogApi
+      .list(filter, page + 1, rowsPerPage)
+      .then((data) => {
+        setEntries(data.entries || []);
+        setTotal(data.total);
+      })
+      .catch((error) => console.error('Error loading audit log:', error))
+      .finally(() => setLoading(false));
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [actor, action, targetType, from, to, page, rowsPerPage]);
+
+  const resetPage = <T,>(setter: (value: T) => void) => (value: T) => {
+    setter(value);
+    setPage(0);
+  };
+
+  return (
+    <Box>
+      <Box
+        display="flex"
+        flexDirection={{ xs: 'column', sm: 'row' }}
+        justifyContent="space-between"
+        alignItems={{ xs: 'stretch', sm: 'center' }}
+        gap={2}
+        mb={3}
+      >
+        <Typography variant="h5" component="h3">
+          Audit Log
+        </Typography>
+        <Button
+          variant="outlined"
+          startIcon={<DownloadIcon />}
+          href={auditLogApi.getExportUrl(filter)}
+          data-testid="export-audit-log-btn"
+          fullWidth
+          sx={{ maxWidth: { sm: 'fit-content' } }}
+        >
+          Export JSONL
+        </Button>
+      </Box>
+
+      <Card>
+        <CardContent>
+          <Stack direction={{ xs: 'column', md: 'row' }} spacing={2} mb={2}>
+            <TextField
+              label="Actor"
+              size="small"
+              value={actor}
+              onChange={(e) => resetPage(setActor)(e.target.value)}
+              inputProps={{ 'data-testid': 'filter-actor' }}
+            />
+            <FormControl size="small" sx={{ minWidth: 140 }}>
+              <InputLabel>Action</InputLabel>
+              <Select
+                value={action}
+                label="Action"
+                onChange={(e) => resetPage(setAction)(e.target.value as AuditAction | '')}
+                data-testid="filter-action"
+              >
+                <MenuItem value="">All</MenuItem>
+                {actions.map((value) => (
+                  <MenuItem key={value} value={value}>
+                    {humanize(value)}
+                  </MenuItem>
+                ))}
+              </Select>
+            </FormControl>
+            <FormControl size="small" sx={{ minWidth: 200 }}>
+              <InputLabel>Target</InputLabel>
+              <Select
+                value={targetType}
+                label="Target"
+                onChange={(e) => resetPage(setTargetType)(e.target.value as AuditTargetType | '')}
+                data-testid="filter-target-type"
+              >
+                <MenuItem value="">All</MenuItem>
+                {targetTypes.map((value) => (
+                  <MenuItem key={value} value={value}>
+                    {humanize(value)}
+                  </MenuItem>
+                ))}
+              </Select>
+            </FormControl>
+            <TextField
+              label="From"
+              type="datetime-local"
+              size="small"
+              value={from}
+              onChange={(e) => resetPage(setFrom)(e.target.value)}
+              InputLabelProps={{ shrink: true }}
+            />
+            <TextField
+              label="To"
+              type="datetime-local"
+              size="small"
+              value={to}
+              onChange={(e) => resetPage(setTo)(e.target.value)}
+              InputLabelProps={{ shrink: true }}
+            />
+          </Stack>
+
+          {loading ? (
+            <Box display="flex" justifyContent="center" py={6}>
+              <CircularProgress />
+            </Box>
+          ) : (
+            <TableContainer component={Paper} variant="outlined">
+              <Table size="small">
+                <TableHead>
+                  <TableRow>
+                    <TableCell />
+                    <TableCell>Time</TableCell>
+                    <TableCell>Actor</TableCell>
+                    <TableCell>Action</TableCell>
+                    <TableCell>Target</TableCell>
+                    <TableCell>Source IP</TableCell>
+                  </TableRow>
+                </TableHead>
+                <TableBody>
+                  {entries.length === 0 && (
+                    <TableRow>
+                      <TableCell colSpan={6} align="center">
+                        <Typography color="text.secondary" py={2}>
+                          No audit log entries match the filter
+                        </Typography>
+                      </TableCell>
+                    </TableRow>
+                  )}
+                  {entries.map((entry) => {
+                    const changes = Object.entries(entry.changes ?? {});
+                    const expanded = expandedId === entry.id;
+                    return (
+                      <Fragment key={entry.id}>
+                        <TableRow hover data-testid={`audit-entry-${entry.id}`}>
+                          <TableCell padding="checkbox">
+                            {changes.length > 0 && (
+                              <IconButton
+                                size="small"
+                                aria-label="show changes"
+                                onClick={() => setExpandedId(expanded ? null : entry.id)}
+                              >
+                                {expanded ? <KeyboardArrowUp /> : <KeyboardArrowDown />}
+                              </IconButton>
+                            )}
+                          </TableCell>
+                          <TableCell>{formatDate(entry.created_at)}</TableCell>
+                          <TableCell>
+                            {entry.actor || '—'}
+                            {entry.token_name && (
+                              <Chip label={`token ${entry.token_name}`} size="small" variant="outlined" sx={{ ml: 1 }} />
+                            )}
+                          </TableCell>
+                          <TableCell>
+                            <Chip label={humanize(entry.action)} size="small" color={actionColors[entry.action]} />
+                          </TableCell>
+                          <TableCell>
+                            {humanize(entry.target_type)}
+                            {entry.target_id !== undefined && ` #${entry.target_id}`}
+                            {entry.target_name && (
+                              <Typography component="span" variant="body2" color="text.secondary">
+                                {' '}
+                                {entry.target_name}
+                              </Typography>
+                            )}
+                          </TableCell>
+                          <TableCell>{entry.source_ip}</TableCell>
+                        </TableRow>
+                        {changes.length > 0 && (
+                          <TableRow>
+                            <TableCell colSpan={6} sx={{ py: 0, borderBottom: expanded ? undefined : 'none' }}>
+                              <Collapse in={expanded} unmountOnExit>
+                                <Table size="small" sx={{ my: 1 }} data-testid={`audit-changes-${entry.id}`}>
+                                  <TableHead>
+                                    <TableRow>
+                                      <TableCell>Field</TableCell>
+                                      <TableCell>Before</TableCell>
+                                      <TableCell>After</TableCell>
+                                    </TableRow>
+                                  </TableHead>
+                                  <TableBody>
+                                    {changes.map(([field, change]) => (
+                                      <TableRow key={field}>
+                                        <TableCell sx={{ fontFamily: 'monospace' }}>{field}</TableCell>
+                                        <TableCell sx={{ wordBreak: 'break-all' }}>{formatValue(change.before)}</TableCell>
+                                        <TableCell sx={{ wordBreak: 'break-all' }}>{formatValue(change.after)}</TableCell>
+                                      </TableRow>
+                                    ))}
+                                  </TableBody>
+                                </Table>
+                              </Collapse>
+                            </TableCell>
+                          </TableRow>
+                        )}
+                      </Fragment>
+                    );
+                  })}
+                </TableBody>
+              </Table>
+              <TablePagination
+                component="div"
+                count={total}
+                page={page}
+                onPageChange={(_, nextPage) => setPage(nextPage)}
+                rowsPerPage={rowsPerPage}
+                onRowsPerPageChange={(event) => {
+                  setRowsPerPage(parseInt(event.target.value, 10));
+                  setPage(0);
+                }}
+                rowsPerPageOptions={[10, 25, 50, 100]}
+              />
+            </TableContainer>
+          )}
+        </CardContent>
+      </Card>
+    </Box>
+  );
+}
+
+export default AuditLog;
@@ -0,0 +1,103 @@
+import { Alert, Box, Button, Card, CardContent, Divider, Stack, TextField, Typography } from '@mui/material';
+import { useEffect, useState } from 'react';
+import { authApi } from '../api';
+import type { AuthStatus, User } from '../types';
+
+interface LoginProps {
+  status: AuthStatus;
+  onLoggedIn: (user: User) => void;
+}
+
+function Login({ status, onLoggedIn }: LoginProps) {
+  const setupRequired = status.setup_required;
+  const [error, setError] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  // Failed single sign-on attempts come back with the reason in the URL
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.search);
+    const loginError = params.get('login_error');
+    if (loginError) {
+      setError(loginError);
+      params.delete('login_error');
+      const query = params.toString();
+      window.history.replaceState(null, '', window.location.pathname + (query ? `?${query}` : ''));
+    }
+  }, []);
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    const formData = new FormData(e.currentTarget);
+    const username = formData.get('username') as string;
+    const password = formData.get('password') as string;
+
+    if (setupRequired && password !== formData.get('confirm_password')) {
+      setError('Passwords do not match');
+      return;
+    }
+
+    setSubmitting(true);
+    setError(null);
+    try {
+      const user = setupRequired ? await authApi.setup(username, password) : await authApi.login(username, password);
+      onLoggedIn(user);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Login failed');
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <Box display="flex" justifyContent="center" alignItems="center" minHeight="100vh" bgcolor="#f5f5f5" p={2}>
+      <Card sx={{ width: '100%', maxWidth: 400 }}>
+        <CardContent>
+          <Typography variant="h5" component="h1" fontWeight="bold" gutterBottom>
+            BackApp
+          </Typography>
+          <Typography variant="body2" color="text.secondary" mb={3}>
+            {setupRequired ? 'Create the first administrator account' : 'Sign in to continue'}
+          </Typography>
+          <form onSubmit={handleSubmit}>
+            <Stack spacing={2}>
+              {error && <Alert severity="error">{error}</Alert>}
+              <TextField name="username" label="Username" required fullWidth autoFocus autoComplete="username" />
+              <TextField
+                name="password"
+                label="Password"
+                type="password"
+                required
+                fullWidth
+                autoComplete={setupRequired ? 'new-password' : 'current-password'}
+                helperText={setupRequired ? 'At least 8 characters' : undefined}
+              />
+              {setupRequired && (
+                <TextField
+                  name="confirm_password"
+                  label="Confirm Password"
+                  type="password"
+                  required
+                  fullWidth
+                  autoComplete="new-password"
+                />
+              )}
+              <Button type="submit" variant="contained" disabled={submitting} data-testid="login-submit-btn">
+                {setupRequired ? 'Create Account' : 'Sign In'}
+              </Button>
+            </Stack>
+          </form>
+          {status.oidc_enabled && !setupRequired && (
+            <>
+              <Divider sx={{ my: 2 }}>or</Divider>
+              <Button variant="outlined" fullWidth href="/api/v1/auth/oidc/login" data-testid="oidc-login-btn">
+                Sign in with {status.oidc_provider_name || 'SSO'}
+              </Button>
+            </>
+          )}
+        </CardContent>
+      </Card>
+    </Box>
+  );
+}
+
+export default Login;
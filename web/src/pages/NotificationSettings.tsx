@@ -37,13 +37,23 @@ import {
   backupProfileApi,
   notificationApi,
   serverApi,
+  type DigestFrequency,
   type NotificationPreference,
   type NotificationPreferenceInput,
 } from '../api';
+import { EmailRecipients } from '../components/common/EmailRecipients';
+import { ChatIntegrations } from '../components/common/ChatIntegrations';
+import { Webhooks } from '../components/common/Webhooks';
 import { NotificationBell } from '../components/common/NotificationBell';
-import type { BackupProfile, Server } from '../types';
+import { QuietHoursField } from '../components/common/QuietHoursField';
+import type { BackupProfile, Server, User } from '../types';
+import { isGlobalAdmin } from '../utils/roles';
 
-function NotificationSettings() {
+interface NotificationSettingsProps {
+  user: User;
+}
+
+function NotificationSettings({ user }: NotificationSettingsProps) {
   const [loading, setLoading] = useState(true);
   const [isSupported, setIsSupported] = useState(true);
   const [isSubscribed, setIsSubscribed] = useState(false);
@@ -62,8 +72,12 @@ function NotificationSettings() {
     notify_on_failure: true,
     notify_on_consecutive_failures: true,
     consecutive_failure_threshold: 3,
+    notify_on_missed_backup: true,
     notify_on_low_storage: true,
     low_storage_threshold: 10,
+    digest_frequency: '',
+    quiet_hours_start: '',
+    quiet_hours_end: '',
   });
   const [newPrefScope, setNewPrefScope] = useState<'global' | 'profile' | 'server'>('global');
   const [selectedProfileId, setSelectedProfileId] = useState<number | ''>('');
@@ -75,6 +89,14 @@ function NotificationSettings() {
 
   const loadData = async () => {
     try {
+      // Load servers and profiles for the form
+      const [serversData, profilesData] = await Promise.all([
+        serverApi.list(),
+        backupProfileApi.list(),
+      ]);
+      setServers(serversData);
+      setProfiles(profilesData);
+
       // Check support
       if (!('serviceWorker' in navigator) || !('PushManager' in window)) {
         setIsSupported(false);
@@ -84,14 +106,6 @@ function NotificationSettings() {
 
       setPermission(Notification.permission);
 
-      // Load servers and profiles for the form
-      const [serversData, profilesData] = await Promise.all([
-        serverApi.list(),
-        backupProfileApi.list(),
-      ]);
-      setServers(serversData);
-      setProfiles(profilesData);
-
       // Check subscription
       const registration = await navigator.serviceWorker.getRegistration('/sw.js');
       if (registration) {
@@ -133,8 +147,12 @@ function NotificationSettings() {
         notify_on_failure: updates.notify_on_failure ?? pref.notify_on_failure,
         notify_on_consecutive_failures: updates.notify_on_consecutive_failures ?? pref.notify_on_consecutive_failures,
         consecutive_failure_threshold: updates.consecutive_failure_threshold ?? pref.consecutive_failure_threshold,
+        notify_on_missed_backup: updates.notify_on_missed_backup ?? pref.notify_on_missed_backup,
         notify_on_low_storage: updates.notify_on_low_storage ?? pref.notify_on_low_storage,
         low_storage_threshold: updates.low_storage_threshold ?? pref.low_storage_threshold,
+        digest_frequency: updates.digest_frequency ?? pref.digest_frequency,
+        quiet_hours_start: updates.quiet_hours_start ?? pref.quiet_hours_start,
+        quiet_hours_end: updates.quiet_hours_end ?? pref.quiet_hours_end,
       });
       
       setPreferences(prefs => prefs.map(p => p.id === pref.id ? { ...p, ...updatedPref } : p));
@@ -177,8 +195,12 @@ function NotificationSettings() {
         notify_on_failure: true,
         notify_on_consecutive_failures: true,
         consecutive_failure_threshold: 3,
+        notify_on_missed_backup: true,
         notify_on_low_storage: true,
         low_storage_threshold: 10,
+        digest_frequency: '',
+        quiet_hours_start: '',
+        quiet_hours_end: '',
       });
       setNewPrefScope('global');
       setSelectedProfileId('');
@@ -209,15 +231,25 @@ function NotificationSettings() {
     );
   }
 
+  // Email and webhooks are independent of the browser, so they are configured even without push support
+  const sharedChannels = isGlobalAdmin(user) && (
+    <>
+      <EmailRecipients servers={servers} profiles={profiles} />
+      <ChatIntegrations servers={servers} profiles={profiles} />
+      <Webhooks servers={servers} profiles={profiles} />
+    </>
+  );
+
   if (!isSupported) {
     return (
       <Box>
         <Typography variant="h4" gutterBottom>
           Notification Settings
         </Typography>
-        <Alert severity="error">
+        <Alert severity="error" sx={{ mb: 3 }}>
           Push notifications are not supported in this browser. Please use a modern browser like Chrome, Firefox, or Edge.
         </Alert>
+        {sharedChannels}
       </Box>
     );
   }
@@ -228,9 +260,10 @@ function NotificationSettings() {
         <Typography variant="h4" gutterBottom>
           Notification Settings
         </Typography>
-        <Alert severity="warning">
+        <Alert severity="warning" sx={{ mb: 3 }}>
           Notifications are blocked by your browser. Please enable notifications in your browser settings to receive backup alerts.
         </Alert>
+        {sharedChannels}
       </Box>
     );
   }
@@ -413,6 +446,60 @@ function NotificationSettings() {
                       )}
                     </Grid>
 
+                    <Grid size={{ xs: 12, md: 6 }}>
+                      <Typography variant="subtitle2" gutterBottom>Missed Backups</Typography>
+                      <FormControlLabel
+                        control={
+                          <Checkbox
+                            checked={newPref.notify_on_missed_backup}
+                            onChange={(e) => setNewPref({ ...newPref, notify_on_missed_backup: e.target.checked })}
+                          />
+                        }
+                        label="Notify when a scheduled backup does not run"
+                      />
+                    </Grid>
+
+                    <Grid size={{ xs: 12, md: 6 }}>
+                      <Typography variant="subtitle2" gutterBottom>Digest</Typography>
+                      <FormControl size="small" sx={{ minWidth: 200 }}>
+                        <InputLabel>Digest</InputLabel>
+                        <Select
+                          value={newPref.digest_frequency}
+                          label="Digest"
+                          onChange={(e) => setNewPref({ ...newPref, digest_frequency: e.target.value as DigestFrequency })}
+                        >
+                          <MenuItem value="">No digest</MenuItem>
+                          <MenuItem value="daily">Daily summary</MenuItem>
+                          <MenuItem value="weekly">Weekly summary</MenuItem>
+                        </Select>
+                      </FormControl>
+                    </Grid>
+
+                    <Grid size={{ xs: 12, md: 6 }}>
+                      <Typography variant="subtitle2" gutterBottom>Quiet Hours</Typography>
+                      <Box display="flex" gap={1}>
+                        <TextField
+                          type="time"
+                          size="small"
+                          label="From"
+                          value={newPref.quiet_hours_start}
+                          onChange={(e) => setNewPref({ ...newPref, quiet_hours_start: e.target.value })}
+                          InputLabelProps={{ shrink: true }}
+                        />
+                        <TextField
+                          type="time"
+                          size="small"
+                          label="Until"
+                          value={newPref.quiet_hours_end}
+                          onChange={(e) => setNewPref({ ...newPref, quiet_hours_end: e.target.value })}
+                          InputLabelProps={{ shrink: true }}
+                        />
+                      </Box>
+                      <Typography variant="caption" color="text.secondary">
+                        Only failures are sent during quiet hours, everything else arrives together afterwards
+                      </Typography>
+                    </Grid>
+
                     {newPrefScope === 'global' && (
                       <Grid size={{ xs: 12 }}>
                         <Typography variant="subtitle2" gutterBottom>Storage Alerts</Typography>
@@ -452,7 +539,8 @@ function NotificationSettings() {
                       onClick={handleAddPreference}
                       disabled={
                         (newPrefScope === 'profile' && !selectedProfileId) ||
-                        (newPrefScope === 'server' && !selectedServerId)
+                        (newPrefScope === 'server' && !selectedServerId) ||
+                        (newPref.quiet_hours_start === '') !== (newPref.quiet_hours_end === '')
                       }
                     >
                       Add Rule
@@ -470,14 +558,17 @@ function NotificationSettings() {
                       <TableCell>Success</TableCell>
                       <TableCell>Failure</TableCell>
                       <TableCell>Consecutive</TableCell>
+                      <TableCell>Missed</TableCell>
                       <TableCell>Low Storage</TableCell>
+                      <TableCell>Digest</TableCell>
+                      <TableCell>Quiet Hours</TableCell>
                       <TableCell align="right">Actions</TableCell>
                     </TableRow>
                   </TableHead>
                   <TableBody>
                     {preferences.length === 0 ? (
                       <TableRow>
-                        <TableCell colSpan={7} align="center">
+                        <TableCell colSpan={10} align="center">
                           <Typography color="text.secondary" py={2}>
                             No notification rules configured. Click "Add Rule" to create one.
                           </Typography>
@@ -538,6 +629,13 @@ function NotificationSettings() {
                               )}
                             </Box>
                           </TableCell>
+                          <TableCell>
+                            <Switch
+                              size="small"
+                              checked={pref.notify_on_missed_backup}
+                              onChange={(e) => handleUpdatePreference(pref, { notify_on_missed_backup: e.target.checked })}
+                            />
+                          </TableCell>
                           <TableCell>
                             {!pref.backup_profile_id && !pref.server_id && (
                               <Box display="flex" alignItems="center" gap={1}>
@@ -552,6 +650,27 @@ function NotificationSettings() {
                               </Box>
                             )}
                           </TableCell>
+                          <TableCell>
+                            <Select
+                              size="small"
+                              variant="standard"
+                              value={pref.digest_frequency || ''}
+                              onChange={(e) => handleUpdatePreference(pref, { digest_frequency: e.target.value as DigestFrequency })}
+                              displayEmpty
+                            >
+                              <MenuItem value="">Off</MenuItem>
+                              <MenuItem value="daily">Daily</MenuItem>
+                              <MenuItem value="weekly">Weekly</MenuItem>
+                            </Select>
+                          </TableCell>
+                          <TableCell>
+                            <QuietHoursField
+                              name="Push"
+                              start={pref.quiet_hours_start || ''}
+                              end={pref.quiet_hours_end || ''}
+                              onChange={(start, end) => handleUpdatePreference(pref, { quiet_hours_start: start, quiet_hours_end: end })}
+                            />
+                          </TableCell>
                           <TableCell align="right">
                             <Tooltip title="Delete rule">
                               <IconButton
@@ -606,7 +725,16 @@ function NotificationSettings() {
                   <Paper variant="outlined" sx={{ p: 2 }}>
                     <Typography variant="subtitle2" color="warning.main">Consecutive Failures</Typography>
                     <Typography variant="body2" color="text.secondary">
-                      Notifies when a backup profile fails multiple times in a row.
+                      Notifies once when a backup profile fails as often in a row as the rule allows, and again
+                      after a day if it keeps failing.
+                    </Typography>
+                  </Paper>
+                </Grid>
+                <Grid size={{ xs: 12, md: 6 }}>
+                  <Paper variant="outlined" sx={{ p: 2 }}>
+                    <Typography variant="subtitle2" color="success.main">Backup Recovered</Typography>
+                    <Typography variant="body2" color="text.secondary">
+                      Notifies when a backup succeeds again after failing, if you were told about the failures.
                     </Typography>
                   </Paper>
                 </Grid>
@@ -614,7 +742,7 @@ function NotificationSettings() {
                   <Paper variant="outlined" sx={{ p: 2 }}>
                     <Typography variant="subtitle2" color="info.main">Low Storage</Typography>
                     <Typography variant="body2" color="text.secondary">
-                      Notifies when storage space falls below the configured threshold.
+                      Notifies when storage space falls below the configured threshold, at most once a day.
                     </Typography>
                   </Paper>
                 </Grid>
@@ -623,6 +751,8 @@ function NotificationSettings() {
           </Card>
         </>
       )}
+
+      <Box mt={3}>{sharedChannels}</Box>
     </Box>
   );
 }
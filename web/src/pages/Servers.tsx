@@ -65,6 +65,23 @@ function Servers() {
         if (password) {
           updates.password = password;
         }
+        const keyfile = formData.get('keyfile') as File | null;
+        if (keyfile && keyfile.size > 0) {
+          updates.private_key = await keyfile.text();
+        }
+        const passphrase = formData.get('key_passphrase') as string;
+        if (passphrase) {
+          updates.key_passphrase = passphrase;
+        }
+        // Fields of other auth types are not rendered and clear the stored value
+        updates.key_file_path = (formData.get('key_file_path') as string) || '';
+        updates.agent_socket = (formData.get('agent_socket') as string) || '';
+        updates.secret_command = (formData.get('secret_command') as string) || '';
+        updates.certificate = (formData.get('certificate') as string) || '';
+        const fallbacks = (formData.get('fallback_auth_types') as string) || '';
+        updates.fallback_auth_types = fallbacks ? fallbacks.split(',') : [];
+        const jumpHostId = formData.get('jump_host_id') as string;
+        updates.jump_host_id = jumpHostId ? parseInt(jumpHostId) : null;
 
         await serverApi.update(editingServer.id, updates);
       } else {
@@ -146,9 +163,10 @@ function Servers() {
       });
       loadServers();
     } catch (error) {
+      const usedAsJumpHost = servers.some((s) => s.jump_host_id === serverToDelete.id);
       setSnackbar({
         open: true,
-        message: 'Failed to delete server',
+        message: usedAsJumpHost ? 'Failed to delete server: it is the jump host of other servers' : 'Failed to delete server',
         severity: 'error',
       });
     } finally {
@@ -246,6 +264,7 @@ function Servers() {
         }}
         onSubmit={handleSubmit}
         server={editingServer || undefined}
+        servers={servers}
       />
 
       <ServerList
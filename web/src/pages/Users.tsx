@@ -0,0 +1,304 @@
+import { Add as AddIcon, Delete as DeleteIcon, Key as KeyIcon } from '@mui/icons-material';
+import {
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Chip,
+  CircularProgress,
+  FormControl,
+  IconButton,
+  InputLabel,
+  List,
+  ListItem,
+  ListItemText,
+  MenuItem,
+  Select,
+  Stack,
+  TextField,
+  Typography,
+} from '@mui/material';
+import { useEffect, useState } from 'react';
+import { backupProfileApi, serverApi, userApi } from '../api';
+import type { BackupProfile, Role, RoleAssignment, Server, User } from '../types';
+
+type RoleScope = 'global' | 'server' | 'profile';
+
+const describeRole = (assignment: RoleAssignment) => {
+  if (assignment.backup_profile_id) {
+    return `${assignment.role} of profile ${assignment.backup_profile?.name ?? assignment.backup_profile_id}`;
+  }
+  if (assignment.server_id) {
+    return `${assignment.role} of server ${assignment.server?.name ?? assignment.server_id}`;
+  }
+  return `${assignment.role} (global)`;
+};
+
+// Roles synced from identity provider groups are replaced at the user's next sign-in
+const roleLabel = (assignment: RoleAssignment) =>
+  assignment.source === 'oidc' ? `${describeRole(assignment)} · SSO` : describeRole(assignment);
+
+function Users() {
+  const [users, setUsers] = useState<User[]>([]);
+  const [servers, setServers] = useState<Server[]>([]);
+  const [profiles, setProfiles] = useState<BackupProfile[]>([]);
+  const [showForm, setShowForm] = useState(false);
+  const [newUserRole, setNewUserRole] = useState<Role | ''>('viewer');
+  const [roleFormUserId, setRoleFormUserId] = useState<number | null>(null);
+  const [role, setRole] = useState<Role>('viewer');
+  const [roleScope, setRoleScope] = useState<RoleScope>('global');
+  const [roleTargetId, setRoleTargetId] = useState<number | ''>('');
+  const [loading, setLoading] = useState(true);
+
+  useEffect(() => {
+    loadUsers();
+    Promise.all([serverApi.list(), backupProfileApi.list()])
+      .then(([serversData, profilesData]) => {
+        setServers(serversData || []);
+        setProfiles(profilesData || []);
+      })
+      .catch((error) => console.error('Error loading servers and profiles:', error));
+  }, []);
+
+  const loadUsers = async () => {
+    try {
+      const data = await userApi.list();
+      setUsers(data || []);
+    } catch (error) {
+      console.error('Error loading users:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    const formData = new FormData(e.currentTarget);
+    try {
+      await userApi.create(
+        formData.get('username') as string,
+        formData.get('password') as string,
+        newUserRole || undefined
+      );
+      setShowForm(false);
+      loadUsers();
+    } catch (error) {
+      console.error('Error creating user:', error);
+      alert('Failed to create user. Usernames must be unique and passwords at least 8 characters.');
+    }
+  };
+
+  const handleSetPassword = async (user: User) => {
+    const password = prompt(`New password for ${user.username}`);
+    if (!password) return;
+    try {
+      await userApi.setPassword(user.id, password);
+    } catch (error) {
+      console.error('Error setting password:', error);
+      alert('Failed to set password. Passwords must be at least 8 characters.');
+    }
+  };
+
+  const openRoleForm = (user: User) => {
+    setRoleFormUserId(roleFormUserId === user.id ? null : user.id);
+    setRole('viewer');
+    setRoleScope('global');
+    setRoleTargetId('');
+  };
+
+  const handleAddRole = async (e: React.FormEvent<HTMLFormElement>, user: User) => {
+    e.preventDefault();
+    if (roleScope !== 'global' && roleTargetId === '') return;
+    try {
+      await userApi.addRole(user.id, {
+        role,
+        server_id: roleScope === 'server' ? (roleTargetId as number) : undefined,
+        backup_profile_id: roleScope === 'profile' ? (roleTargetId as number) : undefined,
+      });
+      setRoleFormUserId(null);
+      loadUsers();
+    } catch (error) {
+      console.error('Error adding role:', error);
+      alert('Failed to add role');
+    }
+  };
+
+  const handleRemoveRole = async (user: User, assignment: RoleAssignment) => {
+    try {
+      await userApi.removeRole(user.id, assignment.id);
+      loadUsers();
+    } catch (error) {
+      console.error('Error removing role:', error);
+      alert('Failed to remove role. At least one user must remain a global admin.');
+    }
+  };
+
+  const handleDelete = async (user: User) => {
+    if (!confirm(`Are you sure you want to delete ${user.username}?`)) return;
+    try {
+      await userApi.delete(user.id);
+      loadUsers();
+    } catch (error) {
+      console.error('Error deleting user:', error);
+    }
+  };
+
+  if (loading) {
+    return (
+      <Box display="flex" justifyContent="center" alignItems="center" py={12}>
+        <CircularProgress />
+      </Box>
+    );
+  }
+
+  return (
+    <Box>
+      <Box
+        display="flex"
+        flexDirection={{ xs: 'column', sm: 'row' }}
+        justifyContent="space-between"
+        alignItems={{ xs: 'stretch', sm: 'center' }}
+        gap={2}
+        mb={3}
+      >
+        <Typography variant="h5" component="h3">
+          Users
+        </Typography>
+        <Button
+          variant="contained"
+          startIcon={<AddIcon />}
+          onClick={() => setShowForm(!showForm)}
+          data-testid="add-user-btn"
+          fullWidth
+          sx={{ maxWidth: { sm: 'fit-content' } }}
+        >
+          Add User
+        </Button>
+      </Box>
+
+      <Card>
+        <CardContent>
+          {showForm && (
+            <form onSubmit={handleSubmit}>
+              <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2} mb={2}>
+                <TextField name="username" label="Username" required size="small" autoComplete="off" />
+                <TextField name="password" label="Password" type="password" required size="small" autoComplete="new-password" />
+                <FormControl size="small" sx={{ minWidth: 160 }}>
+                  <InputLabel>Global Role</InputLabel>
+                  <Select value={newUserRole} label="Global Role" onChange={(e) => setNewUserRole(e.target.value as Role | '')}>
+                    <MenuItem value="">None</MenuItem>
+                    <MenuItem value="viewer">Viewer</MenuItem>
+                    <MenuItem value="operator">Operator</MenuItem>
+                    <MenuItem value="admin">Admin</MenuItem>
+                  </Select>
+                </FormControl>
+                <Button type="submit" variant="contained" size="small">
+                  Create
+                </Button>
+              </Stack>
+            </form>
+          )}
+
+          <List>
+            {users.map((user) => (
+              <ListItem
+                key={user.id}
+                divider
+                secondaryAction={
+                  <Box display="flex" gap={0.5}>
+                    {!user.oidc_subject && (
+                      <IconButton aria-label="set password" onClick={() => handleSetPassword(user)}>
+                        <KeyIcon />
+                      </IconButton>
+                    )}
+                    <IconButton aria-label="delete" onClick={() => handleDelete(user)}>
+                      <DeleteIcon />
+                    </IconButton>
+                  </Box>
+                }
+              >
+                <ListItemText
+                  primary={
+                    <Box component="span" display="flex" gap={1} alignItems="center">
+                      {user.username}
+                      {user.oidc_subject && <Chip label="SSO" size="small" color="info" variant="outlined" />}
+                    </Box>
+                  }
+                  secondary={
+                    <Box component="span" display="flex" flexDirection="column" gap={1} mt={0.5}>
+                      <span>
+                        {user.last_login_at ? `Last login ${new Date(user.last_login_at).toLocaleString()}` : 'Never signed in'}
+                      </span>
+                      <Box component="span" display="flex" gap={0.5} flexWrap="wrap" alignItems="center">
+                        {(user.roles ?? []).map((assignment) => (
+                          <Chip
+                            key={assignment.id}
+                            label={roleLabel(assignment)}
+                            size="small"
+                            onDelete={() => handleRemoveRole(user, assignment)}
+                          />
+                        ))}
+                        <Chip label="Add role" size="small" variant="outlined" icon={<AddIcon />} onClick={() => openRoleForm(user)} />
+                      </Box>
+                      {roleFormUserId === user.id && (
+                        <Box component="form" onSubmit={(e: React.FormEvent<HTMLFormElement>) => handleAddRole(e, user)}>
+                          <Stack direction={{ xs: 'column', sm: 'row' }} spacing={1} mt={1}>
+                            <FormControl size="small" sx={{ minWidth: 130 }}>
+                              <InputLabel>Role</InputLabel>
+                              <Select value={role} label="Role" onChange={(e) => setRole(e.target.value as Role)}>
+                                <MenuItem value="viewer">Viewer</MenuItem>
+                                <MenuItem value="operator">Operator</MenuItem>
+                                <MenuItem value="admin">Admin</MenuItem>
+                              </Select>
+                            </FormControl>
+                            <FormControl size="small" sx={{ minWidth: 130 }}>
+                              <InputLabel>Applies to</InputLabel>
+                              <Select
+                                value={roleScope}
+                                label="Applies to"
+                                onChange={(e) => {
+                                  setRoleScope(e.target.value as RoleScope);
+                                  setRoleTargetId('');
+                                }}
+                              >
+                                <MenuItem value="global">Everything</MenuItem>
+                                <MenuItem value="server">Server</MenuItem>
+                                <MenuItem value="profile">Backup Profile</MenuItem>
+                              </Select>
+                            </FormControl>
+                            {roleScope !== 'global' && (
+                              <FormControl size="small" sx={{ minWidth: 180 }}>
+                                <InputLabel>{roleScope === 'server' ? 'Server' : 'Backup Profile'}</InputLabel>
+                                <Select
+                                  value={roleTargetId}
+                                  label={roleScope === 'server' ? 'Server' : 'Backup Profile'}
+                                  onChange={(e) => setRoleTargetId(e.target.value as number)}
+                                >
+                                  {(roleScope === 'server' ? servers : profiles).map((item) => (
+                                    <MenuItem key={item.id} value={item.id}>
+                                      {item.name}
+                                    </MenuItem>
+                                  ))}
+                                </Select>
+                              </FormControl>
+                            )}
+                            <Button type="submit" variant="contained" size="small">
+                              Add
+                            </Button>
+                          </Stack>
+                        </Box>
+                      )}
+                    </Box>
+                  }
+                  secondaryTypographyProps={{ component: 'div' }}
+                />
+              </ListItem>
+            ))}
+          </List>
+        </CardContent>
+      </Card>
+    </Box>
+  );
+}
+
+export default Users;
@@ -0,0 +1,27 @@
+import type { BackupProfile } from './backup-profile';
+import type { User } from './user';
+
+export type ApiTokenScope = 'read-only' | 'trigger-runs' | 'admin';
+
+export interface ApiToken {
+  id: number;
+  name: string;
+  user_id: number;
+  scope: ApiTokenScope;
+  token_prefix: string;
+  expires_at?: string;
+  last_used_at?: string;
+  created_at: string;
+  restrict_to_profiles: boolean;
+  backup_profiles?: BackupProfile[];
+  user?: User;
+  /** Only present in the response to creating the token */
+  token?: string;
+}
+
+export interface ApiTokenCreateInput {
+  name: string;
+  scope: ApiTokenScope;
+  profile_ids?: number[];
+  expires_at?: string;
+}
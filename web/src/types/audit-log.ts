@@ -0,0 +1,59 @@
+export type AuditAction = 'create' | 'update' | 'delete' | 'run' | 'dry_run' | 'download';
+
+export type AuditTargetType =
+  | 'server'
+  | 'storage_location'
+  | 'naming_rule'
+  | 'backup_profile'
+  | 'command'
+  | 'file_rule'
+  | 'backup_run'
+  | 'backup_file'
+  | 'user'
+  | 'role_assignment'
+  | 'api_token'
+  | 'notification_preference'
+  | 'push_subscription'
+  | 'email_recipient'
+  | 'webhook'
+  | 'chat_integration';
+
+/** Old and new value of a field; secrets are shown as "[redacted]" */
+export interface AuditChange {
+  before: unknown;
+  after: unknown;
+}
+
+export interface AuditLogEntry {
+  id: number;
+  created_at: string;
+  user_id?: number;
+  actor: string;
+  /** Set when the request was made with an API token */
+  token_name?: string;
+  action: AuditAction;
+  target_type: AuditTargetType;
+  target_id?: number;
+  target_name?: string;
+  changes?: Record<string, AuditChange>;
+  source_ip: string;
+  method: string;
+  path: string;
+}
+
+export interface AuditLogFilter {
+  actor?: string;
+  action?: AuditAction;
+  target_type?: AuditTargetType;
+  target_id?: number;
+  /** RFC 3339 timestamps; from is inclusive, to exclusive */
+  from?: string;
+  to?: string;
+}
+
+export interface AuditLogPage {
+  entries: AuditLogEntry[];
+  total: number;
+  page: number;
+  page_size: number;
+}
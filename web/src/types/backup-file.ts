@@ -6,6 +6,12 @@ export interface BackupFile {
   size_bytes?: number;
   file_size?: number;
   checksum?: string;
+  file_type?: 'file' | 'symlink' | 'dir';
+  mode?: number;
+  uid?: number;
+  gid?: number;
+  mod_time?: string;
+  link_target?: string;
   deleted?: boolean;
   deleted_at?: string;
   created_at: string;
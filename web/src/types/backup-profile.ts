@@ -13,6 +13,8 @@ export interface BackupProfile {
   naming_rule_id: number;
   schedule_cron?: string;
   retention_days?: number | null;
+  missed_backup_grace_minutes?: number | null;
+  heartbeat_url?: string;
   enabled: boolean;
   created_at: string;
   server?: Server;
@@ -30,6 +32,8 @@ export interface BackupProfileCreateInput {
   naming_rule_id: number;
   schedule_cron?: string;
   retention_days?: number | null;
+  missed_backup_grace_minutes?: number | null;
+  heartbeat_url?: string;
   enabled: boolean;
 }
 
@@ -40,5 +44,38 @@ export interface BackupProfileUpdateInput {
   naming_rule_id?: number;
   schedule_cron?: string;
   retention_days?: number | null;
+  missed_backup_grace_minutes?: number | null;
+  heartbeat_url?: string;
   enabled?: boolean;
 }
+
+export interface DryRunFile {
+  file_rule_id: number;
+  remote_path: string;
+  local_path: string;
+  size_bytes: number;
+  file_type: 'file' | 'symlink' | 'dir';
+}
+
+export interface DryRunRule {
+  file_rule_id: number;
+  remote_path: string;
+  file_count: number;
+  total_size_bytes: number;
+  error?: string;
+}
+
+export interface BackupDryRun {
+  backup_profile_id: number;
+  backup_path: string;
+  rules: DryRunRule[];
+  files: DryRunFile[];
+  commands: Command[];
+  total_files: number;
+  total_size_bytes: number;
+  storage_location_id: number;
+  storage_free_bytes: number;
+  storage_total_bytes: number;
+  fits: boolean;
+  warnings: string[];
+}
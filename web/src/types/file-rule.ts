@@ -3,18 +3,27 @@ export interface FileRule {
   backup_profile_id: number;
   remote_path: string;
   recursive: boolean;
+  include_pattern?: string;
   exclude_pattern?: string;
+  max_file_size_bytes?: number;
+  modified_since?: string;
   created_at: string;
 }
 
 export interface FileRuleCreateInput {
   remote_path: string;
   recursive: boolean;
+  include_pattern?: string;
   exclude_pattern?: string;
+  max_file_size_bytes?: number;
+  modified_since?: string;
 }
 
 export interface FileRuleUpdateInput {
   remote_path?: string;
   recursive?: boolean;
+  include_pattern?: string;
   exclude_pattern?: string;
+  max_file_size_bytes?: number;
+  modified_since?: string;
 }
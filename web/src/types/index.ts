@@ -8,3 +8,6 @@ export * from './backup-run';
 export * from './backup-run-log';
 export * from './backup-profile';
 export * from './deletion-impact';
+export * from './user';
+export * from './api-token';
+export * from './audit-log';
@@ -1,13 +1,34 @@
 
+/** Where the SSH credentials of a server come from */
+export type ServerAuthType =
+  | 'password'
+  | 'key'
+  | 'key_file'
+  | 'agent'
+  | 'password_command'
+  | 'key_command'
+  | 'keyboard_interactive';
+
 export interface Server {
   id: number;
   name: string;
   host: string;
   port: number;
   username: string;
-  auth_type: 'password' | 'key';
-  password?: string;
-  keyfile?: string;
+  auth_type: ServerAuthType;
+  key_file_path?: string;
+  agent_socket?: string;
+  secret_command?: string;
+  /** Tried in order when auth_type is rejected */
+  fallback_auth_types?: ServerAuthType[];
+  /** OpenSSH user certificate presented with the key */
+  certificate?: string;
+  /** Server this one is reached through */
+  jump_host_id?: number;
+  /** Credentials are write-only; the API only reports whether they are stored */
+  has_password: boolean;
+  has_private_key: boolean;
+  has_key_passphrase: boolean;
   created_at: string;
 }
 
@@ -16,7 +37,15 @@ export interface ServerCreateInput {
   host: string;
   port: number;
   username: string;
-  auth_type: 'password' | 'key';
+  auth_type: ServerAuthType;
   password?: string;
   keyfile?: string;
+  private_key?: string;
+  key_passphrase?: string;
+  key_file_path?: string;
+  agent_socket?: string;
+  secret_command?: string;
+  fallback_auth_types?: ServerAuthType[];
+  certificate?: string;
+  jump_host_id?: number | null;
 }
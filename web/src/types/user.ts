@@ -0,0 +1,40 @@
+import type { BackupProfile } from './backup-profile';
+import type { Server } from './server';
+
+export type Role = 'viewer' | 'operator' | 'admin';
+
+/** A role granted globally, for a server and its profiles, or for a single profile */
+export interface RoleAssignment {
+  id: number;
+  user_id: number;
+  role: Role;
+  server_id?: number;
+  backup_profile_id?: number;
+  /** 'oidc' for roles synced from single sign-on groups on every login */
+  source?: string;
+  created_at: string;
+  server?: Server;
+  backup_profile?: BackupProfile;
+}
+
+export interface RoleAssignmentInput {
+  role: Role;
+  server_id?: number;
+  backup_profile_id?: number;
+}
+
+export interface User {
+  id: number;
+  username: string;
+  /** Set for users provisioned by single sign-on */
+  oidc_subject?: string;
+  last_login_at?: string;
+  created_at: string;
+  roles?: RoleAssignment[];
+}
+
+export interface AuthStatus {
+  setup_required: boolean;
+  oidc_enabled: boolean;
+  oidc_provider_name?: string;
+}
@@ -0,0 +1,5 @@
+import type { User } from '../types';
+
+/** Whether a user holds the admin role globally rather than for a server or profile */
+export const isGlobalAdmin = (user: User): boolean =>
+  (user.roles ?? []).some((r) => r.role === 'admin' && !r.server_id && !r.backup_profile_id);
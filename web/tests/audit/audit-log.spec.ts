@@ -0,0 +1,209 @@
+/**
+ * Audit Log Tests
+ *
+ * Tests that changes, manual runs and downloads are recorded with redacted secrets, and
+ * that the log can be filtered, paged and exported but never changed
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import {
+  createApiTokenViaApi,
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+} from '../helpers/api-helpers';
+import { TEST_BASE_PATH } from '../helpers/fs-helpers';
+
+interface AuditEntry {
+  id: number;
+  actor: string;
+  token_name?: string;
+  action: string;
+  target_type: string;
+  target_id?: number;
+  target_name?: string;
+  changes?: Record<string, { before: unknown; after: unknown }>;
+  source_ip: string;
+}
+
+async function listAuditLog(request: APIRequestContext, query = ''): Promise<{ entries: AuditEntry[]; total: number }> {
+  const response = await request.get(`/api/v1/audit-log${query}`);
+  expect(response.ok()).toBeTruthy();
+  return response.json();
+}
+
+test.describe('Audit Log', () => {
+  let serverId: number;
+  let profileId: number;
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    // The server is never connected to: runs are only created, not executed
+    serverId = await createServerViaApi(request, 'Audit Server', '127.0.0.1', 2254, 'testuser', 'audit-secret');
+    const storageId = await createStorageLocationViaApi(request, 'Audit Storage', `${TEST_BASE_PATH}/audit-log`);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Audit Naming', '{profile}-{TIMESTAMP}');
+    profileId = await createBackupProfileViaApi(request, 'Audit Profile', serverId, storageId, namingRuleId, [
+      { remote_path: '/data' },
+    ]);
+  });
+
+  test('records changes with secrets redacted', async ({ request }) => {
+    const update = await request.put(`/api/v1/servers/${serverId}`, {
+      data: {
+        name: 'Renamed Server',
+        host: '127.0.0.1',
+        port: 2254,
+        username: 'testuser',
+        auth_type: 'password',
+        password: 'new-secret',
+      },
+    });
+    expect(update.ok()).toBeTruthy();
+
+    const { entries } = await listAuditLog(request, `?target_type=server&target_id=${serverId}`);
+    expect(entries.map((e) => e.action)).toEqual(['update', 'create']);
+
+    const [updated, created] = entries;
+    expect(updated.actor).toBe('admin');
+    expect(updated.target_name).toBe('Renamed Server');
+    expect(updated.source_ip).toBeTruthy();
+    expect(updated.changes?.name).toEqual({ before: 'Audit Server', after: 'Renamed Server' });
+    expect(updated.changes?.password).toEqual({ before: '[redacted]', after: '[redacted]' });
+    // Unchanged fields are left out
+    expect(updated.changes?.host).toBeUndefined();
+    expect(created.changes?.password).toEqual({ before: null, after: '[redacted]' });
+
+    const body = JSON.stringify(entries);
+    expect(body).not.toContain('audit-secret');
+    expect(body).not.toContain('new-secret');
+  });
+
+  test('keeps the name of deleted targets', async ({ request }) => {
+    const profiles = await request.delete(`/api/v1/backup-profiles/${profileId}`);
+    expect(profiles.ok()).toBeTruthy();
+
+    const { entries } = await listAuditLog(request, '?action=delete');
+    expect(entries).toHaveLength(1);
+    expect(entries[0].target_type).toBe('backup_profile');
+    expect(entries[0].target_id).toBe(profileId);
+    expect(entries[0].target_name).toBe('Audit Profile');
+    expect(entries[0].changes?.name).toEqual({ before: 'Audit Profile', after: null });
+  });
+
+  test('records removing a push subscription', async ({ request }) => {
+    const endpoint = 'https://push.example.com/audit-subscription';
+    const subscribe = await request.post('/api/v1/notifications/subscribe', {
+      data: { endpoint, keys: { p256dh: 'audit-p256dh', auth: 'audit-auth' } },
+    });
+    expect(subscribe.ok()).toBeTruthy();
+    const subscription = await subscribe.json();
+    expect((await request.post('/api/v1/notifications/unsubscribe', { data: { endpoint } })).ok()).toBeTruthy();
+
+    const { entries } = await listAuditLog(request, '?target_type=push_subscription');
+    expect(entries.map((e) => e.action)).toEqual(['delete', 'create']);
+    expect(entries[0].target_id).toBe(subscription.id);
+    expect(entries[0].target_name).toBe(endpoint);
+    expect(entries[0].changes?.auth).toEqual({ before: '[redacted]', after: null });
+  });
+
+  test('records manual runs with the token that started them', async ({ request, baseURL }) => {
+    const { token } = await createApiTokenViaApi(request, { name: 'ci', scope: 'trigger-runs' });
+    const api = await playwrightRequest.newContext({
+      baseURL,
+      storageState: { cookies: [], origins: [] },
+      extraHTTPHeaders: { Authorization: `Bearer ${token}` },
+    });
+    expect((await api.post(`/api/v1/backup-profiles/${profileId}/run`)).status()).toBe(201);
+    await api.dispose();
+
+    const { entries } = await listAuditLog(request, '?action=run');
+    expect(entries).toHaveLength(1);
+    expect(entries[0]).toMatchObject({
+      actor: 'admin',
+      token_name: 'ci',
+      target_type: 'backup_profile',
+      target_id: profileId,
+      target_name: 'Audit Profile',
+    });
+    expect(entries[0].changes).toBeUndefined();
+  });
+
+  test('does not record failed requests', async ({ request }) => {
+    const before = await listAuditLog(request);
+    expect((await request.put('/api/v1/servers/9999', { data: { name: 'x' } })).ok()).toBeFalsy();
+    expect((await request.post('/api/v1/naming-rules', { data: {} })).ok()).toBeFalsy();
+    const after = await listAuditLog(request);
+    expect(after.total).toBe(before.total);
+  });
+
+  test('filters and pages entries', async ({ request }) => {
+    for (let i = 0; i < 3; i++) {
+      await createNamingRuleViaApi(request, `Rule ${i}`, `{profile}-${i}`);
+    }
+
+    const all = await listAuditLog(request, '?target_type=naming_rule');
+    expect(all.total).toBe(4);
+
+    const first = await listAuditLog(request, '?target_type=naming_rule&page_size=3');
+    const second = await listAuditLog(request, '?target_type=naming_rule&page_size=3&page=2');
+    expect(first.entries).toHaveLength(3);
+    expect(second.entries).toHaveLength(1);
+    expect(first.entries[0].target_name).toBe('Rule 2');
+    expect(second.entries[0].target_name).toBe('Audit Naming');
+
+    const future = encodeURIComponent(new Date(Date.now() + 60_000).toISOString());
+    expect((await listAuditLog(request, `?from=${future}`)).total).toBe(0);
+    expect((await listAuditLog(request, '?actor=nobody')).total).toBe(0);
+    expect((await request.get('/api/v1/audit-log?from=yesterday')).status()).toBe(400);
+  });
+
+  test('exports matching entries as JSON lines', async ({ request }) => {
+    const response = await request.get('/api/v1/audit-log/export?action=create');
+    expect(response.ok()).toBeTruthy();
+    expect(response.headers()['content-type']).toContain('application/x-ndjson');
+    expect(response.headers()['content-disposition']).toContain('audit-log.jsonl');
+
+    const lines = (await response.text()).trim().split('\n').map((line) => JSON.parse(line) as AuditEntry);
+    expect(lines.map((e) => e.target_type)).toEqual(['backup_profile', 'naming_rule', 'storage_location', 'server']);
+    expect(lines.every((e) => e.action === 'create')).toBeTruthy();
+  });
+
+  test('cannot be changed and is only visible to global admins', async ({ request, baseURL }) => {
+    const { entries } = await listAuditLog(request);
+    expect((await request.delete(`/api/v1/audit-log/${entries[0].id}`)).ok()).toBeFalsy();
+    expect((await request.put(`/api/v1/audit-log/${entries[0].id}`, { data: {} })).ok()).toBeFalsy();
+
+    const username = `audit-viewer-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'viewer-password', role: 'viewer' } })).json();
+    const viewer = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    try {
+      expect((await viewer.post('/api/v1/auth/login', { data: { username, password: 'viewer-password' } })).ok()).toBeTruthy();
+      expect((await viewer.get('/api/v1/audit-log')).status()).toBe(403);
+      expect((await viewer.get('/api/v1/audit-log/export')).status()).toBe(403);
+    } finally {
+      await viewer.dispose();
+      await request.delete(`/api/v1/users/${user.id}`);
+    }
+
+    // Creating and deleting the user was recorded as well
+    const users = await listAuditLog(request, '?target_type=user');
+    expect(users.entries.map((e) => e.action)).toEqual(['delete', 'create']);
+    expect(users.entries[0].target_name).toBe(username);
+  });
+
+  test('shows entries and their changes on the audit log page', async ({ page }) => {
+    await page.goto('/audit-log');
+    await expect(page.getByRole('heading', { name: 'Audit Log' })).toBeVisible();
+    await expect(page.getByText('Audit Server')).toBeVisible();
+
+    const { entries } = await listAuditLog(page.request, '?target_type=server');
+    const row = page.getByTestId(`audit-entry-${entries[0].id}`);
+    await row.getByRole('button', { name: 'show changes' }).click();
+    const changes = page.getByTestId(`audit-changes-${entries[0].id}`);
+    await expect(changes.getByText('[redacted]')).toBeVisible();
+
+    await page.getByTestId('filter-actor').fill('nobody');
+    await expect(page.getByText('No audit log entries match the filter')).toBeVisible();
+  });
+});
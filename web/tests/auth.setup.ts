@@ -0,0 +1,22 @@
+/**
+ * Authentication Setup
+ *
+ * Creates the administrator account on first run (or signs in to it) and stores
+ * the session cookie that all other tests run with
+ */
+import { expect, test as setup } from '@playwright/test';
+import { TEST_ADMIN } from './helpers/api-helpers';
+
+const authFile = 'playwright/.auth/admin.json';
+
+setup('authenticate', async ({ request }) => {
+  const statusResponse = await request.get('/api/v1/auth/status');
+  expect(statusResponse.ok()).toBeTruthy();
+  const status = await statusResponse.json();
+
+  const endpoint = status.setup_required ? '/api/v1/auth/setup' : '/api/v1/auth/login';
+  const response = await request.post(endpoint, { data: TEST_ADMIN });
+  expect(response.ok()).toBeTruthy();
+
+  await request.storageState({ path: authFile });
+});
@@ -0,0 +1,114 @@
+/**
+ * API Token Tests
+ *
+ * Tests for token scopes, profile restrictions, expiry and revocation
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import {
+  createApiTokenViaApi,
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+} from '../helpers/api-helpers';
+import { TEST_BASE_PATH } from '../helpers/fs-helpers';
+
+test.describe('API Tokens', () => {
+  let profileA: number;
+  let profileB: number;
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    // The server is never connected to: runs are only created, not executed
+    const serverId = await createServerViaApi(request, 'Token Server', '127.0.0.1', 2244, 'testuser', 'testpass');
+    const storageId = await createStorageLocationViaApi(request, 'Token Storage', `${TEST_BASE_PATH}/api-tokens`);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Token Naming', '{profile}-{TIMESTAMP}');
+    profileA = await createBackupProfileViaApi(request, 'Profile A', serverId, storageId, namingRuleId, [
+      { remote_path: '/data' },
+    ]);
+    profileB = await createBackupProfileViaApi(request, 'Profile B', serverId, storageId, namingRuleId, [
+      { remote_path: '/data' },
+    ]);
+  });
+
+  async function tokenContext(baseURL: string | undefined, token: string): Promise<APIRequestContext> {
+    return playwrightRequest.newContext({
+      baseURL,
+      storageState: { cookies: [], origins: [] },
+      extraHTTPHeaders: { Authorization: `Bearer ${token}` },
+    });
+  }
+
+  test('should only show the token once and store no secret', async ({ request }) => {
+    const created = await createApiTokenViaApi(request, { name: 'once', scope: 'read-only' });
+    expect(created.token).toMatch(/^bkp_/);
+
+    const tokens = await (await request.get('/api/v1/api-tokens')).json();
+    const listed = tokens.find((t: { id: number }) => t.id === created.id);
+    expect(listed.token).toBeUndefined();
+    expect(listed.token_hash).toBeUndefined();
+    expect(created.token.startsWith(listed.token_prefix)).toBeTruthy();
+  });
+
+  test('read-only tokens can read but not write', async ({ request, baseURL }) => {
+    const { token } = await createApiTokenViaApi(request, { name: 'reader', scope: 'read-only' });
+    const api = await tokenContext(baseURL, token);
+
+    expect((await api.get('/api/v1/servers')).ok()).toBeTruthy();
+    expect((await api.get(`/api/v1/backup-profiles/${profileA}`)).ok()).toBeTruthy();
+    expect((await api.post(`/api/v1/backup-profiles/${profileA}/run`)).status()).toBe(403);
+    expect((await api.post('/api/v1/naming-rules', { data: { name: 'x', pattern: 'x' } })).status()).toBe(403);
+
+    // Using the token records when it was last used
+    const tokens = await (await request.get('/api/v1/api-tokens')).json();
+    expect(tokens[0].last_used_at).toBeTruthy();
+    await api.dispose();
+  });
+
+  test('trigger-runs tokens restricted to a profile can only run that profile', async ({ request, baseURL }) => {
+    const { token } = await createApiTokenViaApi(request, {
+      name: 'ci',
+      scope: 'trigger-runs',
+      profile_ids: [profileA],
+    });
+    const api = await tokenContext(baseURL, token);
+
+    const run = await api.post(`/api/v1/backup-profiles/${profileA}/run`);
+    expect(run.status()).toBe(201);
+    const { backup_run_id } = await run.json();
+
+    expect((await api.get(`/api/v1/backup-runs/${backup_run_id}`)).ok()).toBeTruthy();
+    expect((await api.get(`/api/v1/backup-runs?profile_id=${profileA}`)).ok()).toBeTruthy();
+
+    expect((await api.post(`/api/v1/backup-profiles/${profileB}/run`)).status()).toBe(403);
+    expect((await api.get(`/api/v1/backup-runs?profile_id=${profileB}`)).status()).toBe(403);
+    expect((await api.get('/api/v1/servers')).status()).toBe(403);
+    expect((await api.put(`/api/v1/backup-profiles/${profileA}`, { data: {} })).status()).toBe(403);
+    await api.dispose();
+  });
+
+  test('revoked and invalid tokens are rejected', async ({ request, baseURL }) => {
+    const created = await createApiTokenViaApi(request, { name: 'revoke-me', scope: 'admin' });
+    const api = await tokenContext(baseURL, created.token);
+    expect((await api.get('/api/v1/servers')).ok()).toBeTruthy();
+
+    expect((await request.delete(`/api/v1/api-tokens/${created.id}`)).ok()).toBeTruthy();
+    expect((await api.get('/api/v1/servers')).status()).toBe(401);
+    await api.dispose();
+
+    const invalid = await tokenContext(baseURL, 'bkp_invalid');
+    expect((await invalid.get('/api/v1/servers')).status()).toBe(401);
+    await invalid.dispose();
+  });
+
+  test('should reject invalid scopes and past expiry dates', async ({ request }) => {
+    const badScope = await request.post('/api/v1/api-tokens', { data: { name: 'x', scope: 'root' } });
+    expect(badScope.status()).toBe(400);
+
+    const expired = await request.post('/api/v1/api-tokens', {
+      data: { name: 'x', scope: 'read-only', expires_at: '2020-01-01T00:00:00Z' },
+    });
+    expect(expired.status()).toBe(400);
+  });
+});
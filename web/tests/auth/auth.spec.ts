@@ -0,0 +1,95 @@
+/**
+ * Authentication Tests
+ *
+ * Tests for login, logout, session handling and user management
+ */
+import { expect, test } from '@playwright/test';
+import { TEST_ADMIN } from '../helpers/api-helpers';
+
+test.describe('Authentication', () => {
+  test.describe('without a session', () => {
+    test.use({ storageState: { cookies: [], origins: [] } });
+
+    test('should reject API requests without a session', async ({ request }) => {
+      const response = await request.get('/api/v1/servers');
+      expect(response.status()).toBe(401);
+    });
+
+    test('should keep the health check public', async ({ request }) => {
+      const response = await request.get('/api/v1/health');
+      expect(response.ok()).toBeTruthy();
+    });
+
+    test('should not require setup once an admin exists', async ({ request }) => {
+      const status = await (await request.get('/api/v1/auth/status')).json();
+      expect(status.setup_required).toBe(false);
+
+      const response = await request.post('/api/v1/auth/setup', {
+        data: { username: 'intruder', password: 'intruder-password' },
+      });
+      expect(response.status()).toBe(409);
+    });
+
+    test('should reject a wrong password', async ({ request }) => {
+      const response = await request.post('/api/v1/auth/login', {
+        data: { username: TEST_ADMIN.username, password: 'wrong-password' },
+      });
+      expect(response.status()).toBe(401);
+    });
+
+    test('should grant access after login and revoke it on logout', async ({ request }) => {
+      const login = await request.post('/api/v1/auth/login', { data: TEST_ADMIN });
+      expect(login.ok()).toBeTruthy();
+      const user = await login.json();
+      expect(user.username).toBe(TEST_ADMIN.username);
+      expect(user.password_hash).toBeUndefined();
+
+      expect((await request.get('/api/v1/servers')).ok()).toBeTruthy();
+      expect((await request.get('/api/v1/auth/me')).ok()).toBeTruthy();
+
+      expect((await request.post('/api/v1/auth/logout')).ok()).toBeTruthy();
+      expect((await request.get('/api/v1/servers')).status()).toBe(401);
+    });
+
+    test('should show the login page and sign in', async ({ page }) => {
+      await page.goto('/dashboard');
+      await expect(page.getByText('Sign in to continue')).toBeVisible();
+
+      await page.getByLabel('Username').fill(TEST_ADMIN.username);
+      await page.getByLabel('Password').fill(TEST_ADMIN.password);
+      await page.getByTestId('login-submit-btn').click();
+
+      await expect(page.getByTestId('current-username')).toHaveText(TEST_ADMIN.username);
+
+      await page.getByTestId('logout-btn').click();
+      await expect(page.getByText('Sign in to continue')).toBeVisible();
+    });
+  });
+
+  test.describe('user management', () => {
+    test('should create, sign in as and delete a user', async ({ request, playwright, baseURL }) => {
+      const username = `user-${Date.now()}`;
+      const created = await request.post('/api/v1/users', { data: { username, password: 'first-password' } });
+      expect(created.status()).toBe(201);
+      const user = await created.json();
+
+      const duplicate = await request.post('/api/v1/users', { data: { username, password: 'first-password' } });
+      expect(duplicate.status()).toBe(409);
+
+      const tooShort = await request.put(`/api/v1/users/${user.id}`, { data: { password: 'short' } });
+      expect(tooShort.status()).toBe(400);
+
+      const other = await playwright.request.newContext({ baseURL });
+      expect((await other.post('/api/v1/auth/login', { data: { username, password: 'first-password' } })).ok()).toBeTruthy();
+      expect((await other.get('/api/v1/servers')).ok()).toBeTruthy();
+
+      // Resetting the password ends existing sessions
+      expect((await request.put(`/api/v1/users/${user.id}`, { data: { password: 'second-password' } })).ok()).toBeTruthy();
+      expect((await other.get('/api/v1/servers')).status()).toBe(401);
+
+      expect((await request.delete(`/api/v1/users/${user.id}`)).ok()).toBeTruthy();
+      expect((await other.post('/api/v1/auth/login', { data: { username, password: 'second-password' } })).status()).toBe(401);
+      await other.dispose();
+    });
+  });
+});
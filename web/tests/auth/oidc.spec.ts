@@ -0,0 +1,116 @@
+/**
+ * Single Sign-On Tests
+ *
+ * Tests for OpenID Connect login, user provisioning and group to role mapping
+ * against the fake provider configured in playwright.config.ts
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import { FAKE_OIDC_PORT, startFakeOIDCProvider, type FakeOIDCProvider } from '../helpers/fake-oidc-provider';
+
+test.describe('Single sign-on', () => {
+  let provider: FakeOIDCProvider;
+  const contexts: APIRequestContext[] = [];
+
+  test.beforeAll(async () => {
+    provider = await startFakeOIDCProvider(FAKE_OIDC_PORT);
+  });
+
+  test.afterAll(async () => {
+    await provider.close();
+  });
+
+  test.afterEach(async ({ request }) => {
+    while (contexts.length > 0) {
+      await contexts.pop()!.dispose();
+    }
+    const users: { id: number; username: string }[] = await (await request.get('/api/v1/users')).json();
+    for (const user of users.filter((u) => u.username.startsWith('sso-'))) {
+      await request.delete(`/api/v1/users/${user.id}`);
+    }
+  });
+
+  /** Runs the redirect flow with a fresh cookie jar and returns the signed-in context */
+  async function signInWithSSO(baseURL: string | undefined): Promise<APIRequestContext> {
+    const context = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    contexts.push(context);
+    const response = await context.get('/api/v1/auth/oidc/login');
+    expect(response.ok()).toBeTruthy();
+    expect(new URL(response.url()).searchParams.get('login_error')).toBeNull();
+    return context;
+  }
+
+  test('should advertise the provider on the login page', async ({ request }) => {
+    const status = await (await request.get('/api/v1/auth/status')).json();
+    expect(status.oidc_enabled).toBe(true);
+    expect(status.oidc_provider_name).toBe('Keycloak');
+  });
+
+  test.describe('in the browser', () => {
+    test.use({ storageState: { cookies: [], origins: [] } });
+
+    test('should sign in with the identity provider', async ({ page }) => {
+      const username = `sso-${Date.now()}`;
+      provider.setUser({ sub: `sub-${username}`, preferred_username: username, groups: ['/backapp-admins'] });
+
+      await page.goto('/dashboard');
+      await page.getByTestId('oidc-login-btn').click();
+
+      await expect(page.getByTestId('current-username')).toHaveText(username);
+    });
+  });
+
+  test('should provision users with roles from their groups', async ({ baseURL }) => {
+    const username = `sso-${Date.now()}`;
+    provider.setUser({ sub: `sub-${username}`, preferred_username: username, groups: ['/backapp-viewers', 'unmapped'] });
+
+    const context = await signInWithSSO(baseURL);
+    const me = await (await context.get('/api/v1/auth/me')).json();
+    expect(me.username).toBe(username);
+    expect(me.oidc_subject).toBe(`sub-${username}`);
+    expect(me.roles).toHaveLength(1);
+    expect(me.roles[0]).toMatchObject({ role: 'viewer', source: 'oidc' });
+
+    // Viewers can read but not change anything
+    expect((await context.get('/api/v1/servers')).ok()).toBeTruthy();
+    expect((await context.get('/api/v1/users')).status()).toBe(403);
+  });
+
+  test('should resync group roles on every login but keep manual roles', async ({ request, baseURL }) => {
+    const username = `sso-${Date.now()}`;
+    provider.setUser({ sub: `sub-${username}`, preferred_username: username, groups: ['backapp-admins'] });
+    const first = await (await (await signInWithSSO(baseURL)).get('/api/v1/auth/me')).json();
+    expect(first.roles.map((r: { role: string }) => r.role)).toEqual(['admin']);
+
+    // A role granted in BackApp itself is not touched by the provider
+    const manual = await request.post(`/api/v1/users/${first.id}/roles`, { data: { role: 'operator' } });
+    expect(manual.status()).toBe(201);
+
+    provider.setUser({ sub: `sub-${username}`, preferred_username: username, groups: [] });
+    const second = await (await (await signInWithSSO(baseURL)).get('/api/v1/auth/me')).json();
+    expect(second.id).toBe(first.id);
+    expect(second.roles).toHaveLength(1);
+    expect(second.roles[0].role).toBe('operator');
+    expect(second.roles[0].source ?? '').toBe('');
+  });
+
+  test('should not take over a local user with the same name', async ({ request, baseURL }) => {
+    const username = `sso-local-${Date.now()}`;
+    const local = await request.post('/api/v1/users', { data: { username, password: 'local-password' } });
+    expect(local.status()).toBe(201);
+
+    provider.setUser({ sub: `sub-${username}`, preferred_username: username, groups: ['backapp-admins'] });
+    const context = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    contexts.push(context);
+    const response = await context.get('/api/v1/auth/oidc/login');
+    expect(new URL(response.url()).searchParams.get('login_error')).toContain('local user');
+    expect((await context.get('/api/v1/auth/me')).status()).toBe(401);
+  });
+
+  test('should reject callbacks that were not started by this browser', async ({ baseURL }) => {
+    const context = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    contexts.push(context);
+    const response = await context.get('/api/v1/auth/oidc/callback?code=forged&state=forged');
+    expect(new URL(response.url()).searchParams.get('login_error')).toContain('could not be verified');
+    expect((await context.get('/api/v1/auth/me')).status()).toBe(401);
+  });
+});
@@ -0,0 +1,119 @@
+/**
+ * Role-Based Access Control Tests
+ *
+ * Tests for viewer, operator and admin roles scoped globally, per server and per profile
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+} from '../helpers/api-helpers';
+import { TEST_BASE_PATH } from '../helpers/fs-helpers';
+
+test.describe('Roles', () => {
+  let serverA: number;
+  let serverB: number;
+  let profileA: number;
+  let profileB: number;
+  const created: number[] = [];
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    // The servers are never connected to: runs are only created, not executed
+    serverA = await createServerViaApi(request, 'Web Server', '127.0.0.1', 2245, 'testuser', 'testpass');
+    serverB = await createServerViaApi(request, 'Database Server', '127.0.0.1', 2246, 'testuser', 'testpass');
+    const storageId = await createStorageLocationViaApi(request, 'Role Storage', `${TEST_BASE_PATH}/roles`);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Role Naming', '{profile}-{TIMESTAMP}');
+    profileA = await createBackupProfileViaApi(request, 'Web Backup', serverA, storageId, namingRuleId, [
+      { remote_path: '/var/www' },
+    ]);
+    profileB = await createBackupProfileViaApi(request, 'Database Backup', serverB, storageId, namingRuleId, [
+      { remote_path: '/var/lib/db' },
+    ]);
+  });
+
+  test.afterEach(async ({ request }) => {
+    while (created.length > 0) {
+      await request.delete(`/api/v1/users/${created.pop()}`);
+    }
+  });
+
+  async function signInAs(
+    request: APIRequestContext,
+    baseURL: string | undefined,
+    role: { role: string; server_id?: number; backup_profile_id?: number }
+  ): Promise<APIRequestContext> {
+    const username = `role-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'role-password' } })).json();
+    created.push(user.id);
+    expect((await request.post(`/api/v1/users/${user.id}/roles`, { data: role })).status()).toBe(201);
+
+    const context = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    expect((await context.post('/api/v1/auth/login', { data: { username, password: 'role-password' } })).ok()).toBeTruthy();
+    return context;
+  }
+
+  test('profile operators can run their profile but not change it', async ({ request, baseURL }) => {
+    const runB = await (await request.post(`/api/v1/backup-profiles/${profileB}/run`)).json();
+    const api = await signInAs(request, baseURL, { role: 'operator', backup_profile_id: profileA });
+
+    const run = await api.post(`/api/v1/backup-profiles/${profileA}/run`);
+    expect(run.status()).toBe(201);
+    const { backup_run_id } = await run.json();
+    expect((await api.get(`/api/v1/backup-runs/${backup_run_id}`)).ok()).toBeTruthy();
+
+    expect((await api.put(`/api/v1/backup-profiles/${profileA}`, { data: {} })).status()).toBe(403);
+    expect((await api.delete(`/api/v1/backup-runs/${backup_run_id}`)).status()).toBe(403);
+
+    // Other teams' profiles and runs are invisible
+    expect((await api.post(`/api/v1/backup-profiles/${profileB}/run`)).status()).toBe(403);
+    expect((await api.get(`/api/v1/backup-runs/${runB.backup_run_id}`)).status()).toBe(403);
+    const profiles = await (await api.get('/api/v1/backup-profiles')).json();
+    expect(profiles.map((p: { id: number }) => p.id)).toEqual([profileA]);
+    const runs = await (await api.get('/api/v1/backup-runs')).json();
+    expect(runs.every((r: { backup_profile_id: number }) => r.backup_profile_id === profileA)).toBeTruthy();
+    const servers = await (await api.get('/api/v1/servers')).json();
+    expect(servers.map((s: { id: number }) => s.id)).toEqual([serverA]);
+
+    // Administration stays with global admins
+    expect((await api.get('/api/v1/users')).status()).toBe(403);
+    expect((await api.post('/api/v1/servers', { data: {} })).status()).toBe(403);
+    await api.dispose();
+  });
+
+  test('server viewers can read but not run', async ({ request, baseURL }) => {
+    const api = await signInAs(request, baseURL, { role: 'viewer', server_id: serverB });
+
+    expect((await api.get(`/api/v1/backup-profiles/${profileB}`)).ok()).toBeTruthy();
+    expect((await api.post(`/api/v1/backup-profiles/${profileB}/run`)).status()).toBe(403);
+    expect((await api.get(`/api/v1/backup-profiles/${profileA}`)).status()).toBe(403);
+    await api.dispose();
+  });
+
+  test('server admins can edit profiles of their server only', async ({ request, baseURL }) => {
+    const api = await signInAs(request, baseURL, { role: 'admin', server_id: serverA });
+
+    const profile = await (await api.get(`/api/v1/backup-profiles/${profileA}`)).json();
+    const update = await api.put(`/api/v1/backup-profiles/${profileA}`, { data: { ...profile, name: 'Renamed' } });
+    expect(update.ok()).toBeTruthy();
+
+    // Moving the profile to a server they do not administer is refused
+    const move = await api.put(`/api/v1/backup-profiles/${profileA}`, { data: { ...profile, server_id: serverB } });
+    expect(move.status()).toBe(403);
+
+    expect((await api.delete(`/api/v1/backup-profiles/${profileB}`)).status()).toBe(403);
+    await api.dispose();
+  });
+
+  test('the last global admin cannot be removed', async ({ request }) => {
+    const me = await (await request.get('/api/v1/auth/me')).json();
+    const adminRole = me.roles.find((r: { role: string; server_id?: number; backup_profile_id?: number }) =>
+      r.role === 'admin' && !r.server_id && !r.backup_profile_id);
+    expect(adminRole).toBeTruthy();
+
+    expect((await request.delete(`/api/v1/users/${me.id}/roles/${adminRole.id}`)).status()).toBe(409);
+  });
+});
@@ -0,0 +1,91 @@
+/**
+ * Backup Dry Run Tests
+ *
+ * Tests for resolving backup profiles without transferring files
+ */
+import { expect, test } from '@playwright/test';
+import * as path from 'path';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  dryRunBackupProfileViaApi,
+  resetDatabase,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, directoryExistsOnDisk, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import {
+  createVirtualDirectory,
+  createVirtualFile,
+  startFakeSSHServerWithFiles,
+  type VirtualFile,
+} from '../helpers/fake-ssh-server';
+
+test.describe('Backup Dry Run', () => {
+  let sshServer: SSHServer;
+  const SSH_PORT = 2241;
+
+  test.beforeAll(async () => {
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/backup', createVirtualDirectory());
+    virtualFiles.set('/backup/db_dump.sql', createVirtualFile('-- Database dump\nCREATE TABLE users;'));
+    virtualFiles.set('/backup/config.json', createVirtualFile('{"setting": "value"}'));
+
+    sshServer = await startFakeSSHServerWithFiles({
+      port: SSH_PORT,
+      username: 'root',
+      password: 'testpass',
+      virtualFiles,
+    });
+  });
+
+  test.afterAll(async () => {
+    if (sshServer) {
+      sshServer.close();
+    }
+  });
+
+  test.beforeEach(async ({ request }) => {
+    cleanupTestDirectory();
+    await resetDatabase(request);
+  });
+
+  test('should list files and sizes without writing a backup', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'DryRun', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/backup', recursive: false },
+    ]);
+
+    const result = await dryRunBackupProfileViaApi(request, profileId);
+
+    expect(result.total_files).toBe(2);
+    expect(result.files.map((f) => f.remote_path).sort()).toEqual(['/backup/config.json', '/backup/db_dump.sql']);
+    expect(result.total_size_bytes).toBe(
+      Buffer.byteLength('-- Database dump\nCREATE TABLE users;') + Buffer.byteLength('{"setting": "value"}')
+    );
+    expect(result.fits).toBe(true);
+    expect(directoryExistsOnDisk(storagePath)).toBe(false);
+  });
+
+  test('should report missing remote paths per rule', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'DryRunMissing', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/backup/db_dump.sql' },
+      { remote_path: '/does/not/exist' },
+    ]);
+
+    const result = await dryRunBackupProfileViaApi(request, profileId);
+
+    expect(result.total_files).toBe(1);
+    expect(result.rules[1].error).toContain('does not exist');
+    expect(result.warnings.length).toBeGreaterThan(0);
+  });
+});
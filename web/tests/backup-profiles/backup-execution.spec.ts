@@ -4,6 +4,7 @@
  * Tests for running backups and verifying backup run results
  */
 import { expect, test } from '@playwright/test';
+import * as fs from 'fs';
 import * as path from 'path';
 import type { Server as SSHServer } from 'ssh2';
 import {
@@ -41,6 +42,12 @@ test.describe('Backup Execution', () => {
     virtualFiles.set('/backup/db_dump.sql', createVirtualFile('-- Database dump\nCREATE TABLE users;'));
     virtualFiles.set('/backup/config.json', createVirtualFile('{"setting": "value", "debug": true}'));
     virtualFiles.set('/backup/app.log', createVirtualFile('2026-01-02 10:00:00 INFO Starting application'));
+    virtualFiles.set('/scripts', createVirtualDirectory());
+    virtualFiles.set('/scripts/deploy.sh', {
+      ...createVirtualFile('#!/bin/sh\necho deploy'),
+      mode: 0o100750,
+      mtime: new Date('2020-01-02T03:04:05Z'),
+    });
 
     sshServer = await startFakeSSHServerWithFiles({
       port: SSH_PORT,
@@ -94,6 +101,31 @@ test.describe('Backup Execution', () => {
       expect(content).toContain('CREATE TABLE users');
     });
 
+    test('should preserve file mode and modification time', async ({ request }) => {
+      const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+      const storagePath = path.join(TEST_BASE_PATH, 'backups');
+      const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+      const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+
+      const profileId = await createBackupProfileViaApi(request, 'Metadata', serverId, storageLocationId, namingRuleId, [
+        { remote_path: '/scripts/deploy.sh' },
+      ]);
+
+      const runId = await runBackupViaApi(request, profileId);
+      const run = await waitForBackupRunComplete(request, runId);
+      expect(run.status).toBe('completed');
+
+      const files = await getBackupRunFilesViaApi(request, runId);
+      expect(files.length).toBe(1);
+      expect(files[0].file_type).toBe('file');
+      expect(files[0].mode).toBe(0o750);
+      expect(new Date(files[0].mod_time!).toISOString()).toBe('2020-01-02T03:04:05.000Z');
+
+      const stat = fs.statSync(files[0].local_path);
+      expect(stat.mode & 0o777).toBe(0o750);
+      expect(stat.mtime.toISOString()).toBe('2020-01-02T03:04:05.000Z');
+    });
+
     test('should execute backup with multiple file rules', async ({ request }) => {
       const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
       const storagePath = path.join(TEST_BASE_PATH, 'backups');
@@ -0,0 +1,107 @@
+/**
+ * File Rule Pattern Tests
+ *
+ * Tests for gitignore-style include and exclude patterns and the size and age limits of file rules
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import * as path from 'path';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  dryRunBackupProfileViaApi,
+  resetDatabase,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import {
+  createVirtualDirectory,
+  createVirtualFile,
+  startFakeSSHServerWithFiles,
+  type VirtualFile,
+} from '../helpers/fake-ssh-server';
+
+test.describe('File Rule Patterns', () => {
+  let sshServer: SSHServer;
+  const SSH_PORT = 2242;
+
+  test.beforeAll(async () => {
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/catalog.db', { ...createVirtualFile('catalog'), mtime: new Date('2020-01-02T03:04:05Z') });
+    virtualFiles.set('/data/app.log', createVirtualFile('log line'));
+    virtualFiles.set('/data/keep.log', createVirtualFile('important'));
+    virtualFiles.set('/data/notes.txt', createVirtualFile('notes'));
+
+    sshServer = await startFakeSSHServerWithFiles({
+      port: SSH_PORT,
+      username: 'root',
+      password: 'testpass',
+      virtualFiles,
+    });
+  });
+
+  test.afterAll(async () => {
+    if (sshServer) {
+      sshServer.close();
+    }
+  });
+
+  test.beforeEach(async ({ request }) => {
+    cleanupTestDirectory();
+    await resetDatabase(request);
+  });
+
+  async function dryRunWithRule(
+    request: APIRequestContext,
+    rule: { include_pattern?: string; exclude_pattern?: string; max_file_size_bytes?: number; modified_since?: string }
+  ): Promise<string[]> {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', path.join(TEST_BASE_PATH, 'backups'));
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'Patterns', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/data', recursive: false, ...rule },
+    ]);
+
+    const result = await dryRunBackupProfileViaApi(request, profileId);
+    return result.files.map((f) => f.remote_path).sort();
+  }
+
+  test('should not exclude files that merely contain the pattern', async ({ request }) => {
+    const files = await dryRunWithRule(request, { exclude_pattern: 'log' });
+
+    expect(files).toEqual(['/data/app.log', '/data/catalog.db', '/data/keep.log', '/data/notes.txt']);
+  });
+
+  test('should re-include files with negated patterns', async ({ request }) => {
+    const files = await dryRunWithRule(request, { exclude_pattern: '*.log\n!keep.log' });
+
+    expect(files).toEqual(['/data/catalog.db', '/data/keep.log', '/data/notes.txt']);
+  });
+
+  test('should only transfer included files', async ({ request }) => {
+    const files = await dryRunWithRule(request, { include_pattern: '*.db\n*.txt' });
+
+    expect(files).toEqual(['/data/catalog.db', '/data/notes.txt']);
+  });
+
+  test('should keep commas within patterns', async ({ request }) => {
+    const files = await dryRunWithRule(request, { exclude_pattern: '[a,k]*.log' });
+
+    expect(files).toEqual(['/data/catalog.db', '/data/notes.txt']);
+  });
+
+  test('should skip files larger than the size limit', async ({ request }) => {
+    const files = await dryRunWithRule(request, { max_file_size_bytes: 7 });
+
+    expect(files).toEqual(['/data/catalog.db', '/data/notes.txt']);
+  });
+
+  test('should skip files modified before the modified-since time', async ({ request }) => {
+    const files = await dryRunWithRule(request, { modified_since: '2024-01-01T00:00:00Z' });
+
+    expect(files).toEqual(['/data/app.log', '/data/keep.log', '/data/notes.txt']);
+  });
+});
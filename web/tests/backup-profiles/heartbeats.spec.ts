@@ -0,0 +1,127 @@
+/**
+ * Heartbeat Tests
+ *
+ * Tests the Healthchecks.io style pings of a profile's heartbeat URL when runs start,
+ * complete and fail, against a fake monitor
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+test.describe('Heartbeats', () => {
+  const MONITOR_PORT = 2266;
+  const SSH_PORT = 2267;
+  // Nothing listens here, so runs against it fail
+  const CLOSED_PORT = 2268;
+  const storagePath = `${TEST_BASE_PATH}/heartbeats`;
+  let monitor: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    monitor = await startFakeWebhookServer(MONITOR_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await monitor.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Heartbeat Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Heartbeat Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function createProfile(request: APIRequestContext, name: string, port: number, heartbeatUrl: string): Promise<number> {
+    const serverId = await createServerViaApi(request, `${name} Server`, '127.0.0.1', port, 'root', 'testpass');
+    const response = await request.post('/api/v1/backup-profiles', {
+      data: {
+        name,
+        server_id: serverId,
+        storage_location_id: storageId,
+        naming_rule_id: namingRuleId,
+        heartbeat_url: heartbeatUrl,
+        enabled: true,
+        file_rules: [{ remote_path: '/data', recursive: true, run_order: 1 }],
+      },
+    });
+    expect(response.ok()).toBeTruthy();
+    return (await response.json()).id;
+  }
+
+  test('pings start and success with the duration', async ({ request }) => {
+    const profileId = await createProfile(request, 'Monitored', SSH_PORT, `${monitor.url}/ping/abc-123`);
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+
+    const success = await monitor.waitForRequest((r) => r.path === '/ping/abc-123');
+    const pings = monitor.requests.filter((r) => r.path.startsWith('/ping/abc-123'));
+    expect(pings.map((r) => r.path)).toEqual(['/ping/abc-123/start', '/ping/abc-123']);
+    expect(success.headers['content-type']).toContain('text/plain');
+    expect(success.body).toContain("Backup 'Monitored' completed in");
+    expect(success.body).toContain(`Run: #${runId}`);
+    expect(success.body).toMatch(/Duration: \d+ seconds/);
+    expect(success.body).toContain('Files: 1');
+  });
+
+  test('pings fail with the error message', async ({ request }) => {
+    const profileId = await createProfile(request, 'Unreachable', CLOSED_PORT, `${monitor.url}/ping/def-456?create=1`);
+    const runId = await runBackupViaApi(request, profileId);
+    const run = await waitForBackupRunComplete(request, runId);
+    expect(run.status).toBe('failed');
+
+    const failure = await monitor.waitForRequest((r) => r.path === '/ping/def-456/fail?create=1');
+    expect(failure.body).toContain('failed to create SSH client');
+    expect(monitor.requests.some((r) => r.path === '/ping/def-456/start?create=1')).toBeTruthy();
+  });
+
+  test('retries pings the monitor could not accept', async ({ request }) => {
+    const profileId = await createProfile(request, 'Retried', SSH_PORT, `${monitor.url}/ping/ghi-789`);
+    monitor.respondWith(503);
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+
+    // The success ping waits for the retried start ping
+    await monitor.waitForRequest((r) => r.path === '/ping/ghi-789', 20000);
+    const pings = monitor.requests.filter((r) => r.path.startsWith('/ping/ghi-789'));
+    expect(pings.map((r) => `${r.path} ${r.status}`)).toEqual([
+      '/ping/ghi-789/start 503',
+      '/ping/ghi-789/start 200',
+      '/ping/ghi-789 200',
+    ]);
+  });
+
+  test('rejects heartbeat URLs that are not http', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Invalid Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const response = await request.post('/api/v1/backup-profiles', {
+      data: {
+        name: 'Invalid',
+        server_id: serverId,
+        storage_location_id: storageId,
+        naming_rule_id: namingRuleId,
+        heartbeat_url: 'ftp://monitor.example.org/ping',
+        enabled: true,
+      },
+    });
+    expect(response.status()).toBe(400);
+    expect((await response.json()).error).toContain('heartbeat_url');
+  });
+});
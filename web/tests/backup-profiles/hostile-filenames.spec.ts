@@ -0,0 +1,126 @@
+/**
+ * Hostile Filename Tests
+ *
+ * Tests that backups handle filenames with quotes, spaces, newlines and shell syntax
+ */
+import { expect, test } from '@playwright/test';
+import * as path from 'path';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  getBackupRunFilesViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, fileExistsOnDisk, readTestFile, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import {
+  createVirtualDirectory,
+  createVirtualFile,
+  startFakeSSHServerWithFiles,
+  type VirtualFile,
+} from '../helpers/fake-ssh-server';
+
+const HOSTILE_FILES: Record<string, string> = {
+  "it's.txt": 'single quote',
+  'with space.txt': 'space',
+  'new\nline.txt': 'newline',
+  '$(touch pwned).txt': 'command substitution',
+  '-rf': 'leading dash',
+  'sub dir/"double" quote.txt': 'nested',
+};
+
+test.describe('Hostile Filenames', () => {
+  let sshServer: SSHServer;
+  const SSH_PORT = 2243;
+
+  test.beforeAll(async () => {
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/hostile', createVirtualDirectory());
+    virtualFiles.set('/hostile/sub dir', createVirtualDirectory());
+    for (const [name, content] of Object.entries(HOSTILE_FILES)) {
+      virtualFiles.set(`/hostile/${name}`, createVirtualFile(content));
+    }
+
+    sshServer = await startFakeSSHServerWithFiles({
+      port: SSH_PORT,
+      username: 'root',
+      password: 'testpass',
+      virtualFiles,
+    });
+  });
+
+  test.afterAll(async () => {
+    if (sshServer) {
+      sshServer.close();
+    }
+  });
+
+  test.beforeEach(async ({ request }) => {
+    cleanupTestDirectory();
+    await resetDatabase(request);
+  });
+
+  test('should back up every hostile filename recursively', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'Hostile', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/hostile', recursive: true },
+    ]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    const run = await waitForBackupRunComplete(request, runId);
+    expect(run.status).toBe('completed');
+    expect(run.total_files).toBe(Object.keys(HOSTILE_FILES).length);
+
+    const files = await getBackupRunFilesViaApi(request, runId);
+    const backupDir = path.join(storagePath, 'Hostile');
+    for (const [name, content] of Object.entries(HOSTILE_FILES)) {
+      const localPath = path.join(backupDir, name);
+      expect(files.map((f) => f.local_path)).toContain(localPath);
+      expect(fileExistsOnDisk(localPath)).toBe(true);
+      expect(readTestFile(localPath)).toBe(content);
+    }
+  });
+
+  test('should back up hostile filenames in a non-recursive rule', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'Shallow', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/hostile', recursive: false },
+    ]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    const run = await waitForBackupRunComplete(request, runId);
+    expect(run.status).toBe('completed');
+
+    const topLevel = Object.keys(HOSTILE_FILES).filter((name) => !name.includes('/'));
+    expect(run.total_files).toBe(topLevel.length);
+    for (const name of topLevel) {
+      expect(readTestFile(path.join(storagePath, 'Shallow', name))).toBe(HOSTILE_FILES[name]);
+    }
+  });
+
+  test('should back up a single file whose name contains a quote', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'Quote', serverId, storageLocationId, namingRuleId, [
+      { remote_path: "/hostile/it's.txt" },
+    ]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    const run = await waitForBackupRunComplete(request, runId);
+    expect(run.status).toBe('completed');
+    expect(readTestFile(path.join(storagePath, 'Quote', "it's.txt"))).toBe('single quote');
+  });
+});
@@ -0,0 +1,83 @@
+/**
+ * Backup Run Events Tests
+ *
+ * Tests for the Server-Sent Events stream of backup runs
+ */
+import { expect, test } from '@playwright/test';
+import * as path from 'path';
+import type { Server } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  getBackupRunEventsViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import {
+  createVirtualDirectory,
+  createVirtualFile,
+  startFakeSSHServerWithFiles,
+  type VirtualFile,
+} from '../helpers/fake-ssh-server';
+
+test.describe('Backup Run Events', () => {
+  let sshServer: Server;
+  const SSH_PORT = 2240;
+
+  test.beforeAll(async () => {
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/backup', createVirtualDirectory());
+    virtualFiles.set('/backup/db_dump.sql', createVirtualFile('-- Database dump\nCREATE TABLE users;'));
+
+    sshServer = await startFakeSSHServerWithFiles({
+      port: SSH_PORT,
+      username: 'root',
+      password: 'testpass',
+      virtualFiles,
+    });
+  });
+
+  test.afterAll(async () => {
+    if (sshServer) {
+      sshServer.close();
+    }
+  });
+
+  test.beforeEach(async ({ request }) => {
+    cleanupTestDirectory();
+    await resetDatabase(request);
+  });
+
+  test('should replay logs and final state of a finished run', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Test Server', 'localhost', SSH_PORT, 'root', 'testpass');
+    const storagePath = path.join(TEST_BASE_PATH, 'backups');
+    const storageLocationId = await createStorageLocationViaApi(request, 'Test Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Simple', '{profile}');
+    const profileId = await createBackupProfileViaApi(request, 'Events', serverId, storageLocationId, namingRuleId, [
+      { remote_path: '/backup/db_dump.sql' },
+    ]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    await waitForBackupRunComplete(request, runId);
+
+    const events = await getBackupRunEventsViaApi(request, runId);
+    const logs = events.filter((e) => e.event === 'log');
+    expect(logs.length).toBeGreaterThan(0);
+    expect(logs.some((e) => e.data.message.includes('Backup completed successfully'))).toBe(true);
+
+    const last = events[events.length - 1];
+    expect(last.event).toBe('state');
+    expect(last.data.id).toBe(runId);
+    expect(last.data.status).toBe('completed');
+  });
+
+  test('should return 404 for unknown run', async ({ request }) => {
+    const response = await request.get('/api/v1/backup-runs/99999/events');
+    expect(response.status()).toBe(404);
+  });
+});
@@ -0,0 +1,173 @@
+/**
+ * Tracing Tests
+ *
+ * Tests the spans of backup runs exported as OTLP JSON to a fake collector
+ */
+import { expect, test } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+interface ExportedSpan {
+  traceId: string;
+  spanId: string;
+  parentSpanId?: string;
+  name: string;
+  attributes?: { key: string; value: Record<string, string | number | boolean> }[];
+  status: { code?: number; message?: string };
+}
+
+/** Attributes of a span as plain values */
+const attributes = (span: ExportedSpan) =>
+  Object.fromEntries((span.attributes ?? []).map((a) => [a.key, Object.values(a.value)[0]]));
+
+test.describe('Tracing', () => {
+  // Matches OTEL_EXPORTER_OTLP_ENDPOINT in playwright.config.ts
+  const COLLECTOR_PORT = 2277;
+  const SSH_PORT = 2278;
+  // Nothing listens here, so connections fail
+  const CLOSED_PORT = 2279;
+  const storagePath = `${TEST_BASE_PATH}/tracing`;
+  let collector: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    collector = await startFakeWebhookServer(COLLECTOR_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    virtualFiles.set('/data/video.mp4', createVirtualFile('x'.repeat(5000)));
+    virtualFiles.set('/etc', createVirtualDirectory());
+    virtualFiles.set('/etc/hosts', createVirtualFile('127.0.0.1 localhost'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await collector.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Tracing Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Tracing Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  /** Waits for the spans of the run's trace, which are exported together when the run ends */
+  async function spansOfRun(runId: number) {
+    const exported = () =>
+      collector.requests
+        .filter((r) => r.path === '/v1/traces')
+        .flatMap((r) => JSON.parse(r.body).resourceSpans[0].scopeSpans[0].spans as ExportedSpan[]);
+    await expect.poll(() => exported().some((s) => s.name === 'backup run' && attributes(s)['backapp.run.id'] === String(runId))).toBe(true);
+    const root = exported().find((s) => s.name === 'backup run' && attributes(s)['backapp.run.id'] === String(runId))!;
+    return exported().filter((s) => s.traceId === root.traceId);
+  }
+
+  test('exports a span per run, stage, rule and large file', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Tracing Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Media', serverId, storageId, namingRuleId, [
+      { remote_path: '/data', recursive: true },
+      { remote_path: '/etc', recursive: true },
+    ]);
+    const command = await request.post(`/api/v1/backup-profiles/${profileId}/commands`, {
+      data: { command: 'PGPASSWORD=hunter2 pg_dump app', working_directory: '/tmp', run_stage: 'pre', run_order: 1 },
+    });
+    expect(command.ok()).toBeTruthy();
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+    const spans = await spansOfRun(runId);
+
+    const root = spans.find((s) => s.name === 'backup run')!;
+    expect(root.parentSpanId).toBeUndefined();
+    expect(attributes(root)).toMatchObject({
+      'backapp.profile.id': String(profileId),
+      'backapp.profile.name': 'Media',
+      'backapp.server.name': 'Tracing Server',
+      'backapp.run.status': 'completed',
+      'backapp.files': '3',
+      'backapp.bytes': String(5000 + 'meeting notes'.length + '127.0.0.1 localhost'.length),
+    });
+
+    const stages = spans.filter((s) => s.parentSpanId === root.spanId).map((s) => s.name);
+    expect(stages.sort()).toEqual(['connect', 'post', 'pre', 'transfer']);
+    expect(attributes(spans.find((s) => s.name === 'connect')!)).toMatchObject({ 'server.address': '127.0.0.1', 'server.port': String(SSH_PORT) });
+    const pre = spans.find((s) => s.name === 'pre')!;
+    const commandSpan = spans.find((s) => s.name === 'command')!;
+    expect(commandSpan.parentSpanId).toBe(pre.spanId);
+    // Secrets in commands do not leave the host
+    expect(attributes(commandSpan)).toMatchObject({ 'backapp.command.index': '1', 'backapp.command': 'PGPASSWORD=[REDACTED] pg_dump app' });
+
+    const transfer = spans.find((s) => s.name === 'transfer')!;
+    expect(attributes(transfer)).toMatchObject({ 'backapp.rules': '2', 'backapp.files': '3' });
+    const rules = spans.filter((s) => s.name === 'transfer rule');
+    expect(rules.map((s) => s.parentSpanId)).toEqual([transfer.spanId, transfer.spanId]);
+    expect(rules.map((s) => attributes(s))).toEqual([
+      { 'backapp.rule.path': '/data', 'backapp.files': '2', 'backapp.bytes': String(5000 + 'meeting notes'.length) },
+      { 'backapp.rule.path': '/etc', 'backapp.files': '1', 'backapp.bytes': String('127.0.0.1 localhost'.length) },
+    ]);
+    expect(spans.filter((s) => s.name === 'list rule').map((s) => attributes(s)['backapp.rule.path'])).toEqual(['/data', '/etc']);
+
+    // Only files from BACKAPP_TRACE_FILE_MIN_BYTES get a span
+    const files = spans.filter((s) => s.name === 'file');
+    expect(files.map((s) => attributes(s))).toEqual([{ 'file.path': '/data/video.mp4', 'file.size': '5000' }]);
+    expect(files[0].parentSpanId).toBe(rules[0].spanId);
+  });
+
+  test('marks the failed stage and run as errors', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Offline Server', '127.0.0.1', CLOSED_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Offline', serverId, storageId, namingRuleId, [{ remote_path: '/data' }]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('failed');
+    const spans = await spansOfRun(runId);
+
+    expect(spans.map((s) => s.name).sort()).toEqual(['backup run', 'connect']);
+    for (const span of spans) {
+      expect(span.status.code).toBe(2);
+      expect(span.status.message).toContain('connection refused');
+    }
+    expect(attributes(spans.find((s) => s.name === 'backup run')!)['backapp.run.status']).toBe('failed');
+  });
+
+  test('runs retention as a stage of the run', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Tracing Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Kept', serverId, storageId, namingRuleId, [{ remote_path: '/etc', recursive: true }]);
+    const update = await request.put(`/api/v1/backup-profiles/${profileId}`, {
+      data: { name: 'Kept', server_id: serverId, storage_location_id: storageId, naming_rule_id: namingRuleId, retention_days: 7, enabled: true },
+    });
+    expect(update.ok()).toBeTruthy();
+
+    const expiredRunId = await runBackupViaApi(request, profileId);
+    await waitForBackupRunComplete(request, expiredRunId);
+    const dated = await request.put(`/api/v1/test/backup-runs/${expiredRunId}/date`, {
+      data: { end_time: new Date(Date.now() - 10 * 24 * 60 * 60 * 1000).toISOString() },
+    });
+    expect(dated.ok()).toBeTruthy();
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+    const retention = (await spansOfRun(runId)).find((s) => s.name === 'retention')!;
+    expect(attributes(retention)).toEqual({
+      'backapp.retention.runs': '1',
+      'backapp.retention.files': '1',
+      'backapp.retention.bytes': String('127.0.0.1 localhost'.length),
+    });
+    expect((await (await request.get(`/api/v1/backup-runs/${expiredRunId}`)).json()).retention_cleaned_up).toBe(true);
+  });
+});
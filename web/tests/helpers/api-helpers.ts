@@ -165,7 +165,14 @@ export async function createBackupProfileViaApi(
   serverId: number,
   storageLocationId: number,
   namingRuleId: number,
-  fileRules: Array<{ remote_path: string; recursive?: boolean }>
+  fileRules: Array<{
+    remote_path: string;
+    recursive?: boolean;
+    include_pattern?: string;
+    exclude_pattern?: string;
+    max_file_size_bytes?: number;
+    modified_since?: string;
+  }>
 ): Promise<number> {
   const response = await request.post('/api/v1/backup-profiles', {
     data: {
@@ -177,6 +184,10 @@ export async function createBackupProfileViaApi(
       file_rules: fileRules.map((rule, index) => ({
         remote_path: rule.remote_path,
         recursive: rule.recursive ?? false,
+        include_pattern: rule.include_pattern,
+        exclude_pattern: rule.exclude_pattern,
+        max_file_size_bytes: rule.max_file_size_bytes,
+        modified_since: rule.modified_since,
         run_order: index + 1,
       })),
     },
@@ -248,7 +259,9 @@ export async function waitForBackupRunComplete(
 export async function getBackupRunFilesViaApi(
   request: APIRequestContext,
   runId: number
-): Promise<Array<{ id: number; local_path: string; deleted: boolean }>> {
+): Promise<
+  Array<{ id: number; local_path: string; deleted: boolean; file_type?: string; mode?: number; mod_time?: string }>
+> {
   const response = await request.get(`/api/v1/backup-runs/${runId}/files`);
   expect(response.ok()).toBeTruthy();
   return response.json();
@@ -349,3 +362,61 @@ export async function updateBackupProfileViaApi(
   });
   expect(response.ok()).toBeTruthy();
 }
+
+/**
+ * Read the Server-Sent Events stream of a finished backup run via the API
+ */
+export async function getBackupRunEventsViaApi(
+  request: APIRequestContext,
+  runId: number
+): Promise<Array<{ event: string; data: any }>> {
+  const response = await request.get(`/api/v1/backup-runs/${runId}/events`);
+  expect(response.ok()).toBeTruthy();
+  expect(response.headers()['content-type']).toContain('text/event-stream');
+  const body = await response.text();
+  return body
+    .split('\n\n')
+    .filter((block) => block.includes('data:'))
+    .map((block) => {
+      const lines = block.split('\n');
+      const event = lines.find((line) => line.startsWith('event:'))?.slice('event:'.length) ?? 'message';
+      const data = lines.find((line) => line.startsWith('data:'))?.slice('data:'.length) ?? '';
+      return { event, data: JSON.parse(data) };
+    });
+}
+
+/**
+ * Run a dry run of a backup profile via the API
+ */
+export async function dryRunBackupProfileViaApi(
+  request: APIRequestContext,
+  profileId: number
+): Promise<{
+  total_files: number;
+  total_size_bytes: number;
+  files: Array<{ remote_path: string; size_bytes: number }>;
+  rules: Array<{ remote_path: string; file_count: number; error?: string }>;
+  fits: boolean;
+  warnings: string[];
+}> {
+  const response = await request.post(`/api/v1/backup-profiles/${profileId}/dry-run`);
+  expect(response.ok()).toBeTruthy();
+  return response.json();
+}
+
+/**
+ * Administrator account created by the setup project and used by all tests
+ */
+export const TEST_ADMIN = { username: 'admin', password: 'admin-password' };
+
+/**
+ * Create an API token via the API and return the plain token
+ */
+export async function createApiTokenViaApi(
+  request: APIRequestContext,
+  data: { name: string; scope: 'read-only' | 'trigger-runs' | 'admin'; profile_ids?: number[]; expires_at?: string }
+): Promise<{ id: number; token: string }> {
+  const response = await request.post('/api/v1/api-tokens', { data });
+  expect(response.status()).toBe(201);
+  return response.json();
+}
@@ -0,0 +1,148 @@
+/**
+ * Fake OpenID Connect Provider for testing
+ *
+ * Implements discovery, an authorization endpoint that signs in the configured user
+ * without a login form, a token endpoint with PKCE verification and a key set, so that
+ * single sign-on can be tested without Keycloak.
+ */
+import { createHash, generateKeyPairSync, randomBytes, sign, type KeyObject } from 'crypto';
+import { createServer, type IncomingMessage, type Server } from 'http';
+
+/** Port and client the backend is configured with in playwright.config.ts */
+export const FAKE_OIDC_PORT = 2250;
+export const FAKE_OIDC_CLIENT_ID = 'backapp';
+
+/** The identity the provider signs in */
+export interface FakeOIDCUser {
+  sub: string;
+  preferred_username: string;
+  groups?: string[];
+}
+
+interface PendingCode {
+  clientId: string;
+  redirectUri: string;
+  nonce: string;
+  codeChallenge: string;
+  user: FakeOIDCUser;
+}
+
+export interface FakeOIDCProvider {
+  issuer: string;
+  /** Sets the user signed in by the next authorization request */
+  setUser(user: FakeOIDCUser): void;
+  close(): Promise<void>;
+}
+
+const base64url = (data: Buffer | string) => Buffer.from(data).toString('base64url');
+
+function readBody(req: IncomingMessage): Promise<string> {
+  return new Promise((resolve) => {
+    let body = '';
+    req.on('data', (chunk) => (body += chunk));
+    req.on('end', () => resolve(body));
+  });
+}
+
+function signIdToken(privateKey: KeyObject, kid: string, claims: Record<string, unknown>): string {
+  const header = base64url(JSON.stringify({ alg: 'RS256', typ: 'JWT', kid }));
+  const payload = base64url(JSON.stringify(claims));
+  const signature = sign('sha256', Buffer.from(`${header}.${payload}`), privateKey);
+  return `${header}.${payload}.${base64url(signature)}`;
+}
+
+/**
+ * Start the fake provider on the given port
+ */
+export async function startFakeOIDCProvider(port = FAKE_OIDC_PORT): Promise<FakeOIDCProvider> {
+  const issuer = `http://127.0.0.1:${port}`;
+  const { publicKey, privateKey } = generateKeyPairSync('rsa', { modulusLength: 2048 });
+  // A new key id per start looks like a key rotation to a backend that cached the previous key
+  const kid = randomBytes(8).toString('hex');
+  const jwk = { ...publicKey.export({ format: 'jwk' }), kid, use: 'sig', alg: 'RS256' };
+  const codes = new Map<string, PendingCode>();
+  let currentUser: FakeOIDCUser = { sub: 'fake-user', preferred_username: 'fake-user' };
+
+  const server: Server = createServer(async (req, res) => {
+    const url = new URL(req.url ?? '/', issuer);
+    const json = (status: number, body: unknown) => {
+      res.writeHead(status, { 'Content-Type': 'application/json' });
+      res.end(JSON.stringify(body));
+    };
+
+    if (url.pathname === '/.well-known/openid-configuration') {
+      json(200, {
+        issuer,
+        authorization_endpoint: `${issuer}/authorize`,
+        token_endpoint: `${issuer}/token`,
+        jwks_uri: `${issuer}/jwks`,
+        response_types_supported: ['code'],
+        code_challenge_methods_supported: ['S256'],
+      });
+    } else if (url.pathname === '/jwks') {
+      json(200, { keys: [jwk] });
+    } else if (url.pathname === '/authorize') {
+      const params = url.searchParams;
+      if (params.get('response_type') !== 'code' || params.get('code_challenge_method') !== 'S256') {
+        json(400, { error: 'invalid_request' });
+        return;
+      }
+      const code = randomBytes(16).toString('hex');
+      codes.set(code, {
+        clientId: params.get('client_id') ?? '',
+        redirectUri: params.get('redirect_uri') ?? '',
+        nonce: params.get('nonce') ?? '',
+        codeChallenge: params.get('code_challenge') ?? '',
+        user: currentUser,
+      });
+      const redirect = new URL(params.get('redirect_uri') ?? '');
+      redirect.searchParams.set('code', code);
+      redirect.searchParams.set('state', params.get('state') ?? '');
+      res.writeHead(302, { Location: redirect.toString() });
+      res.end();
+    } else if (url.pathname === '/token' && req.method === 'POST') {
+      const form = new URLSearchParams(await readBody(req));
+      const pending = codes.get(form.get('code') ?? '');
+      codes.delete(form.get('code') ?? '');
+      const verifier = form.get('code_verifier') ?? '';
+      if (
+        !pending ||
+        form.get('grant_type') !== 'authorization_code' ||
+        form.get('client_id') !== pending.clientId ||
+        form.get('redirect_uri') !== pending.redirectUri ||
+        base64url(createHash('sha256').update(verifier).digest()) !== pending.codeChallenge
+      ) {
+        json(400, { error: 'invalid_grant' });
+        return;
+      }
+      const now = Math.floor(Date.now() / 1000);
+      json(200, {
+        access_token: randomBytes(16).toString('hex'),
+        token_type: 'Bearer',
+        expires_in: 300,
+        id_token: signIdToken(privateKey, kid, {
+          iss: issuer,
+          aud: pending.clientId,
+          iat: now,
+          exp: now + 300,
+          nonce: pending.nonce,
+          ...pending.user,
+        }),
+      });
+    } else {
+      json(404, { error: 'not_found' });
+    }
+  });
+
+  await new Promise<void>((resolve) => server.listen(port, '127.0.0.1', resolve));
+
+  return {
+    issuer,
+    setUser(user: FakeOIDCUser) {
+      currentUser = user;
+    },
+    close() {
+      return new Promise((resolve) => server.close(() => resolve()));
+    },
+  };
+}
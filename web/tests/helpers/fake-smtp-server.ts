@@ -0,0 +1,170 @@
+/**
+ * Fake SMTP Server for testing
+ *
+ * Accepts every message without TLS or authentication and keeps it in memory, decoded
+ * into its plain text and HTML parts, so that email notifications can be tested without
+ * a mail server.
+ */
+import { createServer, type Server, type Socket } from 'net';
+
+/** Port the backend is configured with in playwright.config.ts */
+export const FAKE_SMTP_PORT = 2255;
+
+export interface ReceivedEmail {
+  from: string;
+  to: string[];
+  subject: string;
+  headers: Record<string, string>;
+  text: string;
+  html: string;
+}
+
+export interface FakeSMTPServer {
+  messages: ReceivedEmail[];
+  /** Waits until a message matching the predicate arrives */
+  waitForMessage(predicate: (message: ReceivedEmail) => boolean, timeout?: number): Promise<ReceivedEmail>;
+  close(): Promise<void>;
+}
+
+const decodeQuotedPrintable = (body: string) =>
+  Buffer.from(
+    body.replace(/=\r?\n/g, '').replace(/=([0-9A-F]{2})/gi, (_, hex) => String.fromCharCode(parseInt(hex, 16))),
+    'latin1'
+  ).toString('utf-8');
+
+function parseHeaders(block: string): Record<string, string> {
+  const headers: Record<string, string> = {};
+  for (const line of block.replace(/\r?\n[ \t]+/g, ' ').split(/\r?\n/)) {
+    const colon = line.indexOf(':');
+    if (colon > 0) headers[line.slice(0, colon).toLowerCase()] = line.slice(colon + 1).trim();
+  }
+  return headers;
+}
+
+function splitHeaders(raw: string): [string, string] {
+  const end = raw.search(/\r?\n\r?\n/);
+  return end < 0 ? [raw, ''] : [raw.slice(0, end), raw.slice(end).replace(/^\r?\n\r?\n/, '')];
+}
+
+function decodeSubject(subject: string): string {
+  return subject.replace(/=\?utf-8\?q\?([^?]*)\?=/gi, (_, text) => decodeQuotedPrintable(text.replace(/_/g, ' ')));
+}
+
+function parseMessage(from: string, to: string[], raw: string): ReceivedEmail {
+  const [headerBlock, body] = splitHeaders(raw);
+  const headers = parseHeaders(headerBlock);
+  const message: ReceivedEmail = { from, to, subject: decodeSubject(headers.subject ?? ''), headers, text: '', html: '' };
+
+  const boundary = /boundary="?([^";]+)"?/.exec(headers['content-type'] ?? '')?.[1];
+  const parts = boundary ? body.split(`--${boundary}`).slice(1, -1) : [raw];
+  for (const part of parts) {
+    const [partHeaderBlock, partBody] = splitHeaders(part.replace(/^\r?\n/, ''));
+    const partHeaders = parseHeaders(partHeaderBlock);
+    const content = /quoted-printable/i.test(partHeaders['content-transfer-encoding'] ?? '')
+      ? decodeQuotedPrintable(partBody)
+      : partBody;
+    if (/text\/html/i.test(partHeaders['content-type'] ?? '')) {
+      message.html = content;
+    } else {
+      message.text = content;
+    }
+  }
+  return message;
+}
+
+function handleSession(socket: Socket, onMessage: (message: ReceivedEmail) => void) {
+  let buffer = '';
+  let inData = false;
+  let data: string[] = [];
+  let from = '';
+  let to: string[] = [];
+  const reply = (line: string) => socket.write(`${line}\r\n`);
+
+  reply('220 fake-smtp ESMTP ready');
+  socket.on('data', (chunk) => {
+    buffer += chunk.toString('latin1');
+    let newline: number;
+    while ((newline = buffer.indexOf('\r\n')) >= 0) {
+      const line = buffer.slice(0, newline);
+      buffer = buffer.slice(newline + 2);
+
+      if (inData) {
+        if (line === '.') {
+          inData = false;
+          onMessage(parseMessage(from, to, Buffer.from(data.join('\r\n'), 'latin1').toString('utf-8')));
+          data = [];
+          to = [];
+          reply('250 OK: queued');
+        } else {
+          // Undo dot-stuffing
+          data.push(line.startsWith('.') ? line.slice(1) : line);
+        }
+        continue;
+      }
+
+      const command = line.slice(0, 4).toUpperCase();
+      if (command === 'EHLO') {
+        reply('250-fake-smtp');
+        reply('250 8BITMIME');
+      } else if (command === 'HELO') {
+        reply('250 fake-smtp');
+      } else if (command === 'MAIL') {
+        from = /<([^>]*)>/.exec(line)?.[1] ?? '';
+        reply('250 OK');
+      } else if (command === 'RCPT') {
+        to.push(/<([^>]*)>/.exec(line)?.[1] ?? '');
+        reply('250 OK');
+      } else if (command === 'DATA') {
+        inData = true;
+        reply('354 End data with <CR><LF>.<CR><LF>');
+      } else if (command === 'QUIT') {
+        reply('221 Bye');
+        socket.end();
+      } else {
+        reply('250 OK');
+      }
+    }
+  });
+  socket.on('error', () => {});
+}
+
+/**
+ * Start the fake SMTP server on the given port
+ */
+export async function startFakeSMTPServer(port = FAKE_SMTP_PORT): Promise<FakeSMTPServer> {
+  const messages: ReceivedEmail[] = [];
+  const waiters: { predicate: (message: ReceivedEmail) => boolean; resolve: (message: ReceivedEmail) => void }[] = [];
+
+  const server: Server = createServer((socket) =>
+    handleSession(socket, (message) => {
+      messages.push(message);
+      for (const waiter of [...waiters]) {
+        if (waiter.predicate(message)) {
+          waiters.splice(waiters.indexOf(waiter), 1);
+          waiter.resolve(message);
+        }
+      }
+    })
+  );
+  await new Promise<void>((resolve) => server.listen(port, '127.0.0.1', resolve));
+
+  return {
+    messages,
+    waitForMessage(predicate, timeout = 10000) {
+      const received = messages.find(predicate);
+      if (received) return Promise.resolve(received);
+      return new Promise((resolve, reject) => {
+        const waiter = { predicate, resolve };
+        waiters.push(waiter);
+        setTimeout(() => {
+          const index = waiters.indexOf(waiter);
+          if (index >= 0) {
+            waiters.splice(index, 1);
+            reject(new Error(`No matching email received within ${timeout}ms`));
+          }
+        }, timeout);
+      });
+    },
+    close: () => new Promise((resolve) => server.close(() => resolve())),
+  };
+}
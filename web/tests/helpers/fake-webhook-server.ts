@@ -0,0 +1,94 @@
+/**
+ * Fake Webhook Receiver for testing
+ *
+ * Records every request it receives and answers with queued status codes, so that
+ * signatures, retries and the delivery log of webhooks can be tested.
+ */
+import { createHmac } from 'crypto';
+import { createServer, type Server } from 'http';
+
+export interface ReceivedWebhook {
+  path: string;
+  headers: Record<string, string>;
+  body: string;
+  /** Status code the request was answered with */
+  status: number;
+}
+
+export interface FakeWebhookServer {
+  url: string;
+  requests: ReceivedWebhook[];
+  /** Answers the next requests with these status codes, then with 200 again */
+  respondWith(...statuses: number[]): void;
+  /** Waits until a request matching the predicate arrives */
+  waitForRequest(predicate: (request: ReceivedWebhook) => boolean, timeout?: number): Promise<ReceivedWebhook>;
+  close(): Promise<void>;
+}
+
+/**
+ * Compute the signature BackApp sends in the X-BackApp-Signature header
+ */
+export function signWebhook(secret: string, timestamp: string, body: string): string {
+  return 'sha256=' + createHmac('sha256', secret).update(`${timestamp}.${body}`).digest('hex');
+}
+
+/**
+ * Start the fake webhook receiver on the given port
+ */
+export async function startFakeWebhookServer(port: number): Promise<FakeWebhookServer> {
+  const requests: ReceivedWebhook[] = [];
+  const statuses: number[] = [];
+  const waiters: { predicate: (request: ReceivedWebhook) => boolean; resolve: (request: ReceivedWebhook) => void }[] = [];
+
+  const server: Server = createServer((req, res) => {
+    const chunks: Buffer[] = [];
+    req.on('data', (chunk: Buffer) => chunks.push(chunk));
+    req.on('end', () => {
+      const headers: Record<string, string> = {};
+      for (const [name, value] of Object.entries(req.headers)) {
+        headers[name] = Array.isArray(value) ? value.join(', ') : (value ?? '');
+      }
+      const received: ReceivedWebhook = {
+        path: req.url ?? '/',
+        headers,
+        body: Buffer.concat(chunks).toString('utf-8'),
+        status: statuses.shift() ?? 200,
+      };
+      requests.push(received);
+      res.writeHead(received.status, { 'Content-Type': 'text/plain' });
+      res.end(`received with ${received.status}`);
+
+      for (const waiter of [...waiters]) {
+        if (waiter.predicate(received)) {
+          waiters.splice(waiters.indexOf(waiter), 1);
+          waiter.resolve(received);
+        }
+      }
+    });
+  });
+  await new Promise<void>((resolve) => server.listen(port, '127.0.0.1', resolve));
+
+  return {
+    url: `http://127.0.0.1:${port}`,
+    requests,
+    respondWith(...codes) {
+      statuses.push(...codes);
+    },
+    waitForRequest(predicate, timeout = 10000) {
+      const received = requests.find(predicate);
+      if (received) return Promise.resolve(received);
+      return new Promise((resolve, reject) => {
+        const waiter = { predicate, resolve };
+        waiters.push(waiter);
+        setTimeout(() => {
+          const index = waiters.indexOf(waiter);
+          if (index >= 0) {
+            waiters.splice(index, 1);
+            reject(new Error(`No matching webhook request received within ${timeout}ms`));
+          }
+        }, timeout);
+      });
+    },
+    close: () => new Promise((resolve) => server.close(() => resolve())),
+  };
+}
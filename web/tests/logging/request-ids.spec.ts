@@ -0,0 +1,33 @@
+/**
+ * Request ID Tests
+ *
+ * Tests the request IDs that the server logs with each request and returns to clients
+ */
+import { expect, test } from '@playwright/test';
+
+test.describe('Request IDs', () => {
+  test('generates a request ID for each request', async ({ request }) => {
+    const first = await request.get('/api/v1/health');
+    const second = await request.get('/api/v1/health');
+    expect(first.headers()['x-request-id']).toMatch(/^[0-9a-f]{16}$/);
+    expect(second.headers()['x-request-id']).not.toBe(first.headers()['x-request-id']);
+  });
+
+  test('keeps the request ID of a proxy', async ({ request }) => {
+    const response = await request.get('/api/v1/health', { headers: { 'X-Request-ID': 'proxy-7f3a.42' } });
+    expect(response.headers()['x-request-id']).toBe('proxy-7f3a.42');
+  });
+
+  test('replaces request IDs that are unsafe to log', async ({ request }) => {
+    const response = await request.get('/api/v1/health', { headers: { 'X-Request-ID': 'forged\tlevel=ERROR' } });
+    expect(response.headers()['x-request-id']).toMatch(/^[0-9a-f]{16}$/);
+  });
+
+  test('sets request IDs on errors and pages', async ({ request }) => {
+    const missing = await request.get('/api/v1/servers/999999');
+    expect(missing.status()).toBe(404);
+    expect(missing.headers()['x-request-id']).toBeTruthy();
+    const page = await request.get('/servers');
+    expect(page.headers()['x-request-id']).toBeTruthy();
+  });
+});
@@ -0,0 +1,133 @@
+/**
+ * Metrics Tests
+ *
+ * Tests the Prometheus metrics of backup runs, storage locations, the scheduler and SSH
+ * connections, scraped with API tokens
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createApiTokenViaApi,
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+
+/** Returns the value of the sample with exactly these labels */
+function sample(metrics: string, name: string, labels: Record<string, string>): number | undefined {
+  const wanted = Object.entries(labels)
+    .map(([key, value]) => `${key}="${value}"`)
+    .join(',');
+  const line = metrics.split('\n').find((l) => l.startsWith(`${name}{${wanted}} `));
+  return line === undefined ? undefined : Number(line.split(' ').pop());
+}
+
+test.describe('Metrics', () => {
+  const SSH_PORT = 2275;
+  // Nothing listens here, so connections fail
+  const CLOSED_PORT = 2276;
+  const storagePath = `${TEST_BASE_PATH}/metrics`;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    virtualFiles.set('/data/todo.txt', createVirtualFile('water plants'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Metrics Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Metrics Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function scrape(request: APIRequestContext, baseURL: string | undefined) {
+    const { token } = await createApiTokenViaApi(request, { name: 'prometheus', scope: 'read-only' });
+    const prometheus = await playwrightRequest.newContext({
+      baseURL,
+      storageState: { cookies: [], origins: [] },
+      extraHTTPHeaders: { Authorization: `Bearer ${token}` },
+    });
+    return prometheus.get('/metrics');
+  }
+
+  test('exposes run metrics per profile', async ({ request, baseURL }) => {
+    const serverId = await createServerViaApi(request, 'Metrics Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Documents', serverId, storageId, namingRuleId, [
+      { remote_path: '/data', recursive: true },
+    ]);
+    const runId = await runBackupViaApi(request, profileId);
+    const run = await waitForBackupRunComplete(request, runId);
+    expect(run.status).toBe('completed');
+
+    const response = await scrape(request, baseURL);
+    expect(response.ok()).toBeTruthy();
+    expect(response.headers()['content-type']).toContain('text/plain; version=0.0.4');
+    const metrics = await response.text();
+    expect(metrics).toContain('# TYPE backapp_backup_runs_total counter');
+
+    const labels = { profile_id: String(profileId), profile: 'Documents', server: 'Metrics Server' };
+    expect(sample(metrics, 'backapp_backup_runs_total', { ...labels, status: 'completed' })).toBe(1);
+    expect(sample(metrics, 'backapp_backup_runs_total', { ...labels, status: 'failed' })).toBe(0);
+    expect(sample(metrics, 'backapp_backup_last_run_status', { ...labels, status: 'completed' })).toBe(1);
+    expect(sample(metrics, 'backapp_backup_last_run_files', labels)).toBe(2);
+    expect(sample(metrics, 'backapp_backup_transferred_bytes_total', labels)).toBe('meeting notes'.length + 'water plants'.length);
+    expect(sample(metrics, 'backapp_backup_last_success_timestamp_seconds', labels)).toBe(Math.floor(Date.parse(run.end_time) / 1000));
+    expect(sample(metrics, 'backapp_backup_last_run_duration_seconds', labels)).toBeGreaterThan(0);
+    expect(sample(metrics, 'backapp_retention_deleted_runs_total', labels)).toBe(0);
+
+    const location = { storage_location_id: String(storageId), storage_location: 'Metrics Storage' };
+    expect(sample(metrics, 'backapp_storage_free_bytes', location)).toBeGreaterThan(0);
+    expect(sample(metrics, 'backapp_storage_backup_bytes', location)).toBeGreaterThan(0);
+    expect(metrics).toMatch(/^backapp_scheduler_queue_depth 0$/m);
+  });
+
+  test('counts failed SSH connections and failed runs', async ({ request, baseURL }) => {
+    const serverId = await createServerViaApi(request, 'Offline "Server"', '127.0.0.1', CLOSED_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Unreachable', serverId, storageId, namingRuleId, [
+      { remote_path: '/data', recursive: true },
+    ]);
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('failed');
+
+    const metrics = await (await scrape(request, baseURL)).text();
+    const server = { server_id: String(serverId), server: 'Offline \\"Server\\"' };
+    expect(sample(metrics, 'backapp_ssh_connection_errors_total', server)).toBeGreaterThanOrEqual(1);
+    const labels = { profile_id: String(profileId), profile: 'Unreachable', server: 'Offline \\"Server\\"' };
+    expect(sample(metrics, 'backapp_backup_runs_total', { ...labels, status: 'failed' })).toBe(1);
+    expect(sample(metrics, 'backapp_backup_last_run_status', { ...labels, status: 'failed' })).toBe(1);
+    expect(sample(metrics, 'backapp_backup_last_success_timestamp_seconds', labels)).toBeUndefined();
+  });
+
+  test('requires a token with a global role', async ({ request, baseURL }) => {
+    const serverId = await createServerViaApi(request, 'Metrics Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Documents', serverId, storageId, namingRuleId, [{ remote_path: '/data' }]);
+
+    const anonymous = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    expect((await anonymous.get('/metrics')).status()).toBe(401);
+
+    const { token } = await createApiTokenViaApi(request, { name: 'one profile', scope: 'read-only', profile_ids: [profileId] });
+    const restricted = await playwrightRequest.newContext({
+      baseURL,
+      storageState: { cookies: [], origins: [] },
+      extraHTTPHeaders: { Authorization: `Bearer ${token}` },
+    });
+    expect((await restricted.get('/metrics')).status()).toBe(403);
+  });
+});
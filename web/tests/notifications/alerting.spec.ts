@@ -0,0 +1,218 @@
+/**
+ * Alerting Tests
+ *
+ * Tests consecutive failure thresholds, snoozing repeated alerts, recovery notifications
+ * and quiet hours, against a fake Slack endpoint and a fake webhook receiver
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+/** HH:MM in local time, which is the server's time zone in tests */
+const localTime = (hoursFromNow: number) => {
+  const date = new Date(Date.now() + hoursFromNow * 60 * 60 * 1000);
+  return `${String(date.getHours()).padStart(2, '0')}:${String(date.getMinutes()).padStart(2, '0')}`;
+};
+
+test.describe('Alerting', () => {
+  const RECEIVER_PORT = 2272;
+  const SSH_PORT = 2273;
+  // Nothing listens here, so runs against it fail
+  const CLOSED_PORT = 2274;
+  const storagePath = `${TEST_BASE_PATH}/alerting`;
+  let receiver: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let serverId: number;
+  let profileId: number;
+
+  test.beforeAll(async () => {
+    receiver = await startFakeWebhookServer(RECEIVER_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await receiver.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    const storageId = await createStorageLocationViaApi(request, 'Alerting Storage', storagePath);
+    const namingRuleId = await createNamingRuleViaApi(request, 'Alerting Naming', '{profile}-{TIMESTAMP}');
+    serverId = await createServerViaApi(request, 'Flaky Server', '127.0.0.1', CLOSED_PORT, 'root', 'testpass');
+    profileId = await createBackupProfileViaApi(request, 'Flaky', serverId, storageId, namingRuleId, [{ remote_path: '/data', recursive: true }]);
+  });
+
+  async function setServerPort(request: APIRequestContext, port: number) {
+    const response = await request.put(`/api/v1/servers/${serverId}`, {
+      data: { name: 'Flaky Server', host: '127.0.0.1', port, username: 'root', auth_type: 'password', password: 'testpass' },
+    });
+    expect(response.ok()).toBeTruthy();
+  }
+
+  async function runBackup(request: APIRequestContext, status: string) {
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe(status);
+  }
+
+  /** Creates a Slack integration posting to the path and replaces its default rule */
+  async function createChat(request: APIRequestContext, name: string, path: string, rule: Record<string, unknown>) {
+    const integration = await (
+      await request.post('/api/v1/notifications/chat-integrations', { data: { name, provider: 'slack', url: `${receiver.url}/${path}` } })
+    ).json();
+    const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+    const response = await request.put(`/api/v1/notifications/preferences/${listed.preferences[0].id}`, {
+      data: {
+        notify_on_start: false,
+        notify_on_success: false,
+        notify_on_failure: false,
+        notify_on_consecutive_failures: false,
+        notify_on_missed_backup: false,
+        notify_on_low_storage: false,
+        ...rule,
+      },
+    });
+    expect(response.ok()).toBeTruthy();
+    return integration.id as number;
+  }
+
+  const messages = (path: string) => receiver.requests.filter((r) => r.path === path).map((r) => JSON.parse(r.body).text);
+
+  test('alerts once at the failure threshold and when the profile recovers', async ({ request }) => {
+    await createChat(request, 'Ops', 'ops', { notify_on_consecutive_failures: true, consecutive_failure_threshold: 2 });
+    const webhook = await request.post('/api/v1/notifications/webhooks', {
+      data: { name: 'Alerts', url: `${receiver.url}/hook`, events: ['backup.consecutive_failures', 'backup.recovered'] },
+    });
+    expect(webhook.status()).toBe(201);
+
+    await runBackup(request, 'failed');
+    await runBackup(request, 'failed');
+    await receiver.waitForRequest((r) => r.path === '/ops');
+    await runBackup(request, 'failed');
+    await setServerPort(request, SSH_PORT);
+    await runBackup(request, 'completed');
+
+    await receiver.waitForRequest((r) => r.path === '/ops' && JSON.parse(r.body).text.includes('recovered'));
+    expect(messages('/ops')).toEqual(["Backup 'Flaky' failed 2 times in a row", "Backup 'Flaky' recovered"]);
+
+    const recovered = await receiver.waitForRequest((r) => r.headers['x-backapp-event'] === 'backup.recovered');
+    expect(JSON.parse(recovered.body).data).toMatchObject({ backup_profile: 'Flaky', status: 'completed', failure_count: 3 });
+    const events = receiver.requests.filter((r) => r.path === '/hook').map((r) => r.headers['x-backapp-event']);
+    expect(events).toEqual(['backup.consecutive_failures', 'backup.recovered']);
+  });
+
+  test('alerts again for a new series of failures', async ({ request }) => {
+    await createChat(request, 'Ops', 'again', { notify_on_consecutive_failures: true, consecutive_failure_threshold: 2 });
+
+    await runBackup(request, 'failed');
+    await runBackup(request, 'failed');
+    await setServerPort(request, SSH_PORT);
+    await runBackup(request, 'completed');
+    await receiver.waitForRequest((r) => r.path === '/again' && JSON.parse(r.body).text.includes('recovered'));
+    await setServerPort(request, CLOSED_PORT);
+    await runBackup(request, 'failed');
+    await runBackup(request, 'failed');
+
+    await expect.poll(() => messages('/again')).toEqual([
+      "Backup 'Flaky' failed 2 times in a row",
+      "Backup 'Flaky' recovered",
+      "Backup 'Flaky' failed 2 times in a row",
+    ]);
+  });
+
+  test('holds notifications other than failures during quiet hours', async ({ request }) => {
+    await createChat(request, 'Night', 'night', {
+      notify_on_success: true,
+      notify_on_failure: true,
+      quiet_hours_start: localTime(-1),
+      quiet_hours_end: localTime(1),
+    });
+
+    await runBackup(request, 'failed');
+    await receiver.waitForRequest((r) => r.path === '/night');
+    await setServerPort(request, SSH_PORT);
+    await runBackup(request, 'completed');
+    await runBackup(request, 'completed');
+    expect(messages('/night')).toEqual(["Backup 'Flaky' failed"]);
+
+    // Nothing is delivered while the quiet hours last
+    const during = await request.post('/api/v1/test/deliver-held-notifications', { data: {} });
+    expect(await during.json()).toEqual([]);
+
+    const after = await request.post('/api/v1/test/deliver-held-notifications', {
+      data: { at: new Date(Date.now() + 2 * 60 * 60 * 1000).toISOString() },
+    });
+    const [batch] = await after.json();
+    expect(batch.notifications).toEqual(["Backup 'Flaky' recovered", "Backup 'Flaky' completed"]);
+
+    const held = await receiver.waitForRequest((r) => r.path === '/night' && JSON.parse(r.body).text.includes('quiet hours'));
+    const message = JSON.parse(held.body);
+    expect(message.text).toBe('2 notifications during quiet hours');
+    expect(message.attachments[0].fields.map((f: { value: string }) => f.value)).toEqual([
+      "Backup 'Flaky' recovered",
+      "Backup 'Flaky' completed",
+    ]);
+  });
+
+  test('rejects incomplete quiet hours', async ({ request }) => {
+    await createChat(request, 'Invalid', 'invalid', {});
+    const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+    const response = await request.put(`/api/v1/notifications/preferences/${listed.preferences[0].id}`, {
+      data: { notify_on_failure: true, quiet_hours_start: '22:00' },
+    });
+    expect(response.status()).toBe(400);
+    expect((await response.json()).error).toContain('quiet_hours');
+  });
+
+  test('rejects failure thresholds below two', async ({ request }) => {
+    await createChat(request, 'Threshold', 'threshold', {});
+    const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+    for (const threshold of [-1, 1]) {
+      const response = await request.put(`/api/v1/notifications/preferences/${listed.preferences[0].id}`, {
+        data: { notify_on_consecutive_failures: true, consecutive_failure_threshold: threshold },
+      });
+      expect(response.status()).toBe(400);
+      expect((await response.json()).error).toContain('consecutive_failure_threshold');
+    }
+
+    // Without a threshold the default applies
+    const response = await request.put(`/api/v1/notifications/preferences/${listed.preferences[0].id}`, {
+      data: { notify_on_consecutive_failures: true },
+    });
+    expect((await response.json()).consecutive_failure_threshold).toBe(3);
+  });
+
+  test('sets the quiet hours of a rule in the notification settings', async ({ page, request }) => {
+    const integrationId = await createChat(request, 'Team Chat', 'team', { notify_on_failure: true });
+
+    await page.goto('/notifications');
+    const card = page.getByTestId(`chat-integration-${integrationId}`);
+    await card.getByLabel('Team Chat Quiet hours from').fill('22:00');
+    await card.getByLabel('Team Chat Quiet hours until').fill('07:00');
+    await card.getByLabel('Team Chat Quiet hours from').focus();
+    await card.getByLabel('Team Chat Quiet hours from').blur();
+
+    await expect
+      .poll(async () => {
+        const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+        return [listed.preferences[0].quiet_hours_start, listed.preferences[0].quiet_hours_end];
+      })
+      .toEqual(['22:00', '07:00']);
+  });
+});
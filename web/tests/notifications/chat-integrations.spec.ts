@@ -0,0 +1,217 @@
+/**
+ * Chat Integration Tests
+ *
+ * Tests Slack, Discord, Matrix, ntfy and Gotify integrations, their message formats and
+ * preference filtering, against a fake receiver
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+interface ChatIntegration {
+  id: number;
+  name: string;
+  provider: string;
+  masked_url: string;
+  has_token: boolean;
+  token?: string;
+  url?: string;
+  preferences?: { id: number }[];
+}
+
+test.describe('Chat Integrations', () => {
+  const RECEIVER_PORT = 2262;
+  const SSH_PORT = 2263;
+  const storagePath = `${TEST_BASE_PATH}/chat-integrations`;
+  let receiver: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    receiver = await startFakeWebhookServer(RECEIVER_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await receiver.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Chat Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Chat Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function createIntegration(request: APIRequestContext, data: Record<string, unknown>): Promise<ChatIntegration> {
+    const response = await request.post('/api/v1/notifications/chat-integrations', { data });
+    expect(response.status()).toBe(201);
+    return response.json();
+  }
+
+  async function sendTest(request: APIRequestContext, integration: ChatIntegration) {
+    const response = await request.post(`/api/v1/notifications/chat-integrations/${integration.id}/test`);
+    expect(response.ok()).toBeTruthy();
+  }
+
+  test('validates integrations and never returns tokens', async ({ request }) => {
+    const invalid = [
+      { name: '', provider: 'slack', url: `${receiver.url}/slack` },
+      { name: 'IRC', provider: 'irc', url: `${receiver.url}/irc` },
+      { name: 'No room', provider: 'matrix', url: receiver.url, token: 'matrix-token' },
+      { name: 'No token', provider: 'matrix', url: receiver.url, channel: '!ops:example.org' },
+      { name: 'No topic', provider: 'ntfy', url: receiver.url },
+      { name: 'No app token', provider: 'gotify', url: receiver.url },
+    ];
+    for (const data of invalid) {
+      expect((await request.post('/api/v1/notifications/chat-integrations', { data })).status()).toBe(400);
+    }
+
+    const gotify = await createIntegration(request, { name: 'Phone', provider: 'gotify', url: receiver.url, token: 'app-token' });
+    expect(gotify).toMatchObject({ has_token: true, enabled: true, masked_url: receiver.url });
+    expect(gotify.token).toBeUndefined();
+    expect(gotify.url).toBeUndefined();
+
+    // An update without a token keeps the stored one
+    const updated = await request.put(`/api/v1/notifications/chat-integrations/${gotify.id}`, {
+      data: { name: 'Phone', provider: 'gotify', url: `${receiver.url}/gotify` },
+    });
+    expect(await updated.json()).toMatchObject({ has_token: true, masked_url: `${receiver.url}/…` });
+    // and one without a URL keeps the stored URL
+    const renamed = await request.put(`/api/v1/notifications/chat-integrations/${gotify.id}`, {
+      data: { name: 'Mobile', provider: 'gotify' },
+    });
+    expect(renamed.ok()).toBeTruthy();
+    await sendTest(request, gotify);
+    const received = await receiver.waitForRequest((r) => r.path === '/gotify/message');
+    expect(received.headers['x-gotify-key']).toBe('app-token');
+
+    const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+    expect(listed).toMatchObject({ id: gotify.id, has_token: true, preferences: [expect.objectContaining({ notify_on_failure: true })] });
+    expect(listed.token).toBeUndefined();
+  });
+
+  test('formats messages for each provider', async ({ request }) => {
+    const slack = await createIntegration(request, { name: 'Slack', provider: 'slack', url: `${receiver.url}/slack` });
+    const discord = await createIntegration(request, { name: 'Discord', provider: 'discord', url: `${receiver.url}/discord` });
+    const matrix = await createIntegration(request, {
+      name: 'Matrix',
+      provider: 'matrix',
+      url: `${receiver.url}/matrix`,
+      channel: '!ops:example.org',
+      token: 'matrix-token',
+    });
+    const ntfy = await createIntegration(request, { name: 'ntfy', provider: 'ntfy', url: `${receiver.url}/ntfy`, channel: 'backups' });
+    for (const integration of [slack, discord, matrix, ntfy]) {
+      await sendTest(request, integration);
+    }
+
+    const slackBody = JSON.parse((await receiver.waitForRequest((r) => r.path === '/slack')).body);
+    expect(slackBody).toMatchObject({ text: 'Test Notification', attachments: [{ title: 'Test Notification', color: '#1976d2' }] });
+
+    const discordBody = JSON.parse((await receiver.waitForRequest((r) => r.path === '/discord')).body);
+    expect(discordBody).toMatchObject({ username: 'BackApp', embeds: [{ title: 'Test Notification', color: 0x1976d2 }] });
+
+    const matrixRequest = await receiver.waitForRequest((r) => r.path.startsWith('/matrix/'));
+    expect(matrixRequest.path).toMatch(/^\/matrix\/_matrix\/client\/v3\/rooms\/%21ops:example\.org\/send\/m\.room\.message\/.+/);
+    expect(matrixRequest.headers.authorization).toBe('Bearer matrix-token');
+    expect(JSON.parse(matrixRequest.body)).toMatchObject({
+      msgtype: 'm.notice',
+      format: 'org.matrix.custom.html',
+      formatted_body: expect.stringContaining('<strong>'),
+    });
+
+    const ntfyRequest = await receiver.waitForRequest((r) => r.path === '/ntfy/');
+    expect(ntfyRequest.headers.authorization).toBeUndefined();
+    expect(JSON.parse(ntfyRequest.body)).toMatchObject({ topic: 'backups', title: 'Test Notification', priority: 3 });
+  });
+
+  test('only notifies integrations whose rules match', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Chat Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, 'Chat Files', serverId, storageId, namingRuleId, [
+      { remote_path: '/data', recursive: true },
+    ]);
+    // Both start with the default rule, which does not include successes
+    await createIntegration(request, { name: 'Failures', provider: 'slack', url: `${receiver.url}/failures-only` });
+    const serverRule = await createIntegration(request, { name: 'Server', provider: 'discord', url: `${receiver.url}/server-rule` });
+    const rule = await request.post(`/api/v1/notifications/chat-integrations/${serverRule.id}/preferences`, {
+      data: { server_id: serverId, notify_on_success: true },
+    });
+    expect(rule.ok()).toBeTruthy();
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+
+    const received = await receiver.waitForRequest((r) => r.path === '/server-rule');
+    expect(JSON.parse(received.body).embeds[0]).toMatchObject({
+      title: "Backup 'Chat Files' completed",
+      fields: expect.arrayContaining([{ name: 'Run', value: `#${runId}`, inline: true }]),
+    });
+    expect(receiver.requests.filter((r) => r.path === '/failures-only')).toHaveLength(0);
+  });
+
+  test('reports provider errors when testing', async ({ request }) => {
+    const integration = await createIntegration(request, { name: 'Broken', provider: 'discord', url: `${receiver.url}/broken` });
+    receiver.respondWith(500);
+
+    const response = await request.post(`/api/v1/notifications/chat-integrations/${integration.id}/test`);
+    expect(response.status()).toBe(502);
+    expect((await response.json()).error).toContain('500');
+  });
+
+  test('only global admins manage chat integrations', async ({ request, baseURL }) => {
+    const integration = await createIntegration(request, { name: 'Admin only', provider: 'slack', url: `${receiver.url}/admin` });
+    const prefId = integration.preferences?.[0]?.id;
+    const username = `chat-viewer-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'viewer-password' } })).json();
+    expect((await request.post(`/api/v1/users/${user.id}/roles`, { data: { role: 'viewer' } })).status()).toBe(201);
+    const viewer = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    try {
+      expect((await viewer.post('/api/v1/auth/login', { data: { username, password: 'viewer-password' } })).ok()).toBeTruthy();
+      expect((await viewer.get('/api/v1/notifications/chat-integrations')).status()).toBe(403);
+      expect((await viewer.post(`/api/v1/notifications/chat-integrations/${integration.id}/test`)).status()).toBe(403);
+      if (prefId) {
+        expect((await viewer.delete(`/api/v1/notifications/preferences/${prefId}`)).status()).toBe(403);
+      }
+    } finally {
+      await viewer.dispose();
+      await request.delete(`/api/v1/users/${user.id}`);
+    }
+  });
+
+  test('adds integrations in the notification settings', async ({ page }) => {
+    await page.goto('/notifications');
+    const card = page.getByTestId('chat-integrations');
+
+    await page.getByTestId('add-chat-integration-btn').click();
+    await page.getByTestId('select-chat-provider').click();
+    await page.getByRole('option', { name: 'ntfy' }).click();
+    await page.getByTestId('input-chat-name').fill('Ops Phone');
+    await page.getByTestId('input-chat-url').fill(`${receiver.url}/ui`);
+    await page.getByTestId('input-chat-channel').fill('ops');
+    await page.getByTestId('save-chat-integration-btn').click();
+
+    await expect(card.getByText('Ops Phone')).toBeVisible();
+    await card.getByRole('button', { name: 'Send Test' }).click();
+    await expect(card.getByText('Test message sent to Ops Phone')).toBeVisible();
+    const received = await receiver.waitForRequest((r) => r.path === '/ui/');
+    expect(JSON.parse(received.body)).toMatchObject({ topic: 'ops' });
+  });
+});
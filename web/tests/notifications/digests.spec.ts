@@ -0,0 +1,189 @@
+/**
+ * Digest Tests
+ *
+ * Tests the daily and weekly digests of runs, transferred data, storage growth, upcoming
+ * retention deletions and overdue profiles, sent through chat integrations and webhooks
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+interface DigestProfile {
+  backup_profile_id: number;
+  backup_profile: string;
+  runs: number;
+  succeeded: number;
+  failed: number;
+  transferred_bytes: number;
+  stored_bytes: number;
+  storage_growth_bytes: number;
+  no_recent_success: boolean;
+  retention_deletions: number;
+}
+
+interface Digest {
+  period: string;
+  server_id?: number;
+  scope?: string;
+  runs: number;
+  succeeded: number;
+  failed: number;
+  profiles: DigestProfile[];
+}
+
+const daysFromNow = (days: number) => new Date(Date.now() + days * 24 * 60 * 60 * 1000).toISOString();
+
+test.describe('Digests', () => {
+  const RECEIVER_PORT = 2269;
+  const SSH_PORT = 2270;
+  // Nothing listens here, so runs against it fail
+  const CLOSED_PORT = 2271;
+  const storagePath = `${TEST_BASE_PATH}/digests`;
+  let receiver: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    receiver = await startFakeWebhookServer(RECEIVER_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await receiver.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Digest Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Digest Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function createProfile(request: APIRequestContext, name: string, port: number): Promise<{ serverId: number; profileId: number }> {
+    const serverId = await createServerViaApi(request, `${name} Server`, '127.0.0.1', port, 'root', 'testpass');
+    const profileId = await createBackupProfileViaApi(request, name, serverId, storageId, namingRuleId, [{ remote_path: '/data', recursive: true }]);
+    return { serverId, profileId };
+  }
+
+  async function runBackup(request: APIRequestContext, profileId: number, status: string) {
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe(status);
+  }
+
+  async function triggerDigest(request: APIRequestContext, period: string, at?: string): Promise<Digest[]> {
+    const response = await request.post('/api/v1/test/trigger-digest', { data: { period, at } });
+    expect(response.ok()).toBeTruthy();
+    return response.json();
+  }
+
+  async function createChatDigest(request: APIRequestContext, path: string, data: Record<string, unknown>) {
+    const integration = await (
+      await request.post('/api/v1/notifications/chat-integrations', { data: { name: path, provider: 'slack', url: `${receiver.url}/${path}` } })
+    ).json();
+    const response = await request.post(`/api/v1/notifications/chat-integrations/${integration.id}/preferences`, {
+      data: { notify_on_failure: false, ...data },
+    });
+    expect(response.ok()).toBeTruthy();
+  }
+
+  test('summarizes the runs of the day per profile', async ({ request }) => {
+    const files = await createProfile(request, 'Files', SSH_PORT);
+    const broken = await createProfile(request, 'Broken', CLOSED_PORT);
+    await runBackup(request, files.profileId, 'completed');
+    await runBackup(request, files.profileId, 'completed');
+    await runBackup(request, broken.profileId, 'failed');
+    await createChatDigest(request, 'daily', { digest_frequency: 'daily' });
+
+    const [digest] = await triggerDigest(request, 'daily');
+    expect(digest).toMatchObject({ period: 'daily', runs: 3, succeeded: 2, failed: 1 });
+    const byName = Object.fromEntries(digest.profiles.map((p) => [p.backup_profile, p]));
+    expect(byName['Files']).toMatchObject({ runs: 2, succeeded: 2, failed: 0, no_recent_success: false });
+    expect(byName['Files'].transferred_bytes).toBe(2 * 'meeting notes'.length);
+    expect(byName['Files'].storage_growth_bytes).toBe(byName['Files'].stored_bytes);
+    expect(byName['Broken']).toMatchObject({ runs: 1, failed: 1, no_recent_success: true, stored_bytes: 0 });
+
+    const message = JSON.parse((await receiver.waitForRequest((r) => r.path === '/daily')).body);
+    expect(message.text).toBe('Daily Backup Digest');
+    expect(message.attachments[0].text).toContain('No recent successful backup: Broken');
+    expect(message.attachments[0].fields).toEqual(
+      expect.arrayContaining([expect.objectContaining({ title: 'Files', value: expect.stringContaining('2 runs (2 succeeded, 0 failed)') })])
+    );
+  });
+
+  test('reports overdue profiles and upcoming retention deletions', async ({ request }) => {
+    const { serverId, profileId } = await createProfile(request, 'Kept', SSH_PORT);
+    const update = await request.put(`/api/v1/backup-profiles/${profileId}`, {
+      data: { name: 'Kept', server_id: serverId, storage_location_id: storageId, naming_rule_id: namingRuleId, retention_days: 3, enabled: true },
+    });
+    expect(update.ok()).toBeTruthy();
+    await runBackup(request, profileId, 'completed');
+    await createChatDigest(request, 'weekly', { digest_frequency: 'weekly' });
+
+    // The run expires within the next week, and nobody subscribed to daily digests
+    expect((await triggerDigest(request, 'weekly'))[0].profiles[0]).toMatchObject({ retention_deletions: 1, no_recent_success: false });
+    expect(await triggerDigest(request, 'daily')).toEqual([]);
+    const [later] = await triggerDigest(request, 'weekly', daysFromNow(8));
+    expect(later.profiles[0]).toMatchObject({ runs: 0, no_recent_success: true, retention_deletions: 1 });
+  });
+
+  test('sends scoped digests to scoped webhooks', async ({ request }) => {
+    const first = await createProfile(request, 'First', SSH_PORT);
+    await createProfile(request, 'Second', SSH_PORT);
+    await runBackup(request, first.profileId, 'completed');
+    const webhook = await request.post('/api/v1/notifications/webhooks', {
+      data: { name: 'Weekly', url: `${receiver.url}/scoped`, events: ['digest.weekly'], server_id: first.serverId },
+    });
+    expect(webhook.status()).toBe(201);
+
+    expect(await triggerDigest(request, 'daily')).toEqual([]);
+    const [digest] = await triggerDigest(request, 'weekly');
+    expect(digest).toMatchObject({ server_id: first.serverId, scope: 'First Server', runs: 1 });
+    expect(digest.profiles.map((p) => p.backup_profile)).toEqual(['First']);
+
+    const received = await receiver.waitForRequest((r) => r.path === '/scoped');
+    expect(received.headers['x-backapp-event']).toBe('digest.weekly');
+    expect(JSON.parse(received.body).data).toMatchObject({ period: 'weekly', scope: 'First Server' });
+  });
+
+  test('rejects unknown digest frequencies', async ({ request }) => {
+    const integration = await (
+      await request.post('/api/v1/notifications/chat-integrations', { data: { name: 'Hourly', provider: 'slack', url: `${receiver.url}/hourly` } })
+    ).json();
+    const response = await request.post(`/api/v1/notifications/chat-integrations/${integration.id}/preferences`, {
+      data: { digest_frequency: 'hourly' },
+    });
+    expect(response.status()).toBe(400);
+  });
+
+  test('chooses the digest of a rule in the notification settings', async ({ page, request }) => {
+    await createProfile(request, 'Picked', SSH_PORT);
+    const integration = await (
+      await request.post('/api/v1/notifications/chat-integrations', { data: { name: 'Team Chat', provider: 'slack', url: `${receiver.url}/ui` } })
+    ).json();
+
+    await page.goto('/notifications');
+    await page.getByTestId(`chat-integration-${integration.id}`).getByRole('combobox', { name: 'Team Chat Digest' }).click();
+    await page.getByRole('option', { name: 'Weekly' }).click();
+    await expect.poll(async () => {
+      const [listed] = await (await request.get('/api/v1/notifications/chat-integrations')).json();
+      return listed.preferences[0].digest_frequency;
+    }).toBe('weekly');
+  });
+});
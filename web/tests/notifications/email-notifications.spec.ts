@@ -0,0 +1,215 @@
+/**
+ * Email Notification Tests
+ *
+ * Tests email recipients, their preferences and the notification emails sent for
+ * backup runs, against a fake SMTP server
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeSMTPServer, type FakeSMTPServer } from '../helpers/fake-smtp-server';
+
+interface EmailRecipient {
+  id: number;
+  address: string;
+  preferences: { id: number; notify_on_failure: boolean; notify_on_success: boolean }[];
+}
+
+const preference = (overrides: Record<string, unknown> = {}) => ({
+  notify_on_start: false,
+  notify_on_success: false,
+  notify_on_failure: false,
+  notify_on_consecutive_failures: false,
+  consecutive_failure_threshold: 3,
+  notify_on_low_storage: false,
+  low_storage_threshold: 10,
+  ...overrides,
+});
+
+test.describe('Email Notifications', () => {
+  const SSH_PORT = 2256;
+  // Nothing listens here, so connecting fails
+  const CLOSED_PORT = 2257;
+  const storagePath = `${TEST_BASE_PATH}/email-notifications`;
+  let smtp: FakeSMTPServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    smtp = await startFakeSMTPServer();
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/report.txt', createVirtualFile('quarterly numbers'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await smtp.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Email Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Email Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function addRecipient(request: APIRequestContext, address: string): Promise<EmailRecipient> {
+    const response = await request.post('/api/v1/notifications/email-recipients', { data: { address } });
+    expect(response.status()).toBe(201);
+    return response.json();
+  }
+
+  async function setOnlyPreference(request: APIRequestContext, recipient: EmailRecipient, overrides: Record<string, unknown>) {
+    const [defaultPref] = recipient.preferences ?? (await listRecipients(request)).find((r) => r.id === recipient.id)!.preferences;
+    const response = await request.put(`/api/v1/notifications/preferences/${defaultPref.id}`, { data: preference(overrides) });
+    expect(response.ok()).toBeTruthy();
+  }
+
+  async function listRecipients(request: APIRequestContext): Promise<EmailRecipient[]> {
+    return (await request.get('/api/v1/notifications/email-recipients')).json();
+  }
+
+  async function createProfile(request: APIRequestContext, name: string, port = SSH_PORT): Promise<number> {
+    const serverId = await createServerViaApi(request, `${name} Server`, '127.0.0.1', port, 'root', 'testpass');
+    return createBackupProfileViaApi(request, name, serverId, storageId, namingRuleId, [{ remote_path: '/data', recursive: true }]);
+  }
+
+  test('reports the configured sender', async ({ request }) => {
+    const status = await (await request.get('/api/v1/notifications/email')).json();
+    expect(status).toEqual({ enabled: true, from: 'backapp@example.com' });
+  });
+
+  test('validates recipients and gives them default preferences', async ({ request }) => {
+    const recipient = await addRecipient(request, 'Ops Team <OPS@example.com>');
+    expect(recipient.address).toBe('ops@example.com');
+
+    expect((await request.post('/api/v1/notifications/email-recipients', { data: { address: 'ops@example.com' } })).status()).toBe(409);
+    expect((await request.post('/api/v1/notifications/email-recipients', { data: { address: 'not an address' } })).status()).toBe(400);
+
+    const [listed] = await listRecipients(request);
+    expect(listed.preferences).toHaveLength(1);
+    expect(listed.preferences[0]).toMatchObject({ notify_on_failure: true, notify_on_success: false });
+
+    expect((await request.delete(`/api/v1/notifications/email-recipients/${recipient.id}`)).ok()).toBeTruthy();
+    expect(await listRecipients(request)).toEqual([]);
+    expect((await request.delete(`/api/v1/notifications/email-recipients/${recipient.id}`)).status()).toBe(404);
+  });
+
+  test('sends test emails with a plain text and an HTML part', async ({ request }) => {
+    const recipient = await addRecipient(request, 'test-mail@example.com');
+    expect((await request.post(`/api/v1/notifications/email-recipients/${recipient.id}/test`)).ok()).toBeTruthy();
+
+    const email = await smtp.waitForMessage((m) => m.to.includes('test-mail@example.com'));
+    expect(email.from).toBe('backapp@example.com');
+    expect(email.subject).toBe('Test Notification');
+    expect(email.headers['message-id']).toMatch(/@example\.com>$/);
+    expect(email.text).toContain('This is a test notification from BackApp');
+    expect(email.html).toContain('<html>');
+    expect(email.html).toContain('href="http://localhost:8081/notifications"');
+  });
+
+  test('emails successful runs with the log tail and a link to the run', async ({ request }) => {
+    const recipient = await addRecipient(request, 'success@example.com');
+    await setOnlyPreference(request, recipient, { notify_on_success: true });
+    const profileId = await createProfile(request, 'Nightly Files');
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+
+    const email = await smtp.waitForMessage((m) => m.to.includes('success@example.com'));
+    expect(email.subject).toBe("Backup 'Nightly Files' completed");
+    expect(email.text).toContain(`Run: #${runId}`);
+    expect(email.text).toContain('Last lines of the run log:');
+    expect(email.text).toContain('Backup completed successfully');
+    expect(email.text).toContain(`http://localhost:8081/backup-runs/${runId}`);
+    expect(email.html).toContain(`href="http://localhost:8081/backup-runs/${runId}"`);
+    // The HTML part escapes the profile name
+    expect(email.html).toContain('Backup &#39;Nightly Files&#39; completed');
+  });
+
+  test('emails failed runs with the error', async ({ request }) => {
+    const recipient = await addRecipient(request, 'failure@example.com');
+    await setOnlyPreference(request, recipient, { notify_on_failure: true });
+    const profileId = await createProfile(request, 'Broken Profile', CLOSED_PORT);
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('failed');
+
+    const email = await smtp.waitForMessage((m) => m.to.includes('failure@example.com'));
+    expect(email.subject).toBe("Backup 'Broken Profile' failed");
+    expect(email.text).toMatch(/Error: .*connection refused/);
+    expect(email.text).toContain('Backup failed');
+  });
+
+  test('only emails recipients whose preferences match the profile', async ({ request }) => {
+    const profileA = await createProfile(request, 'Profile A');
+    const profileB = await createProfile(request, 'Profile B');
+
+    const everything = await addRecipient(request, 'everything@example.com');
+    await setOnlyPreference(request, everything, { notify_on_success: true });
+    const onlyB = await addRecipient(request, 'only-b@example.com');
+    await setOnlyPreference(request, onlyB, {});
+    const scoped = await request.post(`/api/v1/notifications/email-recipients/${onlyB.id}/preferences`, {
+      data: preference({ backup_profile_id: profileB, notify_on_success: true }),
+    });
+    expect(scoped.ok()).toBeTruthy();
+
+    const runA = await runBackupViaApi(request, profileA);
+    await waitForBackupRunComplete(request, runA);
+    await smtp.waitForMessage((m) => m.to.includes('everything@example.com') && m.subject.includes('Profile A'));
+
+    const runB = await runBackupViaApi(request, profileB);
+    await waitForBackupRunComplete(request, runB);
+    await smtp.waitForMessage((m) => m.to.includes('only-b@example.com') && m.subject.includes('Profile B'));
+
+    expect(smtp.messages.filter((m) => m.to.includes('only-b@example.com') && m.subject.includes('Profile A'))).toHaveLength(0);
+  });
+
+  test('only global admins manage email recipients', async ({ request, baseURL }) => {
+    const recipient = await addRecipient(request, 'shared@example.com');
+    const username = `email-viewer-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'viewer-password' } })).json();
+    expect((await request.post(`/api/v1/users/${user.id}/roles`, { data: { role: 'viewer' } })).status()).toBe(201);
+    const viewer = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    try {
+      expect((await viewer.post('/api/v1/auth/login', { data: { username, password: 'viewer-password' } })).ok()).toBeTruthy();
+      expect((await viewer.get('/api/v1/notifications/email-recipients')).status()).toBe(403);
+      expect((await viewer.post('/api/v1/notifications/email-recipients', { data: { address: 'x@example.com' } })).status()).toBe(403);
+      const [pref] = (await listRecipients(request))[0].preferences;
+      expect((await viewer.put(`/api/v1/notifications/preferences/${pref.id}`, { data: preference() })).status()).toBe(403);
+      expect((await viewer.delete(`/api/v1/notifications/preferences/${pref.id}`)).status()).toBe(403);
+      expect((await viewer.post(`/api/v1/notifications/email-recipients/${recipient.id}/test`)).status()).toBe(403);
+    } finally {
+      await viewer.dispose();
+      await request.delete(`/api/v1/users/${user.id}`);
+    }
+  });
+
+  test('adds recipients and sends test emails from the notification settings', async ({ page }) => {
+    await page.goto('/notifications');
+    const card = page.getByTestId('email-recipients');
+    await expect(card.getByText('Emails are sent from backapp@example.com')).toBeVisible();
+
+    await page.getByTestId('input-email-recipient').fill('ui-test@example.com');
+    await page.getByTestId('add-email-recipient-btn').click();
+    await expect(card.getByText('ui-test@example.com')).toBeVisible();
+
+    await card.getByRole('button', { name: 'Send Test' }).click();
+    await expect(card.getByText('Test email sent to ui-test@example.com')).toBeVisible();
+    await smtp.waitForMessage((m) => m.to.includes('ui-test@example.com'));
+  });
+});
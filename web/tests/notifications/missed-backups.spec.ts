@@ -0,0 +1,176 @@
+/**
+ * Missed Backup Tests
+ *
+ * Tests the watchdog that reports scheduled profiles without a successful run in their
+ * expected window, using the test endpoint to check as if it was a later time
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { startFakeWebhookServer, type FakeWebhookServer } from '../helpers/fake-webhook-server';
+
+interface MissedBackup {
+  backup_profile_id: number;
+  backup_profile: string;
+  schedule_cron: string;
+  expected_at?: string;
+  last_success_at?: string;
+  reason: string;
+}
+
+const minutesFromNow = (minutes: number) => new Date(Date.now() + minutes * 60 * 1000).toISOString();
+
+test.describe('Missed Backups', () => {
+  const RECEIVER_PORT = 2264;
+  const SSH_PORT = 2265;
+  const storagePath = `${TEST_BASE_PATH}/missed-backups`;
+  let receiver: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let serverId: number;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    receiver = await startFakeWebhookServer(RECEIVER_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/notes.txt', createVirtualFile('meeting notes'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await receiver.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    serverId = await createServerViaApi(request, 'Watched Server', '127.0.0.1', SSH_PORT, 'root', 'testpass');
+    storageId = await createStorageLocationViaApi(request, 'Watched Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Watched Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function createScheduledProfile(request: APIRequestContext, name: string, data: Record<string, unknown>): Promise<number> {
+    const response = await request.post('/api/v1/backup-profiles', {
+      data: {
+        name,
+        server_id: serverId,
+        storage_location_id: storageId,
+        naming_rule_id: namingRuleId,
+        enabled: true,
+        file_rules: [{ remote_path: '/data', recursive: true, run_order: 1 }],
+        ...data,
+      },
+    });
+    expect(response.ok()).toBeTruthy();
+    return (await response.json()).id;
+  }
+
+  async function triggerWatchdog(request: APIRequestContext, at: string): Promise<MissedBackup[]> {
+    const response = await request.post('/api/v1/test/trigger-watchdog', { data: { at } });
+    expect(response.ok()).toBeTruthy();
+    return response.json();
+  }
+
+  test('reports schedules without a successful run after the grace period', async ({ request }) => {
+    const nightly = await createScheduledProfile(request, 'Nightly', { schedule_cron: '0 3 * * *' });
+    const paused = await createScheduledProfile(request, 'Paused', { schedule_cron: '0 3 * * *', enabled: false });
+    const broken = await createScheduledProfile(request, 'Broken', { schedule_cron: 'every night' });
+    await createScheduledProfile(request, 'Unwatched', { schedule_cron: '0 3 * * *', missed_backup_grace_minutes: 0 });
+    await createScheduledProfile(request, 'Manual', {});
+
+    // Nothing is due right away, but an invalid schedule never runs
+    expect(await triggerWatchdog(request, minutesFromNow(0))).toEqual([
+      expect.objectContaining({ backup_profile_id: broken, reason: expect.stringContaining('invalid') }),
+    ]);
+
+    const missed = await triggerWatchdog(request, minutesFromNow(2 * 24 * 60));
+    expect(missed).toHaveLength(3);
+    expect(missed).toEqual(
+      expect.arrayContaining([
+        expect.objectContaining({ backup_profile_id: nightly, reason: 'no run started since the scheduled time', expected_at: expect.any(String) }),
+        expect.objectContaining({ backup_profile_id: paused, reason: 'the profile is disabled' }),
+        expect.objectContaining({ backup_profile_id: broken }),
+      ])
+    );
+    expect(missed.find((m) => m.backup_profile_id === nightly)?.last_success_at).toBeUndefined();
+  });
+
+  test('a successful run resets the watch', async ({ request }) => {
+    const profileId = await createScheduledProfile(request, 'Every Minute', {
+      schedule_cron: '* * * * *',
+      missed_backup_grace_minutes: 5,
+    });
+    expect(await triggerWatchdog(request, minutesFromNow(10))).toEqual([
+      expect.objectContaining({ backup_profile_id: profileId }),
+    ]);
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+    expect(await triggerWatchdog(request, minutesFromNow(3))).toEqual([]);
+
+    const missed = await triggerWatchdog(request, minutesFromNow(10));
+    expect(missed).toEqual([expect.objectContaining({ backup_profile_id: profileId, last_success_at: expect.any(String) })]);
+  });
+
+  test('sends a backup.missed webhook once per missed run', async ({ request }) => {
+    const profileId = await createScheduledProfile(request, 'Nightly Files', { schedule_cron: '0 3 * * *' });
+    const webhook = await request.post('/api/v1/notifications/webhooks', {
+      data: { name: 'Missed', url: `${receiver.url}/missed`, events: ['backup.missed'] },
+    });
+    expect(webhook.status()).toBe(201);
+
+    const at = minutesFromNow(2 * 24 * 60);
+    expect(await triggerWatchdog(request, at)).toHaveLength(1);
+    const received = await receiver.waitForRequest((r) => r.path === '/missed');
+    expect(received.headers['x-backapp-event']).toBe('backup.missed');
+    expect(JSON.parse(received.body)).toMatchObject({
+      event: 'backup.missed',
+      data: {
+        backup_profile_id: profileId,
+        backup_profile: 'Nightly Files',
+        server_id: serverId,
+        server: 'Watched Server',
+        schedule_cron: '0 3 * * *',
+        reason: 'no run started since the scheduled time',
+      },
+    });
+
+    // The same missed run is not reported again
+    expect(await triggerWatchdog(request, at)).toHaveLength(1);
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+    expect(receiver.requests.filter((r) => r.path === '/missed')).toHaveLength(1);
+  });
+
+  test('sets the grace period in the profile form', async ({ page, request }) => {
+    const profileId = await createScheduledProfile(request, 'Form Profile', { schedule_cron: '0 3 * * *' });
+    await page.goto(`/backup-profiles/${profileId}`);
+    await expect(page.getByText('after 60 minutes')).toBeVisible();
+
+    const response = await request.put(`/api/v1/backup-profiles/${profileId}`, {
+      data: {
+        name: 'Form Profile',
+        server_id: serverId,
+        storage_location_id: storageId,
+        naming_rule_id: namingRuleId,
+        schedule_cron: '0 3 * * *',
+        missed_backup_grace_minutes: 0,
+        enabled: true,
+      },
+    });
+    expect((await response.json()).missed_backup_grace_minutes).toBe(0);
+    await page.reload();
+    await expect(page.getByText('Missed backup alert:')).toContainText('Off');
+  });
+});
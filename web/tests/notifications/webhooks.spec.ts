@@ -0,0 +1,235 @@
+/**
+ * Webhook Tests
+ *
+ * Tests webhook endpoints, their signed payloads, event filters, retries and the
+ * delivery log, against a fake receiver
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import {
+  createBackupProfileViaApi,
+  createNamingRuleViaApi,
+  createServerViaApi,
+  createStorageLocationViaApi,
+  resetDatabase,
+  runBackupViaApi,
+  waitForBackupRunComplete,
+} from '../helpers/api-helpers';
+import { cleanupTestDirectory, TEST_BASE_PATH } from '../helpers/fs-helpers';
+import { createVirtualDirectory, createVirtualFile, startFakeSSHServerWithFiles, type VirtualFile } from '../helpers/fake-ssh-server';
+import { signWebhook, startFakeWebhookServer, type FakeWebhookServer, type ReceivedWebhook } from '../helpers/fake-webhook-server';
+
+interface Webhook {
+  id: number;
+  secret?: string;
+}
+
+interface WebhookDelivery {
+  id: number;
+  event: string;
+  event_id: string;
+  status: string;
+  attempts: number;
+  response_code?: number;
+  error?: string;
+  redelivery_of?: number;
+}
+
+test.describe('Webhooks', () => {
+  const RECEIVER_PORT = 2258;
+  const SSH_PORT = 2259;
+  // Nothing listens here, so connecting fails
+  const CLOSED_PORT = 2261;
+  const storagePath = `${TEST_BASE_PATH}/webhooks`;
+  let receiver: FakeWebhookServer;
+  let sshServer: SSHServer;
+  let storageId: number;
+  let namingRuleId: number;
+
+  test.beforeAll(async () => {
+    receiver = await startFakeWebhookServer(RECEIVER_PORT);
+    const virtualFiles = new Map<string, VirtualFile>();
+    virtualFiles.set('/', createVirtualDirectory());
+    virtualFiles.set('/data', createVirtualDirectory());
+    virtualFiles.set('/data/report.txt', createVirtualFile('quarterly numbers'));
+    sshServer = await startFakeSSHServerWithFiles({ port: SSH_PORT, username: 'root', password: 'testpass', virtualFiles });
+  });
+
+  test.afterAll(async () => {
+    await receiver.close();
+    sshServer.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    cleanupTestDirectory();
+    storageId = await createStorageLocationViaApi(request, 'Webhook Storage', storagePath);
+    namingRuleId = await createNamingRuleViaApi(request, 'Webhook Naming', '{profile}-{TIMESTAMP}');
+  });
+
+  async function createWebhook(request: APIRequestContext, data: Record<string, unknown>): Promise<Webhook> {
+    const response = await request.post('/api/v1/notifications/webhooks', { data });
+    expect(response.status()).toBe(201);
+    return response.json();
+  }
+
+  async function listDeliveries(request: APIRequestContext, webhookId: number): Promise<WebhookDelivery[]> {
+    return (await request.get(`/api/v1/notifications/webhooks/${webhookId}/deliveries`)).json();
+  }
+
+  async function createProfile(request: APIRequestContext, name: string, port = SSH_PORT): Promise<number> {
+    const serverId = await createServerViaApi(request, `${name} Server`, '127.0.0.1', port, 'root', 'testpass');
+    return createBackupProfileViaApi(request, name, serverId, storageId, namingRuleId, [{ remote_path: '/data', recursive: true }]);
+  }
+
+  const expectSigned = (received: ReceivedWebhook, secret: string) => {
+    const timestamp = received.headers['x-backapp-timestamp'];
+    expect(Math.abs(Date.now() / 1000 - Number(timestamp))).toBeLessThan(60);
+    expect(received.headers['x-backapp-signature']).toBe(signWebhook(secret, timestamp, received.body));
+  };
+
+  test('validates webhooks and never returns the secret again', async ({ request }) => {
+    const invalid = [
+      { name: '', url: `${receiver.url}/hook` },
+      { name: 'FTP', url: 'ftp://example.com/hook' },
+      { name: 'Unknown event', url: `${receiver.url}/hook`, events: ['backup.exploded'] },
+    ];
+    for (const data of invalid) {
+      expect((await request.post('/api/v1/notifications/webhooks', { data })).status()).toBe(400);
+    }
+
+    const webhook = await createWebhook(request, { name: 'Generated', url: `${receiver.url}/hook` });
+    expect(webhook.secret).toMatch(/^[A-Za-z0-9_-]{43}$/);
+    const [listed] = await (await request.get('/api/v1/notifications/webhooks')).json();
+    expect(listed).toMatchObject({ id: webhook.id, enabled: true, events: [] });
+    expect(listed.secret).toBeUndefined();
+
+    expect((await request.delete(`/api/v1/notifications/webhooks/${webhook.id}`)).ok()).toBeTruthy();
+    expect((await request.delete(`/api/v1/notifications/webhooks/${webhook.id}`)).status()).toBe(404);
+  });
+
+  test('posts signed payloads for completed runs', async ({ request }) => {
+    const webhook = await createWebhook(request, { name: 'Runs', url: `${receiver.url}/completed`, secret: 'completed-secret' });
+    const profileId = await createProfile(request, 'Nightly Files');
+
+    const runId = await runBackupViaApi(request, profileId);
+    expect((await waitForBackupRunComplete(request, runId)).status).toBe('completed');
+
+    const received = await receiver.waitForRequest((r) => r.path === '/completed' && r.headers['x-backapp-event'] === 'backup.completed');
+    expect(received.headers['content-type']).toBe('application/json');
+    expectSigned(received, 'completed-secret');
+    const payload = JSON.parse(received.body);
+    expect(payload).toMatchObject({
+      event: 'backup.completed',
+      data: {
+        backup_run_id: runId,
+        backup_profile_id: profileId,
+        backup_profile: 'Nightly Files',
+        server: 'Nightly Files Server',
+        status: 'completed',
+        url: `http://localhost:8081/backup-runs/${runId}`,
+      },
+    });
+    expect(payload.id).toMatch(/^evt_/);
+
+    await expect.poll(async () => (await listDeliveries(request, webhook.id)).find((d) => d.event === 'backup.completed'))
+      .toMatchObject({ event_id: payload.id, status: 'succeeded', attempts: 1, response_code: 200 });
+  });
+
+  test('only sends subscribed events for matching profiles', async ({ request }) => {
+    const working = await createProfile(request, 'Working Profile');
+    const broken = await createProfile(request, 'Broken Profile', CLOSED_PORT);
+    await createWebhook(request, { name: 'Failures', url: `${receiver.url}/failures`, secret: 's', events: ['backup.failed'] });
+    await createWebhook(request, { name: 'Working only', url: `${receiver.url}/working`, secret: 's', backup_profile_id: working });
+
+    const failedRun = await runBackupViaApi(request, broken);
+    expect((await waitForBackupRunComplete(request, failedRun)).status).toBe('failed');
+    const failure = await receiver.waitForRequest((r) => r.path === '/failures' && r.body.includes(`"backup_run_id":${failedRun}`));
+    expect(JSON.parse(failure.body).data).toMatchObject({ status: 'failed', error: expect.stringContaining('connection refused') });
+
+    const completedRun = await runBackupViaApi(request, working);
+    await waitForBackupRunComplete(request, completedRun);
+    await receiver.waitForRequest((r) => r.path === '/working' && r.headers['x-backapp-event'] === 'backup.completed');
+
+    const ofRun = (path: string, runId: number) =>
+      receiver.requests.filter((r) => r.path === path && r.body.includes(`"backup_run_id":${runId}`));
+    expect(ofRun('/failures', completedRun)).toHaveLength(0);
+    expect(ofRun('/working', failedRun)).toHaveLength(0);
+  });
+
+  test('retries failed deliveries with backoff', async ({ request }) => {
+    test.setTimeout(45000);
+    const webhook = await createWebhook(request, { name: 'Flaky', url: `${receiver.url}/flaky`, secret: 'flaky-secret' });
+    receiver.respondWith(503);
+
+    const response = await request.post(`/api/v1/notifications/webhooks/${webhook.id}/test`);
+    expect(response.ok()).toBeTruthy();
+    const delivery: WebhookDelivery & { next_attempt_at?: string } = await response.json();
+    expect(delivery).toMatchObject({ event: 'ping', status: 'pending', attempts: 1, response_code: 503 });
+    expect(delivery.error).toContain('503');
+    expect(delivery.next_attempt_at).toBeTruthy();
+
+    await expect.poll(async () => (await listDeliveries(request, webhook.id))[0], { timeout: 20000 })
+      .toMatchObject({ id: delivery.id, status: 'succeeded', attempts: 2, response_code: 200 });
+    const attempts = receiver.requests.filter((r) => r.path === '/flaky');
+    expect(attempts.map((r) => r.status)).toEqual([503, 200]);
+    // Every attempt is signed with its own timestamp
+    attempts.forEach((attempt) => expectSigned(attempt, 'flaky-secret'));
+  });
+
+  test('redelivers logged deliveries with the same event ID', async ({ request }) => {
+    const webhook = await createWebhook(request, { name: 'Redeliver', url: `${receiver.url}/redeliver`, secret: 'redeliver-secret' });
+    const original: WebhookDelivery = await (await request.post(`/api/v1/notifications/webhooks/${webhook.id}/test`)).json();
+
+    const response = await request.post(`/api/v1/notifications/webhooks/${webhook.id}/deliveries/${original.id}/redeliver`);
+    expect(response.status()).toBe(201);
+    const redelivery: WebhookDelivery = await response.json();
+    expect(redelivery).toMatchObject({ event: 'ping', event_id: original.event_id, redelivery_of: original.id, status: 'succeeded' });
+
+    const received = receiver.requests.filter((r) => r.path === '/redeliver');
+    expect(received).toHaveLength(2);
+    expect(received[0].body).toBe(received[1].body);
+    expect(received[1].headers['x-backapp-delivery']).toBe(String(redelivery.id));
+    expectSigned(received[1], 'redeliver-secret');
+
+    const missing = await request.post(`/api/v1/notifications/webhooks/${webhook.id}/deliveries/999999/redeliver`);
+    expect(missing.status()).toBe(404);
+  });
+
+  test('only global admins manage webhooks', async ({ request, baseURL }) => {
+    const webhook = await createWebhook(request, { name: 'Admin only', url: `${receiver.url}/admin` });
+    const username = `webhook-viewer-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'viewer-password' } })).json();
+    expect((await request.post(`/api/v1/users/${user.id}/roles`, { data: { role: 'viewer' } })).status()).toBe(201);
+    const viewer = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    try {
+      expect((await viewer.post('/api/v1/auth/login', { data: { username, password: 'viewer-password' } })).ok()).toBeTruthy();
+      expect((await viewer.get('/api/v1/notifications/webhooks')).status()).toBe(403);
+      expect((await viewer.get(`/api/v1/notifications/webhooks/${webhook.id}/deliveries`)).status()).toBe(403);
+      expect((await viewer.post(`/api/v1/notifications/webhooks/${webhook.id}/test`)).status()).toBe(403);
+    } finally {
+      await viewer.dispose();
+      await request.delete(`/api/v1/users/${user.id}`);
+    }
+  });
+
+  test('creates webhooks and shows deliveries in the notification settings', async ({ page }) => {
+    await page.goto('/notifications');
+    const card = page.getByTestId('webhooks');
+
+    await page.getByTestId('add-webhook-btn').click();
+    await page.getByTestId('input-webhook-name').fill('Chat Ops');
+    await page.getByTestId('input-webhook-url').fill(`${receiver.url}/ui`);
+    await card.getByLabel('Backup failed').check();
+    await page.getByTestId('save-webhook-btn').click();
+
+    await expect(page.getByTestId('created-webhook-secret')).not.toBeEmpty();
+    await expect(card.getByText('Chat Ops')).toBeVisible();
+    await expect(card.getByText('backup.failed')).toBeVisible();
+
+    await card.getByRole('button', { name: 'Send Test' }).click();
+    await receiver.waitForRequest((r) => r.path === '/ui' && r.headers['x-backapp-event'] === 'ping');
+    await expect(card.getByRole('cell', { name: 'ping' })).toBeVisible();
+    await expect(card.getByText('succeeded')).toBeVisible();
+  });
+});
@@ -0,0 +1,150 @@
+/**
+ * Server Auth Method Tests
+ *
+ * Tests for SSH user certificates, keyboard-interactive authentication and fallback
+ * auth types that are tried in order
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import { resetDatabase } from '../helpers/api-helpers';
+import {
+  SSH_PRIVATE_KEY,
+  SSH_PUBLIC_KEY,
+  SSH_USER_CERTIFICATE,
+  createVirtualDirectory,
+  startFakeSSHServerWithFiles,
+} from '../helpers/fake-ssh-server';
+
+test.describe('Server auth methods', () => {
+  let keyboardServer: SSHServer;
+  let certificateServer: SSHServer;
+  const KEYBOARD_PORT = 2251;
+  const CERTIFICATE_PORT = 2252;
+  const keyboardOnly = { host: 'localhost', port: KEYBOARD_PORT, username: 'testuser' };
+  const certificateOnly = { host: 'localhost', port: CERTIFICATE_PORT, username: 'testuser' };
+
+  test.beforeAll(async () => {
+    const virtualFiles = () => new Map([['/', createVirtualDirectory()]]);
+    keyboardServer = await startFakeSSHServerWithFiles({
+      port: KEYBOARD_PORT,
+      username: 'testuser',
+      password: 'testpass',
+      virtualFiles: virtualFiles(),
+      authMethods: ['keyboard-interactive'],
+    });
+    certificateServer = await startFakeSSHServerWithFiles({
+      port: CERTIFICATE_PORT,
+      username: 'testuser',
+      password: 'testpass',
+      virtualFiles: virtualFiles(),
+      authMethods: ['certificate'],
+    });
+  });
+
+  test.afterAll(async () => {
+    keyboardServer?.close();
+    certificateServer?.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+  });
+
+  async function createServer(request: APIRequestContext, data: Record<string, unknown>): Promise<number> {
+    const response = await request.post('/api/v1/servers', { data: { name: `Server ${Date.now()}`, ...data } });
+    expect(response.status()).toBe(201);
+    return (await response.json()).id;
+  }
+
+  async function testConnection(request: APIRequestContext, serverId: number): Promise<{ success: boolean; message: string }> {
+    return (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json();
+  }
+
+  test('should answer keyboard-interactive prompts with the password', async ({ request }) => {
+    const keyboard = await createServer(request, { ...keyboardOnly, auth_type: 'keyboard_interactive', password: 'testpass' });
+    expect((await testConnection(request, keyboard)).success).toBe(true);
+
+    const password = await createServer(request, { ...keyboardOnly, auth_type: 'password', password: 'testpass' });
+    expect((await testConnection(request, password)).success).toBe(false);
+  });
+
+  test('should present the certificate with the key', async ({ request }) => {
+    const plainKey = await createServer(request, { ...certificateOnly, auth_type: 'key', private_key: SSH_PRIVATE_KEY });
+    expect((await testConnection(request, plainKey)).success).toBe(false);
+
+    const withCertificate = await createServer(request, {
+      ...certificateOnly,
+      auth_type: 'key',
+      private_key: SSH_PRIVATE_KEY,
+      certificate: SSH_USER_CERTIFICATE,
+    });
+    const server = await (await request.get(`/api/v1/servers/${withCertificate}`)).json();
+    expect(server.certificate).toBe(SSH_USER_CERTIFICATE);
+    expect((await testConnection(request, withCertificate)).success).toBe(true);
+  });
+
+  test('should try fallback auth types in order', async ({ request }) => {
+    const serverId = await createServer(request, {
+      ...keyboardOnly,
+      auth_type: 'key',
+      private_key: SSH_PRIVATE_KEY,
+      password: 'testpass',
+      fallback_auth_types: ['password', 'keyboard_interactive'],
+    });
+    const server = await (await request.get(`/api/v1/servers/${serverId}`)).json();
+    expect(server.fallback_auth_types).toEqual(['password', 'keyboard_interactive']);
+    expect((await testConnection(request, serverId)).success).toBe(true);
+
+    const failingFirst = await createServer(request, {
+      ...keyboardOnly,
+      auth_type: 'password_command',
+      secret_command: 'exit 1',
+      password: 'testpass',
+      fallback_auth_types: ['keyboard_interactive'],
+    });
+    expect((await testConnection(request, failingFirst)).success).toBe(true);
+  });
+
+  test('should validate certificates and fallbacks', async ({ request }) => {
+    const publicKey = await request.post('/api/v1/servers', {
+      data: { name: 'Public Key', ...certificateOnly, auth_type: 'key', private_key: SSH_PRIVATE_KEY, certificate: SSH_PUBLIC_KEY },
+    });
+    expect(publicKey.status()).toBe(400);
+
+    const withoutKey = await request.post('/api/v1/servers', {
+      data: { name: 'No Key', ...certificateOnly, auth_type: 'password', password: 'testpass', certificate: SSH_USER_CERTIFICATE },
+    });
+    expect(withoutKey.status()).toBe(400);
+
+    const duplicate = await request.post('/api/v1/servers', {
+      data: { name: 'Twice', ...keyboardOnly, auth_type: 'password', password: 'testpass', fallback_auth_types: ['password'] },
+    });
+    expect(duplicate.status()).toBe(400);
+
+    const unknown = await request.post('/api/v1/servers', {
+      data: { name: 'Unknown', ...keyboardOnly, auth_type: 'password', password: 'testpass', fallback_auth_types: ['telepathy'] },
+    });
+    expect(unknown.status()).toBe(400);
+  });
+
+  test('should configure keyboard-interactive with fallbacks in the dialog', async ({ page, request }) => {
+    await page.goto('/servers');
+    await page.getByTestId('add-server-btn').click();
+    await page.getByTestId('input-name').locator('input').fill('Appliance');
+    await page.getByTestId('input-host').locator('input').fill('localhost');
+    await page.getByTestId('input-port').locator('input').fill(KEYBOARD_PORT.toString());
+    await page.getByTestId('input-username').locator('input').fill('testuser');
+    await page.getByTestId('auth-type-keyboard-interactive').click();
+    await page.getByTestId('input-password').locator('input').fill('testpass');
+    await page.getByTestId('input-fallback-auth-types').getByRole('combobox').click();
+    await page.getByTestId('fallback-option-password').click();
+    await page.keyboard.press('Escape');
+    await page.getByTestId('save-server-btn').click();
+
+    await expect(page.getByText('Appliance')).toBeVisible();
+    const servers = await (await request.get('/api/v1/servers')).json();
+    expect(servers[0].auth_type).toBe('keyboard_interactive');
+    expect(servers[0].fallback_auth_types).toEqual(['password']);
+    expect((await testConnection(request, servers[0].id)).success).toBe(true);
+  });
+});
@@ -0,0 +1,158 @@
+/**
+ * Server Auth Source Tests
+ *
+ * Tests for credentials that are resolved on every connection: key files on the BackApp
+ * host, encrypted keys with passphrase and secret commands
+ */
+import { expect, request as playwrightRequest, test, type APIRequestContext } from '@playwright/test';
+import * as fs from 'fs';
+import * as path from 'path';
+import type { Server as SSHServer } from 'ssh2';
+import { resetDatabase } from '../helpers/api-helpers';
+import {
+  SSH_ENCRYPTED_PRIVATE_KEY,
+  SSH_KEY_PASSPHRASE,
+  SSH_PRIVATE_KEY,
+  startFakeSSHServer,
+} from '../helpers/fake-ssh-server';
+import { TEST_BASE_PATH } from '../helpers/fs-helpers';
+
+test.describe('Server auth sources', () => {
+  let sshServer: SSHServer;
+  const SSH_PORT = 2247;
+  const keyDir = path.join(TEST_BASE_PATH, 'auth-sources');
+  const connection = { host: 'localhost', port: SSH_PORT, username: 'testuser' };
+
+  test.beforeAll(async () => {
+    sshServer = await startFakeSSHServer(SSH_PORT, 'testuser', 'testpass');
+  });
+
+  test.afterAll(async () => {
+    if (sshServer) {
+      sshServer.close();
+    }
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    fs.mkdirSync(keyDir, { recursive: true });
+    fs.writeFileSync(path.join(keyDir, 'id_plain'), SSH_PRIVATE_KEY + '\n', { mode: 0o600 });
+    fs.writeFileSync(path.join(keyDir, 'id_encrypted'), SSH_ENCRYPTED_PRIVATE_KEY + '\n', { mode: 0o600 });
+  });
+
+  async function createServer(request: APIRequestContext, data: Record<string, unknown>): Promise<number> {
+    const response = await request.post('/api/v1/servers', { data: { name: `Server ${Date.now()}`, ...connection, ...data } });
+    expect(response.status()).toBe(201);
+    return (await response.json()).id;
+  }
+
+  async function testConnection(request: APIRequestContext, serverId: number): Promise<{ success: boolean; message: string }> {
+    return (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json();
+  }
+
+  test('should read the key file on every connection', async ({ request }) => {
+    const keyFile = path.join(keyDir, 'id_rotating');
+    fs.writeFileSync(keyFile, SSH_PRIVATE_KEY + '\n', { mode: 0o600 });
+    const serverId = await createServer(request, { auth_type: 'key_file', key_file_path: keyFile });
+
+    const server = await (await request.get(`/api/v1/servers/${serverId}`)).json();
+    expect(server.key_file_path).toBe(keyFile);
+    expect(server.has_private_key).toBe(false);
+    expect((await testConnection(request, serverId)).success).toBe(true);
+
+    fs.rmSync(keyFile);
+    const missing = await testConnection(request, serverId);
+    expect(missing.success).toBe(false);
+    expect(missing.message).toContain('failed to read private key file');
+  });
+
+  test('should decrypt encrypted keys with the passphrase', async ({ request }) => {
+    const keyFile = path.join(keyDir, 'id_encrypted');
+    const withoutPassphrase = await createServer(request, { auth_type: 'key_file', key_file_path: keyFile });
+    const result = await testConnection(request, withoutPassphrase);
+    expect(result.success).toBe(false);
+    expect(result.message).toContain('no passphrase');
+
+    const withPassphrase = await createServer(request, {
+      auth_type: 'key_file',
+      key_file_path: keyFile,
+      key_passphrase: SSH_KEY_PASSPHRASE,
+    });
+    const server = await (await request.get(`/api/v1/servers/${withPassphrase}`)).json();
+    expect(server.has_key_passphrase).toBe(true);
+    expect(JSON.stringify(server)).not.toContain(SSH_KEY_PASSPHRASE);
+    expect((await testConnection(request, withPassphrase)).success).toBe(true);
+
+    const storedKey = await createServer(request, {
+      auth_type: 'key',
+      private_key: SSH_ENCRYPTED_PRIVATE_KEY,
+      key_passphrase: SSH_KEY_PASSPHRASE,
+    });
+    expect((await testConnection(request, storedKey)).success).toBe(true);
+  });
+
+  test('should fetch credentials from secret commands', async ({ request }) => {
+    const passwordCommand = await createServer(request, {
+      auth_type: 'password_command',
+      secret_command: "printf 'testpass\\n'",
+    });
+    expect((await testConnection(request, passwordCommand)).success).toBe(true);
+
+    const keyCommand = await createServer(request, {
+      auth_type: 'key_command',
+      secret_command: `cat ${path.join(keyDir, 'id_encrypted')}`,
+      key_passphrase: SSH_KEY_PASSPHRASE,
+    });
+    expect((await testConnection(request, keyCommand)).success).toBe(true);
+
+    const failing = await createServer(request, {
+      auth_type: 'password_command',
+      secret_command: 'echo "vault is sealed" >&2; exit 2',
+    });
+    const result = await testConnection(request, failing);
+    expect(result.success).toBe(false);
+    expect(result.message).toContain('vault is sealed');
+  });
+
+  test('should validate the auth source', async ({ request }) => {
+    const missingPath = await request.post('/api/v1/servers', { data: { name: 'No Path', ...connection, auth_type: 'key_file' } });
+    expect(missingPath.status()).toBe(400);
+
+    const missingCommand = await request.post('/api/v1/servers', {
+      data: { name: 'No Command', ...connection, auth_type: 'password_command' },
+    });
+    expect(missingCommand.status()).toBe(400);
+
+    const unknown = await request.post('/api/v1/servers', { data: { name: 'Unknown', ...connection, auth_type: 'telepathy' } });
+    expect(unknown.status()).toBe(400);
+  });
+
+  test('should only let global admins configure host credentials', async ({ request, baseURL }) => {
+    const serverId = await createServer(request, { auth_type: 'password', password: 'testpass' });
+    const username = `server-admin-${Date.now()}`;
+    const user = await (await request.post('/api/v1/users', { data: { username, password: 'server-admin-password' } })).json();
+    await request.post(`/api/v1/users/${user.id}/roles`, { data: { role: 'admin', server_id: serverId } });
+
+    const serverAdmin = await playwrightRequest.newContext({ baseURL, storageState: { cookies: [], origins: [] } });
+    await serverAdmin.post('/api/v1/auth/login', { data: { username, password: 'server-admin-password' } });
+    const details = { name: 'Shared Server', ...connection };
+
+    const hostCommand = await serverAdmin.put(`/api/v1/servers/${serverId}`, {
+      data: { ...details, auth_type: 'password_command', secret_command: 'cat /etc/shadow' },
+    });
+    expect(hostCommand.status()).toBe(403);
+
+    const password = await serverAdmin.put(`/api/v1/servers/${serverId}`, {
+      data: { ...details, auth_type: 'password', password: 'testpass' },
+    });
+    expect(password.ok()).toBeTruthy();
+
+    const globalAdmin = await request.put(`/api/v1/servers/${serverId}`, {
+      data: { ...details, auth_type: 'password_command', secret_command: "printf 'testpass'" },
+    });
+    expect(globalAdmin.ok()).toBeTruthy();
+
+    await serverAdmin.dispose();
+    await request.delete(`/api/v1/users/${user.id}`);
+  });
+});
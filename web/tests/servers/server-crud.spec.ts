@@ -138,6 +138,52 @@ test.describe('Server CRUD Operations', () => {
       expect(response.ok()).toBeFalsy();
     });
 
+    test('should never return stored credentials', async ({ request }) => {
+      const serverId = await createServerViaApi(request, 'Secret Server', 'localhost', SSH_PORT, 'testuser', 'testpass');
+
+      const server = await (await request.get(`/api/v1/servers/${serverId}`)).json();
+      expect(server.password).toBeUndefined();
+      expect(server.private_key_path).toBeUndefined();
+      expect(server.has_password).toBe(true);
+      expect(server.has_private_key).toBe(false);
+
+      const listed = await (await request.get('/api/v1/servers')).text();
+      expect(listed).not.toContain('testpass');
+    });
+
+    test('should keep the stored password unless a new one is sent', async ({ request }) => {
+      const serverId = await createServerViaApi(request, 'Rotating Server', 'localhost', SSH_PORT, 'testuser', 'testpass');
+      const details = { name: 'Rotating Server', host: 'localhost', port: SSH_PORT, username: 'testuser', auth_type: 'password' };
+
+      expect((await request.put(`/api/v1/servers/${serverId}`, { data: details })).ok()).toBeTruthy();
+      expect((await request.post(`/api/v1/servers/${serverId}/test-connection`)).ok()).toBeTruthy();
+
+      const updated = await request.put(`/api/v1/servers/${serverId}`, { data: { ...details, password: 'wrongpass' } });
+      expect(updated.ok()).toBeTruthy();
+      expect(await updated.text()).not.toContain('wrongpass');
+      expect((await request.post(`/api/v1/servers/${serverId}/test-connection`)).ok()).toBeFalsy();
+    });
+
+    test('should accept a private key in the JSON body', async ({ request }) => {
+      const response = await request.post('/api/v1/servers', {
+        data: {
+          name: 'Key Server',
+          host: 'localhost',
+          port: SSH_PORT,
+          username: 'testuser',
+          auth_type: 'key',
+          private_key: SSH_PRIVATE_KEY,
+        },
+      });
+      expect(response.status()).toBe(201);
+      const server = await response.json();
+      expect(server.has_private_key).toBe(true);
+      expect(JSON.stringify(server)).not.toContain('PRIVATE KEY');
+
+      const result = await request.post(`/api/v1/servers/${server.id}/test-connection`);
+      expect(result.ok()).toBeTruthy();
+    });
+
     test('should return 404 for non-existent server', async ({ request }) => {
       const response = await request.get('/api/v1/servers/99999');
       expect(response.status()).toBe(404);
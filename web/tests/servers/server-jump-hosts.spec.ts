@@ -0,0 +1,109 @@
+/**
+ * Server Jump Host Tests
+ *
+ * Tests for servers that are reached through one or more jump hosts
+ */
+import { expect, test, type APIRequestContext } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import { resetDatabase } from '../helpers/api-helpers';
+import { startFakeSSHServer } from '../helpers/fake-ssh-server';
+
+test.describe('Server jump hosts', () => {
+  let bastionServer: SSHServer;
+  let targetServer: SSHServer;
+  const BASTION_PORT = 2248;
+  const TARGET_PORT = 2249;
+  const bastion = { name: 'Bastion', host: 'localhost', port: BASTION_PORT, username: 'jumper', auth_type: 'password' };
+  const target = { name: 'Database', host: 'localhost', port: TARGET_PORT, username: 'testuser', auth_type: 'password' };
+
+  test.beforeAll(async () => {
+    bastionServer = await startFakeSSHServer(BASTION_PORT, 'jumper', 'jumppass');
+    targetServer = await startFakeSSHServer(TARGET_PORT, 'testuser', 'testpass');
+  });
+
+  test.afterAll(async () => {
+    bastionServer?.close();
+    targetServer?.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+  });
+
+  async function createServer(request: APIRequestContext, data: Record<string, unknown>): Promise<number> {
+    const response = await request.post('/api/v1/servers', { data });
+    expect(response.status()).toBe(201);
+    return (await response.json()).id;
+  }
+
+  async function testConnection(request: APIRequestContext, serverId: number): Promise<{ success: boolean; message: string }> {
+    return (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json();
+  }
+
+  test('should connect through a chain of jump hosts', async ({ request }) => {
+    const bastionId = await createServer(request, { ...bastion, password: 'jumppass' });
+    const innerId = await createServer(request, { ...bastion, name: 'Inner Bastion', password: 'jumppass', jump_host_id: bastionId });
+    const targetId = await createServer(request, { ...target, password: 'testpass', jump_host_id: bastionId });
+    const chainedId = await createServer(request, { ...target, name: 'Chained', password: 'testpass', jump_host_id: innerId });
+
+    const server = await (await request.get(`/api/v1/servers/${targetId}`)).json();
+    expect(server.jump_host_id).toBe(bastionId);
+    expect((await testConnection(request, targetId)).success).toBe(true);
+    expect((await testConnection(request, chainedId)).success).toBe(true);
+
+    const files = await (await request.get(`/api/v1/servers/${targetId}/files?path=/`)).json();
+    expect(files.error).toBeUndefined();
+  });
+
+  test('should name the hop that failed', async ({ request }) => {
+    const bastionId = await createServer(request, { ...bastion, password: 'wrongpass' });
+    const targetId = await createServer(request, { ...target, password: 'testpass', jump_host_id: bastionId });
+    const badBastion = await testConnection(request, targetId);
+    expect(badBastion.success).toBe(false);
+    expect(badBastion.message).toContain('jump host "Bastion"');
+
+    await request.put(`/api/v1/servers/${bastionId}`, { data: { ...bastion, password: 'jumppass' } });
+    await request.put(`/api/v1/servers/${targetId}`, { data: { ...target, port: 1, jump_host_id: bastionId } });
+    const badTarget = await testConnection(request, targetId);
+    expect(badTarget.success).toBe(false);
+    expect(badTarget.message).toContain('"Database"');
+    expect(badTarget.message).toContain('via jump host "Bastion"');
+  });
+
+  test('should reject loops and unknown jump hosts', async ({ request }) => {
+    const bastionId = await createServer(request, { ...bastion, password: 'jumppass' });
+    const targetId = await createServer(request, { ...target, password: 'testpass', jump_host_id: bastionId });
+
+    const self = await request.put(`/api/v1/servers/${bastionId}`, { data: { ...bastion, jump_host_id: bastionId } });
+    expect(self.status()).toBe(400);
+    const loop = await request.put(`/api/v1/servers/${bastionId}`, { data: { ...bastion, jump_host_id: targetId } });
+    expect(loop.status()).toBe(400);
+    const unknown = await request.post('/api/v1/servers', { data: { ...target, password: 'testpass', jump_host_id: 99999 } });
+    expect(unknown.status()).toBe(400);
+  });
+
+  test('should not delete a jump host that is in use', async ({ request }) => {
+    const bastionId = await createServer(request, { ...bastion, password: 'jumppass' });
+    const targetId = await createServer(request, { ...target, password: 'testpass', jump_host_id: bastionId });
+
+    expect((await request.delete(`/api/v1/servers/${bastionId}`)).status()).toBe(409);
+
+    await request.put(`/api/v1/servers/${targetId}`, { data: { ...target } });
+    expect((await request.delete(`/api/v1/servers/${bastionId}`)).ok()).toBeTruthy();
+  });
+
+  test('should select the jump host in the server dialog', async ({ page, request }) => {
+    const bastionId = await createServer(request, { ...bastion, password: 'jumppass' });
+    const targetId = await createServer(request, { ...target, password: 'testpass' });
+
+    await page.goto('/servers');
+    await page.getByTestId(`edit-server-btn-${targetId}`).click();
+    await page.getByTestId('input-jump-host').getByRole('combobox').click();
+    await page.getByTestId(`jump-host-option-${bastionId}`).click();
+    await page.getByTestId('update-server-btn').click();
+
+    await expect(page.getByTestId(`server-row-${targetId}`).getByTestId('server-jump-host')).toHaveText('via Bastion');
+    const server = await (await request.get(`/api/v1/servers/${targetId}`)).json();
+    expect(server.jump_host_id).toBe(bastionId);
+  });
+});
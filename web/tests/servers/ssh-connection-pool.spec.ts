@@ -0,0 +1,55 @@
+/**
+ * SSH Connection Pool Tests
+ *
+ * Tests that operations on a server share one pooled SSH connection
+ */
+import { expect, test } from '@playwright/test';
+import type { Server as SSHServer } from 'ssh2';
+import { createServerViaApi, resetDatabase } from '../helpers/api-helpers';
+import { startFakeSSHServer } from '../helpers/fake-ssh-server';
+
+test.describe('SSH connection pool', () => {
+  let sshServer: SSHServer;
+  let connections = 0;
+  const SSH_PORT = 2253;
+
+  test.beforeAll(async () => {
+    sshServer = await startFakeSSHServer(SSH_PORT, 'testuser', 'testpass');
+    sshServer.on('connection', () => {
+      connections++;
+    });
+  });
+
+  test.afterAll(async () => {
+    sshServer?.close();
+  });
+
+  test.beforeEach(async ({ request }) => {
+    await resetDatabase(request);
+    connections = 0;
+  });
+
+  test('should reuse the connection across operations', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Pooled', 'localhost', SSH_PORT, 'testuser', 'testpass');
+
+    for (let i = 0; i < 3; i++) {
+      const result = await (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json();
+      expect(result.success).toBe(true);
+    }
+
+    expect(connections).toBe(1);
+  });
+
+  test('should reconnect after the server settings changed', async ({ request }) => {
+    const serverId = await createServerViaApi(request, 'Pooled', 'localhost', SSH_PORT, 'testuser', 'testpass');
+    expect((await (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json()).success).toBe(true);
+
+    const details = { name: 'Pooled', host: 'localhost', port: SSH_PORT, username: 'testuser', auth_type: 'password' };
+    await request.put(`/api/v1/servers/${serverId}`, { data: { ...details, password: 'wrongpass' } });
+    expect((await (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json()).success).toBe(false);
+
+    await request.put(`/api/v1/servers/${serverId}`, { data: { ...details, password: 'testpass' } });
+    expect((await (await request.post(`/api/v1/servers/${serverId}/test-connection`)).json()).success).toBe(true);
+    expect(connections).toBe(3);
+  });
+});